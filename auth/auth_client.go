@@ -11,6 +11,7 @@ import (
 	"github.com/fabric8-services/fabric8-common/log"
 	authclient "github.com/fabric8-services/fabric8-tenant/auth/client"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
 	goaclient "github.com/goadesign/goa/client"
 )
 
@@ -44,7 +45,10 @@ func (d *doer) Do(ctx context.Context, req *http.Request) (*http.Response, error
 	if d.token != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.token))
 	}
-	return d.target.Do(ctx, req)
+	ctx, span := tracing.StartRequest(ctx, fmt.Sprintf("auth %s %s", req.Method, req.URL.Path), req)
+	resp, err := d.target.Do(ctx, req)
+	tracing.EndRequest(span, resp, err)
+	return resp, err
 }
 
 // ValidateResponse function when given client and response checks if the
@@ -12,12 +12,14 @@ import (
 	"github.com/fabric8-services/fabric8-common/log"
 	authclient "github.com/fabric8-services/fabric8-tenant/auth/client"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
 	"github.com/fabric8-services/fabric8-wit/rest"
 	goaclient "github.com/goadesign/goa/client"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
 	"github.com/pkg/errors"
 	errs "github.com/pkg/errors"
 	"github.com/satori/go.uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/square/go-jose.v2"
 	"net/http"
 )
@@ -31,12 +33,14 @@ type Service interface {
 	ResolveUserToken(ctx context.Context, target, userToken string) (user, accessToken string, err error)
 	ResolveSaToken(ctx context.Context, target string) (username, accessToken string, err error)
 	GetPublicKeys() ([]*rsa.PublicKey, error)
+	InvalidateToken(target, accessToken string)
 }
 
 type authService struct {
 	config        *configuration.Data
 	clientOptions []configuration.HTTPClientOption
 	saToken       string
+	tokenCache    *TokenCache
 }
 
 // NewAuthService retrieves SA OAuth token and creates a service instance that is the main point for communication with auth service
@@ -44,6 +48,7 @@ func NewAuthService(config *configuration.Data, options ...configuration.HTTPCli
 	service := &authService{
 		config:        config,
 		clientOptions: options,
+		tokenCache:    NewTokenCache(config.GetAuthTokenCacheTTL(), config.GetAuthTokenCacheFailureTTL()),
 	}
 	saToken, err := service.getOAuthToken(context.Background())
 	if err != nil {
@@ -59,6 +64,7 @@ func NewAuthServiceWithToken(config *configuration.Data, saToken string, options
 		config:        config,
 		clientOptions: options,
 		saToken:       saToken,
+		tokenCache:    NewTokenCache(config.GetAuthTokenCacheTTL(), config.GetAuthTokenCacheFailureTTL()),
 	}
 }
 
@@ -72,7 +78,12 @@ type User struct {
 
 // GetUser retrieves user data from auth service related to JWT token stored in the given context.
 // It also retrieves OS username and user token for the user's cluster.
-func (s *authService) GetUser(ctx context.Context) (*User, error) {
+func (s *authService) GetUser(ctx context.Context) (user *User, err error) {
+	ctx, span := tracing.Start(ctx, "auth.GetUser")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	userToken := goajwt.ContextJWT(ctx)
 	if userToken == nil {
 		return nil, commonerrs.NewUnauthorizedError("Missing JWT token")
@@ -80,7 +91,11 @@ func (s *authService) GetUser(ctx context.Context) (*User, error) {
 	tenantToken := TenantToken{Token: userToken}
 
 	// fetch the cluster the user belongs to
-	userData, err := s.GetAuthUserData(ctx, tenantToken)
+	userData, found, err := s.tokenCache.GetUserData(userToken.Raw)
+	if !found {
+		userData, err = s.GetAuthUserData(ctx, tenantToken)
+		s.tokenCache.PutUserData(userToken.Raw, userData, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -189,6 +204,11 @@ func (s *authService) ResolveSaToken(ctx context.Context, target string) (userna
 
 // ResolveTargetToken resolves the token for a human user or a service account user on the given target environment (can be GitHub, OpenShift Online, etc.)
 func (s *authService) ResolveTargetToken(ctx context.Context, target, token string, forcePull bool, decode Decode) (username, accessToken string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ResolveTargetToken", attribute.String("target", target), attribute.Bool("forcePull", forcePull))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	// auth can return empty token so validate against that
 	if token == "" {
 		return "", "", fmt.Errorf("token must not be empty")
@@ -199,6 +219,24 @@ func (s *authService) ResolveTargetToken(ctx context.Context, target, token stri
 		return "", "", fmt.Errorf("target must not be empty")
 	}
 
+	// the forced pull validates the token on auth so the cached one isn't used
+	if !forcePull {
+		if cachedUsername, cachedToken, found, cachedErr := s.tokenCache.GetToken(token, target); found {
+			return cachedUsername, cachedToken, cachedErr
+		}
+	}
+	username, accessToken, err = s.retrieveTargetToken(ctx, target, token, forcePull, decode)
+	s.tokenCache.PutToken(token, target, username, accessToken, err)
+	return username, accessToken, err
+}
+
+// InvalidateToken removes the token resolved for the target from the cache so it is resolved again by the next request.
+// It should be called when the target rejects the token
+func (s *authService) InvalidateToken(target, accessToken string) {
+	s.tokenCache.Invalidate(target, accessToken)
+}
+
+func (s *authService) retrieveTargetToken(ctx context.Context, target, token string, forcePull bool, decode Decode) (string, string, error) {
 	client, err := s.newClient(token)
 	if err != nil {
 		return "", "", err
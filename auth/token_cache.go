@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/fabric8-services/fabric8-common/log"
+	authclient "github.com/fabric8-services/fabric8-tenant/auth/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tokenCacheLookupsTotalName = "auth_token_cache_lookups_total"
+
+	// userDataTarget is the target the user data retrieved from auth are cached for
+	userDataTarget = "auth"
+	// tokenExpiryLeeway is subtracted from the expiry of the tokens so the cached token isn't used right before it expires
+	tokenExpiryLeeway = 30 * time.Second
+)
+
+// TokenCacheLookupsCounter counts the lookups in the token cache by the kind of the cached value and the result of the lookup
+var TokenCacheLookupsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: tokenCacheLookupsTotalName,
+	Help: "Total number of the lookups of the user data and tokens in the cache of the values resolved by auth",
+}, []string{"kind", "result"})
+
+// TokenCache caches the user data and the tokens resolved by auth for the subject and the target they were resolved for.
+// The entries expire together with the tokens, but not later than after the configured TTL. The failed resolutions are
+// cached for a short period as well so every request doesn't hit auth again when auth is unavailable.
+type TokenCache struct {
+	lock       sync.Mutex
+	entries    map[tokenCacheKey]*tokenCacheEntry
+	ttl        time.Duration
+	failureTTL time.Duration
+	lastSweep  time.Time
+}
+
+type tokenCacheKey struct {
+	subject string
+	target  string
+}
+
+type tokenCacheEntry struct {
+	username    string
+	accessToken string
+	userData    *authclient.UserDataAttributes
+	err         error
+	expiresAt   time.Time
+}
+
+// NewTokenCache creates a cache whose entries are kept for the given TTL at most. The failures are kept for the failure TTL.
+// The zero TTL disables the cache
+func NewTokenCache(ttl, failureTTL time.Duration) *TokenCache {
+	return &TokenCache{
+		entries:    map[tokenCacheKey]*tokenCacheEntry{},
+		ttl:        ttl,
+		failureTTL: failureTTL,
+		lastSweep:  time.Now(),
+	}
+}
+
+// GetUserData returns the cached user data of the subject of the given token
+func (c *TokenCache) GetUserData(subjectToken string) (userData *authclient.UserDataAttributes, found bool, err error) {
+	entry := c.get("user", subjectToken, userDataTarget)
+	if entry == nil {
+		return nil, false, nil
+	}
+	return entry.userData, true, entry.err
+}
+
+// PutUserData caches the user data (or the failure of their retrieval) of the subject of the given token
+func (c *TokenCache) PutUserData(subjectToken string, userData *authclient.UserDataAttributes, err error) {
+	c.put(subjectToken, userDataTarget, &tokenCacheEntry{userData: userData, err: err})
+}
+
+// GetToken returns the cached token resolved for the subject of the given token and the target
+func (c *TokenCache) GetToken(subjectToken, target string) (username, accessToken string, found bool, err error) {
+	entry := c.get("token", subjectToken, target)
+	if entry == nil {
+		return "", "", false, nil
+	}
+	return entry.username, entry.accessToken, true, entry.err
+}
+
+// PutToken caches the token (or the failure of its resolution) resolved for the subject of the given token and the target
+func (c *TokenCache) PutToken(subjectToken, target, username, accessToken string, err error) {
+	c.put(subjectToken, cleanTarget(target), &tokenCacheEntry{username: username, accessToken: accessToken, err: err})
+}
+
+// Invalidate removes the given token resolved for the target from the cache - it is called when the target rejected the token
+func (c *TokenCache) Invalidate(target, accessToken string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	target = cleanTarget(target)
+	for key, entry := range c.entries {
+		if key.target == target && entry.accessToken == accessToken {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *TokenCache) get(kind, subjectToken, target string) *tokenCacheEntry {
+	if c == nil || c.ttl <= 0 {
+		return nil
+	}
+	subject := subjectOf(subjectToken)
+	if subject == "" {
+		return nil
+	}
+	key := tokenCacheKey{subject: subject, target: cleanTarget(target)}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, found := c.entries[key]
+	if found && !time.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		found = false
+	}
+	switch {
+	case !found:
+		recordTokenCacheLookup(kind, "miss")
+		return nil
+	case entry.err != nil:
+		recordTokenCacheLookup(kind, "failure")
+	default:
+		recordTokenCacheLookup(kind, "hit")
+	}
+	return entry
+}
+
+func (c *TokenCache) put(subjectToken, target string, entry *tokenCacheEntry) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	subject := subjectOf(subjectToken)
+	if subject == "" {
+		return
+	}
+	now := time.Now()
+	if entry.err != nil {
+		entry.expiresAt = now.Add(c.failureTTL)
+	} else {
+		entry.expiresAt = earliestExpiry(now.Add(c.ttl), subjectToken, entry.accessToken)
+	}
+	if !now.Before(entry.expiresAt) {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[tokenCacheKey{subject: subject, target: target}] = entry
+	// the expired entries are removed once per TTL so the cache doesn't grow with the entries that are never looked up again
+	if now.Sub(c.lastSweep) > c.ttl {
+		for key, cached := range c.entries {
+			if !now.Before(cached.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// earliestExpiry returns the earliest of the given time and the expiry of the tokens - the tokens that aren't JWTs are ignored
+func earliestExpiry(expiresAt time.Time, tokens ...string) time.Time {
+	for _, token := range tokens {
+		claims := parseClaims(token)
+		if claims == nil {
+			continue
+		}
+		if exp, ok := claims["exp"].(float64); ok {
+			tokenExpiresAt := time.Unix(int64(exp), 0).Add(-tokenExpiryLeeway)
+			if tokenExpiresAt.Before(expiresAt) {
+				expiresAt = tokenExpiresAt
+			}
+		}
+	}
+	return expiresAt
+}
+
+// subjectOf returns the `sub` claim of the token. The token isn't verified - it has been already verified
+// by the JWT middleware or it is the token of the service account
+func subjectOf(token string) string {
+	claims := parseClaims(token)
+	if claims == nil || claims["sub"] == nil {
+		return ""
+	}
+	return fmt.Sprint(claims["sub"])
+}
+
+func parseClaims(token string) jwt.MapClaims {
+	if token == "" {
+		return nil
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
+func cleanTarget(target string) string {
+	return strings.TrimSuffix(target, "/")
+}
+
+func recordTokenCacheLookup(kind, result string) {
+	if counter, err := TokenCacheLookupsCounter.GetMetricWithLabelValues(kind, result); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": tokenCacheLookupsTotalName,
+			"kind":        kind,
+			"result":      result,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		counter.Inc()
+	}
+}
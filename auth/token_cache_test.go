@@ -0,0 +1,139 @@
+package auth_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/auth"
+	authclient "github.com/fabric8-services/fabric8-tenant/auth/client"
+	testsupport "github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTokenCache(t *testing.T) {
+	userToken := newRawToken(t, map[string]interface{}{"sub": "user_foo"})
+
+	t.Run("resolved token is cached for the subject and target", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, time.Minute)
+		cache.PutToken(userToken, "http://api.cluster1/", "foo", "an_openshift_token", nil)
+
+		// when
+		username, accessToken, found, err := cache.GetToken(newRawToken(t, map[string]interface{}{"sub": "user_foo"}), "http://api.cluster1")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "foo", username)
+		assert.Equal(t, "an_openshift_token", accessToken)
+		_, _, found, _ = cache.GetToken(userToken, "http://api.cluster2/")
+		assert.False(t, found)
+		_, _, found, _ = cache.GetToken(newRawToken(t, map[string]interface{}{"sub": "user_bar"}), "http://api.cluster1/")
+		assert.False(t, found)
+	})
+
+	t.Run("user data are cached for the subject", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, time.Minute)
+		cache.PutUserData(userToken, &authclient.UserDataAttributes{Cluster: ptr.String("http://api.cluster1/")}, nil)
+
+		// when
+		userData, found, err := cache.GetUserData(userToken)
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "http://api.cluster1/", *userData.Cluster)
+	})
+
+	t.Run("failure is cached for the failure TTL", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, time.Minute)
+		cache.PutToken(userToken, "http://api.cluster1/", "", "", fmt.Errorf("auth is unavailable"))
+
+		// when
+		_, _, found, err := cache.GetToken(userToken, "http://api.cluster1/")
+
+		// then
+		assert.True(t, found)
+		testsupport.AssertError(t, err, testsupport.HasMessage("auth is unavailable"))
+	})
+
+	t.Run("failure is not cached with zero failure TTL", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, 0)
+		cache.PutToken(userToken, "http://api.cluster1/", "", "", fmt.Errorf("auth is unavailable"))
+
+		// when
+		_, _, found, err := cache.GetToken(userToken, "http://api.cluster1/")
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("token that is about to expire is not cached", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, time.Minute)
+		expiringToken := newRawToken(t, map[string]interface{}{"sub": "user_foo", "exp": time.Now().Add(10 * time.Second).Unix()})
+		cache.PutToken(userToken, "http://api.cluster1/", "foo", expiringToken, nil)
+		cache.PutToken(expiringToken, "http://api.cluster2/", "foo", "an_openshift_token", nil)
+
+		// when
+		_, _, resolvedFound, _ := cache.GetToken(userToken, "http://api.cluster1/")
+		_, _, subjectFound, _ := cache.GetToken(expiringToken, "http://api.cluster2/")
+
+		// then
+		assert.False(t, resolvedFound)
+		assert.False(t, subjectFound)
+	})
+
+	t.Run("token without subject is not cached", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, time.Minute)
+		noSubject := newRawToken(t, map[string]interface{}{})
+		cache.PutToken(noSubject, "http://api.cluster1/", "foo", "an_openshift_token", nil)
+
+		// when
+		_, _, found, _ := cache.GetToken(noSubject, "http://api.cluster1/")
+
+		// then
+		assert.False(t, found)
+	})
+
+	t.Run("nothing is cached with zero TTL", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(0, time.Minute)
+		cache.PutToken(userToken, "http://api.cluster1/", "foo", "an_openshift_token", nil)
+
+		// when
+		_, _, found, _ := cache.GetToken(userToken, "http://api.cluster1/")
+
+		// then
+		assert.False(t, found)
+	})
+
+	t.Run("token rejected by the target is invalidated", func(t *testing.T) {
+		// given
+		cache := auth.NewTokenCache(time.Minute, time.Minute)
+		cache.PutToken(userToken, "http://api.cluster1/", "foo", "an_openshift_token", nil)
+		cache.PutToken(userToken, "http://api.cluster2/", "foo", "an_openshift_token", nil)
+
+		// when
+		cache.Invalidate("http://api.cluster1", "an_openshift_token")
+
+		// then
+		_, _, found, _ := cache.GetToken(userToken, "http://api.cluster1/")
+		assert.False(t, found)
+		_, _, found, _ = cache.GetToken(userToken, "http://api.cluster2/")
+		assert.True(t, found)
+	})
+}
+
+func newRawToken(t *testing.T, claims map[string]interface{}) string {
+	token, err := testsupport.NewToken(claims, "../test/private_key.pem")
+	require.NoError(t, err)
+	return token.Raw
+}
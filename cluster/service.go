@@ -39,6 +39,9 @@ type Service interface {
 	GetCluster(ctx context.Context, target string) (Cluster, error)
 	GetClusters(ctx context.Context) []Cluster
 	GetUserClusterForType(ctx context.Context, user *auth.User) (ForType, error)
+	GetCacheStatus() CacheStatus
+	GetClustersHealth() []Health
+	InvalidateToken(clusterURL, token string)
 	Start() error
 	Stop()
 }
@@ -50,6 +53,26 @@ type Stats struct {
 	CacheRefreshes int
 }
 
+// CacheStatus says when the cached list of clusters was successfully refreshed for the last time and what was the error
+// of the latest attempt to refresh it, if any
+type CacheStatus struct {
+	LastRefresh time.Time
+	LastError   error
+}
+
+// Health says how the cluster behaved during the refreshes of the cached list of clusters. When the refresh of the cluster fails,
+// the last good entry of the cluster is kept in the cache
+type Health struct {
+	APIURL string
+	// Cached says if there is an entry of the cluster in the cache - there is none if the cluster has never been successfully refreshed
+	Cached bool
+	// TokenValid says if the token of the cluster was resolved and accepted by the cluster during the latest refresh
+	TokenValid  bool
+	LastSuccess time.Time
+	LastError   error
+	LastErrorAt time.Time
+}
+
 type clusterService struct {
 	authService      auth.Service
 	clientOptions    []configuration.HTTPClientOption
@@ -59,6 +82,10 @@ type clusterService struct {
 	cacheMissed      int
 	cacheRefreshes   int
 	cachedClusters   []Cluster
+	cacheStatus      CacheStatus
+	clustersHealth   []Health
+	// refreshing contains the clusters whose rejected tokens are being resolved again
+	refreshing map[string]bool
 }
 
 // NewClusterService creates an instance of service that using the Auth service retrieves information about clusters
@@ -70,10 +97,13 @@ func NewClusterService(refreshInt time.Duration, authService auth.Service, optio
 		clientOptions:    options,
 		cacheRefresher:   cacheRefresher,
 		cacheRefreshLock: &sync.RWMutex{},
+		refreshing:       map[string]bool{},
 	}
 	return service
 }
 
+// Start loads the list of clusters and starts refreshing it periodically. It fails only when the list cannot be retrieved
+// from auth - the clusters whose tokens cannot be resolved or verified are left out and reported in their health
 func (s *clusterService) Start() error {
 	//immediately load the list of clusters before returning
 	err := s.refreshCache(context.Background())
@@ -135,6 +165,99 @@ func (s *clusterService) GetClusters(ctx context.Context) []Cluster {
 
 }
 
+// GetCacheStatus returns the status of the latest refresh of the cached list of clusters
+func (s *clusterService) GetCacheStatus() CacheStatus {
+	s.cacheRefreshLock.RLock()
+	defer s.cacheRefreshLock.RUnlock()
+	return s.cacheStatus
+}
+
+// GetClustersHealth returns the health of all clusters retrieved from auth during the latest refresh
+func (s *clusterService) GetClustersHealth() []Health {
+	s.cacheRefreshLock.RLock()
+	defer s.cacheRefreshLock.RUnlock()
+	health := make([]Health, len(s.clustersHealth))
+	copy(health, s.clustersHealth)
+	return health
+}
+
+// InvalidateToken is called when the given token was rejected by the cluster. The token is dropped from the cache of the resolved tokens
+// and when it is the token of the cached cluster entry, then the entry is marked as stale and refreshed right away instead of
+// waiting for the next refresh of the whole list
+func (s *clusterService) InvalidateToken(clusterURL, token string) {
+	s.authService.InvalidateToken(clusterURL, token)
+
+	s.cacheRefreshLock.Lock()
+	defer s.cacheRefreshLock.Unlock()
+	for _, cl := range s.cachedClusters {
+		if cleanURL(cl.APIURL) != cleanURL(clusterURL) || cl.Token != token {
+			continue
+		}
+		for i, health := range s.clustersHealth {
+			if cleanURL(health.APIURL) == cleanURL(clusterURL) {
+				s.clustersHealth[i].TokenValid = false
+				s.clustersHealth[i].LastError = fmt.Errorf("the token of the cluster %s was rejected by the cluster", cl.APIURL)
+				s.clustersHealth[i].LastErrorAt = time.Now()
+			}
+		}
+		if !s.refreshing[cleanURL(clusterURL)] {
+			s.refreshing[cleanURL(clusterURL)] = true
+			go s.refreshCluster(cl)
+		}
+		return
+	}
+}
+
+// refreshCluster resolves the token of the given stale cluster entry again and replaces the entry in the cache. When it fails,
+// the stale entry is kept and the cluster is refreshed again by the next refresh of the whole list
+func (s *clusterService) refreshCluster(stale Cluster) {
+	ctx := context.Background()
+	refreshed, err := s.loadCluster(ctx, &authclient.ClusterData{
+		APIURL:            stale.APIURL,
+		AppDNS:            stale.AppDNS,
+		ConsoleURL:        stale.ConsoleURL,
+		MetricsURL:        stale.MetricsURL,
+		LoggingURL:        stale.LoggingURL,
+		CapacityExhausted: stale.CapacityExhausted,
+	})
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":         err,
+			"cluster_url": stale.APIURL,
+		}, "failed to refresh the cluster whose token was rejected")
+	}
+
+	s.cacheRefreshLock.Lock()
+	defer s.cacheRefreshLock.Unlock()
+	delete(s.refreshing, cleanURL(stale.APIURL))
+	now := time.Now()
+	for i, health := range s.clustersHealth {
+		if cleanURL(health.APIURL) != cleanURL(stale.APIURL) {
+			continue
+		}
+		if err != nil {
+			s.clustersHealth[i].LastError = err
+			s.clustersHealth[i].LastErrorAt = now
+		} else {
+			s.clustersHealth[i].TokenValid = true
+			s.clustersHealth[i].LastError = nil
+			s.clustersHealth[i].LastSuccess = now
+		}
+	}
+	if err != nil {
+		return
+	}
+	// the cached list is replaced, not changed in place, the same way as by the refresh of the whole list
+	clusters := make([]Cluster, len(s.cachedClusters))
+	for i, cl := range s.cachedClusters {
+		if cleanURL(cl.APIURL) == cleanURL(stale.APIURL) {
+			cl = refreshed
+		}
+		clusters[i] = cl
+	}
+	s.cachedClusters = clusters
+}
+
 func (s *clusterService) Stop() {
 	s.cacheRefresher.Stop()
 }
@@ -147,6 +270,16 @@ func cleanURL(url string) string {
 }
 
 func (s *clusterService) refreshCache(ctx context.Context) error {
+	err := s.loadClusters(ctx)
+	if err != nil {
+		s.cacheRefreshLock.Lock()
+		s.cacheStatus.LastError = err
+		s.cacheRefreshLock.Unlock()
+	}
+	return err
+}
+
+func (s *clusterService) loadClusters(ctx context.Context) error {
 	log.Debug(ctx, nil, "refreshing cached list of clusters...")
 	defer log.Debug(ctx, nil, "refreshed cached list of clusters.")
 	s.cacheRefreshes = s.cacheRefreshes + 1
@@ -174,30 +307,44 @@ func (s *clusterService) refreshCache(ctx context.Context) error {
 		return errors.Wrapf(err, "error from server %q", s.authService.GetAuthURL())
 	}
 
+	// the last good entries and the health of the clusters are kept when the refresh of the cluster fails
+	s.cacheRefreshLock.RLock()
+	lastGood := make(map[string]Cluster, len(s.cachedClusters))
+	for _, cl := range s.cachedClusters {
+		lastGood[cleanURL(cl.APIURL)] = cl
+	}
+	lastHealth := make(map[string]Health, len(s.clustersHealth))
+	for _, health := range s.clustersHealth {
+		lastHealth[cleanURL(health.APIURL)] = health
+	}
+	s.cacheRefreshLock.RUnlock()
+
 	var cls []Cluster
+	var clustersHealth []Health
 	for _, cluster := range clusters.Data {
-		// resolve/obtain the cluster token
-		clusterUser, clusterToken, err := s.authService.ResolveSaToken(ctx, cluster.APIURL)
+		now := time.Now()
+		health := lastHealth[cleanURL(cluster.APIURL)]
+		health.APIURL = cluster.APIURL
+		cl, err := s.loadCluster(ctx, cluster)
 		if err != nil {
-			return errors.Wrapf(err, "Unable to resolve token for cluster %v", cluster.APIURL)
+			log.Error(ctx, map[string]interface{}{
+				"err":         err,
+				"cluster_url": cluster.APIURL,
+			}, "failed to refresh the cluster, the last good entry is kept")
+			health.TokenValid = false
+			health.LastError = err
+			health.LastErrorAt = now
+			cl, health.Cached = lastGood[cleanURL(cluster.APIURL)]
+		} else {
+			health.TokenValid = true
+			health.LastSuccess = now
+			health.LastError = nil
+			health.Cached = true
 		}
-		// verify the token
-		_, err = WhoAmI(ctx, cluster.APIURL, clusterToken, s.clientOptions...)
-		if err != nil {
-			return errors.Wrapf(err, "token retrieved for cluster %v is invalid", cluster.APIURL)
+		clustersHealth = append(clustersHealth, health)
+		if health.Cached {
+			cls = append(cls, cl)
 		}
-
-		cls = append(cls, Cluster{
-			APIURL:            cluster.APIURL,
-			AppDNS:            cluster.AppDNS,
-			ConsoleURL:        cluster.ConsoleURL,
-			MetricsURL:        cluster.MetricsURL,
-			LoggingURL:        cluster.LoggingURL,
-			CapacityExhausted: cluster.CapacityExhausted,
-
-			User:  clusterUser,
-			Token: clusterToken,
-		})
 	}
 	// lock to avoid concurrent writes
 	s.cacheRefreshLock.Lock()
@@ -207,5 +354,34 @@ func (s *clusterService) refreshCache(ctx context.Context) error {
 	}()
 	log.Debug(ctx, nil, "write lock acquired")
 	s.cachedClusters = cls // only replace at the end of this function and within a Write lock scope, i.e., when all retrieved clusters have been processed
+	s.clustersHealth = clustersHealth
+	s.cacheStatus = CacheStatus{LastRefresh: time.Now()}
 	return nil
 }
+
+// loadCluster resolves the token of the given cluster and verifies that the cluster accepts it
+func (s *clusterService) loadCluster(ctx context.Context, cluster *authclient.ClusterData) (Cluster, error) {
+	clusterUser, clusterToken, err := s.authService.ResolveSaToken(ctx, cluster.APIURL)
+	if err != nil {
+		return Cluster{}, errors.Wrapf(err, "Unable to resolve token for cluster %v", cluster.APIURL)
+	}
+	// verify the token
+	_, err = WhoAmI(ctx, cluster.APIURL, clusterToken, s.clientOptions...)
+	if err != nil {
+		// the token is resolved again by the next refresh
+		s.authService.InvalidateToken(cluster.APIURL, clusterToken)
+		return Cluster{}, errors.Wrapf(err, "token retrieved for cluster %v is invalid", cluster.APIURL)
+	}
+
+	return Cluster{
+		APIURL:            cluster.APIURL,
+		AppDNS:            cluster.AppDNS,
+		ConsoleURL:        cluster.ConsoleURL,
+		MetricsURL:        cluster.MetricsURL,
+		LoggingURL:        cluster.LoggingURL,
+		CapacityExhausted: cluster.CapacityExhausted,
+
+		User:  clusterUser,
+		Token: clusterToken,
+	}, nil
+}
@@ -54,6 +54,99 @@ func TestUpdateCluster(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestRefreshKeepsLastGoodEntryOfFailingCluster(t *testing.T) {
+	// given
+	defer gock.Off()
+	otherClusterURL := "http://api.cluster2"
+	testdoubles.MockCommunicationWithAuthSettingCapacityFlag(testsupport.ClusterURL, false, false, otherClusterURL)
+	clusterService, _, _, reset := testdoubles.PrepareConfigClusterAndAuthServiceWithRefreshInt(time.Second, t)
+	defer reset()
+	defer clusterService.Stop()
+	require.Len(t, clusterService.GetClusters(context.Background()), 2)
+
+	// when
+	gock.New(otherClusterURL).
+		Get("/apis/user.openshift.io/v1/users/~").
+		Reply(401)
+	testdoubles.MockCommunicationWithAuthSettingCapacityFlag(testsupport.ClusterURL, true, false, otherClusterURL)
+
+	// then
+	err := testsupport.WaitWithTimeout(3 * time.Second).Until(func() error {
+		for _, health := range clusterService.GetClustersHealth() {
+			if health.LastError != nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("the refresh of the cluster %s hasn't failed yet", otherClusterURL)
+	})
+	require.NoError(t, err)
+	clusters := clusterService.GetClusters(context.Background())
+	require.Len(t, clusters, 2)
+	assert.Equal(t, "http://api.cluster2/", clusters[0].APIURL)
+	assert.False(t, clusters[0].CapacityExhausted)
+	assert.Equal(t, "http://api.cluster1/", clusters[1].APIURL)
+	assert.True(t, clusters[1].CapacityExhausted)
+
+	health := clusterService.GetClustersHealth()
+	require.Len(t, health, 2)
+	assert.True(t, health[0].Cached)
+	assert.False(t, health[0].TokenValid)
+	assert.False(t, health[0].LastSuccess.IsZero())
+	assert.False(t, health[0].LastErrorAt.IsZero())
+	testsupport.AssertError(t, health[0].LastError, testsupport.HasMessageContaining("token retrieved for cluster http://api.cluster2/ is invalid"))
+	assert.True(t, health[1].Cached)
+	assert.True(t, health[1].TokenValid)
+	assert.NoError(t, health[1].LastError)
+}
+
+func TestRejectedTokenRefreshesCluster(t *testing.T) {
+	// given
+	defer gock.Off()
+	testdoubles.MockCommunicationWithAuthSettingCapacityFlag(testsupport.ClusterURL, false, false)
+	clusterService, _, _, reset := testdoubles.PrepareConfigClusterAndAuthServiceWithRefreshInt(time.Hour, t)
+	defer reset()
+	defer clusterService.Stop()
+	clusters := clusterService.GetClusters(context.Background())
+	require.Len(t, clusters, 1)
+	testdoubles.MockCommunicationWithAuthSettingCapacityFlag(testsupport.ClusterURL, false, false)
+
+	t.Run("token not used by the cached entry is ignored", func(t *testing.T) {
+		// when
+		clusterService.InvalidateToken(clusters[0].APIURL, "other-token")
+
+		// then
+		health := clusterService.GetClustersHealth()
+		require.Len(t, health, 1)
+		assert.True(t, health[0].TokenValid)
+		assert.NoError(t, health[0].LastError)
+		assert.False(t, gock.IsDone())
+	})
+
+	t.Run("token of the cached entry refreshes the cluster", func(t *testing.T) {
+		// when
+		clusterService.InvalidateToken(clusters[0].APIURL, clusters[0].Token)
+
+		// then
+		err := testsupport.WaitWithTimeout(3 * time.Second).Until(func() error {
+			health := clusterService.GetClustersHealth()
+			if !gock.IsDone() || !health[0].TokenValid {
+				return fmt.Errorf("the cluster %s hasn't been refreshed yet", clusters[0].APIURL)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		health := clusterService.GetClustersHealth()
+		require.Len(t, health, 1)
+		assert.True(t, health[0].Cached)
+		assert.NoError(t, health[0].LastError)
+		assert.False(t, health[0].LastErrorAt.IsZero())
+		refreshed := clusterService.GetClusters(context.Background())
+		require.Len(t, refreshed, 1)
+		assert.Equal(t, clusters[0].APIURL, refreshed[0].APIURL)
+		assert.Equal(t, clusters[0].Token, refreshed[0].Token)
+	})
+}
+
 func TestResolveCluster(t *testing.T) {
 
 	// given
@@ -19,6 +19,7 @@ func WhoAmI(ctx context.Context, clusterURL string, token string, clientOptions
 	if err != nil {
 		return "", errors.Wrapf(err, "unable to retrieve the username from the `whoami` API endpoint")
 	}
+	req = req.WithContext(ctx)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 	client := http.DefaultClient
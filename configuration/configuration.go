@@ -29,6 +29,8 @@ const (
 	varPostgresConnectionMaxOpen       = "postgres.connection.maxopen"
 	varHTTPAddress                     = "http.address"
 	varMetricsHTTPAddress              = "metrics.http.address"
+	varShutdownTimeout                 = "shutdown.timeout"
+	varReadinessCheckTimeout           = "readiness.check.timeout"
 	varDeveloperModeEnabled            = "developer.mode.enabled"
 	varKeycloakClientID                = "keycloak.client.id"
 	varKeycloakRealm                   = "keycloak.realm"
@@ -47,15 +49,76 @@ const (
 	varLogJSON                         = "log.json"
 	varEnvironment                     = "environment"
 	varSentryDSN                       = "sentry.dsn"
+	varTracingExporter                 = "tracing.exporter"
+	varTracingOTLPEndpoint             = "tracing.otlp.endpoint"
+	varTracingFile                     = "tracing.file"
+	varTracingSampleRatio              = "tracing.sample.ratio"
 	varAutomatedUpdateRetrySleep       = "automated.update.retry.sleep"
 	varAutomatedUpdateTimeGap          = "automated.update.time.gap"
 	varAutomatedUpdateEnabled          = "automated.update.enabled"
 
+	varAutomatedUpdateCanaryPercentage          = "automated.update.canary.percentage"
+	varAutomatedUpdateCanaryToggle              = "automated.update.canary.toggle"
+	varAutomatedUpdateCanaryEmailDomain         = "automated.update.canary.email.domain"
+	varAutomatedUpdateCanaryMaxFailedPercentage = "automated.update.canary.max.failed.percentage"
+	varAutomatedUpdateHaltConsecutiveFailures   = "automated.update.halt.consecutive.failures"
+	varAutomatedUpdateHaltFailedPercentage      = "automated.update.halt.failed.percentage"
+	varAutomatedUpdateHaltMinAttempts           = "automated.update.halt.min.attempts"
+	varAutomatedUpdateHaltScope                 = "automated.update.halt.scope"
+	varAutomatedUpdateConcurrency               = "automated.update.concurrency"
+	varAutomatedUpdatePacingMaxDelay            = "automated.update.pacing.max.delay"
+	varAutomatedUpdatePacingTargetLatency       = "automated.update.pacing.target.latency"
+	varAutomatedUpdateWindows                   = "automated.update.windows"
+	varAutomatedUpdateClusterWindows            = "automated.update.cluster.windows"
+	varAutomatedUpdateWindowsLocation           = "automated.update.windows.location"
+	varAutomatedUpdateSchedulerInterval         = "automated.update.scheduler.interval"
+	varAutomatedUpdatePauseCheckInterval        = "automated.update.pause.check.interval"
+	varAutomatedUpdateRequestsCheckInterval     = "automated.update.requests.check.interval"
+	varAutomatedUpdateWorkDistributed           = "automated.update.work.distributed"
+	varAutomatedUpdateWorkPollInterval          = "automated.update.work.poll.interval"
+	varAutomatedUpdateWorkHeartbeatInterval     = "automated.update.work.heartbeat.interval"
+	varAutomatedUpdateWorkStaleTimeout          = "automated.update.work.stale.timeout"
+	varAutomatedUpdateWorkMaxAttempts           = "automated.update.work.max.attempts"
+
+	varLeaderLeaseDuration = "leader.lease.duration"
+	varLeaderRenewInterval = "leader.renew.interval"
+
+	varWebhookDeliveryPollInterval   = "webhook.delivery.poll.interval"
+	varWebhookDeliveryBatchSize      = "webhook.delivery.batch.size"
+	varWebhookDeliveryTimeout        = "webhook.delivery.timeout"
+	varWebhookDeliveryMaxAttempts    = "webhook.delivery.max.attempts"
+	varWebhookDeliveryInitialBackoff = "webhook.delivery.initial.backoff"
+	varWebhookDeliveryMaxBackoff     = "webhook.delivery.max.backoff"
+
+	varTenantEventsKeepAliveInterval = "tenant.events.keepalive.interval"
+	varTenantEventsBufferSize        = "tenant.events.buffer.size"
+
+	varJanitorEnabled       = "janitor.enabled"
+	varJanitorCheckInterval = "janitor.check.interval"
+	varJanitorStuckTimeout  = "janitor.stuck.timeout"
+	varJanitorBatchSize     = "janitor.batch.size"
+
+	varPurgeEnabled       = "purge.enabled"
+	varPurgeCheckInterval = "purge.check.interval"
+	varPurgeRetention     = "purge.retention"
+	varPurgeBatchSize     = "purge.batch.size"
+
+	varIdlerEnabled          = "idler.enabled"
+	varIdlerCheckInterval    = "idler.check.interval"
+	varIdlerInactivityPeriod = "idler.inactivity.period"
+	varIdlerBatchSize        = "idler.batch.size"
+
 	varAuthURL              = "auth.url"
 	varClustersRefreshDelay = "cluster.refresh.delay"
 	varAuthClientID         = "service.account.id"
 	varClientSecret         = "service.account.secret"
 	varAuthTokenKey         = "auth.token.key"
+
+	varAuthTokenCacheTTL        = "auth.token.cache.ttl"
+	varAuthTokenCacheFailureTTL = "auth.token.cache.failure.ttl"
+
+	varCircuitBreakerFailureThreshold = "circuit.breaker.failure.threshold"
+	varCircuitBreakerOpenDuration     = "circuit.breaker.open.duration"
 )
 
 // Data encapsulates the Viper configuration object which stores the configuration data in-memory.
@@ -120,6 +183,10 @@ func (c *Data) setConfigDefaults() {
 	//-----
 	c.v.SetDefault(varHTTPAddress, "0.0.0.0:8080")
 	c.v.SetDefault(varMetricsHTTPAddress, "0.0.0.0:8080")
+	// How long the service waits for the requests and operations in flight when it is shutting down
+	c.v.SetDefault(varShutdownTimeout, 25*time.Second)
+	// How long the readiness endpoint waits for the responses of the auth service and of the clusters
+	c.v.SetDefault(varReadinessCheckTimeout, 5*time.Second)
 
 	//-----
 	// Misc
@@ -139,6 +206,18 @@ func (c *Data) setConfigDefaults() {
 	c.v.SetDefault(varDeveloperModeEnabled, false)
 	c.v.SetDefault(varLogLevel, defaultLogLevel)
 
+	//-----
+	// Tracing
+	//-----
+	// The exporter the spans are sent by - one of "otlp", "stdout", "file"; the tracing is disabled when empty
+	c.v.SetDefault(varTracingExporter, "")
+	// The host and port of the OTLP collector accepting the spans over HTTP
+	c.v.SetDefault(varTracingOTLPEndpoint, "localhost:4318")
+	// The file the spans are written to by the "file" exporter
+	c.v.SetDefault(varTracingFile, "traces.json")
+	// The ratio of the traces that are sampled - the traces started by another service follow the decision of that service
+	c.v.SetDefault(varTracingSampleRatio, 1.0)
+
 	//-----
 	// Auth
 	// ----
@@ -149,6 +228,94 @@ func (c *Data) setConfigDefaults() {
 	c.v.SetDefault(varAutomatedUpdateRetrySleep, 10*time.Minute)
 	c.v.SetDefault(varAutomatedUpdateTimeGap, 4*time.Second)
 	c.v.SetDefault(varAutomatedUpdateEnabled, false)
+
+	// Canary stage of the automated update - disabled by default
+	c.v.SetDefault(varAutomatedUpdateCanaryPercentage, 0)
+	c.v.SetDefault(varAutomatedUpdateCanaryToggle, "")
+	c.v.SetDefault(varAutomatedUpdateCanaryEmailDomain, "")
+	c.v.SetDefault(varAutomatedUpdateCanaryMaxFailedPercentage, 10)
+
+	// Thresholds of failed tenant updates that halt the automated update - 0 disables the threshold
+	c.v.SetDefault(varAutomatedUpdateHaltConsecutiveFailures, 10)
+	c.v.SetDefault(varAutomatedUpdateHaltFailedPercentage, 50)
+	c.v.SetDefault(varAutomatedUpdateHaltMinAttempts, 20)
+	c.v.SetDefault(varAutomatedUpdateHaltScope, HaltScopeCluster)
+
+	// Number of tenants updated in parallel per cluster and the adaptive pacing of the updates
+	c.v.SetDefault(varAutomatedUpdateConcurrency, 1)
+	c.v.SetDefault(varAutomatedUpdatePacingMaxDelay, time.Minute)
+	c.v.SetDefault(varAutomatedUpdatePacingTargetLatency, 30*time.Second)
+
+	// Maintenance windows of the automated update - no window means that the update runs only once at startup
+	c.v.SetDefault(varAutomatedUpdateWindows, "")
+	c.v.SetDefault(varAutomatedUpdateClusterWindows, "")
+	c.v.SetDefault(varAutomatedUpdateWindowsLocation, "UTC")
+	c.v.SetDefault(varAutomatedUpdateSchedulerInterval, time.Minute)
+
+	// How often the workers of a paused update check if the update was resumed
+	c.v.SetDefault(varAutomatedUpdatePauseCheckInterval, 10*time.Second)
+
+	// How often the leader checks the update requests received by other replicas
+	c.v.SetDefault(varAutomatedUpdateRequestsCheckInterval, 5*time.Second)
+
+	// Distribution of the batches of tenants among all replicas - a batch of a replica that stopped reporting
+	// the heartbeat is reassigned to another one
+	c.v.SetDefault(varAutomatedUpdateWorkDistributed, false)
+	c.v.SetDefault(varAutomatedUpdateWorkPollInterval, 2*time.Second)
+	c.v.SetDefault(varAutomatedUpdateWorkHeartbeatInterval, 5*time.Second)
+	c.v.SetDefault(varAutomatedUpdateWorkStaleTimeout, 30*time.Second)
+	c.v.SetDefault(varAutomatedUpdateWorkMaxAttempts, 3)
+
+	// Leader election of the replica that drives the updates
+	c.v.SetDefault(varLeaderLeaseDuration, 15*time.Second)
+	c.v.SetDefault(varLeaderRenewInterval, 3*time.Second)
+
+	// Delivery of the tenant lifecycle events to the webhook subscriptions
+	c.v.SetDefault(varWebhookDeliveryPollInterval, 2*time.Second)
+	c.v.SetDefault(varWebhookDeliveryBatchSize, 20)
+	c.v.SetDefault(varWebhookDeliveryTimeout, 10*time.Second)
+	c.v.SetDefault(varWebhookDeliveryMaxAttempts, 10)
+	// The delay before the next attempt is doubled after every failed one up to the maximal backoff
+	c.v.SetDefault(varWebhookDeliveryInitialBackoff, 5*time.Second)
+	c.v.SetDefault(varWebhookDeliveryMaxBackoff, time.Hour)
+
+	// Streams of the namespace changes and of the progress of the operations sent to the tenant as server-sent events
+	// The comment sent in the interval keeps the idle connection open in the proxies
+	c.v.SetDefault(varTenantEventsKeepAliveInterval, 15*time.Second)
+	c.v.SetDefault(varTenantEventsBufferSize, 100)
+
+	// Janitor of the namespaces stuck in the provisioning or updating state
+	c.v.SetDefault(varJanitorEnabled, true)
+	c.v.SetDefault(varJanitorCheckInterval, 5*time.Minute)
+	// The namespace is considered stuck when its state hasn't been changed for the timeout
+	c.v.SetDefault(varJanitorStuckTimeout, 30*time.Minute)
+	c.v.SetDefault(varJanitorBatchSize, 50)
+
+	// Purge of the soft-deleted tenants and namespaces
+	c.v.SetDefault(varPurgeEnabled, true)
+	c.v.SetDefault(varPurgeCheckInterval, time.Hour)
+	// The deleted tenant can be restored and its names aren't reused within the retention period
+	c.v.SetDefault(varPurgeRetention, 30*24*time.Hour)
+	c.v.SetDefault(varPurgeBatchSize, 100)
+
+	// Idler of the namespaces of the inactive tenants - it scales down the workloads of the users, so it has to be enabled explicitly
+	c.v.SetDefault(varIdlerEnabled, false)
+	c.v.SetDefault(varIdlerCheckInterval, 15*time.Minute)
+	// The tenant is considered inactive when the user hasn't accessed the tenant and auth hasn't reported any activity for the period
+	c.v.SetDefault(varIdlerInactivityPeriod, 7*24*time.Hour)
+	c.v.SetDefault(varIdlerBatchSize, 50)
+
+	// Cache of the user data and tokens resolved by auth - the tokens are cached until they expire, but not longer than the TTL.
+	// The zero TTL disables the cache
+	c.v.SetDefault(varAuthTokenCacheTTL, 5*time.Minute)
+	// The failures are cached for a short time so every request doesn't hit auth when it is unavailable
+	c.v.SetDefault(varAuthTokenCacheFailureTTL, 10*time.Second)
+
+	// Circuit breaker per cluster - the requests to the cluster fail fast after the given number of consecutive connection errors
+	// or 5xx responses. After the open duration a single request is let through to check if the cluster is back.
+	// The zero threshold disables the circuit breakers
+	c.v.SetDefault(varCircuitBreakerFailureThreshold, 5)
+	c.v.SetDefault(varCircuitBreakerOpenDuration, 30*time.Second)
 }
 
 // GetPostgresHost returns the postgres host as set via default, config file, or environment variable
@@ -229,6 +396,18 @@ func (c *Data) GetMetricsHTTPAddress() string {
 	return c.v.GetString(varMetricsHTTPAddress)
 }
 
+// GetShutdownTimeout returns how long the service waits for the requests and operations in flight when it is shutting down.
+// It should be shorter than the termination grace period of the pod
+func (c *Data) GetShutdownTimeout() time.Duration {
+	return c.v.GetDuration(varShutdownTimeout)
+}
+
+// GetReadinessCheckTimeout returns how long the readiness endpoint waits for the responses of the dependencies.
+// It should be shorter than the timeout of the readiness probe
+func (c *Data) GetReadinessCheckTimeout() time.Duration {
+	return c.v.GetDuration(varReadinessCheckTimeout)
+}
+
 // IsDeveloperModeEnabled returns if development related features (as set via default, config file, or environment variable),
 // e.g. token generation endpoint are enabled
 func (c *Data) IsDeveloperModeEnabled() bool {
@@ -304,6 +483,26 @@ func (c *Data) GetAuthURL() string {
 	return c.v.GetString(varAuthURL)
 }
 
+// GetAuthTokenCacheTTL returns the maximal time the user data and tokens resolved by auth are cached for
+func (c *Data) GetAuthTokenCacheTTL() time.Duration {
+	return c.v.GetDuration(varAuthTokenCacheTTL)
+}
+
+// GetAuthTokenCacheFailureTTL returns how long the failures of the resolution of the user data and tokens are cached for
+func (c *Data) GetAuthTokenCacheFailureTTL() time.Duration {
+	return c.v.GetDuration(varAuthTokenCacheFailureTTL)
+}
+
+// GetCircuitBreakerFailureThreshold returns the number of consecutive failed requests to a cluster that open its circuit breaker
+func (c *Data) GetCircuitBreakerFailureThreshold() int {
+	return c.v.GetInt(varCircuitBreakerFailureThreshold)
+}
+
+// GetCircuitBreakerOpenDuration returns how long the requests to a cluster fail fast before the cluster is checked again
+func (c *Data) GetCircuitBreakerOpenDuration() time.Duration {
+	return c.v.GetDuration(varCircuitBreakerOpenDuration)
+}
+
 // GetClustersRefreshDelay returns delay of clusters refresh (in minutes)
 func (c *Data) GetClustersRefreshDelay() time.Duration {
 	return time.Duration(c.v.GetInt(varClustersRefreshDelay) * int(time.Minute))
@@ -314,6 +513,26 @@ func (c *Data) GetTogglesURL() string {
 	return c.v.GetString(varTogglesURL)
 }
 
+// GetTracingExporter returns the name of the exporter the spans are sent by. An empty string means that the tracing is disabled
+func (c *Data) GetTracingExporter() string {
+	return c.v.GetString(varTracingExporter)
+}
+
+// GetTracingOTLPEndpoint returns the host and port of the OTLP collector
+func (c *Data) GetTracingOTLPEndpoint() string {
+	return c.v.GetString(varTracingOTLPEndpoint)
+}
+
+// GetTracingFile returns the path of the file the spans are written to by the "file" exporter
+func (c *Data) GetTracingFile() string {
+	return c.v.GetString(varTracingFile)
+}
+
+// GetTracingSampleRatio returns the ratio of the traces that are sampled
+func (c *Data) GetTracingSampleRatio() float64 {
+	return c.v.GetFloat64(varTracingSampleRatio)
+}
+
 // UseOpenshiftCurrentCluster returns if we should use the current cluster to provision tenant service
 func (c *Data) UseOpenshiftCurrentCluster() bool {
 	return c.v.GetBool(varOpenshiftUseCurrentCluster)
@@ -351,7 +570,8 @@ func (c *Data) GetAutomatedUpdateRetrySleep() time.Duration {
 	return c.v.GetDuration(varAutomatedUpdateRetrySleep)
 }
 
-// GetAutomatedUpdateTimeGap returns the duration the automated update should wait after single tenant update
+// GetAutomatedUpdateTimeGap returns the duration the automated update should wait after single tenant update.
+// It is the minimal delay used by the adaptive pacing of the updates
 func (c *Data) GetAutomatedUpdateTimeGap() time.Duration {
 	return c.v.GetDuration(varAutomatedUpdateTimeGap)
 }
@@ -361,6 +581,243 @@ func (c *Data) IsAutomatedUpdateEnabled() bool {
 	return c.v.GetBool(varAutomatedUpdateEnabled)
 }
 
+// GetAutomatedUpdateCanaryPercentage returns the percentage of outdated tenants that should be updated in the canary stage
+// before the update continues with the rest of the tenants
+func (c *Data) GetAutomatedUpdateCanaryPercentage() int {
+	return c.v.GetInt(varAutomatedUpdateCanaryPercentage)
+}
+
+// GetAutomatedUpdateCanaryToggle returns the name of the feature toggle that selects tenants updated in the canary stage
+func (c *Data) GetAutomatedUpdateCanaryToggle() string {
+	return c.v.GetString(varAutomatedUpdateCanaryToggle)
+}
+
+// GetAutomatedUpdateCanaryEmailDomain returns the email domain of (internal) users that are updated in the canary stage
+func (c *Data) GetAutomatedUpdateCanaryEmailDomain() string {
+	return c.v.GetString(varAutomatedUpdateCanaryEmailDomain)
+}
+
+// GetAutomatedUpdateCanaryMaxFailedPercentage returns the maximal percentage of failed tenant updates in the canary stage
+// that is still acceptable to proceed with the rest of the tenants
+func (c *Data) GetAutomatedUpdateCanaryMaxFailedPercentage() int {
+	return c.v.GetInt(varAutomatedUpdateCanaryMaxFailedPercentage)
+}
+
+// IsAutomatedUpdateCanaryEnabled returns if any canary cohort is configured for the automated update
+func (c *Data) IsAutomatedUpdateCanaryEnabled() bool {
+	return c.GetAutomatedUpdateCanaryPercentage() > 0 || c.GetAutomatedUpdateCanaryToggle() != "" ||
+		c.GetAutomatedUpdateCanaryEmailDomain() != ""
+}
+
+// GetAutomatedUpdateHaltConsecutiveFailures returns the number of consecutive failed tenant updates that halts the automated update.
+// Zero means that the threshold is disabled
+func (c *Data) GetAutomatedUpdateHaltConsecutiveFailures() int {
+	return c.v.GetInt(varAutomatedUpdateHaltConsecutiveFailures)
+}
+
+// GetAutomatedUpdateHaltFailedPercentage returns the percentage of failed tenant updates that halts the automated update.
+// Zero means that the threshold is disabled
+func (c *Data) GetAutomatedUpdateHaltFailedPercentage() int {
+	return c.v.GetInt(varAutomatedUpdateHaltFailedPercentage)
+}
+
+// GetAutomatedUpdateHaltMinAttempts returns the minimal number of tenant updates that have to be done before
+// the percentage of failed updates is evaluated
+func (c *Data) GetAutomatedUpdateHaltMinAttempts() int {
+	return c.v.GetInt(varAutomatedUpdateHaltMinAttempts)
+}
+
+// GetAutomatedUpdateHaltScope returns if the failure thresholds halt only the affected cluster (HaltScopeCluster)
+// or the whole update (HaltScopeAll)
+func (c *Data) GetAutomatedUpdateHaltScope() string {
+	return c.v.GetString(varAutomatedUpdateHaltScope)
+}
+
+// GetAutomatedUpdateConcurrency returns the number of tenants that are updated in parallel on one cluster
+func (c *Data) GetAutomatedUpdateConcurrency() int {
+	return c.v.GetInt(varAutomatedUpdateConcurrency)
+}
+
+// GetAutomatedUpdatePacingMaxDelay returns the maximal duration the automated update waits after single tenant update
+// when the cluster API is slow or failing
+func (c *Data) GetAutomatedUpdatePacingMaxDelay() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdatePacingMaxDelay)
+}
+
+// GetAutomatedUpdatePacingTargetLatency returns the duration of single tenant update that is considered as healthy -
+// longer updates slow down the pace of the automated update
+func (c *Data) GetAutomatedUpdatePacingTargetLatency() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdatePacingTargetLatency)
+}
+
+// GetAutomatedUpdateWindows returns the global maintenance windows the automated update is allowed to run in.
+// The windows are separated by semicolon and each of them consists of a cron expression and a duration, eg. "0 22 * * 1-5 6h"
+func (c *Data) GetAutomatedUpdateWindows() string {
+	return c.v.GetString(varAutomatedUpdateWindows)
+}
+
+// GetAutomatedUpdateClusterWindows returns the cluster specific maintenance windows that override the global ones.
+// The windows are separated by semicolon and each of them is in the format <cluster-url>=<cron expression> <duration>
+func (c *Data) GetAutomatedUpdateClusterWindows() string {
+	return c.v.GetString(varAutomatedUpdateClusterWindows)
+}
+
+// GetAutomatedUpdateWindowsLocation returns the name of the location (time zone) the maintenance windows are defined in
+func (c *Data) GetAutomatedUpdateWindowsLocation() string {
+	return c.v.GetString(varAutomatedUpdateWindowsLocation)
+}
+
+// GetAutomatedUpdateSchedulerInterval returns how often the scheduler checks if a maintenance window has been opened
+func (c *Data) GetAutomatedUpdateSchedulerInterval() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdateSchedulerInterval)
+}
+
+// GetAutomatedUpdatePauseCheckInterval returns how often the workers of a paused update check if the update was resumed
+func (c *Data) GetAutomatedUpdatePauseCheckInterval() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdatePauseCheckInterval)
+}
+
+// GetAutomatedUpdateRequestsCheckInterval returns how often the leader checks the update requests received by other replicas
+func (c *Data) GetAutomatedUpdateRequestsCheckInterval() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdateRequestsCheckInterval)
+}
+
+// IsAutomatedUpdateWorkDistributed returns if the batches of tenants are updated by all replicas instead of only by the one driving the update
+func (c *Data) IsAutomatedUpdateWorkDistributed() bool {
+	return c.v.GetBool(varAutomatedUpdateWorkDistributed)
+}
+
+// GetAutomatedUpdateWorkPollInterval returns how often the replicas check if there is a batch of tenants to be updated
+// and how often the driving replica checks the progress of the batches
+func (c *Data) GetAutomatedUpdateWorkPollInterval() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdateWorkPollInterval)
+}
+
+// GetAutomatedUpdateWorkHeartbeatInterval returns how often a replica reports that it is still updating the claimed batch
+func (c *Data) GetAutomatedUpdateWorkHeartbeatInterval() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdateWorkHeartbeatInterval)
+}
+
+// GetAutomatedUpdateWorkStaleTimeout returns how long a claimed batch can be without a heartbeat before it is reassigned
+func (c *Data) GetAutomatedUpdateWorkStaleTimeout() time.Duration {
+	return c.v.GetDuration(varAutomatedUpdateWorkStaleTimeout)
+}
+
+// GetAutomatedUpdateWorkMaxAttempts returns how many times a batch can be claimed before it is abandoned
+func (c *Data) GetAutomatedUpdateWorkMaxAttempts() int {
+	return c.v.GetInt(varAutomatedUpdateWorkMaxAttempts)
+}
+
+// GetLeaderLeaseDuration returns how long the leadership lease is valid when it is not renewed
+func (c *Data) GetLeaderLeaseDuration() time.Duration {
+	return c.v.GetDuration(varLeaderLeaseDuration)
+}
+
+// GetLeaderRenewInterval returns how often the replicas try to acquire or renew the leadership lease
+func (c *Data) GetLeaderRenewInterval() time.Duration {
+	return c.v.GetDuration(varLeaderRenewInterval)
+}
+
+// GetWebhookDeliveryPollInterval returns how often the pending deliveries of the webhook events are looked up
+func (c *Data) GetWebhookDeliveryPollInterval() time.Duration {
+	return c.v.GetDuration(varWebhookDeliveryPollInterval)
+}
+
+// GetWebhookDeliveryBatchSize returns the maximal number of deliveries a replica claims at once
+func (c *Data) GetWebhookDeliveryBatchSize() int {
+	return c.v.GetInt(varWebhookDeliveryBatchSize)
+}
+
+// GetWebhookDeliveryTimeout returns how long a replica waits for the response of the webhook
+func (c *Data) GetWebhookDeliveryTimeout() time.Duration {
+	return c.v.GetDuration(varWebhookDeliveryTimeout)
+}
+
+// GetWebhookDeliveryMaxAttempts returns how many times the delivery of an event is attempted before it is marked as failed
+func (c *Data) GetWebhookDeliveryMaxAttempts() int {
+	return c.v.GetInt(varWebhookDeliveryMaxAttempts)
+}
+
+// GetWebhookDeliveryInitialBackoff returns the delay before the second attempt of the delivery
+func (c *Data) GetWebhookDeliveryInitialBackoff() time.Duration {
+	return c.v.GetDuration(varWebhookDeliveryInitialBackoff)
+}
+
+// GetWebhookDeliveryMaxBackoff returns the maximal delay between two attempts of the delivery
+func (c *Data) GetWebhookDeliveryMaxBackoff() time.Duration {
+	return c.v.GetDuration(varWebhookDeliveryMaxBackoff)
+}
+
+// GetTenantEventsKeepAliveInterval returns how often a comment is sent to the idle stream of the tenant events
+func (c *Data) GetTenantEventsKeepAliveInterval() time.Duration {
+	return c.v.GetDuration(varTenantEventsKeepAliveInterval)
+}
+
+// GetTenantEventsBufferSize returns how many events can wait for a slow reader of the stream before the next ones are dropped
+func (c *Data) GetTenantEventsBufferSize() int {
+	return c.v.GetInt(varTenantEventsBufferSize)
+}
+
+// IsJanitorEnabled returns if the namespaces stuck in the provisioning or updating state are repaired in background
+func (c *Data) IsJanitorEnabled() bool {
+	return c.v.GetBool(varJanitorEnabled)
+}
+
+// GetJanitorCheckInterval returns how often the leader looks up the stuck namespaces
+func (c *Data) GetJanitorCheckInterval() time.Duration {
+	return c.v.GetDuration(varJanitorCheckInterval)
+}
+
+// GetJanitorStuckTimeout returns how long the namespace can stay in the provisioning or updating state before it is considered stuck
+func (c *Data) GetJanitorStuckTimeout() time.Duration {
+	return c.v.GetDuration(varJanitorStuckTimeout)
+}
+
+// GetJanitorBatchSize returns the maximal number of the stuck namespaces repaired in one check
+func (c *Data) GetJanitorBatchSize() int {
+	return c.v.GetInt(varJanitorBatchSize)
+}
+
+// IsPurgeEnabled returns if the soft-deleted tenants and namespaces are permanently removed from DB after the retention period
+func (c *Data) IsPurgeEnabled() bool {
+	return c.v.GetBool(varPurgeEnabled)
+}
+
+// GetPurgeCheckInterval returns how often the leader looks up the soft-deleted entities to be purged
+func (c *Data) GetPurgeCheckInterval() time.Duration {
+	return c.v.GetDuration(varPurgeCheckInterval)
+}
+
+// GetPurgeRetention returns how long the soft-deleted tenants and namespaces are kept in DB
+func (c *Data) GetPurgeRetention() time.Duration {
+	return c.v.GetDuration(varPurgeRetention)
+}
+
+// GetPurgeBatchSize returns the maximal number of the tenants and of the namespaces purged in one check
+func (c *Data) GetPurgeBatchSize() int {
+	return c.v.GetInt(varPurgeBatchSize)
+}
+
+// IsIdlerEnabled returns if the namespaces of the inactive tenants are idled
+func (c *Data) IsIdlerEnabled() bool {
+	return c.v.GetBool(varIdlerEnabled)
+}
+
+// GetIdlerCheckInterval returns how often the leader looks up the namespaces of the inactive tenants to be idled
+func (c *Data) GetIdlerCheckInterval() time.Duration {
+	return c.v.GetDuration(varIdlerCheckInterval)
+}
+
+// GetIdlerInactivityPeriod returns how long the user has to be inactive for the namespaces of the tenant to be idled
+func (c *Data) GetIdlerInactivityPeriod() time.Duration {
+	return c.v.GetDuration(varIdlerInactivityPeriod)
+}
+
+// GetIdlerBatchSize returns the maximal number of the namespaces idled in one check
+func (c *Data) GetIdlerBatchSize() int {
+	return c.v.GetInt(varIdlerBatchSize)
+}
+
 // IsLogJSON returns if we should log json format (as set via config file or environment variable)
 func (c *Data) IsLogJSON() bool {
 	if c.v.IsSet(varLogJSON) {
@@ -420,3 +877,10 @@ const (
 	defaultWitURL     = "https://api.prod-preview.openshift.io/api/"
 	defaultTogglesURL = "http://f8toggles/api"
 )
+
+const (
+	// HaltScopeCluster means that only the cluster where the failure threshold was reached is halted
+	HaltScopeCluster = "cluster"
+	// HaltScopeAll means that the whole automated update is halted when any failure threshold is reached
+	HaltScopeAll = "all"
+)
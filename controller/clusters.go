@@ -0,0 +1,93 @@
+package controller
+
+import (
+	commonauth "github.com/fabric8-services/fabric8-common/auth"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/app"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/jsonapi"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/goadesign/goa"
+	"strings"
+)
+
+// ClustersController implements the clusters resource.
+type ClustersController struct {
+	*goa.Controller
+	tenantService  tenant.Service
+	clusterService cluster.Service
+}
+
+// NewClustersController creates a clusters controller.
+func NewClustersController(service *goa.Service, tenantService tenant.Service, clusterService cluster.Service) *ClustersController {
+	return &ClustersController{
+		Controller:     service.NewController("ClustersController"),
+		tenantService:  tenantService,
+		clusterService: clusterService,
+	}
+}
+
+// List runs the list action.
+func (c *ClustersController) List(ctx *app.ListClustersContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	counts, err := c.tenantService.CountTenantsPerCluster()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of the numbers of tenants per cluster failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	tenantsCount := map[string]int{}
+	for _, count := range counts {
+		tenantsCount[trimSlash(count.MasterURL)] += count.Count
+	}
+
+	health := c.clusterService.GetClustersHealth()
+	result := &app.ClusterDataList{
+		Data: []*app.ClusterData{},
+		Meta: &app.ClusterListMeta{
+			TotalCount: len(health),
+		},
+	}
+	for _, clusterHealth := range health {
+		// the cluster isn't found when it is not cached
+		cl, _ := c.clusterService.GetCluster(ctx, clusterHealth.APIURL)
+		result.Data = append(result.Data, convertCluster(clusterHealth, cl, tenantsCount[trimSlash(clusterHealth.APIURL)]))
+	}
+	return ctx.OK(result)
+}
+
+func convertCluster(health cluster.Health, cl cluster.Cluster, tenantsCount int) *app.ClusterData {
+	data := &app.ClusterData{
+		APIURL:         ptr.String(health.APIURL),
+		Cached:         ptr.Bool(health.Cached),
+		TokenValid:     ptr.Bool(health.TokenValid),
+		CircuitBreaker: ptr.String(string(openshift.GetCircuitState(health.APIURL))),
+		TenantsCount:   ptr.Int(tenantsCount),
+	}
+	if cl.APIURL != "" {
+		data.ConsoleURL = optional(cl.ConsoleURL)
+		data.CapacityExhausted = ptr.Bool(cl.CapacityExhausted)
+	}
+	if !health.LastSuccess.IsZero() {
+		data.LastSuccess = ptr.Time(health.LastSuccess)
+	}
+	if health.LastError != nil {
+		data.LastError = ptr.String(health.LastError.Error())
+	}
+	if !health.LastErrorAt.IsZero() {
+		data.LastErrorAt = ptr.Time(health.LastErrorAt)
+	}
+	return data
+}
+
+// trimSlash removes the trailing slash so the URLs of the clusters stored in DB and the ones retrieved from auth can be compared
+func trimSlash(url string) string {
+	return strings.TrimSuffix(url, "/")
+}
@@ -0,0 +1,74 @@
+package controller_test
+
+import (
+	"context"
+	goatest "github.com/fabric8-services/fabric8-tenant/app/test"
+	"github.com/fabric8-services/fabric8-tenant/controller"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	"github.com/fabric8-services/fabric8-tenant/test/stub"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ClustersControllerTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestClustersController(t *testing.T) {
+	suite.Run(t, &ClustersControllerTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func (s *ClustersControllerTestSuite) TestListClustersFailures() {
+	// given
+	svc, ctrl := s.newClustersController()
+
+	s.T().Run("Unauthorized - no token", func(t *testing.T) {
+		// when/then
+		goatest.ListClustersUnauthorized(t, context.Background(), svc, ctrl)
+	})
+
+	s.T().Run("Unauthorized - no SA token", func(t *testing.T) {
+		// when/then
+		goatest.ListClustersUnauthorized(t, createInvalidSAContext(), svc, ctrl)
+	})
+
+	s.T().Run("Unauthorized - wrong SA token", func(t *testing.T) {
+		// when/then
+		goatest.ListClustersUnauthorized(t, createValidSAContext("fabric8-auth"), svc, ctrl)
+	})
+}
+
+func (s *ClustersControllerTestSuite) TestListClustersWithHealthAndTenantsCount() {
+	// given
+	svc, ctrl := s.newClustersController()
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces())
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().MasterURL("http://api.cluster2"))
+
+	// when
+	_, clusters := goatest.ListClustersOK(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+
+	// then
+	require.Len(s.T(), clusters.Data, 1)
+	assert.Equal(s.T(), 1, clusters.Meta.TotalCount)
+	cluster := clusters.Data[0]
+	assert.Equal(s.T(), test.ClusterURL, *cluster.APIURL)
+	assert.True(s.T(), *cluster.Cached)
+	assert.True(s.T(), *cluster.TokenValid)
+	assert.False(s.T(), *cluster.CapacityExhausted)
+	assert.NotNil(s.T(), cluster.LastSuccess)
+	assert.Nil(s.T(), cluster.LastError)
+	assert.Equal(s.T(), "closed", *cluster.CircuitBreaker)
+	assert.Equal(s.T(), 2, *cluster.TenantsCount)
+}
+
+func (s *ClustersControllerTestSuite) newClustersController() (*goa.Service, *controller.ClustersController) {
+	svc := goa.New("Tenants-service")
+	clusterService := &stub.ClusterService{APIURL: test.ClusterURL, User: "devtools-sre", Token: "clusterToken"}
+	return svc, controller.NewClustersController(svc, tenant.NewDBService(s.DB), clusterService)
+}
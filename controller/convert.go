@@ -37,6 +37,7 @@ func convertTenant(ctx context.Context, tenant *tenant.Tenant, namespaces []*ten
 			Type:                     ptr.String(ns.Type.String()),
 			Version:                  &ns.Version,
 			State:                    ptr.String(ns.State.String()),
+			StateReason:              optional(ns.StateReason),
 			ClusterCapacityExhausted: &nsCluster.CapacityExhausted,
 		})
 	}
@@ -45,10 +46,30 @@ func convertTenant(ctx context.Context, tenant *tenant.Tenant, namespaces []*ten
 		ID:   &tenant.ID,
 		Type: "tenants",
 		Attributes: &app.TenantAttributes{
-			CreatedAt:  &tenant.CreatedAt,
-			Email:      &tenant.Email,
-			Profile:    &tenant.Profile,
-			Namespaces: nsAttributes,
+			CreatedAt:        &tenant.CreatedAt,
+			Email:            &tenant.Email,
+			Profile:          &tenant.Profile,
+			LastActiveAt:     tenant.LastActiveAt,
+			Suspended:        ptr.Bool(tenant.IsSuspended()),
+			SuspendedAt:      tenant.SuspendedAt,
+			SuspensionReason: optional(tenant.SuspensionReason),
+			Namespaces:       nsAttributes,
 		},
 	}
 }
+
+func convertStateTransition(transition *tenant.StateTransition) *app.NamespaceStateTransition {
+	transitionID := transition.ID
+	namespaceID := transition.NamespaceID
+	return &app.NamespaceStateTransition{
+		ID:          &transitionID,
+		NamespaceID: &namespaceID,
+		Name:        ptr.String(transition.Name),
+		Type:        ptr.String(transition.Type.String()),
+		FromState:   optional(transition.FromState.String()),
+		ToState:     ptr.String(transition.ToState.String()),
+		Actor:       ptr.String(string(transition.Actor)),
+		Error:       optional(transition.Error),
+		CreatedAt:   ptr.Time(transition.CreatedAt),
+	}
+}
@@ -0,0 +1,209 @@
+package controller
+
+import (
+	commonauth "github.com/fabric8-services/fabric8-common/auth"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/app"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/jsonapi"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/fabric8-services/fabric8-wit/rest"
+	"github.com/goadesign/goa"
+	"github.com/jinzhu/gorm"
+)
+
+// OperationsController implements the operations resource.
+type OperationsController struct {
+	*goa.Controller
+	db     *gorm.DB
+	runner *update.OperationRunner
+}
+
+// NewOperationsController creates an operations controller.
+func NewOperationsController(service *goa.Service, db *gorm.DB, runner *update.OperationRunner) *OperationsController {
+	return &OperationsController{
+		Controller: service.NewController("OperationsController"),
+		db:         db,
+		runner:     runner,
+	}
+}
+
+// Create runs the create action.
+func (c *OperationsController) Create(ctx *app.CreateOperationsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	var envTypes []environment.Type
+	for _, envType := range ctx.Payload.EnvTypes {
+		envTypes = append(envTypes, environment.Type(envType))
+	}
+	selector := &update.Selector{TenantIDs: ctx.Payload.Tenants}
+	if len(selector.TenantIDs) == 0 {
+		selector.Query = convertOperationFilter(ctx.Payload.Filter)
+		// an empty filter would select all tenants
+		if *selector.Query == (tenant.TenantsQuery{}) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("filter", "either tenants or a non-empty filter has to be specified"))
+		}
+	}
+
+	operation, err := c.runner.Start(update.Action(ctx.Payload.Action), envTypes, selector)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":    err,
+			"action": ctx.Payload.Action,
+		}, "start of bulk operation failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"operation_id": operation.ID,
+		"action":       operation.Action,
+		"tenants":      operation.TotalCount,
+	}, "bulk operation started")
+	ctx.ResponseData.Header().Set("Location", rest.AbsoluteURL(ctx.RequestData.Request, app.OperationsHref(operation.ID)))
+	return ctx.Accepted(&app.OperationDataSingle{Data: convertOperation(operation)})
+}
+
+func convertOperationFilter(filter *app.OperationFilter) *tenant.TenantsQuery {
+	if filter == nil {
+		return &tenant.TenantsQuery{}
+	}
+	return &tenant.TenantsQuery{
+		MasterURL:      value(filter.MasterURL),
+		Namespace:      value(filter.Namespace),
+		EnvType:        environment.Type(value(filter.Type)),
+		State:          tenant.NamespaceState(value(filter.State)),
+		Version:        value(filter.Version),
+		Profile:        value(filter.Profile),
+		EmailPrefix:    value(filter.Email),
+		UsernamePrefix: value(filter.Username),
+		CreatedAfter:   filter.CreatedAfter,
+		CreatedBefore:  filter.CreatedBefore,
+		UpdatedAfter:   filter.UpdatedAfter,
+		UpdatedBefore:  filter.UpdatedBefore,
+	}
+}
+
+// List runs the list action.
+func (c *OperationsController) List(ctx *app.ListOperationsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	offset, limit := 0, 20
+	if ctx.Offset != nil {
+		offset = *ctx.Offset
+	}
+	if ctx.Limit != nil {
+		limit = *ctx.Limit
+	}
+	operations, totalCount, err := update.NewRepository(c.db).GetOperations(offset, limit)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of bulk operations failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	result := &app.OperationDataList{
+		Data: []*app.OperationData{},
+		Meta: &app.OperationListMeta{
+			TotalCount: totalCount,
+		},
+	}
+	for _, operation := range operations {
+		result.Data = append(result.Data, convertOperation(operation))
+	}
+	return ctx.OK(result)
+}
+
+// Show runs the show action.
+func (c *OperationsController) Show(ctx *app.ShowOperationsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	repo := update.NewRepository(c.db)
+	operation, err := repo.GetOperation(ctx.OperationID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":          err,
+			"operation_id": ctx.OperationID,
+		}, "retrieval of bulk operation failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	tenants, err := repo.GetOperationTenants(operation.ID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":          err,
+			"operation_id": ctx.OperationID,
+		}, "retrieval of tenant results of bulk operation failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	operationData := convertOperation(operation)
+	for _, tnnt := range tenants {
+		tenantID := tnnt.TenantID
+		operationData.Tenants = append(operationData.Tenants, &app.OperationTenant{
+			TenantID:   &tenantID,
+			MasterURL:  optional(tnnt.MasterURL),
+			EnvTypes:   tnnt.GetEnvTypes(),
+			Namespaces: tnnt.GetNamespaces(),
+			Status:     ptr.String(tnnt.Status.String()),
+			Error:      optional(tnnt.Error),
+			FinishedAt: tnnt.FinishedAt,
+		})
+	}
+	return ctx.OK(&app.OperationDataSingle{Data: operationData})
+}
+
+// Stop runs the stop action.
+func (c *OperationsController) Stop(ctx *app.StopOperationsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	repo := update.NewRepository(c.db)
+	if _, err := repo.GetOperation(ctx.OperationID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	stopped, err := repo.StopOperation(ctx.OperationID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":          err,
+			"operation_id": ctx.OperationID,
+		}, "stopping of bulk operation failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if !stopped {
+		msg := "The operation is not running so it cannot be stopped."
+		return ctx.Conflict(&app.ConflictMsgSingle{
+			Data: &app.ConflictMsg{
+				ConflictMsg: &msg}})
+	}
+	return ctx.Accepted()
+}
+
+func convertOperation(operation *update.Operation) *app.OperationData {
+	operationID := operation.ID
+	var envTypes []string
+	for _, envType := range operation.GetEnvTypes() {
+		envTypes = append(envTypes, envType.String())
+	}
+	return &app.OperationData{
+		ID:            &operationID,
+		Action:        ptr.String(string(operation.Action)),
+		EnvTypes:      envTypes,
+		Driver:        optional(operation.Driver),
+		Status:        ptr.String(operation.Status.String()),
+		HaltReason:    optional(operation.HaltReason),
+		TotalCount:    ptr.Int(operation.TotalCount),
+		FinishedCount: ptr.Int(operation.FinishedCount),
+		FailedCount:   ptr.Int(operation.FailedCount),
+		StartedAt:     ptr.Time(operation.StartedAt),
+		FinishedAt:    operation.FinishedAt,
+	}
+}
@@ -0,0 +1,161 @@
+package controller_test
+
+import (
+	"context"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/app"
+	goatest "github.com/fabric8-services/fabric8-tenant/app/test"
+	"github.com/fabric8-services/fabric8-tenant/controller"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type OperationsControllerTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestOperationsController(t *testing.T) {
+	suite.Run(t, &OperationsControllerTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func (s *OperationsControllerTestSuite) TestOperationsFailures() {
+	// given
+	executor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	svc, ctrl, reset := s.newOperationsController(executor)
+	defer reset()
+	payload := &app.OperationPayload{Action: "clean", Tenants: []uuid.UUID{uuid.NewV4()}}
+
+	s.T().Run("Unauthorized - no token", func(t *testing.T) {
+		// when/then
+		goatest.CreateOperationsUnauthorized(t, context.Background(), svc, ctrl, payload)
+		goatest.ListOperationsUnauthorized(t, context.Background(), svc, ctrl, nil, nil)
+	})
+
+	s.T().Run("Unauthorized - no SA token", func(t *testing.T) {
+		// when/then
+		goatest.CreateOperationsUnauthorized(t, createInvalidSAContext(), svc, ctrl, payload)
+	})
+
+	s.T().Run("Unauthorized - wrong SA token", func(t *testing.T) {
+		// when/then
+		goatest.CreateOperationsUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, payload)
+		goatest.StopOperationsUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, uuid.NewV4())
+	})
+
+	s.T().Run("Bad request - no tenant is selected", func(t *testing.T) {
+		// when/then
+		goatest.CreateOperationsBadRequest(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl,
+			&app.OperationPayload{Action: "delete"})
+		goatest.CreateOperationsBadRequest(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl,
+			&app.OperationPayload{Action: "delete", Filter: &app.OperationFilter{}})
+	})
+
+	s.T().Run("Conflict - automated update is ongoing", func(t *testing.T) {
+		// given
+		testupdate.Tx(t, s.DB, func(repo update.Repository) error {
+			return repo.PrepareForUpdating()
+		})
+		defer testupdate.Tx(t, s.DB, func(repo update.Repository) error {
+			return repo.UpdateStatus(update.Finished)
+		})
+
+		// when/then
+		goatest.CreateOperationsConflict(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, payload)
+	})
+
+	s.T().Run("Not found - operation doesn't exist", func(t *testing.T) {
+		// when/then
+		goatest.ShowOperationsNotFound(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, uuid.NewV4())
+		goatest.StopOperationsNotFound(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, uuid.NewV4())
+	})
+	assert.Equal(s.T(), 0, int(*executor.NumberOfCalls))
+}
+
+func (s *OperationsControllerTestSuite) TestOperationLifecycle() {
+	// given
+	executor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	svc, ctrl, reset := s.newOperationsController(executor)
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddNamespaces(environment.TypeUser).MasterURL(test.ClusterURL))
+	ctx := createValidSAContext("fabric8-tenant-update")
+
+	// when
+	_, created := goatest.CreateOperationsAccepted(s.T(), ctx, svc, ctrl, &app.OperationPayload{
+		Action:   "update",
+		EnvTypes: []string{"che"},
+		Tenants:  []uuid.UUID{fxt.Tenants[0].ID, fxt.Tenants[1].ID},
+	})
+
+	// then
+	require.NotNil(s.T(), created.Data.ID)
+	operationID := *created.Data.ID
+	assert.Equal(s.T(), "update", *created.Data.Action)
+	assert.Equal(s.T(), []string{"che"}, created.Data.EnvTypes)
+	assert.Equal(s.T(), 2, *created.Data.TotalCount)
+
+	var shown *app.OperationDataSingle
+	for i := 0; i < 100; i++ {
+		_, shown = goatest.ShowOperationsOK(s.T(), ctx, svc, ctrl, operationID)
+		if *shown.Data.Status != "updating" {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(s.T(), "finished", *shown.Data.Status)
+	assert.Equal(s.T(), 2, *shown.Data.FinishedCount)
+	assert.Equal(s.T(), 0, *shown.Data.FailedCount)
+	require.Len(s.T(), shown.Data.Tenants, 2)
+	for _, result := range shown.Data.Tenants {
+		assert.Equal(s.T(), "finished", *result.Status)
+		assert.Equal(s.T(), test.ClusterURL, *result.MasterURL)
+		// the tenants don't have any che namespace that could be updated
+		assert.Empty(s.T(), result.EnvTypes)
+	}
+	assert.Equal(s.T(), 0, int(*executor.NumberOfCalls))
+
+	s.T().Run("operation is listed", func(t *testing.T) {
+		// when
+		_, list := goatest.ListOperationsOK(t, ctx, svc, ctrl, ptr.Int(100), nil)
+
+		// then
+		var ids []uuid.UUID
+		for _, operation := range list.Data {
+			ids = append(ids, *operation.ID)
+		}
+		assert.Contains(t, ids, operationID)
+		assert.True(t, list.Meta.TotalCount >= 1)
+	})
+
+	s.T().Run("finished operation cannot be stopped", func(t *testing.T) {
+		// when/then
+		goatest.StopOperationsConflict(t, ctx, svc, ctrl, operationID)
+	})
+}
+
+func (s *OperationsControllerTestSuite) newOperationsController(executor *testupdate.DummyUpdateExecutor) (*goa.Service, *controller.OperationsController, func()) {
+	resetEnvs := test.SetEnvironments(
+		test.Env("F8_AUTH_TOKEN_KEY", "foo"),
+		test.Env("F8_API_SERVER_USE_TLS", "false"),
+		test.Env("F8_AUTOMATED_UPDATE_TIME_GAP", "0"),
+		test.Env("F8_AUTOMATED_UPDATE_PACING_MAX_DELAY", "0"))
+	clusterService, _, config, reset := testdoubles.PrepareConfigClusterAndAuthService(s.T())
+	svc := goa.New("Tenants-service")
+	executor.ClusterService = clusterService
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+	return svc, controller.NewOperationsController(svc, s.DB, runner), func() {
+		resetEnvs()
+		reset()
+	}
+}
@@ -1,32 +1,51 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"github.com/fabric8-services/fabric8-tenant/app"
+	"github.com/fabric8-services/fabric8-tenant/auth"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/toggles"
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	componentOK       = "ok"
+	componentDegraded = "degraded"
+	componentFailed   = "failed"
 )
 
 // StatusController implements the status resource.
 type StatusController struct {
 	*goa.Controller
-	db *gorm.DB
+	db             *gorm.DB
+	config         *configuration.Data
+	authService    auth.Service
+	clusterService cluster.Service
 }
 
 // NewStatusController creates a status controller.
-func NewStatusController(service *goa.Service, db *gorm.DB) *StatusController {
+func NewStatusController(service *goa.Service, db *gorm.DB, config *configuration.Data, authService auth.Service,
+	clusterService cluster.Service) *StatusController {
 	return &StatusController{
-		Controller: service.NewController("StatusController"),
-		db:         db,
+		Controller:     service.NewController("StatusController"),
+		db:             db,
+		config:         config,
+		authService:    authService,
+		clusterService: clusterService,
 	}
 }
 
 // Show runs the show action.
 func (c *StatusController) Show(ctx *app.ShowStatusContext) error {
-	res := &app.Status{}
-	res.Commit = configuration.Commit
-	res.BuildTime = configuration.BuildTime
-	res.StartTime = configuration.StartTime
+	res := newStatus()
 
 	_, err := c.db.DB().Exec("select 1")
 	if err != nil {
@@ -37,3 +56,202 @@ func (c *StatusController) Show(ctx *app.ShowStatusContext) error {
 	}
 	return ctx.OK(res)
 }
+
+// Liveness runs the liveness action. It doesn't check any dependency - the process is alive as long as it responds,
+// so none of the instances is restarted when the DB or any of the external services is down.
+func (c *StatusController) Liveness(ctx *app.LivenessStatusContext) error {
+	return ctx.OK(newStatus())
+}
+
+// Readiness runs the readiness action. It checks all components the instance depends on - the instance is not ready when
+// any of the critical ones fails. Failures of the other ones (auth, a single cluster, toggles) are only reported as degraded.
+// The external services are not called by the checks - their state is taken from the latest refresh of the cached clusters.
+func (c *StatusController) Readiness(ctx *app.ReadinessStatusContext) error {
+	res := newStatus()
+	checks := []componentCheck{
+		{name: "database", critical: true, check: c.checkDatabase},
+		{name: "auth", check: c.checkAuth},
+		{name: "cluster-cache", critical: true, check: c.checkClusterCache},
+		{name: "templates", critical: true, check: c.checkTemplates},
+		{name: "toggles", check: checkToggles},
+	}
+	for _, health := range c.clusterService.GetClustersHealth() {
+		checks = append(checks, componentCheck{name: "cluster " + health.APIURL, check: checkCluster(health)})
+	}
+	if !c.runChecks(ctx, res, checks...) {
+		return ctx.ServiceUnavailable(res)
+	}
+	return ctx.OK(res)
+}
+
+func newStatus() *app.Status {
+	return &app.Status{
+		Commit:    configuration.Commit,
+		BuildTime: configuration.BuildTime,
+		StartTime: configuration.StartTime,
+	}
+}
+
+type componentCheck struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) (string, error)
+}
+
+// degradedError is returned by the check of a critical component that still works, but with limited function
+type degradedError struct {
+	error
+}
+
+// runChecks runs all the given checks in parallel and waits for their results at most for the configured timeout.
+// The results are stored in the given status; returns false if any of the critical checks failed
+func (c *StatusController) runChecks(ctx context.Context, res *app.Status, checks ...componentCheck) bool {
+	ctx, cancel := context.WithTimeout(ctx, c.config.GetReadinessCheckTimeout())
+	defer cancel()
+
+	type result struct {
+		index   int
+		message string
+		err     error
+	}
+	results := make(chan result, len(checks))
+	for index, check := range checks {
+		go func(index int, check componentCheck) {
+			message, err := check.check(ctx)
+			results <- result{index: index, message: message, err: err}
+		}(index, check)
+	}
+
+	components := make([]*app.ComponentStatus, len(checks))
+	timedOut := false
+	for received := 0; received < len(checks) && !timedOut; received++ {
+		select {
+		case r := <-results:
+			components[r.index] = newComponentStatus(checks[r.index], r.message, r.err)
+		case <-ctx.Done():
+			timedOut = true
+		}
+	}
+
+	ok := true
+	var failed []string
+	for index, component := range components {
+		if component == nil {
+			component = newComponentStatus(checks[index], "", fmt.Errorf("the check timed out after %s", c.config.GetReadinessCheckTimeout()))
+			components[index] = component
+		}
+		if component.Status == componentFailed {
+			ok = false
+			failed = append(failed, component.Name)
+		}
+	}
+	res.Components = components
+	if !ok {
+		message := fmt.Sprintf("these components are not ready: %v", failed)
+		res.Error = &message
+	}
+	return ok
+}
+
+func newComponentStatus(check componentCheck, message string, err error) *app.ComponentStatus {
+	status := &app.ComponentStatus{
+		Name:   check.name,
+		Status: componentOK,
+	}
+	if err != nil {
+		status.Status = componentDegraded
+		if _, degraded := err.(degradedError); check.critical && !degraded {
+			status.Status = componentFailed
+		}
+		message = err.Error()
+	}
+	if message != "" {
+		status.Message = &message
+	}
+	return status
+}
+
+func (c *StatusController) checkDatabase(ctx context.Context) (string, error) {
+	_, err := c.db.DB().Exec("select 1")
+	return "", err
+}
+
+// checkAuth reports the result of the latest request of the service account to the auth service - the list of clusters
+// is retrieved from auth by every refresh of the cached clusters
+func (c *StatusController) checkAuth(ctx context.Context) (string, error) {
+	cacheStatus := c.clusterService.GetCacheStatus()
+	if cacheStatus.LastError != nil {
+		return "", errors.Wrapf(cacheStatus.LastError, "the latest request to %s failed", c.authService.GetAuthURL())
+	}
+	if cacheStatus.LastRefresh.IsZero() {
+		return "", fmt.Errorf("no response has been received from %s yet", c.authService.GetAuthURL())
+	}
+	return fmt.Sprintf("responded %s ago", time.Since(cacheStatus.LastRefresh)), nil
+}
+
+// checkClusterCache verifies that the list of clusters has been loaded. When it hasn't been successfully refreshed within
+// two refresh intervals, the last loaded list is still used, so the cache is only reported as degraded
+func (c *StatusController) checkClusterCache(ctx context.Context) (string, error) {
+	cacheStatus := c.clusterService.GetCacheStatus()
+	if cacheStatus.LastRefresh.IsZero() {
+		if cacheStatus.LastError != nil {
+			return "", errors.Wrapf(cacheStatus.LastError, "the list of clusters has never been loaded")
+		}
+		return "", fmt.Errorf("the list of clusters has never been loaded")
+	}
+	age := time.Since(cacheStatus.LastRefresh)
+	if age > 2*c.config.GetClustersRefreshDelay() {
+		if cacheStatus.LastError != nil {
+			return "", degradedError{errors.Wrapf(cacheStatus.LastError, "the list of clusters was last refreshed %s ago", age)}
+		}
+		return "", degradedError{fmt.Errorf("the list of clusters was last refreshed %s ago", age)}
+	}
+	return fmt.Sprintf("%d clusters refreshed %s ago", len(c.clusterService.GetClusters(ctx)), age), nil
+}
+
+// checkCluster verifies that the circuit breaker of the given cluster is closed and that the cluster accepted its token
+// during the latest refresh of the cached clusters
+func checkCluster(health cluster.Health) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if state := openshift.GetCircuitState(health.APIURL); state != openshift.CircuitClosed {
+			return "", fmt.Errorf("the circuit breaker of the cluster is %s - the requests sent to the cluster fail fast", state)
+		}
+		if !health.TokenValid {
+			reason := "the latest refresh of the cluster failed - the last good entry is used"
+			if !health.Cached {
+				reason = "the cluster has never been successfully refreshed"
+			}
+			return "", fmt.Errorf("%s: %v", reason, health.LastError)
+		}
+		return fmt.Sprintf("refreshed %s ago", time.Since(health.LastSuccess)), nil
+	}
+}
+
+// checkTemplates verifies that all templates can be loaded, that their versions are set and that all variables
+// needed for processing them are configured
+func (c *StatusController) checkTemplates(ctx context.Context) (string, error) {
+	if _, err := c.config.GetTemplateValues(); err != nil {
+		return "", err
+	}
+	var versions []string
+	for _, envType := range environment.DefaultEnvTypes {
+		envData, err := environment.NewService().GetEnvData(ctx, envType)
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to load templates of %s environment", envType)
+		}
+		for _, template := range envData.Templates {
+			if template.Version == "" {
+				return "", fmt.Errorf("the version of the template %s is not set", template.Filename)
+			}
+		}
+		versions = append(versions, fmt.Sprintf("%s:%s", envType, envData.Version()))
+	}
+	return fmt.Sprintf("%v", versions), nil
+}
+
+func checkToggles(ctx context.Context) (string, error) {
+	if !toggles.IsReady() {
+		return "", fmt.Errorf("the toggles client is not ready, the default values of the features are used")
+	}
+	return "", nil
+}
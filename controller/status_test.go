@@ -0,0 +1,151 @@
+package controller_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/app"
+	goatest "github.com/fabric8-services/fabric8-tenant/app/test"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/controller"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/h2non/gock.v1"
+	"testing"
+	"time"
+)
+
+type StatusControllerTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestStatusController(t *testing.T) {
+	suite.Run(t, &StatusControllerTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func (s *StatusControllerTestSuite) TestLiveness() {
+	// given
+	svc, ctrl, reset := s.newTestStatusController(true)
+	defer reset()
+
+	// when
+	_, status := goatest.LivenessStatusOK(s.T(), context.Background(), svc, ctrl)
+
+	// then
+	assert.Empty(s.T(), status.Components)
+	assert.Nil(s.T(), status.Error)
+}
+
+func (s *StatusControllerTestSuite) TestReadiness() {
+	defer gock.OffAll()
+	testdoubles.SetTemplateVersions()
+
+	s.T().Run("ready", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+		svc, ctrl, reset := s.newTestStatusController(true)
+		defer reset()
+
+		// when
+		_, status := goatest.ReadinessStatusOK(t, context.Background(), svc, ctrl)
+
+		// then
+		require.Len(t, status.Components, 6)
+		assertComponent(t, status, "database", "ok")
+		assertComponent(t, status, "auth", "ok")
+		assertComponent(t, status, "cluster-cache", "ok")
+		assertComponent(t, status, "templates", "ok")
+		assertComponent(t, status, "toggles", "degraded")
+		assertComponent(t, status, "cluster "+test.ClusterURL+"/", "ok")
+		assert.Nil(t, status.Error)
+	})
+
+	s.T().Run("cluster cache never loaded", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+		svc, ctrl, reset := s.newTestStatusController(false)
+		defer reset()
+
+		// when
+		_, status := goatest.ReadinessStatusServiceUnavailable(t, context.Background(), svc, ctrl)
+
+		// then
+		require.Len(t, status.Components, 5)
+		assertComponent(t, status, "auth", "degraded")
+		component := assertComponent(t, status, "cluster-cache", "failed")
+		assert.Contains(t, *component.Message, "the list of clusters has never been loaded")
+		require.NotNil(t, status.Error)
+		assert.Contains(t, *status.Error, "cluster-cache")
+	})
+
+	s.T().Run("auth not reachable", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+		clusterService, authService, config, reset := testdoubles.PrepareConfigClusterAndAuthServiceWithRefreshInt(50*time.Millisecond, t)
+		defer reset()
+		gock.OffAll()
+		err := test.WaitWithTimeout(5 * time.Second).Until(func() error {
+			if clusterService.GetCacheStatus().LastError == nil {
+				return fmt.Errorf("the refresh of the clusters hasn't failed yet")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		svc := goa.New("Status-service")
+		ctrl := controller.NewStatusController(svc, s.DB, config, authService, clusterService)
+
+		// when
+		_, status := goatest.ReadinessStatusOK(t, context.Background(), svc, ctrl)
+
+		// then
+		component := assertComponent(t, status, "auth", "degraded")
+		assert.Contains(t, *component.Message, "the latest request to http://authservice failed")
+		assertComponent(t, status, "cluster-cache", "ok")
+		assertComponent(t, status, "cluster "+test.ClusterURL+"/", "ok")
+	})
+
+	s.T().Run("cluster not reachable", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		testdoubles.MockCommunicationWithAuthSettingCapacityFlag(test.ClusterURL, false, false)
+		gock.New("http://authservice").Get("/api/clusters/").Persist().Reply(200).
+			BodyString(`{"data":[{"api-url": "` + test.ClusterURL + `/"}]}`)
+		svc, ctrl, reset := s.newTestStatusController(true)
+		defer reset()
+
+		// when
+		_, status := goatest.ReadinessStatusOK(t, context.Background(), svc, ctrl)
+
+		// then
+		assertComponent(t, status, "cluster-cache", "ok")
+		assertComponent(t, status, "cluster "+test.ClusterURL+"/", "degraded")
+	})
+}
+
+func (s *StatusControllerTestSuite) newTestStatusController(clustersLoaded bool) (*goa.Service, *controller.StatusController, func()) {
+	clusterService, authService, config, reset := testdoubles.PrepareConfigClusterAndAuthService(s.T())
+	if !clustersLoaded {
+		clusterService = cluster.NewClusterService(time.Hour, authService)
+	}
+	svc := goa.New("Status-service")
+	ctrl := controller.NewStatusController(svc, s.DB, config, authService, clusterService)
+	return svc, ctrl, reset
+}
+
+func assertComponent(t *testing.T, status *app.Status, name, expectedStatus string) *app.ComponentStatus {
+	for _, component := range status.Components {
+		if component.Name == name {
+			assert.Equal(t, expectedStatus, component.Status, "status of the component %s", name)
+			return component
+		}
+	}
+	require.Fail(t, "the component wasn't found", name)
+	return nil
+}
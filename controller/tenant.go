@@ -2,7 +2,12 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/fabric8-services/fabric8-common/errors"
 	"github.com/fabric8-services/fabric8-common/log"
@@ -14,7 +19,9 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/jsonapi"
 	"github.com/fabric8-services/fabric8-tenant/metric"
 	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/progress"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
 	"github.com/fabric8-services/fabric8-wit/rest"
 	"github.com/goadesign/goa"
 	errs "github.com/pkg/errors"
@@ -79,7 +86,8 @@ func (c *TenantController) Clean(ctx *app.CleanTenantContext) error {
 
 	// checks if the namespaces should be only cleaned or totally removed - restrict deprovision from cluster to internal users only
 	deleteOptions := openshift.DeleteOpts().EnableSelfHealing()
-	if user.UserData.FeatureLevel != nil && *user.UserData.FeatureLevel == auth.InternalFeatureLevel && ctx.Remove {
+	removeFromCluster := user.UserData.FeatureLevel != nil && *user.UserData.FeatureLevel == auth.InternalFeatureLevel && ctx.Remove
+	if removeFromCluster {
 		deleteOptions.RemoveFromCluster()
 	}
 
@@ -95,7 +103,7 @@ func (c *TenantController) Clean(ctx *app.CleanTenantContext) error {
 	// perform delete method on the list of existing namespaces
 	err = openShiftService.Delete(environment.DefaultEnvTypes, namespaces, deleteOptions)
 	if err != nil {
-		metric.RecordCleanedTenant(false, dbTenant.NsBaseName)
+		metric.RecordCleanedTenant(false, value(user.UserData.Cluster))
 		namespaces, getErr := tenantRepository.GetNamespaces()
 		if getErr != nil {
 			log.Error(ctx, map[string]interface{}{
@@ -109,7 +117,14 @@ func (c *TenantController) Clean(ctx *app.CleanTenantContext) error {
 		log.Error(ctx, params, "deletion of namespaces failed")
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
-	metric.RecordCleanedTenant(true, dbTenant.NsBaseName)
+	metric.RecordCleanedTenant(true, value(user.UserData.Cluster))
+	eventType := webhook.TenantCleaned
+	if removeFromCluster {
+		eventType = webhook.TenantDeleted
+	}
+	webhook.Publish(ctx, webhook.NewTenantEvent(eventType, user.ID, map[string]interface{}{
+		"cluster_url": value(user.UserData.Cluster),
+	}))
 	return ctx.NoContent()
 }
 
@@ -161,7 +176,19 @@ func (c *TenantController) Setup(ctx *app.SetupTenantContext) error {
 			}, "retrieval of tenant entity from DB failed")
 			return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("tenant", user.ID.String()))
 		}
+		if dbTenant.IsSuspended() {
+			return jsonapi.JSONErrorResponse(ctx, newSuspendedError(dbTenant))
+		}
 	} else {
+		// the user registers again after the tenant was deleted - the deleted tenant is replaced by the new one.
+		// Only the admin can bring the deleted tenant back by restoring it
+		if err := tenantRepository.PurgeDeletedTenant(); err != nil {
+			log.Error(ctx, map[string]interface{}{
+				"err":      err,
+				"tenantID": user.ID,
+			}, "unable to purge the deleted tenant")
+			return jsonapi.JSONErrorResponse(ctx, err)
+		}
 		nsBaseName, err := tenant.ConstructNsBaseName(c.tenantService, environment.RetrieveUserName(user.OpenShiftUsername))
 		if err != nil {
 			log.Error(ctx, map[string]interface{}{
@@ -187,8 +214,16 @@ func (c *TenantController) Setup(ctx *app.SetupTenantContext) error {
 			}, "unable to store tenant configuration")
 			return jsonapi.JSONErrorResponse(ctx, err)
 		}
+		webhook.Publish(ctx, webhook.NewTenantEvent(webhook.TenantCreated, user.ID, map[string]interface{}{
+			"cluster_url":  value(user.UserData.Cluster),
+			"os_username":  dbTenant.OSUsername,
+			"ns_base_name": dbTenant.NsBaseName,
+		}))
 	}
 
+	// the access of the user wakes up the idled namespaces
+	c.wakeUp(ctx, user.ID, namespaces)
+
 	// check if any environment type is missing - should be provisioned
 	missing, existing := filterMissingAndExisting(namespaces)
 	if len(missing) == 0 {
@@ -278,9 +313,211 @@ func (c *TenantController) Show(ctx *app.ShowTenantContext) error {
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
 
+	// the access of the user wakes up the idled namespaces - the namespaces of the suspended tenant stay idled
+	if !tenant.IsSuspended() {
+		c.wakeUp(ctx, user.ID, namespaces)
+	}
+
 	return ctx.OK(&app.TenantSingle{Data: convertTenant(ctx, tenant, namespaces, c.clusterService.GetCluster)})
 }
 
+// Unidle runs the unidle action.
+func (c *TenantController) Unidle(ctx *app.UnidleTenantContext) error {
+	// get user info
+	user, err := c.authClientService.GetUser(ctx)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err}, "creation of the user failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	// checks that the tenant exists
+	dbTenant, err := c.getExistingTenant(ctx, user.ID, user.OpenShiftUsername)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "retrieval of tenant entity from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("tenants", user.ID.String()))
+	}
+	if dbTenant.IsSuspended() {
+		return jsonapi.JSONErrorResponse(ctx, newSuspendedError(dbTenant))
+	}
+
+	tenantRepository := c.tenantService.NewTenantRepository(user.ID)
+	namespaces, err := tenantRepository.GetNamespaces()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "retrieval of existing namespaces from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if err := tenantRepository.RecordActivity(time.Now()); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "unable to record the activity of the tenant")
+	}
+
+	if err := c.unidle(ctx, namespaces); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "unidling of namespaces failed")
+		if openshift.IsClusterUnavailable(err) {
+			return jsonapi.JSONErrorResponse(ctx, err)
+		}
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	return ctx.NoContent()
+}
+
+// wakeUp records the activity of the user and unidles the idled namespaces of the tenant. The failures are only logged
+// so they don't prevent the user from accessing the tenant - the unidling can be requested explicitly
+func (c *TenantController) wakeUp(ctx context.Context, tenantID uuid.UUID, namespaces []*tenant.Namespace) {
+	if err := c.tenantService.NewTenantRepository(tenantID).RecordActivity(time.Now()); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "unable to record the activity of the tenant")
+	}
+	if err := c.unidle(ctx, namespaces); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "unidling of namespaces failed")
+	}
+}
+
+// unidle unidles all idled namespaces among the given ones on behalf of the user
+func (c *TenantController) unidle(ctx context.Context, namespaces []*tenant.Namespace) error {
+	updater := TenantUpdater{Config: c.config, ClusterService: c.clusterService, TenantService: c.tenantService}
+	for _, namespace := range namespaces {
+		if namespace.State != tenant.Idled {
+			continue
+		}
+		if err := updater.Unidle(tenant.WithActor(ctx, tenant.ActorUser), namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// History runs the history action.
+func (c *TenantController) History(ctx *app.HistoryTenantContext) error {
+	// get user info
+	user, err := c.authClientService.GetUser(ctx)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err}, "creation of the user failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	// checks that the tenant exists
+	if _, err := c.getExistingTenant(ctx, user.ID, user.OpenShiftUsername); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "retrieval of tenant entity from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("tenants", user.ID.String()))
+	}
+
+	transitions, err := c.tenantService.NewTenantRepository(user.ID).GetStateTransitions()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "retrieval of the history of namespace states from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	result := &app.NamespaceStateTransitionList{Data: []*app.NamespaceStateTransition{}}
+	for _, transition := range transitions {
+		result.Data = append(result.Data, convertStateTransition(transition))
+	}
+	return ctx.OK(result)
+}
+
+// Events runs the events action.
+func (c *TenantController) Events(ctx *app.EventsTenantContext) error {
+	// get user info
+	user, err := c.authClientService.GetUser(ctx)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err}, "creation of the user failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	flusher, ok := ctx.ResponseData.ResponseWriter.(http.Flusher)
+	if !ok {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalErrorFromString(ctx, "streaming of the events is not supported"))
+	}
+
+	// subscribe before the current state is read so no change is missed. The tenant doesn't have to exist yet - the stream
+	// can be opened before the setup of the tenant is requested
+	events, unsubscribe := progress.Subscribe(user.ID, c.config.GetTenantEventsBufferSize())
+	defer unsubscribe()
+	namespaces, err := c.tenantService.NewTenantRepository(user.ID).GetNamespaces()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": user.ID,
+		}, "retrieval of existing namespaces from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	ctx.ResponseData.Header().Set("Content-Type", "text/event-stream")
+	ctx.ResponseData.Header().Set("Cache-Control", "no-cache")
+	// disables buffering of the response in the nginx based proxies
+	ctx.ResponseData.Header().Set("X-Accel-Buffering", "no")
+	ctx.ResponseData.WriteHeader(http.StatusOK)
+
+	for _, namespace := range namespaces {
+		err := writeServerSentEvent(ctx.ResponseData, &progress.Event{
+			Type:      progress.NamespaceChanged,
+			TenantID:  namespace.TenantID,
+			Namespace: namespace.Name,
+			NsType:    namespace.Type.String(),
+			State:     namespace.State.String(),
+			Time:      namespace.UpdatedAt,
+		})
+		if err != nil {
+			return nil
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(c.config.GetTenantEventsKeepAliveInterval())
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, open := <-events:
+			// the stream is closed when the service is shutting down
+			if !open {
+				return nil
+			}
+			if err := writeServerSentEvent(ctx.ResponseData, event); err != nil {
+				return nil
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(ctx.ResponseData, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeServerSentEvent writes the event in the format of server-sent events - the type of the event is used as its name
+func writeServerSentEvent(writer io.Writer, event *progress.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
 // Update runs the update action.
 func (c *TenantController) Update(ctx *app.UpdateTenantContext) error {
 	// get user info
@@ -299,6 +536,9 @@ func (c *TenantController) Update(ctx *app.UpdateTenantContext) error {
 		}, "retrieval of tenant entity from DB failed")
 		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("tenant", user.ID.String()))
 	}
+	if dbTenant.IsSuspended() {
+		return jsonapi.JSONErrorResponse(ctx, newSuspendedError(dbTenant))
+	}
 
 	err = TenantUpdater{Config: c.config, ClusterService: c.clusterService, TenantService: c.tenantService}.
 		Update(ctx, dbTenant, user, environment.DefaultEnvTypes, true)
@@ -307,11 +547,13 @@ func (c *TenantController) Update(ctx *app.UpdateTenantContext) error {
 			"err":      err,
 			"tenantID": dbTenant.ID,
 		}, "update of namespaces failed")
-		metric.RecordUpdatedTenant(false, dbTenant.NsBaseName)
+		metric.RecordUpdatedTenant(false, value(user.UserData.Cluster))
+		publishTenantUpdated(ctx, dbTenant, false)
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
 
-	metric.RecordUpdatedTenant(true, dbTenant.NsBaseName)
+	metric.RecordUpdatedTenant(true, value(user.UserData.Cluster))
+	publishTenantUpdated(ctx, dbTenant, true)
 	ctx.ResponseData.Header().Set("Location", rest.AbsoluteURL(ctx.RequestData.Request, app.TenantHref()))
 	return ctx.Accepted()
 }
@@ -323,6 +565,10 @@ type TenantUpdater struct {
 }
 
 func (u TenantUpdater) Update(ctx context.Context, dbTenant *tenant.Tenant, user *auth.User, envTypes []environment.Type, allowSelfHealing bool) error {
+	// the update would restore the access of the user to the namespaces
+	if dbTenant.IsSuspended() {
+		return newSuspendedError(dbTenant)
+	}
 	tenantRepository := u.TenantService.NewTenantRepository(dbTenant.ID)
 	// get tenant's namespaces
 	namespaces, err := tenantRepository.GetNamespaces()
@@ -337,6 +583,175 @@ func (u TenantUpdater) Update(ctx context.Context, dbTenant *tenant.Tenant, user
 	}
 
 	// create openshift service
+	openShiftService := u.newOpenShiftService(ctx, dbTenant, user, clusterMapping)
+
+	// perform patch method on the list of exiting namespaces
+	return openShiftService.Update(envTypes, namespaces, openshift.UpdateOpts().EnableSelfHealing())
+}
+
+// Clean removes the objects of all namespaces of the tenant using the cluster token. If removeFromCluster is true,
+// then the namespaces are removed from the cluster and the tenant is removed from DB.
+func (u TenantUpdater) Clean(ctx context.Context, dbTenant *tenant.Tenant, removeFromCluster bool) error {
+	namespaces, err := u.TenantService.NewTenantRepository(dbTenant.ID).GetNamespaces()
+	if err != nil {
+		return errs.Wrap(err, "retrieval of existing namespaces from DB failed")
+	}
+
+	// create cluster mapping from existing namespaces
+	clusterMapping, err := GetClusterMapping(ctx, u.ClusterService, namespaces)
+	if err != nil {
+		return err
+	}
+
+	deleteOptions := openshift.DeleteOpts().EnableSelfHealing()
+	eventType := webhook.TenantCleaned
+	if removeFromCluster {
+		deleteOptions.RemoveFromCluster()
+		eventType = webhook.TenantDeleted
+	}
+
+	// perform delete method on the list of existing namespaces
+	err = u.newOpenShiftService(ctx, dbTenant, nil, clusterMapping).Delete(environment.DefaultEnvTypes, namespaces, deleteOptions)
+	if err != nil {
+		return err
+	}
+	webhook.Publish(ctx, webhook.NewTenantEvent(eventType, dbTenant.ID, nil))
+	return nil
+}
+
+// Create creates the namespaces of the given types for the existing tenant. The namespaces are created in the cluster
+// the rest of the tenant's namespaces is located in.
+func (u TenantUpdater) Create(ctx context.Context, dbTenant *tenant.Tenant, envTypes []environment.Type) error {
+	namespaces, err := u.TenantService.NewTenantRepository(dbTenant.ID).GetNamespaces()
+	if err != nil {
+		return errs.Wrap(err, "retrieval of existing namespaces from DB failed")
+	}
+	if len(namespaces) == 0 {
+		return fmt.Errorf("the tenant %s has no namespace the cluster could be determined from", dbTenant.ID)
+	}
+
+	// all namespaces of the tenant are located in the same cluster
+	return u.CreateInCluster(ctx, dbTenant, namespaces[0].MasterURL, envTypes)
+}
+
+// CreateInCluster creates the namespaces of the given types for the tenant in the cluster with the given URL
+func (u TenantUpdater) CreateInCluster(ctx context.Context, dbTenant *tenant.Tenant, clusterURL string, envTypes []environment.Type) error {
+	clustr, err := u.ClusterService.GetCluster(ctx, clusterURL)
+	if err != nil {
+		return err
+	}
+	clusterMapping := map[environment.Type]cluster.Cluster{}
+	for _, envType := range environment.DefaultEnvTypes {
+		clusterMapping[envType] = clustr
+	}
+
+	// perform post method on the list of missing environment types
+	return u.newOpenShiftService(ctx, dbTenant, nil, cluster.ForTypeMapping(clusterMapping)).
+		Create(envTypes, openshift.CreateOpts().EnableSelfHealing())
+}
+
+// NamespaceExists checks if the namespace is present in the cluster it is located in
+func (u TenantUpdater) NamespaceExists(ctx context.Context, namespace *tenant.Namespace) (bool, error) {
+	clustr, err := u.ClusterService.GetCluster(ctx, namespace.MasterURL)
+	if err != nil {
+		return false, err
+	}
+	return openshift.NamespaceExists(ctx, u.Config, clustr, namespace.Name)
+}
+
+// Idle marks the ready namespace as idled and scales its workloads down to zero using the cluster token.
+// When the scaling fails, the objects that have already been scaled down are restored
+func (u TenantUpdater) Idle(ctx context.Context, namespace *tenant.Namespace) error {
+	clustr, err := u.ClusterService.GetCluster(ctx, namespace.MasterURL)
+	if err != nil {
+		return err
+	}
+	// the state is changed first, so the namespace is unidled on the next access even if the scaling is interrupted
+	err = u.TenantService.NewTenantRepository(namespace.TenantID).
+		ChangeNamespaceState(namespace, tenant.Idled, tenant.ActorFromContext(ctx), nil)
+	if err != nil {
+		metric.RecordIdledNamespace("idle", false)
+		return err
+	}
+	if err := openshift.IdleNamespace(ctx, u.Config, clustr, namespace.Name); err != nil {
+		metric.RecordIdledNamespace("idle", false)
+		if unidleErr := u.Unidle(ctx, namespace); unidleErr != nil {
+			log.Error(ctx, map[string]interface{}{
+				"err":       unidleErr,
+				"namespace": namespace.Name,
+			}, "unable to restore the namespace whose idling failed")
+		}
+		return errs.Wrapf(err, "unable to idle the namespace %s", namespace.Name)
+	}
+	metric.RecordIdledNamespace("idle", true)
+	return nil
+}
+
+// Unidle scales the workloads of the idled namespace back to their original number of replicas and marks the namespace as ready
+func (u TenantUpdater) Unidle(ctx context.Context, namespace *tenant.Namespace) error {
+	clustr, err := u.ClusterService.GetCluster(ctx, namespace.MasterURL)
+	if err == nil {
+		err = openshift.UnidleNamespace(ctx, u.Config, clustr, namespace.Name)
+	}
+	if err == nil {
+		err = u.TenantService.NewTenantRepository(namespace.TenantID).
+			ChangeNamespaceState(namespace, tenant.Ready, tenant.ActorFromContext(ctx), nil)
+	}
+	metric.RecordIdledNamespace("unidle", err == nil)
+	if err != nil {
+		return errs.Wrapf(err, "unable to unidle the namespace %s", namespace.Name)
+	}
+	return nil
+}
+
+// Suspend removes the edit and admin rights of the user from all namespaces of the tenant and scales their workloads
+// down to zero using the cluster token
+func (u TenantUpdater) Suspend(ctx context.Context, dbTenant *tenant.Tenant) error {
+	namespaces, err := u.TenantService.NewTenantRepository(dbTenant.ID).GetNamespaces()
+	if err != nil {
+		return errs.Wrap(err, "retrieval of existing namespaces from DB failed")
+	}
+	for _, namespace := range namespaces {
+		clustr, err := u.ClusterService.GetCluster(ctx, namespace.MasterURL)
+		if err != nil {
+			return err
+		}
+		if err := openshift.SuspendNamespace(ctx, u.Config, clustr, namespace.Name, dbTenant.OSUsername); err != nil {
+			return errs.Wrapf(err, "unable to suspend the namespace %s", namespace.Name)
+		}
+	}
+	return nil
+}
+
+// Resume restores the rights of the user and the workloads in all namespaces of the suspended tenant. The idled namespaces
+// are woken up as well
+func (u TenantUpdater) Resume(ctx context.Context, dbTenant *tenant.Tenant) error {
+	tenantRepository := u.TenantService.NewTenantRepository(dbTenant.ID)
+	namespaces, err := tenantRepository.GetNamespaces()
+	if err != nil {
+		return errs.Wrap(err, "retrieval of existing namespaces from DB failed")
+	}
+	for _, namespace := range namespaces {
+		clustr, err := u.ClusterService.GetCluster(ctx, namespace.MasterURL)
+		if err != nil {
+			return err
+		}
+		if err := openshift.ResumeNamespace(ctx, u.Config, clustr, namespace.Name, dbTenant.OSUsername); err != nil {
+			return errs.Wrapf(err, "unable to resume the namespace %s", namespace.Name)
+		}
+		if namespace.State == tenant.Idled {
+			err := tenantRepository.ChangeNamespaceState(namespace, tenant.Ready, tenant.ActorFromContext(ctx), nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newOpenShiftService creates the openshift service acting on behalf of the user or with the cluster token if the user is nil
+func (u TenantUpdater) newOpenShiftService(ctx context.Context, dbTenant *tenant.Tenant, user *auth.User,
+	clusterMapping cluster.ForType) *openshift.ServiceBuilder {
 	nsRepo := u.TenantService.NewTenantRepository(dbTenant.ID)
 
 	var envService *environment.Service
@@ -351,10 +766,23 @@ func (u TenantUpdater) Update(ctx context.Context, dbTenant *tenant.Tenant, user
 
 	serviceContext := openshift.NewServiceContext(
 		ctx, u.Config, clusterMapping, dbTenant.OSUsername, dbTenant.NsBaseName, userTokenResolver)
-	openShiftService := openshift.NewService(serviceContext, nsRepo, envService)
+	return openshift.NewService(serviceContext, nsRepo, envService)
+}
 
-	// perform patch method on the list of exiting namespaces
-	return openShiftService.Update(envTypes, namespaces, openshift.UpdateOpts().EnableSelfHealing())
+// newSuspendedError returns the error the operations on the namespaces of the suspended tenant are refused with
+func newSuspendedError(dbTenant *tenant.Tenant) error {
+	msg := fmt.Sprintf("the tenant %s is suspended", dbTenant.ID)
+	if dbTenant.SuspensionReason != "" {
+		msg += ": " + dbTenant.SuspensionReason
+	}
+	return errors.NewForbiddenError(msg)
+}
+
+// publishTenantUpdated notifies the webhooks that the update of the namespaces of the tenant was finished
+func publishTenantUpdated(ctx context.Context, dbTenant *tenant.Tenant, successful bool) {
+	webhook.Publish(ctx, webhook.NewTenantEvent(webhook.TenantUpdated, dbTenant.ID, map[string]interface{}{
+		"successful": successful,
+	}))
 }
 
 func (c *TenantController) getExistingTenant(ctx context.Context, id uuid.UUID, osUsername string) (*tenant.Tenant, error) {
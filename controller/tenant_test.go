@@ -6,14 +6,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/fabric8-services/fabric8-tenant/app"
 	apptest "github.com/fabric8-services/fabric8-tenant/app/test"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
 	"github.com/fabric8-services/fabric8-tenant/controller"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/progress"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/fabric8-services/fabric8-tenant/test"
 	"github.com/fabric8-services/fabric8-tenant/test/assertion"
@@ -80,6 +83,180 @@ func (s *TenantControllerTestSuite) TestShowTenant() {
 	})
 }
 
+func (s *TenantControllerTestSuite) TestTenantHistory() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, _, reset := s.newTestTenantController()
+	defer reset()
+
+	s.T().Run("OK", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		fxt := tf.NewTestFixture(t, s.DB, tf.Tenants(1), tf.Namespaces(1, func(fxt *tf.TestFixture, idx int) error {
+			fxt.Namespaces[idx].State = tenant.Ready
+			return nil
+		}))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		namespace := fxt.Namespaces[0]
+		require.NoError(t, repo.ChangeNamespaceState(namespace, tenant.Updating, tenant.ActorUpdater, nil))
+		require.NoError(t, repo.ChangeNamespaceState(namespace, tenant.Failed, tenant.ActorUpdater, fmt.Errorf("quota exceeded")))
+		// when
+		_, history := apptest.HistoryTenantOK(t,
+			testdoubles.CreateAndMockUserAndToken(s.T(), fxt.Tenants[0].ID.String(), false), svc, ctrl)
+		// then
+		require.Len(t, history.Data, 2)
+		assert.Equal(t, namespace.ID, *history.Data[0].NamespaceID)
+		assert.Equal(t, "updating", *history.Data[0].FromState)
+		assert.Equal(t, "failed", *history.Data[0].ToState)
+		assert.Equal(t, "updater", *history.Data[0].Actor)
+		assert.Equal(t, "quota exceeded", *history.Data[0].Error)
+		assert.Equal(t, "updating", *history.Data[1].ToState)
+		assert.Nil(t, history.Data[1].Error)
+	})
+
+	s.T().Run("Not found - non existing user", func(t *testing.T) {
+		defer gock.OffAll()
+		// when/then
+		apptest.HistoryTenantNotFound(t,
+			testdoubles.CreateAndMockUserAndToken(t, uuid.NewV4().String(), false), svc, ctrl)
+	})
+}
+
+func (s *TenantControllerTestSuite) TestUnidleTenant() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, _, reset := s.newTestTenantController()
+	defer reset()
+
+	s.T().Run("OK", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("john")),
+			tf.AddNamespaces(environment.TypeUser).State(tenant.Idled))
+		mockScalableObjectsOfIdledNamespace("john", "jenkins", 2)
+
+		// when
+		apptest.UnidleTenantNoContent(t,
+			testdoubles.CreateAndMockUserAndToken(s.T(), fxt.Tenants[0].ID.String(), false), svc, ctrl)
+
+		// then
+		assertion.AssertTenantFromDB(t, s.DB, fxt.Tenants[0].ID).
+			HasNamespaceOfTypeThat(environment.TypeUser).
+			HasState(tenant.Ready)
+		tnnt, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).GetTenant()
+		require.NoError(t, err)
+		assert.NotNil(t, tnnt.LastActiveAt)
+	})
+
+	s.T().Run("Internal error - unidling failed", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("johny")),
+			tf.AddNamespaces(environment.TypeUser).State(tenant.Idled))
+		gock.New(test.ClusterURL).
+			Get("/apis/apps.openshift.io/v1/namespaces/johny/deploymentconfigs").
+			Reply(500)
+
+		// when
+		apptest.UnidleTenantInternalServerError(t,
+			testdoubles.CreateAndMockUserAndToken(s.T(), fxt.Tenants[0].ID.String(), false), svc, ctrl)
+
+		// then
+		assertion.AssertTenantFromDB(t, s.DB, fxt.Tenants[0].ID).
+			HasNamespaceOfTypeThat(environment.TypeUser).
+			HasState(tenant.Idled)
+	})
+
+	s.T().Run("Not found - non existing user", func(t *testing.T) {
+		defer gock.OffAll()
+		// when/then
+		apptest.UnidleTenantNotFound(t,
+			testdoubles.CreateAndMockUserAndToken(t, uuid.NewV4().String(), false), svc, ctrl)
+	})
+}
+
+func (s *TenantControllerTestSuite) TestShowTenantUnidlesIdledNamespaces() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, _, reset := s.newTestTenantController()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddSpecificTenants(tf.SingleWithName("john")),
+		tf.AddNamespaces(environment.TypeUser).State(tenant.Idled))
+	mockScalableObjectsOfIdledNamespace("john", "jenkins", 1)
+
+	// when
+	_, tnnt := apptest.ShowTenantOK(s.T(),
+		testdoubles.CreateAndMockUserAndToken(s.T(), fxt.Tenants[0].ID.String(), false), svc, ctrl)
+
+	// then
+	require.Len(s.T(), tnnt.Data.Attributes.Namespaces, 1)
+	assert.Equal(s.T(), "ready", *tnnt.Data.Attributes.Namespaces[0].State)
+	assert.NotNil(s.T(), tnnt.Data.Attributes.LastActiveAt)
+}
+
+// mockScalableObjectsOfIdledNamespace mocks the cluster responses for the unidling of the namespace with one idled deployment config
+func mockScalableObjectsOfIdledNamespace(namespace, dcName string, idledReplicas int) {
+	gock.New(test.ClusterURL).
+		Get(fmt.Sprintf("/apis/apps.openshift.io/v1/namespaces/%s/deploymentconfigs", namespace)).
+		Reply(200).
+		BodyString(fmt.Sprintf(`{"items": [{"metadata": {"name": "%s", "annotations": {"tenant.fabric8.io/idled-replicas": "%d"}}, "spec": {"replicas": 0}}]}`,
+			dcName, idledReplicas))
+	gock.New(test.ClusterURL).
+		Get(fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments", namespace)).
+		Reply(200).
+		BodyString(`{"items": []}`)
+	gock.New(test.ClusterURL).
+		Get(fmt.Sprintf("/apis/apps/v1/namespaces/%s/statefulsets", namespace)).
+		Reply(200).
+		BodyString(`{"items": []}`)
+	gock.New(test.ClusterURL).
+		Patch(fmt.Sprintf("/apis/apps.openshift.io/v1/namespaces/%s/deploymentconfigs/%s", namespace, dcName)).
+		SetMatcher(test.ExpectRequest(test.HasJSONBody(
+			fmt.Sprintf(`{"metadata": {"annotations": {"tenant.fabric8.io/idled-replicas": null}}, "spec": {"replicas": %d}}`, idledReplicas)))).
+		Reply(200)
+}
+
+func (s *TenantControllerTestSuite) TestTenantEvents() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, _, reset := s.newTestTenantController()
+	defer reset()
+
+	s.T().Run("OK - current state followed by the changes", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		fxt := tf.NewTestFixture(t, s.DB, tf.Tenants(1), tf.Namespaces(1))
+		ctx, cancel := context.WithTimeout(
+			testdoubles.CreateAndMockUserAndToken(t, fxt.Tenants[0].ID.String(), false), 500*time.Millisecond)
+		defer cancel()
+		// the event is published repeatedly as the stream doesn't have to be subscribed yet
+		go func() {
+			for ctx.Err() == nil {
+				progress.Publish(&progress.Event{Type: progress.ObjectApplied, TenantID: fxt.Tenants[0].ID,
+					Namespace: fxt.Namespaces[0].Name, Action: "update", Kind: "Role", Name: "admin", Applied: 3, Total: 20})
+				time.Sleep(20 * time.Millisecond)
+			}
+		}()
+
+		// when
+		rw := apptest.EventsTenantOK(t, ctx, svc, ctrl)
+
+		// then
+		assert.Equal(t, "text/event-stream", rw.Header().Get("Content-Type"))
+		body := rw.(*httptest.ResponseRecorder).Body.String()
+		assert.True(t, strings.HasPrefix(body, "event: namespace\ndata: "), body)
+		assert.Contains(t, body, fmt.Sprintf(`"namespace":"%s"`, fxt.Namespaces[0].Name))
+		assert.Contains(t, body, "event: progress\ndata: ")
+		assert.Contains(t, body, `"kind":"Role","name":"admin","applied":3,"total":20`)
+	})
+
+	s.T().Run("Unauhorized - no token", func(t *testing.T) {
+		defer gock.OffAll()
+		// when/then
+		apptest.EventsTenantUnauthorized(t, context.Background(), svc, ctrl)
+	})
+}
+
 func (s *TenantControllerTestSuite) TestSetupTenantOKWhenNoTenantExists() {
 	// given
 	defer gock.OffAll()
@@ -222,6 +399,33 @@ func (s *TenantControllerTestSuite) TestSetupTenantOKWhenTenantExistsInParallelF
 	}
 }
 
+func (s *TenantControllerTestSuite) TestSetupTenantOKWhenTenantWasDeleted() {
+	// given
+	defer gock.OffAll()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddSpecificTenants(tf.SingleWithName("johny")), tf.AddDefaultNamespaces())
+	id := fxt.Tenants[0].ID
+	repo := tenant.NewTenantRepository(s.DB, id)
+	require.NoError(s.T(), repo.DeleteNamespaces())
+	require.NoError(s.T(), repo.DeleteTenant())
+	svc, ctrl, config, reset := s.newTestTenantController()
+	defer reset()
+	calls := 0
+	testdoubles.MockPostRequestsToOS(&calls, test.ClusterURL, environment.DefaultEnvTypes, "johny")
+
+	// when
+	apptest.SetupTenantAccepted(s.T(), testdoubles.CreateAndMockUserAndToken(s.T(), id.String(), false), svc, ctrl)
+
+	// then
+	assert.Equal(s.T(), testdoubles.ExpectedNumberOfCallsWhenPost(s.T(), config), calls)
+	// the deleted tenant is replaced so its base name is used again
+	assertion.AssertTenantFromDB(s.T(), s.DB, id).
+		HasNsBaseName("johny").
+		HasNumberOfNamespaces(len(environment.DefaultEnvTypes))
+	deleted, err := repo.GetDeletedNamespaces()
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), deleted)
+}
+
 func (s *TenantControllerTestSuite) TestSetupTenantOKWhenAlreadyExists() {
 	// given
 	defer gock.OffAll()
@@ -328,6 +532,36 @@ func (s *TenantControllerTestSuite) TestSetupConflictFailure() {
 	apptest.SetupTenantConflict(s.T(), testdoubles.CreateAndMockUserAndToken(s.T(), id.String(), false), svc, ctrl)
 }
 
+func (s *TenantControllerTestSuite) TestSuspendedTenantIsRefused() {
+	// given
+	defer gock.OffAll()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddSpecificTenants(tf.SingleWithName("johny")), tf.AddNamespaces(environment.TypeUser))
+	id := fxt.Tenants[0].ID.String()
+	require.NoError(s.T(), tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).SuspendTenant("banned"))
+	svc, ctrl, _, reset := s.newTestTenantController()
+	defer reset()
+
+	s.T().Run("Forbidden - setup", func(t *testing.T) {
+		defer gock.OffAll()
+		// when
+		apptest.SetupTenantForbidden(t, testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl)
+		// then
+		assertion.AssertTenantFromDB(t, s.DB, fxt.Tenants[0].ID).HasNumberOfNamespaces(1)
+	})
+
+	s.T().Run("Forbidden - update", func(t *testing.T) {
+		defer gock.OffAll()
+		// when/then
+		apptest.UpdateTenantForbidden(t, testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl)
+	})
+
+	s.T().Run("Forbidden - unidle", func(t *testing.T) {
+		defer gock.OffAll()
+		// when/then
+		apptest.UnidleTenantForbidden(t, testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl)
+	})
+}
+
 func (s *TenantControllerTestSuite) TestDeleteTenantOK() {
 	// given
 	defer gock.OffAll()
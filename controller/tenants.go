@@ -16,8 +16,11 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/jsonapi"
 	"github.com/fabric8-services/fabric8-tenant/openshift"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
+	"github.com/fabric8-services/fabric8-wit/rest"
 	"github.com/goadesign/goa"
 	errs "github.com/pkg/errors"
+	"time"
 )
 
 var SERVICE_ACCOUNTS = []string{"fabric8-jenkins-idler", "rh-che"}
@@ -81,52 +84,307 @@ func (c *TenantsController) Show(ctx *app.ShowTenantsContext) error {
 	return ctx.OK(result)
 }
 
-// Search runs the search action.
-func (c *TenantsController) Search(ctx *app.SearchTenantsContext) error {
-	if !commonauth.IsSpecificServiceAccount(ctx, SERVICE_ACCOUNTS...) {
+// Restore runs the restore action.
+func (c *TenantsController) Restore(ctx *app.RestoreTenantsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
 		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
 	}
 
-	// find tenant in DB
-	tenant, err := c.tenantService.LookupTenantByClusterAndNamespace(ctx.MasterURL, ctx.Namespace)
+	tenantID := ctx.TenantID
+	tenantRepository := c.tenantService.NewTenantRepository(tenantID)
+	dbTenant, err := tenantRepository.GetDeletedTenant()
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
-			"err":        err,
-			"clusterURL": ctx.MasterURL,
-			"namespace":  ctx.Namespace,
-		}, "lookup for a tenant entity failed")
+			"err":      err,
+			"tenantID": tenantID,
+		}, "retrieval of deleted tenant entity from DB failed")
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
 
-	tenantRepository := c.tenantService.NewTenantRepository(tenant.ID)
-	// gets tenant's namespaces
+	// the namespaces are provisioned again in the cluster the last deleted namespace was located in
+	var clusterURL string
+	if ctx.Provision {
+		deletedNamespaces, err := tenantRepository.GetDeletedNamespaces()
+		if err != nil {
+			log.Error(ctx, map[string]interface{}{
+				"err":      err,
+				"tenantID": tenantID,
+			}, "retrieval of deleted namespaces from DB failed")
+			return jsonapi.JSONErrorResponse(ctx, err)
+		}
+		if len(deletedNamespaces) == 0 {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("provision",
+				"the tenant has no deleted namespace the cluster could be determined from"))
+		}
+		clusterURL = deletedNamespaces[0].MasterURL
+	}
+
+	if err := tenantRepository.RestoreTenant(); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "restoration of the tenant failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{"tenant_id": tenantID}, "tenant restored")
+
+	if ctx.Provision {
+		if dbTenant.NsBaseName == "" {
+			dbTenant.NsBaseName = environment.RetrieveUserName(dbTenant.OSUsername)
+		}
+		// the namespaces are provisioned by the service account on behalf of the admin
+		adminCtx := tenant.WithActor(ctx, tenant.ActorAdmin)
+		err := TenantUpdater{Config: c.config, ClusterService: c.clusterService, TenantService: c.tenantService}.
+			CreateInCluster(adminCtx, dbTenant, clusterURL, environment.DefaultEnvTypes)
+		if err != nil {
+			metric.RecordProvisionedTenant(false)
+			log.Error(ctx, map[string]interface{}{
+				"err":         err,
+				"tenantID":    tenantID,
+				"cluster_url": clusterURL,
+			}, "provisioning of the namespaces of the restored tenant failed")
+			return jsonapi.JSONErrorResponse(ctx, err)
+		}
+		metric.RecordProvisionedTenant(true)
+	}
+
+	namespaces, err := tenantRepository.GetNamespaces()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "retrieval of existing namespaces from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TenantSingle{Data: convertTenant(ctx, dbTenant, namespaces, c.clusterService.GetCluster)})
+}
+
+// Suspend runs the suspend action.
+func (c *TenantsController) Suspend(ctx *app.SuspendTenantsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	tenantID := ctx.TenantID
+	tenantRepository := c.tenantService.NewTenantRepository(tenantID)
+	dbTenant, err := tenantRepository.GetTenant()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "retrieval of tenant entity from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	// the flag is set first so the user can't get the rights back by the setup or update while the tenant is being suspended
+	if err := tenantRepository.SuspendTenant(value(ctx.Reason)); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "unable to mark the tenant as suspended")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	err = TenantUpdater{Config: c.config, ClusterService: c.clusterService, TenantService: c.tenantService}.
+		Suspend(tenant.WithActor(ctx, tenant.ActorAdmin), dbTenant)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "suspension of the namespaces failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	log.Info(ctx, map[string]interface{}{"tenant_id": tenantID}, "tenant suspended")
+	webhook.Publish(ctx, webhook.NewTenantEvent(webhook.TenantSuspended, tenantID, map[string]interface{}{
+		"reason": value(ctx.Reason),
+	}))
+
+	dbTenant, err = tenantRepository.GetTenant()
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	namespaces, err := tenantRepository.GetNamespaces()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "retrieval of existing namespaces from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TenantSingle{Data: convertTenant(ctx, dbTenant, namespaces, c.clusterService.GetCluster)})
+}
+
+// Resume runs the resume action.
+func (c *TenantsController) Resume(ctx *app.ResumeTenantsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	tenantID := ctx.TenantID
+	tenantRepository := c.tenantService.NewTenantRepository(tenantID)
+	dbTenant, err := tenantRepository.GetTenant()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "retrieval of tenant entity from DB failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if !dbTenant.IsSuspended() {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("tenantID", "the tenant is not suspended"))
+	}
+
+	// the flag is removed only when the namespaces are restored so the resume can be repeated when it fails
+	err = TenantUpdater{Config: c.config, ClusterService: c.clusterService, TenantService: c.tenantService}.
+		Resume(tenant.WithActor(ctx, tenant.ActorAdmin), dbTenant)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "resuming of the namespaces failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if err := tenantRepository.ResumeTenant(); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "unable to remove the suspension of the tenant")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{"tenant_id": tenantID}, "tenant resumed")
+	webhook.Publish(ctx, webhook.NewTenantEvent(webhook.TenantResumed, tenantID, nil))
+
+	dbTenant, err = tenantRepository.GetTenant()
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
 	namespaces, err := tenantRepository.GetNamespaces()
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"err":      err,
-			"tenantID": tenant.ID,
+			"tenantID": tenantID,
 		}, "retrieval of existing namespaces from DB failed")
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
+	return ctx.OK(&app.TenantSingle{Data: convertTenant(ctx, dbTenant, namespaces, c.clusterService.GetCluster)})
+}
+
+// Activity runs the activity action.
+func (c *TenantsController) Activity(ctx *app.ActivityTenantsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, "fabric8-auth") {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	tenantID := ctx.TenantID
+	tenantRepository := c.tenantService.NewTenantRepository(tenantID)
+	if !tenantRepository.Exists() {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("tenants", tenantID.String()))
+	}
+	lastActiveAt := time.Now()
+	if ctx.LastActiveAt != nil {
+		lastActiveAt = *ctx.LastActiveAt
+	}
+	if err := tenantRepository.RecordActivity(lastActiveAt); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":      err,
+			"tenantID": tenantID,
+		}, "unable to record the activity of the tenant")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.NoContent()
+}
+
+// Search runs the search action.
+func (c *TenantsController) Search(ctx *app.SearchTenantsContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		if !commonauth.IsSpecificServiceAccount(ctx, SERVICE_ACCOUNTS...) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+		}
+		// the other services can only look up the tenant owning the namespace - listing the tenants is reserved for admins
+		if value(ctx.MasterURL) == "" {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("master_url", "the cluster has to be specified"))
+		}
+		if value(ctx.Namespace) == "" {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("namespace", "the namespace has to be specified"))
+		}
+	}
+
+	query := &tenant.TenantsQuery{
+		MasterURL:      value(ctx.MasterURL),
+		Namespace:      value(ctx.Namespace),
+		EnvType:        environment.Type(value(ctx.Type)),
+		State:          tenant.NamespaceState(value(ctx.State)),
+		Version:        value(ctx.Version),
+		Profile:        value(ctx.Profile),
+		EmailPrefix:    value(ctx.Email),
+		UsernamePrefix: value(ctx.Username),
+		CreatedAfter:   ctx.CreatedAfter,
+		CreatedBefore:  ctx.CreatedBefore,
+		UpdatedAfter:   ctx.UpdatedAfter,
+		UpdatedBefore:  ctx.UpdatedBefore,
+		Sort:           ctx.Sort,
+		After:          value(ctx.PageAfter),
+		Limit:          ctx.PageLimit,
+	}
+	// find tenants in DB
+	page, err := c.tenantService.SearchTenants(query)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":        err,
+			"clusterURL": query.MasterURL,
+			"namespace":  query.Namespace,
+		}, "search for tenant entities failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	// the namespace identifies the tenant - keeps the original lookup behavior
+	if query.Namespace != "" && len(page.Tenants) == 0 {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("tenant", ""))
+	}
 
 	result := app.TenantList{
-		Data: []*app.Tenant{
-			convertTenant(ctx, tenant, namespaces, c.clusterService.GetCluster),
-		},
-		// skipping the paging links for now
+		Data:  []*app.Tenant{},
+		Links: searchLinks(ctx, page),
 		Meta: &app.TenantListMeta{
-			TotalCount: 1,
+			TotalCount: page.TotalCount,
 		},
 	}
+	for _, tnnt := range page.Tenants {
+		result.Data = append(result.Data, convertTenant(ctx, tnnt, page.Namespaces[tnnt.ID], c.clusterService.GetCluster))
+	}
 	return ctx.OK(&result)
 }
 
+// searchLinks creates the links to the first and the next page of the tenants keeping all filters of the request
+func searchLinks(ctx *app.SearchTenantsContext, page *tenant.TenantsPage) *app.PagingLinks {
+	pageURL := func(after string) *string {
+		params := ctx.RequestData.URL.Query()
+		params.Del("page[after]")
+		if after != "" {
+			params.Set("page[after]", after)
+		}
+		link := rest.AbsoluteURL(ctx.RequestData.Request, ctx.RequestData.URL.Path)
+		if encoded := params.Encode(); encoded != "" {
+			link += "?" + encoded
+		}
+		return &link
+	}
+	links := &app.PagingLinks{
+		First: pageURL(""),
+	}
+	if page.Next != "" {
+		links.Next = pageURL(page.Next)
+	}
+	return links
+}
+
 // Delete runs the `delete` action to deprovision a user
 func (c *TenantsController) Delete(ctx *app.DeleteTenantsContext) error {
 	if !commonauth.IsSpecificServiceAccount(ctx, "fabric8-auth") {
 		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
 	}
 
+	// the namespaces are removed by the service account on behalf of the admin
+	adminCtx := tenant.WithActor(ctx, tenant.ActorAdmin)
+
 	// find tenant in DB
 	tenantID := ctx.TenantID
 	tenantRepository := c.tenantService.NewTenantRepository(tenantID)
@@ -161,7 +419,7 @@ func (c *TenantsController) Delete(ctx *app.DeleteTenantsContext) error {
 
 	// create openshift service
 	// we don't need token as DELETE uses cluster token
-	context := openshift.NewServiceContext(ctx, c.config, clusterMapping, tenant.OSUsername, nsBaseName, openshift.TokenResolver())
+	context := openshift.NewServiceContext(adminCtx, c.config, clusterMapping, tenant.OSUsername, nsBaseName, openshift.TokenResolver())
 	service := openshift.NewService(context, c.tenantService.NewTenantRepository(tenantID), environment.NewService())
 
 	// perform delete method on the list of existing namespaces
@@ -193,6 +451,7 @@ func (c *TenantsController) Delete(ctx *app.DeleteTenantsContext) error {
 	}
 
 	metric.RecordDeletedTenant(true)
+	webhook.Publish(ctx, webhook.NewTenantEvent(webhook.TenantDeleted, tenantID, nil))
 	log.Info(ctx, map[string]interface{}{"tenant_id": tenantID}, "tenant deleted")
 	return ctx.NoContent()
 }
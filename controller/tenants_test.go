@@ -2,7 +2,10 @@ package controller_test
 
 import (
 	"context"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/app"
 	goatest "github.com/fabric8-services/fabric8-tenant/app/test"
 	"github.com/fabric8-services/fabric8-tenant/client"
 	"github.com/fabric8-services/fabric8-tenant/cluster"
@@ -21,7 +24,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/h2non/gock.v1"
+	"net/url"
 	"testing"
+	"time"
 )
 
 type TenantsControllerTestSuite struct {
@@ -97,33 +102,106 @@ func (s *TenantsControllerTestSuite) TestSearchTenants() {
 		// given
 		fxt := tf.NewTestFixture(t, s.DB, tf.Tenants(1), tf.Namespaces(1))
 		// when
-		_, tenant := goatest.SearchTenantsOK(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, fxt.Namespaces[0].MasterURL, fxt.Namespaces[0].Name)
+		tenant := tenantsSearch{masterURL: &fxt.Namespaces[0].MasterURL, namespace: &fxt.Namespaces[0].Name}.
+			ok(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl)
 		// then
 		require.Len(t, tenant.Data, 1)
 		assert.Equal(t, fxt.Tenants[0].ID, *tenant.Data[0].ID)
 		assert.Equal(t, 1, len(tenant.Data[0].Attributes.Namespaces))
 	})
 
+	s.T().Run("OK - filtered and paginated list", func(t *testing.T) {
+		// given
+		clusterURL := "http://api." + uuid.NewV4().String() + ".example.com/"
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(3), tf.AddNamespaces(environment.TypeUser).State(tenant.Failed).MasterURL(clusterURL))
+		search := tenantsSearch{masterURL: &clusterURL, state: ptr.String("failed"), pageLimit: ptr.Int(2)}
+
+		// when
+		first := search.ok(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+
+		// then
+		require.Len(t, first.Data, 2)
+		assert.Equal(t, fxt.Tenants[0].ID, *first.Data[0].ID)
+		assert.Equal(t, fxt.Tenants[1].ID, *first.Data[1].ID)
+		assert.Equal(t, 3, first.Meta.TotalCount)
+		require.NotNil(t, first.Links.Next)
+		nextURL, err := url.Parse(*first.Links.Next)
+		require.NoError(t, err)
+		assert.Equal(t, clusterURL, nextURL.Query().Get("master_url"))
+		assert.Equal(t, "failed", nextURL.Query().Get("state"))
+
+		// and when
+		search.pageAfter = ptr.String(nextURL.Query().Get("page[after]"))
+		second := search.ok(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+
+		// then
+		require.Len(t, second.Data, 1)
+		assert.Equal(t, fxt.Tenants[2].ID, *second.Data[0].ID)
+		assert.Nil(t, second.Links.Next)
+	})
+
 	s.T().Run("Failures", func(t *testing.T) {
+		search := tenantsSearch{masterURL: ptr.String("foo"), namespace: ptr.String("bar")}
 
 		t.Run("Unauhorized - no token", func(t *testing.T) {
-			goatest.SearchTenantsUnauthorized(t, context.Background(), svc, ctrl, "foo", "bar")
+			search.unauthorized(t, context.Background(), svc, ctrl)
 		})
 
 		t.Run("Unauhorized - no SA token", func(t *testing.T) {
-			goatest.SearchTenantsUnauthorized(t, createInvalidSAContext(), svc, ctrl, "foo", "bar")
+			search.unauthorized(t, createInvalidSAContext(), svc, ctrl)
 		})
 
 		t.Run("Unauhorized - wrong SA token", func(t *testing.T) {
-			goatest.SearchTenantsUnauthorized(t, createValidSAContext("other service account"), svc, ctrl, "foo", "bar")
+			search.unauthorized(t, createValidSAContext("other service account"), svc, ctrl)
 		})
 
 		t.Run("Not found", func(t *testing.T) {
-			goatest.SearchTenantsNotFound(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, "foo", "bar")
+			search.notFound(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl)
+		})
+
+		t.Run("Bad request - wrong cursor", func(t *testing.T) {
+			tenantsSearch{pageAfter: ptr.String("wrong")}.badRequest(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+		})
+
+		t.Run("Bad request - listing by other than tenant update SA", func(t *testing.T) {
+			tenantsSearch{masterURL: ptr.String("foo")}.badRequest(t, createValidSAContext("rh-che"), svc, ctrl)
+			tenantsSearch{namespace: ptr.String("bar")}.badRequest(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl)
+			tenantsSearch{state: ptr.String("failed")}.badRequest(t, createValidSAContext("rh-che"), svc, ctrl)
 		})
 	})
 }
 
+// tenantsSearch holds the optional parameters of the search action passed to the generated test functions
+type tenantsSearch struct {
+	createdAfter, createdBefore            *time.Time
+	email, masterURL, namespace, pageAfter *string
+	pageLimit                              *int
+	profile, sort, state, nsType           *string
+	updatedAfter, updatedBefore            *time.Time
+	username, version                      *string
+}
+
+func (q tenantsSearch) ok(t *testing.T, ctx context.Context, svc *goa.Service, ctrl app.TenantsController) *app.TenantList {
+	_, list := goatest.SearchTenantsOK(t, ctx, svc, ctrl, q.createdAfter, q.createdBefore, q.email, q.masterURL, q.namespace,
+		q.pageAfter, q.pageLimit, q.profile, q.sort, q.state, q.nsType, q.updatedAfter, q.updatedBefore, q.username, q.version)
+	return list
+}
+
+func (q tenantsSearch) unauthorized(t *testing.T, ctx context.Context, svc *goa.Service, ctrl app.TenantsController) {
+	goatest.SearchTenantsUnauthorized(t, ctx, svc, ctrl, q.createdAfter, q.createdBefore, q.email, q.masterURL, q.namespace,
+		q.pageAfter, q.pageLimit, q.profile, q.sort, q.state, q.nsType, q.updatedAfter, q.updatedBefore, q.username, q.version)
+}
+
+func (q tenantsSearch) notFound(t *testing.T, ctx context.Context, svc *goa.Service, ctrl app.TenantsController) {
+	goatest.SearchTenantsNotFound(t, ctx, svc, ctrl, q.createdAfter, q.createdBefore, q.email, q.masterURL, q.namespace,
+		q.pageAfter, q.pageLimit, q.profile, q.sort, q.state, q.nsType, q.updatedAfter, q.updatedBefore, q.username, q.version)
+}
+
+func (q tenantsSearch) badRequest(t *testing.T, ctx context.Context, svc *goa.Service, ctrl app.TenantsController) {
+	goatest.SearchTenantsBadRequest(t, ctx, svc, ctrl, q.createdAfter, q.createdBefore, q.email, q.masterURL, q.namespace,
+		q.pageAfter, q.pageLimit, q.profile, q.sort, q.state, q.nsType, q.updatedAfter, q.updatedBefore, q.username, q.version)
+}
+
 func (s *TenantsControllerTestSuite) TestSuccessfullyDeleteTenants() {
 	repo := tenant.NewDBService(s.DB)
 
@@ -318,6 +396,280 @@ func (s *TenantsControllerTestSuite) TestFailedDeleteTenants() {
 	})
 }
 
+func (s *TenantsControllerTestSuite) TestRestoreTenants() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, reset := s.newTestTenantsController()
+	defer reset()
+	saCtx := createValidSAContext("fabric8-tenant-update")
+
+	s.T().Run("OK - without provisioning", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("qux")), tf.AddNamespaces(environment.TypeUser, environment.TypeChe))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		for _, ns := range fxt.Namespaces {
+			require.NoError(t, repo.DeleteNamespace(ns))
+		}
+		require.NoError(t, repo.DeleteTenant())
+
+		// when
+		_, restored := goatest.RestoreTenantsOK(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, false)
+
+		// then
+		assert.Equal(t, fxt.Tenants[0].ID, *restored.Data.ID)
+		assert.Empty(t, restored.Data.Attributes.Namespaces)
+		assertion.AssertTenant(t, repo).
+			Exists().
+			HasNoNamespace()
+		deleted, err := repo.GetDeletedNamespaces()
+		require.NoError(t, err)
+		assert.Len(t, deleted, 2)
+	})
+
+	s.T().Run("Failures", func(t *testing.T) {
+
+		t.Run("Unauhorized - wrong SA token", func(t *testing.T) {
+			// when/then
+			goatest.RestoreTenantsUnauthorized(t, createValidSAContext("fabric8-auth"), svc, ctrl, uuid.NewV4(), false)
+		})
+
+		t.Run("Not found - non existing tenant", func(t *testing.T) {
+			// when/then
+			goatest.RestoreTenantsNotFound(t, saCtx, svc, ctrl, uuid.NewV4(), false)
+		})
+
+		t.Run("Not found - tenant is not deleted", func(t *testing.T) {
+			// given
+			fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+			// when/then
+			goatest.RestoreTenantsNotFound(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, false)
+		})
+
+		t.Run("Bad request - no namespace to determine the cluster from", func(t *testing.T) {
+			// given
+			fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddNamespaces())
+			repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+			require.NoError(t, repo.DeleteTenant())
+			// when
+			goatest.RestoreTenantsBadRequest(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, true)
+			// then
+			assertion.AssertTenant(t, repo).DoesNotExist()
+		})
+	})
+}
+
+func (s *TenantsControllerTestSuite) TestTenantsActivity() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, reset := s.newTestTenantsController()
+	defer reset()
+	saCtx := createValidSAContext("fabric8-auth")
+
+	s.T().Run("OK - reported time of the activity", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1))
+		lastActiveAt := time.Now().Add(-time.Hour)
+
+		// when
+		goatest.ActivityTenantsNoContent(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, &lastActiveAt)
+
+		// then
+		tnnt, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).GetTenant()
+		require.NoError(t, err)
+		require.NotNil(t, tnnt.LastActiveAt)
+		assert.WithinDuration(t, lastActiveAt, *tnnt.LastActiveAt, time.Millisecond)
+	})
+
+	s.T().Run("OK - current time when not reported", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1))
+
+		// when
+		goatest.ActivityTenantsNoContent(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, nil)
+
+		// then
+		tnnt, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).GetTenant()
+		require.NoError(t, err)
+		require.NotNil(t, tnnt.LastActiveAt)
+		assert.WithinDuration(t, time.Now(), *tnnt.LastActiveAt, time.Minute)
+	})
+
+	s.T().Run("Failures", func(t *testing.T) {
+
+		t.Run("Unauhorized - wrong SA token", func(t *testing.T) {
+			// when/then
+			goatest.ActivityTenantsUnauthorized(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, uuid.NewV4(), nil)
+		})
+
+		t.Run("Not found - non existing tenant", func(t *testing.T) {
+			// when/then
+			goatest.ActivityTenantsNotFound(t, saCtx, svc, ctrl, uuid.NewV4(), nil)
+		})
+	})
+}
+
+func (s *TenantsControllerTestSuite) TestSuspendTenants() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, reset := s.newTestTenantsController()
+	defer reset()
+	saCtx := createValidSAContext("fabric8-tenant-update")
+
+	s.T().Run("OK", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("john")), tf.AddNamespaces(environment.TypeUser))
+		gock.New(test.ClusterURL).
+			Get("/oapi/v1/namespaces/john/rolebindings").
+			Reply(200).
+			BodyString(`{"items": [{"metadata": {"name": "user-edit"}, "roleRef": {"name": "edit"}, "subjects": [{"kind": "User", "name": "john"}]}]}`)
+		gock.New(test.ClusterURL).
+			Get("/api/v1/namespaces/john").
+			Reply(200).
+			BodyString(`{"metadata": {"name": "john"}}`)
+		gock.New(test.ClusterURL).
+			Patch("/api/v1/namespaces/john").
+			Reply(200)
+		gock.New(test.ClusterURL).
+			Delete("/oapi/v1/namespaces/john/rolebindings/user-edit").
+			Reply(200)
+		mockNoScalableObjects("john")
+
+		// when
+		_, suspended := goatest.SuspendTenantsOK(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, ptr.String("banned"))
+
+		// then
+		assert.True(t, *suspended.Data.Attributes.Suspended)
+		assert.NotNil(t, suspended.Data.Attributes.SuspendedAt)
+		assert.Equal(t, "banned", *suspended.Data.Attributes.SuspensionReason)
+		tnnt, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).GetTenant()
+		require.NoError(t, err)
+		assert.True(t, tnnt.IsSuspended())
+	})
+
+	s.T().Run("Failures", func(t *testing.T) {
+
+		t.Run("Unauhorized - wrong SA token", func(t *testing.T) {
+			// when/then
+			goatest.SuspendTenantsUnauthorized(t, createValidSAContext("fabric8-auth"), svc, ctrl, uuid.NewV4(), nil)
+		})
+
+		t.Run("Not found - non existing tenant", func(t *testing.T) {
+			// when/then
+			goatest.SuspendTenantsNotFound(t, saCtx, svc, ctrl, uuid.NewV4(), nil)
+		})
+
+		t.Run("Internal error - namespace cannot be suspended", func(t *testing.T) {
+			// given
+			defer gock.OffAll()
+			fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("johny")), tf.AddNamespaces(environment.TypeUser))
+			gock.New(test.ClusterURL).
+				Get("/oapi/v1/namespaces/johny/rolebindings").
+				Reply(500)
+
+			// when
+			goatest.SuspendTenantsInternalServerError(t, saCtx, svc, ctrl, fxt.Tenants[0].ID, nil)
+
+			// then the tenant stays suspended so the suspension can be repeated
+			tnnt, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).GetTenant()
+			require.NoError(t, err)
+			assert.True(t, tnnt.IsSuspended())
+		})
+	})
+}
+
+func (s *TenantsControllerTestSuite) TestResumeTenants() {
+	// given
+	defer gock.OffAll()
+	svc, ctrl, reset := s.newTestTenantsController()
+	defer reset()
+	saCtx := createValidSAContext("fabric8-tenant-update")
+
+	s.T().Run("OK", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("john")),
+			tf.AddNamespaces(environment.TypeUser).State(tenant.Idled))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		require.NoError(t, repo.SuspendTenant("banned"))
+		mockNoScalableObjects("john")
+		gock.New(test.ClusterURL).
+			Get("/api/v1/namespaces/john").
+			Reply(200).
+			BodyString(`{"metadata": {"name": "john", "annotations": {"tenant.fabric8.io/suspended-role-bindings": "{\"user-edit\":\"edit\"}"}}}`)
+		gock.New(test.ClusterURL).
+			Post("/oapi/v1/namespaces/john/rolebindings").
+			Reply(201)
+		gock.New(test.ClusterURL).
+			Patch("/api/v1/namespaces/john").
+			Reply(200)
+
+		// when
+		_, resumed := goatest.ResumeTenantsOK(t, saCtx, svc, ctrl, fxt.Tenants[0].ID)
+
+		// then
+		assert.False(t, *resumed.Data.Attributes.Suspended)
+		assert.Nil(t, resumed.Data.Attributes.SuspendedAt)
+		assertion.AssertTenant(t, repo).
+			HasNamespaceOfTypeThat(environment.TypeUser).
+			HasState(tenant.Ready)
+	})
+
+	s.T().Run("Failures", func(t *testing.T) {
+
+		t.Run("Unauhorized - wrong SA token", func(t *testing.T) {
+			// when/then
+			goatest.ResumeTenantsUnauthorized(t, createValidSAContext("fabric8-auth"), svc, ctrl, uuid.NewV4())
+		})
+
+		t.Run("Not found - non existing tenant", func(t *testing.T) {
+			// when/then
+			goatest.ResumeTenantsNotFound(t, saCtx, svc, ctrl, uuid.NewV4())
+		})
+
+		t.Run("Bad request - tenant is not suspended", func(t *testing.T) {
+			// given
+			fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+			// when/then
+			goatest.ResumeTenantsBadRequest(t, saCtx, svc, ctrl, fxt.Tenants[0].ID)
+		})
+
+		t.Run("Internal error - namespace cannot be resumed", func(t *testing.T) {
+			// given
+			defer gock.OffAll()
+			fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("johny")), tf.AddNamespaces(environment.TypeUser))
+			repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+			require.NoError(t, repo.SuspendTenant("banned"))
+			gock.New(test.ClusterURL).
+				Get("/apis/apps.openshift.io/v1/namespaces/johny/deploymentconfigs").
+				Reply(500)
+
+			// when
+			goatest.ResumeTenantsInternalServerError(t, saCtx, svc, ctrl, fxt.Tenants[0].ID)
+
+			// then
+			tnnt, err := repo.GetTenant()
+			require.NoError(t, err)
+			assert.True(t, tnnt.IsSuspended())
+		})
+	})
+}
+
+func mockNoScalableObjects(namespace string) {
+	gock.New(test.ClusterURL).
+		Get(fmt.Sprintf("/apis/apps.openshift.io/v1/namespaces/%s/deploymentconfigs", namespace)).
+		Reply(200).
+		BodyString(`{"items": []}`)
+	gock.New(test.ClusterURL).
+		Get(fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments", namespace)).
+		Reply(200).
+		BodyString(`{"items": []}`)
+	gock.New(test.ClusterURL).
+		Get(fmt.Sprintf("/apis/apps/v1/namespaces/%s/statefulsets", namespace)).
+		Reply(200).
+		BodyString(`{"items": []}`)
+}
+
 func createValidSAContext(sub string) context.Context {
 	claims := jwt.MapClaims{}
 	claims["service_accountname"] = sub
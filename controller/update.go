@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	commonauth "github.com/fabric8-services/fabric8-common/auth"
 	"github.com/fabric8-services/fabric8-common/convert/ptr"
@@ -12,10 +13,12 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/dbsupport"
 	"github.com/fabric8-services/fabric8-tenant/environment"
 	"github.com/fabric8-services/fabric8-tenant/jsonapi"
+	"github.com/fabric8-services/fabric8-tenant/leader"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/fabric8-services/fabric8-tenant/update"
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
+	"time"
 )
 
 // UpdateController implements the update resource.
@@ -25,16 +28,19 @@ type UpdateController struct {
 	config         *configuration.Data
 	clusterService cluster.Service
 	updateExecutor update.Executor
+	elector        *leader.Elector
 }
 
-// NewUpdateController creates a update controller.
-func NewUpdateController(service *goa.Service, db *gorm.DB, config *configuration.Data, clusterService cluster.Service, updateExecutor update.Executor) *UpdateController {
+// NewUpdateController creates a update controller. The elector may be nil if there is only one replica of the service.
+func NewUpdateController(service *goa.Service, db *gorm.DB, config *configuration.Data, clusterService cluster.Service, updateExecutor update.Executor,
+	elector *leader.Elector) *UpdateController {
 	return &UpdateController{
 		Controller:     service.NewController("UpdateController"),
 		db:             db,
 		config:         config,
 		clusterService: clusterService,
-		updateExecutor: updateExecutor}
+		updateExecutor: updateExecutor,
+		elector:        elector}
 }
 
 // Start runs the start action.
@@ -61,7 +67,7 @@ func (c *UpdateController) Start(ctx *app.StartUpdateContext) error {
 		}, "retrieval of TenantsUpdate entity failed")
 		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
 	}
-	if tenantsUpdate.Status == update.Updating && !update.IsOlderThanTimeout(tenantsUpdate.LastTimeUpdated, c.config) {
+	if tenantsUpdate.IsOngoing() && !update.IsOlderThanTimeout(tenantsUpdate.LastTimeUpdated, c.config) {
 		msg := fmt.Sprintf("There is an ongoing update with the last updated timestamp %s. "+
 			"To be sure that the update was interupted and a new one can be started, you have to wait %s since that time.",
 			tenantsUpdate.LastTimeUpdated, c.config.GetAutomatedUpdateRetrySleep())
@@ -70,11 +76,34 @@ func (c *UpdateController) Start(ctx *app.StartUpdateContext) error {
 				ConflictMsg: &msg}})
 	}
 
-	go update.NewTenantsUpdater(c.db, c.config, c.clusterService, c.updateExecutor, envTypesFilter, value(ctx.ClusterURL)).UpdateAllTenants()
+	if !c.elector.IsLeader() {
+		if err := c.handOverToLeader(ctx, update.NewStartRequest(value(ctx.EnvType), value(ctx.ClusterURL))); err != nil {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+		}
+		return ctx.Accepted()
+	}
+	go update.NewTenantsUpdater(c.db, c.config, c.clusterService, c.updateExecutor, envTypesFilter, value(ctx.ClusterURL),
+		update.WithTrigger(update.TriggerAPI), update.WithLeader(c.elector)).UpdateAllTenants()
 
 	return ctx.Accepted()
 }
 
+// handOverToLeader stores the request so the replica that is the leader can take it and execute it
+func (c *UpdateController) handOverToLeader(ctx context.Context, request *update.Request) error {
+	if err := update.NewRepository(c.db).AddRequest(request); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":    err,
+			"action": request.Action,
+		}, "unable to hand over the update request to the leader")
+		return err
+	}
+	log.Info(ctx, map[string]interface{}{
+		"action":   request.Action,
+		"identity": c.elector.Identity(),
+	}, "this replica is not the leader - the update request was handed over to the leader")
+	return nil
+}
+
 func isOneOfDefaults(envType environment.Type) bool {
 	for _, defEnvType := range environment.DefaultEnvTypes {
 		if defEnvType == envType {
@@ -127,7 +156,28 @@ func (c *UpdateController) Show(ctx *app.ShowUpdateContext) error {
 		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
 	}
 
+	schedule, err := update.NewSchedule(c.config)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "parsing of maintenance windows failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	leaderIdentity, err := leader.GetLeader(c.db, update.LeaderLeaseName)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of the leader failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
 	updateData := convert(tenantsUpdate, numberOfOutdated)
+	updateData.Leader = optional(leaderIdentity)
+	if start, end, found := schedule.NextWindow(value(ctx.ClusterURL), time.Now()); found {
+		updateData.NextWindowStart = &start
+		updateData.NextWindowEnd = &end
+	}
 	return ctx.OK(&app.UpdateDataSingle{Data: updateData})
 }
 
@@ -140,8 +190,19 @@ func convert(tenantsUpdate *update.TenantsUpdate, numberOfOutdated int) *app.Upd
 				Version:  ptr.String(verManager.GetStoredVersion(tenantsUpdate)),
 			})
 	}
+	var stage, haltReason *string
+	if tenantsUpdate.Stage != "" {
+		stage = ptr.String(tenantsUpdate.Stage.String())
+	}
+	if tenantsUpdate.HaltReason != "" {
+		haltReason = ptr.String(tenantsUpdate.HaltReason)
+	}
 	return &app.UpdateData{
+		RunID:           tenantsUpdate.RunID,
+		Driver:          optional(tenantsUpdate.Driver),
 		Status:          ptr.String(tenantsUpdate.Status.String()),
+		Stage:           stage,
+		HaltReason:      haltReason,
 		LastTimeUpdated: ptr.Time(tenantsUpdate.LastTimeUpdated),
 		FailedCount:     ptr.Int(tenantsUpdate.FailedCount),
 		FileVersions:    fileVersions,
@@ -149,6 +210,297 @@ func convert(tenantsUpdate *update.TenantsUpdate, numberOfOutdated int) *app.Upd
 	}
 }
 
+// ListRuns runs the listRuns action.
+func (c *UpdateController) ListRuns(ctx *app.ListRunsUpdateContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	offset, limit := 0, 20
+	if ctx.Offset != nil {
+		offset = *ctx.Offset
+	}
+	if ctx.Limit != nil {
+		limit = *ctx.Limit
+	}
+	runs, totalCount, err := update.NewRepository(c.db).GetRuns(offset, limit)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of update runs failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	result := &app.UpdateRunDataList{
+		Data: []*app.UpdateRunData{},
+		Meta: &app.UpdateRunListMeta{
+			TotalCount: totalCount,
+		},
+	}
+	for _, run := range runs {
+		result.Data = append(result.Data, convertRun(run))
+	}
+	return ctx.OK(result)
+}
+
+// ShowRun runs the showRun action.
+func (c *UpdateController) ShowRun(ctx *app.ShowRunUpdateContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	repo := update.NewRepository(c.db)
+	run, err := repo.GetRun(ctx.RunID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":    err,
+			"run_id": ctx.RunID,
+		}, "retrieval of update run failed")
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	clusters, err := repo.GetRunClusters(run.ID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":    err,
+			"run_id": ctx.RunID,
+		}, "retrieval of clusters of update run failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	tenants, err := repo.GetRunTenants(run.ID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":    err,
+			"run_id": ctx.RunID,
+		}, "retrieval of tenant results of update run failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	workItems, err := repo.GetWorkItems(run.ID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":    err,
+			"run_id": ctx.RunID,
+		}, "retrieval of work items of update run failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	runData := convertRun(run)
+	for _, cluster := range clusters {
+		runData.Clusters = append(runData.Clusters, &app.UpdateRunCluster{
+			MasterURL:    ptr.String(cluster.MasterURL),
+			UpdatedCount: ptr.Int(cluster.UpdatedCount),
+			FailedCount:  ptr.Int(cluster.FailedCount),
+		})
+	}
+	for _, tnnt := range tenants {
+		runData.Tenants = append(runData.Tenants, convertRunTenant(tnnt))
+	}
+	for _, item := range workItems {
+		itemID := item.ID
+		runData.WorkItems = append(runData.WorkItems, &app.UpdateRunWorkItem{
+			ID:           &itemID,
+			MasterURL:    ptr.String(item.MasterURL),
+			Stage:        ptr.String(item.Stage.String()),
+			Status:       ptr.String(string(item.Status)),
+			ClaimedBy:    optional(item.ClaimedBy),
+			Attempts:     ptr.Int(item.Attempts),
+			TenantsCount: ptr.Int(len(item.GetTenantIDs())),
+			FailedCount:  ptr.Int(item.FailedCount),
+			Error:        optional(item.Error),
+			HeartbeatAt:  item.HeartbeatAt,
+			FinishedAt:   item.FinishedAt,
+		})
+	}
+	return ctx.OK(&app.UpdateRunDataSingle{Data: runData})
+}
+
+// ListFailed runs the listFailed action.
+func (c *UpdateController) ListFailed(ctx *app.ListFailedUpdateContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	failed, err := update.NewRepository(c.db).GetFailedTenants(nil)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of failed tenants failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	result := &app.UpdateRunTenantList{
+		Data: []*app.UpdateRunTenant{},
+		Meta: &app.UpdateRunListMeta{
+			TotalCount: len(failed),
+		},
+	}
+	for _, tnnt := range failed {
+		result.Data = append(result.Data, convertRunTenant(tnnt))
+	}
+	return ctx.OK(result)
+}
+
+// Retry runs the retry action.
+func (c *UpdateController) Retry(ctx *app.RetryUpdateContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	repo := update.NewRepository(c.db)
+	tenantsUpdate, err := repo.GetTenantsUpdate()
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of TenantsUpdate entity failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if tenantsUpdate.IsOngoing() && !update.IsOlderThanTimeout(tenantsUpdate.LastTimeUpdated, c.config) {
+		msg := fmt.Sprintf("There is an ongoing update with the last updated timestamp %s. "+
+			"The failed tenants can be retried when the update is finished.", tenantsUpdate.LastTimeUpdated)
+		return ctx.Conflict(&app.ConflictMsgSingle{
+			Data: &app.ConflictMsg{
+				ConflictMsg: &msg}})
+	}
+
+	failed, err := repo.GetFailedTenants(ctx.TenantID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "retrieval of failed tenants failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if len(failed) == 0 {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("failed tenants", fmt.Sprintf("%v", ctx.TenantID)))
+	}
+
+	if !c.elector.IsLeader() {
+		if err := c.handOverToLeader(ctx, update.NewRetryRequest(ctx.TenantID)); err != nil {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+		}
+		return ctx.Accepted()
+	}
+	go update.NewTenantsUpdater(c.db, c.config, c.clusterService, c.updateExecutor, update.AllTypes, "",
+		update.WithLeader(c.elector)).RetryFailedTenants(ctx.TenantID)
+
+	return ctx.Accepted()
+}
+
+func convertRunTenant(tnnt *update.RunTenant) *app.UpdateRunTenant {
+	tenantID := tnnt.TenantID
+	return &app.UpdateRunTenant{
+		TenantID:   &tenantID,
+		MasterURL:  ptr.String(tnnt.MasterURL),
+		EnvTypes:   tnnt.GetEnvTypes(),
+		Namespaces: tnnt.GetNamespaces(),
+		Status:     ptr.String(tnnt.Status.String()),
+		Error:      optional(tnnt.Error),
+		UpdatedAt:  ptr.Time(tnnt.CreatedAt),
+	}
+}
+
+func convertRun(run *update.Run) *app.UpdateRunData {
+	runID := run.ID
+	return &app.UpdateRunData{
+		ID:             &runID,
+		TriggeredBy:    optional(string(run.TriggeredBy)),
+		EnvTypeFilter:  optional(run.EnvTypeFilter),
+		ClusterFilter:  optional(run.ClusterFilter),
+		Status:         ptr.String(run.Status.String()),
+		Stage:          optional(run.Stage.String()),
+		HaltReason:     optional(run.HaltReason),
+		FailedCount:    ptr.Int(run.FailedCount),
+		StartedAt:      ptr.Time(run.StartedAt),
+		FinishedAt:     run.FinishedAt,
+		VersionsBefore: convertFileVersions(run.GetVersionsBefore()),
+		VersionsAfter:  convertFileVersions(run.GetVersionsAfter()),
+	}
+}
+
+func convertFileVersions(versions map[string]string) []*app.FileWithVersion {
+	var fileVersions []*app.FileWithVersion
+	for _, verManager := range update.RetrieveVersionManagers() {
+		if version, found := versions[verManager.FileName]; found {
+			fileVersions = append(fileVersions,
+				&app.FileWithVersion{
+					FileName: ptr.String(verManager.FileName),
+					Version:  ptr.String(version),
+				})
+		}
+	}
+	return fileVersions
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// Pause runs the pause action.
+func (c *UpdateController) Pause(ctx *app.PauseUpdateContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	paused, err := c.changeState(func(repo update.Repository) (bool, error) {
+		return repo.Pause()
+	})
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "pausing of tenants update failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if !paused {
+		msg := "There is no running update that could be paused."
+		return ctx.Conflict(&app.ConflictMsgSingle{
+			Data: &app.ConflictMsg{
+				ConflictMsg: &msg}})
+	}
+
+	return ctx.Accepted()
+}
+
+// Resume runs the resume action.
+func (c *UpdateController) Resume(ctx *app.ResumeUpdateContext) error {
+	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+
+	resumed, err := c.changeState(func(repo update.Repository) (bool, error) {
+		return repo.Resume()
+	})
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "resuming of tenants update failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if !resumed {
+		msg := "There is no paused update that could be resumed."
+		return ctx.Conflict(&app.ConflictMsgSingle{
+			Data: &app.ConflictMsg{
+				ConflictMsg: &msg}})
+	}
+
+	return ctx.Accepted()
+}
+
+// changeState changes the state of the ongoing update and propagates it to the record of the latest run
+func (c *UpdateController) changeState(change func(repo update.Repository) (bool, error)) (bool, error) {
+	var changed bool
+	err := dbsupport.Transaction(c.db, func(tx *gorm.DB) error {
+		repo := update.NewRepository(tx)
+		var err error
+		if changed, err = change(repo); err != nil || !changed {
+			return err
+		}
+		return repo.SyncRun()
+	})
+	return changed, err
+}
+
 // Stop runs the stop action.
 func (c *UpdateController) Stop(ctx *app.StopUpdateContext) error {
 	if !commonauth.IsSpecificServiceAccount(ctx, commonauth.TenantUpdate) {
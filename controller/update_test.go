@@ -9,6 +9,7 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/controller"
 	"github.com/fabric8-services/fabric8-tenant/dbsupport"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/leader"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/fabric8-services/fabric8-tenant/test"
 	"github.com/fabric8-services/fabric8-tenant/test/assertion"
@@ -19,10 +20,12 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/update"
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/h2non/gock.v1"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -254,7 +257,7 @@ func (s *UpdateControllerTestSuite) TestShowUpdateOk() {
 	tf.FillDB(s.T(), s.DB, tf.AddTenants(6), tf.AddDefaultNamespaces().Outdated())
 	tf.FillDB(s.T(), s.DB, tf.AddTenants(4), tf.AddDefaultNamespaces().MasterURL("http://api.cluster2/").Outdated())
 
-	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete"} {
+	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete", "halted"} {
 		s.T().Run("with status "+status, func(t *testing.T) {
 			// given
 			testupdate.Tx(t, s.DB, func(repo update.Repository) error {
@@ -267,6 +270,7 @@ func (s *UpdateControllerTestSuite) TestShowUpdateOk() {
 				}
 				tenantsUpdate.Status = update.Status(status)
 				tenantsUpdate.FailedCount = 10
+				tenantsUpdate.Stage = update.Canary
 				return repo.SaveTenantsUpdate(tenantsUpdate)
 			})
 			after := time.Now()
@@ -277,6 +281,7 @@ func (s *UpdateControllerTestSuite) TestShowUpdateOk() {
 
 			// then
 			assert.Equal(t, status, *updateData.Data.Status)
+			assert.Equal(t, "canary", *updateData.Data.Stage)
 			assert.Equal(t, 10, *updateData.Data.FailedCount)
 			assert.True(t, after.After(*updateData.Data.LastTimeUpdated))
 			assert.Len(t, updateData.Data.FileVersions, len(versionManagers))
@@ -297,6 +302,117 @@ func (s *UpdateControllerTestSuite) TestShowUpdateOk() {
 	}
 }
 
+func (s *UpdateControllerTestSuite) TestListAndShowRunsFailures() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	svc, ctrl, reset := s.newUpdateController(testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration), 0)
+	defer reset()
+
+	s.T().Run("Unauhorized - no token", func(t *testing.T) {
+		// when/then
+		goatest.ListRunsUpdateUnauthorized(t, context.Background(), svc, ctrl, nil, nil)
+		goatest.ShowRunUpdateUnauthorized(t, context.Background(), svc, ctrl, uuid.NewV4())
+	})
+
+	s.T().Run("Unauhorized - wrong SA token", func(t *testing.T) {
+		// when/then
+		goatest.ListRunsUpdateUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, nil, nil)
+		goatest.ShowRunUpdateUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, uuid.NewV4())
+	})
+
+	s.T().Run("Not found", func(t *testing.T) {
+		// when/then
+		goatest.ShowRunUpdateNotFound(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, uuid.NewV4())
+		goatest.RetryUpdateNotFound(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, []uuid.UUID{uuid.NewV4()})
+	})
+
+	s.T().Run("Unauhorized - failed tenants", func(t *testing.T) {
+		// when/then
+		goatest.ListFailedUpdateUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl)
+		goatest.RetryUpdateUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl, nil)
+	})
+}
+
+func (s *UpdateControllerTestSuite) TestListAndShowRunsOk() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	svc, ctrl, reset := s.newUpdateController(testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration), 0)
+	defer reset()
+	var runs []*update.Run
+	for i := 0; i < 3; i++ {
+		run := &update.Run{TriggeredBy: update.TriggerAPI}
+		testupdate.Tx(s.T(), s.DB, func(repo update.Repository) error {
+			return repo.StartRun(run)
+		})
+		runs = append(runs, run)
+	}
+	tenantID := uuid.NewV4()
+	testupdate.Tx(s.T(), s.DB, func(repo update.Repository) error {
+		err := repo.RecordTenantResult(
+			update.NewRunTenant(runs[2].ID, tenantID, test.ClusterURL, environment.DefaultEnvTypes, nil, fmt.Errorf("failed")))
+		if err != nil {
+			return err
+		}
+		return repo.UpdateStatus(update.Failed)
+	})
+	testupdate.Tx(s.T(), s.DB, func(repo update.Repository) error {
+		return repo.SyncRun()
+	})
+
+	s.T().Run("list runs", func(t *testing.T) {
+		// when
+		_, runList := goatest.ListRunsUpdateOK(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, ptr.Int(2), nil)
+
+		// then
+		require.Len(t, runList.Data, 2)
+		assert.True(t, runList.Meta.TotalCount >= 3)
+		assert.Equal(t, runs[2].ID, *runList.Data[0].ID)
+		assert.Equal(t, "failed", *runList.Data[0].Status)
+		assert.NotNil(t, runList.Data[0].FinishedAt)
+		assert.Equal(t, runs[1].ID, *runList.Data[1].ID)
+		assert.Equal(t, "failed", *runList.Data[1].Status)
+		assert.Equal(t, "api", *runList.Data[1].TriggeredBy)
+	})
+
+	s.T().Run("show run", func(t *testing.T) {
+		// when
+		_, runData := goatest.ShowRunUpdateOK(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, runs[2].ID)
+
+		// then
+		assert.Equal(t, runs[2].ID, *runData.Data.ID)
+		assert.Equal(t, "failed", *runData.Data.Status)
+		require.Len(t, runData.Data.Clusters, 1)
+		assert.Equal(t, test.ClusterURL, *runData.Data.Clusters[0].MasterURL)
+		assert.Equal(t, 0, *runData.Data.Clusters[0].UpdatedCount)
+		assert.Equal(t, 1, *runData.Data.Clusters[0].FailedCount)
+		require.Len(t, runData.Data.Tenants, 1)
+		assert.Equal(t, tenantID, *runData.Data.Tenants[0].TenantID)
+		assert.Equal(t, "failed", *runData.Data.Tenants[0].Status)
+		assert.Equal(t, "failed", *runData.Data.Tenants[0].Error)
+	})
+
+	s.T().Run("list failed tenants", func(t *testing.T) {
+		// when
+		_, failedList := goatest.ListFailedUpdateOK(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+
+		// then
+		require.Len(t, failedList.Data, 1)
+		assert.Equal(t, 1, failedList.Meta.TotalCount)
+		assert.Equal(t, tenantID, *failedList.Data[0].TenantID)
+		assert.Equal(t, "failed", *failedList.Data[0].Error)
+	})
+
+	s.T().Run("show update refers to the latest run", func(t *testing.T) {
+		// when
+		_, updateData := goatest.ShowUpdateOK(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl, nil, nil)
+
+		// then
+		assert.Equal(t, runs[2].ID, *updateData.Data.RunID)
+	})
+}
+
 func (s *UpdateControllerTestSuite) TestStopUpdateFailures() {
 	// given
 	defer gock.OffAll()
@@ -388,16 +504,151 @@ func (s *UpdateControllerTestSuite) TestStopUpdateOk() {
 	assert.NotEqual(s.T(), 250, updateExecutor.NumberOfCalls)
 }
 
+func (s *UpdateControllerTestSuite) TestPauseAndResumeUpdateFailures() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	svc, ctrl, reset := s.newUpdateController(testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration), 0)
+	defer reset()
+	testupdate.Tx(s.T(), s.DB, func(repo update.Repository) error {
+		return repo.UpdateStatus(update.Finished)
+	})
+
+	s.T().Run("Unauhorized - wrong SA token", func(t *testing.T) {
+		// when/then
+		goatest.PauseUpdateUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl)
+		goatest.ResumeUpdateUnauthorized(t, createValidSAContext("fabric8-jenkins-idler"), svc, ctrl)
+	})
+
+	s.T().Run("Conflict - nothing to pause or resume", func(t *testing.T) {
+		// when/then
+		goatest.PauseUpdateConflict(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+		goatest.ResumeUpdateConflict(t, createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+	})
+}
+
+func (s *UpdateControllerTestSuite) TestPauseAndResumeUpdateOk() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	updateExecutor.TimeToSleep = 200 * time.Millisecond
+	svc, ctrl, reset := s.newUpdateController(updateExecutor, time.Minute)
+	defer reset()
+	testdoubles.SetTemplateVersions()
+
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(10), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	configuration.Commit = "124abcd"
+	testupdate.Tx(s.T(), s.DB, func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0xy"); err != nil {
+			return err
+		}
+		return repo.UpdateStatus(update.Finished)
+	})
+	goatest.StartUpdateAccepted(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl, nil, nil)
+	s.waitForStatus(update.Updating, 5*time.Second)
+
+	// when
+	goatest.PauseUpdateAccepted(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+
+	// then
+	time.Sleep(500 * time.Millisecond)
+	numberOfCalls := atomic.LoadUint64(updateExecutor.NumberOfCalls)
+	time.Sleep(time.Second)
+	assert.Equal(s.T(), numberOfCalls, atomic.LoadUint64(updateExecutor.NumberOfCalls))
+	assert.True(s.T(), numberOfCalls < 10)
+	_, updateData := goatest.ShowUpdateOK(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl, nil, nil)
+	assert.Equal(s.T(), "paused", *updateData.Data.Status)
+	_, runData := goatest.ShowRunUpdateOK(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl, *updateData.Data.RunID)
+	assert.Equal(s.T(), "paused", *runData.Data.Status)
+	assert.Nil(s.T(), runData.Data.FinishedAt)
+
+	// and when
+	goatest.ResumeUpdateAccepted(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl)
+
+	// then
+	s.waitForStatus(update.Finished, 10*time.Second)
+	assert.Equal(s.T(), uint64(10), atomic.LoadUint64(updateExecutor.NumberOfCalls))
+}
+
+func (s *UpdateControllerTestSuite) waitForStatus(status update.Status, timeout time.Duration) {
+	err := test.WaitWithTimeout(timeout).Until(func() error {
+		tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+		if err != nil {
+			return err
+		}
+		if tenantsUpdate.Status != status {
+			return fmt.Errorf("the status is %s instead of %s", tenantsUpdate.Status, status)
+		}
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *UpdateControllerTestSuite) TestUpdateRequestsAreHandedOverToLeader() {
+	// given
+	defer gock.OffAll()
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	follower := leader.NewElector(s.DB, s.Configuration, update.LeaderLeaseName)
+	svc, ctrl, reset := s.newUpdateControllerWithElector(updateExecutor, 0, follower)
+	defer reset()
+	testdoubles.SetTemplateVersions()
+
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	configuration.Commit = "124abcd"
+	testupdate.Tx(s.T(), s.DB, func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0xy"); err != nil {
+			return err
+		}
+		return repo.UpdateStatus(update.Finished)
+	})
+
+	elected := leader.NewElector(s.DB, s.Configuration, update.LeaderLeaseName)
+	elected.Start()
+	defer elected.Stop()
+	err := test.WaitWithTimeout(5 * time.Second).Until(func() error {
+		if !elected.IsLeader() {
+			return fmt.Errorf("the elector hasn't become the leader yet")
+		}
+		return nil
+	})
+	require.NoError(s.T(), err)
+
+	// when
+	goatest.StartUpdateAccepted(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl, nil, ptr.String("user"))
+
+	// then
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(s.T(), uint64(0), atomic.LoadUint64(updateExecutor.NumberOfCalls))
+	requests, err := update.NewRepository(s.DB).TakeRequests()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), requests, 1)
+	assert.Equal(s.T(), update.RequestStart, requests[0].Action)
+	assert.Equal(s.T(), "user", requests[0].EnvType)
+
+	_, updateData := goatest.ShowUpdateOK(s.T(), createValidSAContext("fabric8-tenant-update"), svc, ctrl, nil, nil)
+	require.NotNil(s.T(), updateData.Data.Leader)
+	assert.Equal(s.T(), elected.Identity(), *updateData.Data.Leader)
+}
+
 func (s *UpdateControllerTestSuite) newUpdateController(executor *testupdate.DummyUpdateExecutor, timeout time.Duration) (*goa.Service, *controller.UpdateController, func()) {
+	return s.newUpdateControllerWithElector(executor, timeout, nil)
+}
+
+func (s *UpdateControllerTestSuite) newUpdateControllerWithElector(executor *testupdate.DummyUpdateExecutor, timeout time.Duration,
+	elector *leader.Elector) (*goa.Service, *controller.UpdateController, func()) {
 	resetEnvs := test.SetEnvironments(
 		test.Env("F8_AUTH_TOKEN_KEY", "foo"),
 		test.Env("F8_AUTOMATED_UPDATE_RETRY_SLEEP", timeout.String()),
 		test.Env("F8_API_SERVER_USE_TLS", "false"),
-		test.Env("F8_AUTOMATED_UPDATE_TIME_GAP", "0"))
+		test.Env("F8_AUTOMATED_UPDATE_TIME_GAP", "0"),
+		test.Env("F8_AUTOMATED_UPDATE_PACING_MAX_DELAY", "0"),
+		test.Env("F8_AUTOMATED_UPDATE_PAUSE_CHECK_INTERVAL", "100ms"))
 	clusterService, _, config, reset := testdoubles.PrepareConfigClusterAndAuthService(s.T())
 	svc := goa.New("Tenants-service")
 	executor.ClusterService = clusterService
-	return svc, controller.NewUpdateController(svc, s.DB, config, clusterService, executor), func() {
+	return svc, controller.NewUpdateController(svc, s.DB, config, clusterService, executor, elector), func() {
 		resetEnvs()
 		reset()
 	}
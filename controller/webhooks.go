@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	commonauth "github.com/fabric8-services/fabric8-common/auth"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/app"
+	"github.com/fabric8-services/fabric8-tenant/jsonapi"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
+	"github.com/goadesign/goa"
+	"github.com/jinzhu/gorm"
+	"net/url"
+)
+
+// WEBHOOK_SERVICE_ACCOUNTS are the service accounts that can subscribe webhooks to the tenant lifecycle events
+var WEBHOOK_SERVICE_ACCOUNTS = []string{"fabric8-wit", "rh-che", "online-registration", "fabric8-jenkins-idler"}
+
+// WebhooksController implements the webhooks resource.
+type WebhooksController struct {
+	*goa.Controller
+	db *gorm.DB
+}
+
+// NewWebhooksController creates a webhooks controller.
+func NewWebhooksController(service *goa.Service, db *gorm.DB) *WebhooksController {
+	return &WebhooksController{
+		Controller: service.NewController("WebhooksController"),
+		db:         db,
+	}
+}
+
+// Create runs the create action.
+func (c *WebhooksController) Create(ctx *app.CreateWebhooksContext) error {
+	owner, ok := webhookOwner(ctx)
+	if !ok {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+	target, err := url.Parse(ctx.Payload.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("url", ctx.Payload.URL))
+	}
+
+	subscription := &webhook.Subscription{
+		Owner:  owner,
+		URL:    ctx.Payload.URL,
+		Secret: ctx.Payload.Secret,
+	}
+	var events []webhook.EventType
+	for _, event := range ctx.Payload.Events {
+		events = append(events, webhook.EventType(event))
+	}
+	subscription.SetEvents(events)
+
+	if err := webhook.NewRepository(c.db).CreateSubscription(subscription); err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":   err,
+			"owner": owner,
+		}, "creation of webhook subscription failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	log.Info(ctx, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"owner":           owner,
+		"events":          subscription.Events,
+	}, "webhook subscribed")
+	return ctx.Created(&app.WebhookDataSingle{Data: convertSubscription(subscription)})
+}
+
+// List runs the list action.
+func (c *WebhooksController) List(ctx *app.ListWebhooksContext) error {
+	owner, ok := webhookOwner(ctx)
+	if !ok {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+	subscriptions, err := webhook.NewRepository(c.db).GetSubscriptions(owner)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":   err,
+			"owner": owner,
+		}, "retrieval of webhook subscriptions failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	result := &app.WebhookDataList{
+		Data: []*app.WebhookData{},
+		Meta: &app.WebhookListMeta{
+			TotalCount: len(subscriptions),
+		},
+	}
+	for _, subscription := range subscriptions {
+		result.Data = append(result.Data, convertSubscription(subscription))
+	}
+	return ctx.OK(result)
+}
+
+// Show runs the show action.
+func (c *WebhooksController) Show(ctx *app.ShowWebhooksContext) error {
+	owner, ok := webhookOwner(ctx)
+	if !ok {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+	subscription, err := webhook.NewRepository(c.db).GetSubscription(owner, ctx.SubscriptionID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":             err,
+			"subscription_id": ctx.SubscriptionID,
+		}, "retrieval of webhook subscription failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if subscription == nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("webhook", ctx.SubscriptionID.String()))
+	}
+	return ctx.OK(&app.WebhookDataSingle{Data: convertSubscription(subscription)})
+}
+
+// Delete runs the delete action.
+func (c *WebhooksController) Delete(ctx *app.DeleteWebhooksContext) error {
+	owner, ok := webhookOwner(ctx)
+	if !ok {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+	deleted, err := webhook.NewRepository(c.db).DeleteSubscription(owner, ctx.SubscriptionID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":             err,
+			"subscription_id": ctx.SubscriptionID,
+		}, "removal of webhook subscription failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if !deleted {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("webhook", ctx.SubscriptionID.String()))
+	}
+	log.Info(ctx, map[string]interface{}{
+		"subscription_id": ctx.SubscriptionID,
+		"owner":           owner,
+	}, "webhook unsubscribed")
+	return ctx.NoContent()
+}
+
+// ListDeliveries runs the listDeliveries action.
+func (c *WebhooksController) ListDeliveries(ctx *app.ListDeliveriesWebhooksContext) error {
+	owner, ok := webhookOwner(ctx)
+	if !ok {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("Wrong token"))
+	}
+	repo := webhook.NewRepository(c.db)
+	subscription, err := repo.GetSubscription(owner, ctx.SubscriptionID)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":             err,
+			"subscription_id": ctx.SubscriptionID,
+		}, "retrieval of webhook subscription failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	if subscription == nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewNotFoundError("webhook", ctx.SubscriptionID.String()))
+	}
+
+	offset, limit := 0, 20
+	if ctx.Offset != nil {
+		offset = *ctx.Offset
+	}
+	if ctx.Limit != nil {
+		limit = *ctx.Limit
+	}
+	deliveries, totalCount, err := repo.GetDeliveries(subscription.ID, offset, limit)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":             err,
+			"subscription_id": ctx.SubscriptionID,
+		}, "retrieval of webhook deliveries failed")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+
+	result := &app.WebhookDeliveryDataList{
+		Data: []*app.WebhookDeliveryData{},
+		Meta: &app.WebhookListMeta{
+			TotalCount: totalCount,
+		},
+	}
+	for _, delivery := range deliveries {
+		result.Data = append(result.Data, convertDelivery(delivery))
+	}
+	return ctx.OK(result)
+}
+
+// webhookOwner returns the name of the calling service account if it is allowed to manage webhooks - every service account
+// sees only the subscriptions it created
+func webhookOwner(ctx context.Context) (string, bool) {
+	if !commonauth.IsSpecificServiceAccount(ctx, WEBHOOK_SERVICE_ACCOUNTS...) {
+		return "", false
+	}
+	return commonauth.ExtractServiceAccountName(ctx)
+}
+
+func convertSubscription(subscription *webhook.Subscription) *app.WebhookData {
+	subscriptionID := subscription.ID
+	events := []string{}
+	for _, event := range subscription.GetEvents() {
+		events = append(events, string(event))
+	}
+	return &app.WebhookData{
+		ID:        &subscriptionID,
+		URL:       ptr.String(subscription.URL),
+		Events:    events,
+		CreatedAt: ptr.Time(subscription.CreatedAt),
+	}
+}
+
+func convertDelivery(delivery *webhook.Delivery) *app.WebhookDeliveryData {
+	deliveryID := delivery.ID
+	eventID := delivery.EventID
+	data := &app.WebhookDeliveryData{
+		ID:             &deliveryID,
+		EventID:        &eventID,
+		EventType:      ptr.String(string(delivery.EventType)),
+		Status:         ptr.String(string(delivery.Status)),
+		Attempts:       ptr.Int(delivery.Attempts),
+		LastStatusCode: ptr.Int(delivery.LastStatusCode),
+		LastError:      optional(delivery.LastError),
+		CreatedAt:      ptr.Time(delivery.CreatedAt),
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	if delivery.Status == webhook.DeliveryPending {
+		data.NextAttemptAt = ptr.Time(delivery.NextAttemptAt)
+	}
+	return data
+}
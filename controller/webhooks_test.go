@@ -0,0 +1,127 @@
+package controller_test
+
+import (
+	"context"
+	"github.com/fabric8-services/fabric8-tenant/app"
+	goatest "github.com/fabric8-services/fabric8-tenant/app/test"
+	"github.com/fabric8-services/fabric8-tenant/controller"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type WebhooksControllerTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestWebhooksController(t *testing.T) {
+	suite.Run(t, &WebhooksControllerTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func (s *WebhooksControllerTestSuite) TestWebhooksFailures() {
+	// given
+	svc, ctrl := s.newWebhooksController()
+	payload := &app.WebhookPayload{URL: "http://che.example.com/events", Secret: "very-secret-webhook-key"}
+
+	s.T().Run("Unauhorized - no token", func(t *testing.T) {
+		// when/then
+		goatest.CreateWebhooksUnauthorized(t, context.Background(), svc, ctrl, payload)
+		goatest.ListWebhooksUnauthorized(t, context.Background(), svc, ctrl)
+	})
+
+	s.T().Run("Unauhorized - no SA token", func(t *testing.T) {
+		// when/then
+		goatest.CreateWebhooksUnauthorized(t, createInvalidSAContext(), svc, ctrl, payload)
+	})
+
+	s.T().Run("Unauhorized - wrong SA token", func(t *testing.T) {
+		// when/then
+		goatest.CreateWebhooksUnauthorized(t, createValidSAContext("other service account"), svc, ctrl, payload)
+		goatest.ListWebhooksUnauthorized(t, createValidSAContext("other service account"), svc, ctrl)
+	})
+
+	s.T().Run("Bad request - unsupported URL scheme", func(t *testing.T) {
+		// when/then
+		goatest.CreateWebhooksBadRequest(t, createValidSAContext("rh-che"), svc, ctrl,
+			&app.WebhookPayload{URL: "ftp://che.example.com/events", Secret: "very-secret-webhook-key"})
+	})
+
+	s.T().Run("Not found", func(t *testing.T) {
+		// when/then
+		goatest.ShowWebhooksNotFound(t, createValidSAContext("rh-che"), svc, ctrl, uuid.NewV4())
+		goatest.DeleteWebhooksNotFound(t, createValidSAContext("rh-che"), svc, ctrl, uuid.NewV4())
+		goatest.ListDeliveriesWebhooksNotFound(t, createValidSAContext("rh-che"), svc, ctrl, uuid.NewV4(), nil, nil)
+	})
+}
+
+func (s *WebhooksControllerTestSuite) TestWebhookLifecycle() {
+	// given
+	svc, ctrl := s.newWebhooksController()
+	webhook.Init(s.DB)
+	defer webhook.Init(nil)
+	payload := &app.WebhookPayload{
+		URL:    "https://che.example.com/events",
+		Secret: "very-secret-webhook-key",
+		Events: []string{string(webhook.NamespaceReady), string(webhook.TenantDeleted)},
+	}
+
+	// when
+	_, created := goatest.CreateWebhooksCreated(s.T(), createValidSAContext("rh-che"), svc, ctrl, payload)
+
+	// then
+	require.NotNil(s.T(), created.Data.ID)
+	subscriptionID := *created.Data.ID
+	assert.Equal(s.T(), payload.URL, *created.Data.URL)
+	assert.Equal(s.T(), payload.Events, created.Data.Events)
+
+	s.T().Run("subscription is listed and shown to the owner only", func(t *testing.T) {
+		// when
+		_, list := goatest.ListWebhooksOK(t, createValidSAContext("rh-che"), svc, ctrl)
+		_, shown := goatest.ShowWebhooksOK(t, createValidSAContext("rh-che"), svc, ctrl, subscriptionID)
+		_, otherList := goatest.ListWebhooksOK(t, createValidSAContext("fabric8-wit"), svc, ctrl)
+
+		// then
+		require.Len(t, list.Data, 1)
+		assert.Equal(t, subscriptionID, *list.Data[0].ID)
+		assert.Equal(t, subscriptionID, *shown.Data.ID)
+		assert.Empty(t, otherList.Data)
+		goatest.ShowWebhooksNotFound(t, createValidSAContext("fabric8-wit"), svc, ctrl, subscriptionID)
+		goatest.DeleteWebhooksNotFound(t, createValidSAContext("fabric8-wit"), svc, ctrl, subscriptionID)
+	})
+
+	s.T().Run("published events are listed in deliveries", func(t *testing.T) {
+		// given
+		webhook.Publish(context.Background(), webhook.NewTenantEvent(webhook.TenantDeleted, uuid.NewV4(), nil))
+		webhook.Publish(context.Background(), webhook.NewTenantEvent(webhook.TenantUpdated, uuid.NewV4(), nil))
+
+		// when
+		_, deliveries := goatest.ListDeliveriesWebhooksOK(t, createValidSAContext("rh-che"), svc, ctrl, subscriptionID, nil, nil)
+
+		// then
+		require.Len(t, deliveries.Data, 1)
+		assert.Equal(t, 1, deliveries.Meta.TotalCount)
+		assert.Equal(t, string(webhook.TenantDeleted), *deliveries.Data[0].EventType)
+		assert.Equal(t, string(webhook.DeliveryPending), *deliveries.Data[0].Status)
+		assert.NotNil(t, deliveries.Data[0].NextAttemptAt)
+	})
+
+	s.T().Run("deleted subscription is gone", func(t *testing.T) {
+		// when
+		goatest.DeleteWebhooksNoContent(t, createValidSAContext("rh-che"), svc, ctrl, subscriptionID)
+
+		// then
+		goatest.ShowWebhooksNotFound(t, createValidSAContext("rh-che"), svc, ctrl, subscriptionID)
+		_, list := goatest.ListWebhooksOK(t, createValidSAContext("rh-che"), svc, ctrl)
+		assert.Empty(t, list.Data)
+	})
+}
+
+func (s *WebhooksControllerTestSuite) newWebhooksController() (*goa.Service, *controller.WebhooksController) {
+	svc := goa.New("Webhooks-service")
+	return svc, controller.NewWebhooksController(svc, s.DB)
+}
@@ -0,0 +1,53 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var clusterData = a.Type("ClusterData", func() {
+	a.Description(`JSONAPI for the cluster object. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("api-url", d.String, "The URL of the OSO cluster")
+	a.Attribute("console-url", d.String, "The URL of the web console of the cluster")
+	a.Attribute("capacity-exhausted", d.Boolean, "Whether the cluster has no capacity left for new tenants")
+	a.Attribute("cached", d.Boolean, "Whether the cluster is in the cache - the last good entry is kept when the refresh of the cluster fails")
+	a.Attribute("token-valid", d.Boolean, "Whether the token of the cluster was resolved and accepted by the cluster during the latest refresh")
+	a.Attribute("last-success", d.DateTime, "When the cluster was successfully refreshed for the last time", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+	a.Attribute("last-error", d.String, "The error the latest refresh of the cluster failed with")
+	a.Attribute("last-error-at", d.DateTime, "When the refresh of the cluster failed for the last time", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+	a.Attribute("circuit-breaker", d.String, "The state of the circuit breaker of the requests sent to the cluster", func() {
+		a.Enum("closed", "open", "half-open")
+	})
+	a.Attribute("tenants-count", d.Integer, "The number of tenants that have namespaces located in the cluster")
+})
+
+var clusterListMeta = a.Type("ClusterListMeta", func() {
+	a.Attribute("totalCount", d.Integer)
+	a.Required("totalCount")
+})
+
+var clusterList = JSONList(
+	"ClusterData", "Holds a list of clusters with their health",
+	clusterData,
+	nil,
+	clusterListMeta)
+
+var _ = a.Resource("clusters", func() {
+	a.BasePath("/api/clusters")
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET(""),
+		)
+
+		a.Description("List the clusters retrieved from auth with their health and the numbers of tenants located in them.")
+		a.Response(d.OK, clusterList)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
@@ -0,0 +1,170 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var operationActions = []interface{}{"update", "clean", "recreate-missing", "delete"}
+
+var operationData = a.Type("OperationData", func() {
+	a.Description(`JSONAPI for the bulk operation object. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.UUID, "ID of the operation")
+	a.Attribute("action", d.String, "The action performed on the selected tenants", func() {
+		a.Enum(operationActions...)
+	})
+	a.Attribute("env-types", a.ArrayOf(d.String), "Types of the namespaces the update or re-creation is limited to")
+	a.Attribute("driver", d.String, "Identity of the replica that performs the operation")
+	a.Attribute("status", d.String, "The status of the operation", func() {
+		a.Enum("updating", "finished", "failed", "killed", "halted", "interrupted")
+	})
+	a.Attribute("halt-reason", d.String, "The reason why the operation was halted")
+	a.Attribute("total-count", d.Integer, "The number of the selected tenants")
+	a.Attribute("finished-count", d.Integer, "The number of tenants the operation was successfully performed on")
+	a.Attribute("failed-count", d.Integer, "The number of tenants the operation failed for")
+	a.Attribute("started-at", d.DateTime, "When the operation was started", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+	a.Attribute("finished-at", d.DateTime, "When the operation was finished", func() {
+		a.Example("2016-11-29T23:48:14Z")
+	})
+	a.Attribute("tenants", a.ArrayOf(operationTenant), "Results of the operation per tenant")
+})
+
+var operationTenant = a.Type("OperationTenant", func() {
+	a.Attribute("tenant-id", d.UUID, "ID of the tenant")
+	a.Attribute("master-url", d.String, "The URL of the OSO cluster the tenant is located in")
+	a.Attribute("env-types", a.ArrayOf(d.String), "Types of the namespaces the operation was performed on")
+	a.Attribute("namespaces", a.ArrayOf(d.String), "Names of the namespaces the operation was performed on")
+	a.Attribute("status", d.String, "The result of the operation - the pending tenants are skipped when the operation is stopped", func() {
+		a.Enum("pending", "finished", "failed")
+	})
+	a.Attribute("error", d.String, "The error the operation failed with")
+	a.Attribute("finished-at", d.DateTime, "When the operation was finished for the tenant")
+})
+
+var operationFilter = a.Type("OperationFilter", func() {
+	a.Description("The same filters as the ones of the tenants search")
+	a.Attribute("master-url", d.String, "the URL of the OSO cluster where the user's project are located")
+	a.Attribute("namespace", d.String, "the user's namespace (ie, the name of the OSO 'base' project)")
+	a.Attribute("type", d.String, "the type of the namespace", func() {
+		a.Enum("user", "che")
+	})
+	a.Attribute("state", d.String, "the state of the namespace", func() {
+		a.Enum("provisioning", "updating", "ready", "failed", "deleting", "idled")
+	})
+	a.Attribute("version", d.String, "the version of the templates the namespace was provisioned or updated with")
+	a.Attribute("profile", d.String, "the profile of the tenant")
+	a.Attribute("email", d.String, "the prefix of the tenant's email - case insensitive")
+	a.Attribute("username", d.String, "the prefix of the tenant's OpenShift username")
+	a.Attribute("created-after", d.DateTime, "the tenants created at or after the time")
+	a.Attribute("created-before", d.DateTime, "the tenants created before the time")
+	a.Attribute("updated-after", d.DateTime, "the tenants updated at or after the time")
+	a.Attribute("updated-before", d.DateTime, "the tenants updated before the time")
+})
+
+var operationPayload = a.Type("OperationPayload", func() {
+	a.Attribute("action", d.String, "The action that should be performed on the selected tenants", func() {
+		a.Enum(operationActions...)
+	})
+	a.Attribute("env-types", a.ArrayOf(d.String, func() {
+		a.Enum("user", "che")
+	}), "Types of the namespaces the update or re-creation should be limited to - all of them when empty")
+	a.Attribute("tenants", a.ArrayOf(d.UUID), "IDs of the selected tenants")
+	a.Attribute("filter", operationFilter, "The filter selecting the tenants when no ID is given")
+	a.Required("action")
+})
+
+var operationListMeta = a.Type("OperationListMeta", func() {
+	a.Attribute("totalCount", d.Integer)
+	a.Required("totalCount")
+})
+
+var operationSingle = JSONSingle(
+	"OperationData", "Holds information about one bulk operation",
+	operationData,
+	nil)
+
+var operationList = JSONList(
+	"OperationData", "Holds a list of bulk operations",
+	operationData,
+	nil,
+	operationListMeta)
+
+var _ = a.Resource("operations", func() {
+	a.BasePath("/api/operations")
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST(""),
+		)
+		a.Payload(operationPayload)
+
+		a.Description(`Start a bulk operation on the tenants selected either by their IDs or by the filter. The tenants are processed
+in background with the same concurrency, pacing and failure limits as the automated update. The selected tenants that don't exist
+are reported as failed. The operation cannot be started while the automated update or another operation is ongoing.`)
+		a.Response(d.Accepted, operationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Conflict, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET(""),
+		)
+		a.Params(func() {
+			a.Param("offset", d.Integer, "the number of the latest operations to skip", func() {
+				a.Minimum(0)
+			})
+			a.Param("limit", d.Integer, "the maximal number of operations to return (20 by default)", func() {
+				a.Minimum(1)
+				a.Maximum(100)
+			})
+		})
+
+		a.Description("List the bulk operations starting from the latest one.")
+		a.Response(d.OK, operationList)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:operationID"),
+		)
+		a.Params(func() {
+			a.Param("operationID", d.UUID, "ID of the operation")
+		})
+
+		a.Description("Get information about the bulk operation including the results per tenant.")
+		a.Response(d.OK, operationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("stop", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:operationID"),
+		)
+		a.Params(func() {
+			a.Param("operationID", d.UUID, "ID of the operation")
+		})
+
+		a.Description("Stop the ongoing bulk operation - the tenants being processed are finished, the pending ones are skipped.")
+		a.Response(d.Accepted)
+		a.Response(d.Conflict, conflictInfo)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
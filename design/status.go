@@ -5,6 +5,15 @@ import (
 	a "github.com/goadesign/goa/design/apidsl"
 )
 
+var componentStatus = a.Type("ComponentStatus", func() {
+	a.Attribute("name", d.String, "The name of the checked component")
+	a.Attribute("status", d.String, "The result of the check", func() {
+		a.Enum("ok", "degraded", "failed")
+	})
+	a.Attribute("message", d.String, "Details of the check, the error if it failed")
+	a.Required("name", "status")
+})
+
 // TenantStatus defines the status of the current running Tenant instance
 var TenantStatus = a.MediaType("application/vnd.status+json", func() {
 	a.Description("The status of the current running instance")
@@ -13,6 +22,7 @@ var TenantStatus = a.MediaType("application/vnd.status+json", func() {
 		a.Attribute("buildTime", d.String, "The time when built")
 		a.Attribute("startTime", d.String, "The time when started")
 		a.Attribute("error", d.String, "The error if any")
+		a.Attribute("components", a.ArrayOf(componentStatus), "The results of the checks of the components the instance depends on")
 		a.Required("commit", "buildTime", "startTime")
 	})
 	a.View("default", func() {
@@ -20,6 +30,7 @@ var TenantStatus = a.MediaType("application/vnd.status+json", func() {
 		a.Attribute("buildTime")
 		a.Attribute("startTime")
 		a.Attribute("error")
+		a.Attribute("components")
 	})
 })
 
@@ -36,4 +47,22 @@ var _ = a.Resource("status", func() {
 		a.Response(d.OK)
 		a.Response(d.ServiceUnavailable, TenantStatus)
 	})
+
+	a.Action("liveness", func() {
+		a.Routing(
+			a.GET("/liveness"),
+		)
+		a.Description("Check that the process of the current running instance is alive, i.e. that it doesn't need to be restarted. " +
+			"No dependency is checked, so an outage of any of them doesn't restart the instance")
+		a.Response(d.OK)
+	})
+
+	a.Action("readiness", func() {
+		a.Routing(
+			a.GET("/readiness"),
+		)
+		a.Description("Check that the current running instance and all components it depends on are ready to serve requests")
+		a.Response(d.OK)
+		a.Response(d.ServiceUnavailable, TenantStatus)
+	})
 })
@@ -32,6 +32,17 @@ var tenantAttributes = a.Type("TenantAttributes", func() {
 	a.Attribute("os-username", d.String, "The tenant's OpenShift username", func() {
 		a.Example("foobar")
 	})
+	a.Attribute("last-active-at", d.DateTime, "When the user was last active - the namespaces of inactive tenants are idled", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+	a.Attribute("suspended", d.Boolean, "Whether the tenant is suspended - the user has no access to the namespaces", func() {
+	})
+	a.Attribute("suspended-at", d.DateTime, "When the tenant was suspended", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+	a.Attribute("suspension-reason", d.String, "Why the tenant was suspended", func() {
+		a.Example("unpaid subscription")
+	})
 	a.Attribute("namespaces", a.ArrayOf(namespaceAttributes), "The tenant namespaces", func() {
 	})
 })
@@ -51,6 +62,8 @@ var namespaceAttributes = a.Type("NamespaceAttributes", func() {
 	})
 	a.Attribute("state", d.String, "The namespaces state", func() {
 	})
+	a.Attribute("state-reason", d.String, "Why the namespace ended up in the state - the error the last operation failed with", func() {
+	})
 	a.Attribute("cluster-url", d.String, "The cluster url", func() {
 	})
 	a.Attribute("cluster-console-url", d.String, "The cluster console url", func() {
@@ -68,6 +81,31 @@ var namespaceAttributes = a.Type("NamespaceAttributes", func() {
 	})
 })
 
+var namespaceStateTransition = a.Type("NamespaceStateTransition", func() {
+	a.Description(`JSONAPI for one change of the state of a tenant namespace. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.UUID, "ID of the state transition")
+	a.Attribute("namespace-id", d.UUID, "ID of the namespace - the namespace may not exist anymore")
+	a.Attribute("name", d.String, "The namespace name")
+	a.Attribute("type", d.String, "The namespace type", func() {
+		a.Enum("user", "che")
+	})
+	a.Attribute("from-state", d.String, "The state the namespace was in before the transition - empty when the namespace was created")
+	a.Attribute("to-state", d.String, "The state the namespace was moved to")
+	a.Attribute("actor", d.String, "Who caused the transition", func() {
+		a.Enum("user", "updater", "admin", "janitor", "idler")
+	})
+	a.Attribute("error", d.String, "The error that made the namespace end up in the state")
+	a.Attribute("created-at", d.DateTime, "When the transition happened", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+})
+
+var namespaceStateTransitionList = JSONList(
+	"NamespaceStateTransition", "Holds the history of the states of the tenant namespaces",
+	namespaceStateTransition,
+	nil,
+	nil)
+
 var tenantSingle = JSONSingle(
 	"tenant", "Holds a single Tenant",
 	tenant,
@@ -107,7 +145,9 @@ var _ = a.Resource("tenant", func() {
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.ServiceUnavailable, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
 	})
 
 	a.Action("update", func() {
@@ -121,7 +161,9 @@ var _ = a.Resource("tenant", func() {
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.ServiceUnavailable, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
 	})
 
 	a.Action("show", func() {
@@ -153,6 +195,52 @@ var _ = a.Resource("tenant", func() {
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.ServiceUnavailable, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("history", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/history"),
+		)
+
+		a.Description("Show the history of the states of the tenant namespaces starting from the latest change.")
+		a.Response(d.OK, namespaceStateTransitionList)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("unidle", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/unidle"),
+		)
+
+		a.Description("Scale the workloads of the idled tenant namespaces back to their original number of replicas.")
+		a.Response(d.NoContent)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.ServiceUnavailable, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("events", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/events"),
+		)
+
+		a.Description(`Stream the changes of the tenant namespaces as server-sent events. The current state of every namespace is sent first
+as the "namespace" event, then the "namespace" event is sent whenever an operation on the namespace starts or finishes
+and the "progress" event whenever an object of the namespace is applied on the cluster.`)
+		a.Response(d.OK, "text/event-stream")
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
 	})
 })
@@ -184,6 +272,79 @@ var _ = a.Resource("tenants", func() {
 			a.Param("tenantID", d.UUID, "ID of the tenant to delete/deprovision")
 		})
 		a.Description("delete/deprovision a single tenant environment.")
+		a.Response(d.NoContent)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.ServiceUnavailable, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("restore", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:tenantID/restore"),
+		)
+		a.Params(func() {
+			a.Param("tenantID", d.UUID, "ID of the deleted tenant to restore")
+			a.Param("provision", d.Boolean, "Provision the namespaces of the tenant again with the original base name in the cluster they were located in", func() {
+				a.Default(false)
+			})
+		})
+		a.Description(`Restore a deleted tenant. The tenant can be restored until it is purged after the retention period.
+The namespaces removed from the cluster are not restored unless they should be provisioned again.`)
+		a.Response(d.OK, tenantSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("suspend", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:tenantID/suspend"),
+		)
+		a.Params(func() {
+			a.Param("tenantID", d.UUID, "ID of the tenant to suspend")
+			a.Param("reason", d.String, "why the tenant is suspended")
+		})
+		a.Description(`Suspend a tenant without removing any data. The edit and admin rights of the user are removed from the namespaces
+and the workloads are scaled down. The setup and update of the suspended tenant are refused until it is resumed.`)
+		a.Response(d.OK, tenantSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("resume", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:tenantID/resume"),
+		)
+		a.Params(func() {
+			a.Param("tenantID", d.UUID, "ID of the suspended tenant to resume")
+		})
+		a.Description("Resume a suspended tenant - the rights of the user and the workloads of the namespaces are restored.")
+		a.Response(d.OK, tenantSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("activity", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:tenantID/activity"),
+		)
+		a.Params(func() {
+			a.Param("tenantID", d.UUID, "ID of the tenant whose user was active")
+			a.Param("last_active_at", d.DateTime, "when the user was last active - the current time is used if not set")
+		})
+		a.Description(`Record the last activity of the user reported by auth. The namespaces of the tenants that haven't been active
+for the configured period are idled.`)
 		a.Response(d.NoContent)
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
@@ -199,11 +360,36 @@ var _ = a.Resource("tenants", func() {
 		a.Params(func() {
 			a.Param("master_url", d.String, "the URL of the OSO cluster where the user's project are located")
 			a.Param("namespace", d.String, "the user's namespace (ie, the name of the OSO 'base' project)")
-			a.Required("master_url")
-			a.Required("namespace")
+			a.Param("type", d.String, "the type of the namespace", func() {
+				a.Enum("user", "che")
+			})
+			a.Param("state", d.String, "the state of the namespace", func() {
+				a.Enum("provisioning", "updating", "ready", "failed", "deleting", "idled")
+			})
+			a.Param("version", d.String, "the version of the templates the namespace was provisioned or updated with")
+			a.Param("profile", d.String, "the profile of the tenant")
+			a.Param("email", d.String, "the prefix of the tenant's email - case insensitive")
+			a.Param("username", d.String, "the prefix of the tenant's OpenShift username")
+			a.Param("created_after", d.DateTime, "the tenants created at or after the time")
+			a.Param("created_before", d.DateTime, "the tenants created before the time")
+			a.Param("updated_after", d.DateTime, "the tenants updated at or after the time")
+			a.Param("updated_before", d.DateTime, "the tenants updated before the time")
+			a.Param("sort", d.String, "the order of the tenants - the minus sign means the descending order", func() {
+				a.Enum("created-at", "-created-at", "updated-at", "-updated-at", "email", "-email", "username", "-username")
+				a.Default("created-at")
+			})
+			a.Param("page[after]", d.String, "the cursor of the page - it is taken from the next link of the previous page")
+			a.Param("page[limit]", d.Integer, "the maximal number of tenants in the page", func() {
+				a.Minimum(1)
+				a.Maximum(100)
+				a.Default(20)
+			})
 		})
 
-		a.Description("Lookup a tenant by cluster/namespace.")
+		a.Description(`Search the tenants by the cluster, type, state and version of their namespaces and by their profile, email,
+username and time of creation and last update. When the namespace is specified, then it looks up the tenant owning the namespace
+and responds with NotFound if there is no such a tenant. Only the tenant update service account can list the tenants -
+the other service accounts have to specify both the cluster and the namespace.`)
 		a.Response(d.OK, tenantList)
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
@@ -8,7 +8,11 @@ import (
 var updateData = a.Type("UpdateData", func() {
 	a.Description(`JSONAPI for the update info object. See also http://jsonapi.org/format/#document-resource-object`)
 	a.Attribute("status", d.String, "The update status", func() {
-		a.Enum("finished", "updating", "failed", "killed", "incomplete")
+		a.Enum("finished", "updating", "failed", "killed", "incomplete", "halted", "postponed", "paused", "interrupted")
+	})
+	a.Attribute("halt-reason", d.String, "The reason why the update was halted")
+	a.Attribute("stage", d.String, "The stage of the update - tenants of the canary cohort are updated before the rest of them", func() {
+		a.Enum("canary", "rollout")
 	})
 	a.Attribute("last-time-updated", d.DateTime, "When an update of the last batch of tenants was finished", func() {
 		a.Example("2016-11-29T23:18:14Z")
@@ -18,6 +22,15 @@ var updateData = a.Type("UpdateData", func() {
 	a.Attribute("file-versions", a.ArrayOf(fileWithVersion), "Lis of files and their versions used for the last finished run", func() {
 	})
 	a.Attribute("to-update", d.Integer, "The number of outdated tenants.")
+	a.Attribute("run-id", d.UUID, "ID of the latest update run")
+	a.Attribute("leader", d.String, "Identity of the replica that is currently the leader and drives the automated updates")
+	a.Attribute("driver", d.String, "Identity of the replica that drives the latest update run")
+	a.Attribute("next-window-start", d.DateTime, "Start of the current or the next maintenance window the automated update can run in", func() {
+		a.Example("2016-11-29T22:00:00Z")
+	})
+	a.Attribute("next-window-end", d.DateTime, "End of the current or the next maintenance window the automated update can run in", func() {
+		a.Example("2016-11-30T04:00:00Z")
+	})
 	a.Attribute("links", genericLinks)
 })
 
@@ -26,6 +39,93 @@ var fileWithVersion = a.Type("FileWithVersion", func() {
 	a.Attribute("version", d.String, "Version of the file that was set when the last update was finished")
 })
 
+var updateRunData = a.Type("UpdateRunData", func() {
+	a.Description(`JSONAPI for the update run object. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.UUID, "ID of the update run")
+	a.Attribute("triggered-by", d.String, "What started the update run", func() {
+		a.Enum("startup", "schedule", "api", "retry", "takeover")
+	})
+	a.Attribute("env-type-filter", d.String, "Environment type the update run was limited to")
+	a.Attribute("cluster-filter", d.String, "The URL of the OSO cluster the update run was limited to")
+	a.Attribute("status", d.String, "The status of the update run", func() {
+		a.Enum("finished", "updating", "failed", "killed", "incomplete", "halted", "postponed", "paused", "interrupted")
+	})
+	a.Attribute("stage", d.String, "The last stage of the update run", func() {
+		a.Enum("canary", "rollout")
+	})
+	a.Attribute("halt-reason", d.String, "The reason why the update run was halted")
+	a.Attribute("failed-count", d.Integer, "The number of failed tenant updates")
+	a.Attribute("started-at", d.DateTime, "When the update run was started", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+	a.Attribute("finished-at", d.DateTime, "When the update run was finished", func() {
+		a.Example("2016-11-29T23:48:14Z")
+	})
+	a.Attribute("versions-before", a.ArrayOf(fileWithVersion), "List of files and their versions stored when the run was started")
+	a.Attribute("versions-after", a.ArrayOf(fileWithVersion), "List of files and their versions stored when the run was finished")
+	a.Attribute("clusters", a.ArrayOf(updateRunCluster), "The numbers of updated and failed tenants per cluster")
+	a.Attribute("tenants", a.ArrayOf(updateRunTenant), "Results of the updates of the tenants")
+	a.Attribute("work-items", a.ArrayOf(updateRunWorkItem), "Batches of tenants distributed among the replicas")
+})
+
+var updateRunCluster = a.Type("UpdateRunCluster", func() {
+	a.Attribute("master-url", d.String, "The URL of the OSO cluster")
+	a.Attribute("updated-count", d.Integer, "The number of successfully updated tenants")
+	a.Attribute("failed-count", d.Integer, "The number of failed tenant updates")
+})
+
+var updateRunTenant = a.Type("UpdateRunTenant", func() {
+	a.Attribute("tenant-id", d.UUID, "ID of the tenant")
+	a.Attribute("master-url", d.String, "The URL of the OSO cluster the tenant is located in")
+	a.Attribute("env-types", a.ArrayOf(d.String), "Types of the namespaces that were updated")
+	a.Attribute("namespaces", a.ArrayOf(d.String), "Names of the namespaces that were updated")
+	a.Attribute("status", d.String, "The result of the tenant update", func() {
+		a.Enum("finished", "failed")
+	})
+	a.Attribute("error", d.String, "The error the tenant update failed with")
+	a.Attribute("updated-at", d.DateTime, "When the tenant update was finished")
+})
+
+var updateRunWorkItem = a.Type("UpdateRunWorkItem", func() {
+	a.Attribute("id", d.UUID, "ID of the batch")
+	a.Attribute("master-url", d.String, "The URL of the OSO cluster the tenants of the batch are located in")
+	a.Attribute("stage", d.String, "The stage of the update the batch belongs to", func() {
+		a.Enum("canary", "rollout")
+	})
+	a.Attribute("status", d.String, "The state of the batch", func() {
+		a.Enum("pending", "claimed", "finished", "abandoned", "cancelled")
+	})
+	a.Attribute("claimed-by", d.String, "Identity of the replica that claimed the batch the last time")
+	a.Attribute("attempts", d.Integer, "How many times the batch was claimed")
+	a.Attribute("tenants-count", d.Integer, "The number of tenants in the batch")
+	a.Attribute("failed-count", d.Integer, "The number of failed tenant updates in the batch")
+	a.Attribute("error", d.String, "The error the update of the batch failed with")
+	a.Attribute("heartbeat-at", d.DateTime, "When the replica reported the last time that it is updating the batch")
+	a.Attribute("finished-at", d.DateTime, "When the update of the batch was finished")
+})
+
+var updateRunListMeta = a.Type("UpdateRunListMeta", func() {
+	a.Attribute("totalCount", d.Integer)
+	a.Required("totalCount")
+})
+
+var updateRunSingle = JSONSingle(
+	"UpdateRunData", "Holds information about one update run",
+	updateRunData,
+	nil)
+
+var updateRunList = JSONList(
+	"UpdateRunData", "Holds a list of update runs",
+	updateRunData,
+	nil,
+	updateRunListMeta)
+
+var failedTenantList = JSONList(
+	"UpdateRunTenant", "Holds a list of tenants that failed in the latest update run",
+	updateRunTenant,
+	nil,
+	updateRunListMeta)
+
 var updateInfo = JSONSingle(
 	"UpdateData", "Holds information about last/ongoing update",
 	updateData,
@@ -80,6 +180,101 @@ var _ = a.Resource("update", func() {
 		a.Response(d.InternalServerError, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
 	})
+	a.Action("listRuns", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/runs"),
+		)
+		a.Params(func() {
+			a.Param("offset", d.Integer, "the number of the latest runs to skip", func() {
+				a.Minimum(0)
+			})
+			a.Param("limit", d.Integer, "the maximal number of runs to return (20 by default)", func() {
+				a.Minimum(1)
+				a.Maximum(100)
+			})
+		})
+
+		a.Description("List the history of update runs starting from the latest one.")
+		a.Response(d.OK, updateRunList)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("showRun", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/runs/:runID"),
+		)
+		a.Params(func() {
+			a.Param("runID", d.UUID, "ID of the update run to show")
+		})
+
+		a.Description("Get information about the update run including results per cluster and per tenant.")
+		a.Response(d.OK, updateRunSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("listFailed", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/failed"),
+		)
+
+		a.Description("List tenants that failed in the latest update run and haven't been successfully retried since then.")
+		a.Response(d.OK, failedTenantList)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("retry", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/failed"),
+		)
+		a.Params(func() {
+			a.Param("tenant_id", a.ArrayOf(d.UUID), "IDs of the failed tenants the retry should be limited to")
+		})
+
+		a.Description("Retry update of tenants that failed in the latest update run.")
+		a.Response(d.Accepted)
+		a.Response(d.Conflict, conflictInfo)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("pause", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/pause"),
+		)
+
+		a.Description("Pauses an ongoing cluster-wide update - the workers wait between tenants until the update is resumed.")
+		a.Response(d.Accepted)
+		a.Response(d.Conflict, conflictInfo)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("resume", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.PATCH(""),
+		)
+
+		a.Description("Resumes a paused cluster-wide update.")
+		a.Response(d.Accepted)
+		a.Response(d.Conflict, conflictInfo)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
 	a.Action("stop", func() {
 		a.Security("jwt")
 		a.Routing(
@@ -0,0 +1,162 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var webhookEventTypes = []interface{}{"tenant.created", "namespace.ready", "namespace.failed", "tenant.updated", "tenant.cleaned", "tenant.deleted",
+	"tenant.suspended", "tenant.resumed"}
+
+var webhookData = a.Type("WebhookData", func() {
+	a.Description(`JSONAPI for the webhook subscription object. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.UUID, "ID of the subscription")
+	a.Attribute("url", d.String, "The URL the events are sent to", func() {
+		a.Example("https://billing.example.com/api/tenant-events")
+	})
+	a.Attribute("events", a.ArrayOf(d.String), "Types of the events sent to the webhook - all of them when empty")
+	a.Attribute("created-at", d.DateTime, "When the subscription was created", func() {
+		a.Example("2016-11-29T23:18:14Z")
+	})
+})
+
+var webhookDeliveryData = a.Type("WebhookDeliveryData", func() {
+	a.Description(`JSONAPI for the delivery of one event to the webhook. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.UUID, "ID of the delivery - it is sent in the X-Tenant-Delivery header")
+	a.Attribute("event-id", d.UUID, "ID of the delivered event")
+	a.Attribute("event-type", d.String, "Type of the delivered event", func() {
+		a.Enum(webhookEventTypes...)
+	})
+	a.Attribute("status", d.String, "The state of the delivery", func() {
+		a.Enum("pending", "delivered", "failed")
+	})
+	a.Attribute("attempts", d.Integer, "How many times the delivery was attempted")
+	a.Attribute("last-status-code", d.Integer, "The status code the webhook responded with the last time - 0 if there was no response")
+	a.Attribute("last-error", d.String, "The error the last attempt failed with")
+	a.Attribute("next-attempt-at", d.DateTime, "When the delivery will be attempted again if it is still pending")
+	a.Attribute("created-at", d.DateTime, "When the event was published")
+	a.Attribute("delivered-at", d.DateTime, "When the event was successfully delivered")
+})
+
+var webhookPayload = a.Type("WebhookPayload", func() {
+	a.Attribute("url", d.String, "The URL the events should be sent to", func() {
+		a.Format("uri")
+	})
+	a.Attribute("secret", d.String, "The secret the HMAC-SHA256 signature of the events sent in the X-Tenant-Signature header is computed with", func() {
+		a.MinLength(16)
+	})
+	a.Attribute("events", a.ArrayOf(d.String, func() {
+		a.Enum(webhookEventTypes...)
+	}), "Types of the events that should be sent to the webhook - all of them when empty")
+	a.Required("url", "secret")
+})
+
+var webhookListMeta = a.Type("WebhookListMeta", func() {
+	a.Attribute("totalCount", d.Integer)
+	a.Required("totalCount")
+})
+
+var webhookSingle = JSONSingle(
+	"WebhookData", "Holds information about one webhook subscription",
+	webhookData,
+	nil)
+
+var webhookList = JSONList(
+	"WebhookData", "Holds a list of webhook subscriptions",
+	webhookData,
+	nil,
+	webhookListMeta)
+
+var webhookDeliveryList = JSONList(
+	"WebhookDeliveryData", "Holds a list of deliveries of events to a webhook",
+	webhookDeliveryData,
+	nil,
+	webhookListMeta)
+
+var _ = a.Resource("webhooks", func() {
+	a.BasePath("/api/webhooks")
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST(""),
+		)
+		a.Payload(webhookPayload)
+
+		a.Description("Subscribe a webhook to the tenant lifecycle events.")
+		a.Response(d.Created, webhookSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET(""),
+		)
+
+		a.Description("List the webhooks subscribed by the calling service account.")
+		a.Response(d.OK, webhookList)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:subscriptionID"),
+		)
+		a.Params(func() {
+			a.Param("subscriptionID", d.UUID, "ID of the subscription")
+		})
+
+		a.Description("Get the webhook subscription.")
+		a.Response(d.OK, webhookSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("delete", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:subscriptionID"),
+		)
+		a.Params(func() {
+			a.Param("subscriptionID", d.UUID, "ID of the subscription")
+		})
+
+		a.Description("Unsubscribe the webhook - the pending deliveries are dropped.")
+		a.Response(d.NoContent)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("listDeliveries", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:subscriptionID/deliveries"),
+		)
+		a.Params(func() {
+			a.Param("subscriptionID", d.UUID, "ID of the subscription")
+			a.Param("offset", d.Integer, "the number of the latest deliveries to skip", func() {
+				a.Minimum(0)
+			})
+			a.Param("limit", d.Integer, "the maximal number of deliveries to return (20 by default)", func() {
+				a.Minimum(1)
+				a.Maximum(100)
+			})
+		})
+
+		a.Description("List the deliveries of the events to the webhook starting from the latest one.")
+		a.Response(d.OK, webhookDeliveryList)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
@@ -8,8 +8,10 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/auth"
 	authclient "github.com/fabric8-services/fabric8-tenant/auth/client"
 	"github.com/fabric8-services/fabric8-tenant/environment/generated"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
 	"github.com/fabric8-services/fabric8-tenant/utils"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
+	"go.opentelemetry.io/otel/attribute"
 	"path"
 	"strconv"
 	"strings"
@@ -112,7 +114,12 @@ func (e *EnvData) Version() string {
 	return e.Templates.ConstructCompleteVersion()
 }
 
-func (s *Service) GetEnvData(ctx context.Context, envType Type) (*EnvData, error) {
+func (s *Service) GetEnvData(ctx context.Context, envType Type) (envData *EnvData, err error) {
+	_, span := tracing.Start(ctx, "environment.GetEnvData", attribute.String("type", envType.String()))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	var templates Templates
 	var mappedTemplates = RetrieveMappedTemplates()
 	templates = mappedTemplates[envType]
@@ -124,7 +131,7 @@ func (s *Service) GetEnvData(ctx context.Context, envType Type) (*EnvData, error
 		}
 	}
 
-	err := s.retrieveTemplates(templates)
+	err = s.retrieveTemplates(templates)
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/fabric8-services/fabric8-common/errors"
 	"github.com/fabric8-services/fabric8-common/log"
@@ -24,8 +25,16 @@ const (
 	ErrorCodeForbiddenError    = "forbidden_error"
 	ErrorCodeJWTSecurityError  = "jwt_security_error"
 	ErrorCodeDataConflict      = "data_conflict_error"
+	ErrorCodeUnavailableError  = "unavailable_error"
 )
 
+// UnavailableError is implemented by the errors saying that a service the request depends on is temporarily unavailable.
+// They are mapped to 503 with the Retry-After header
+type UnavailableError interface {
+	error
+	RetryAfter() time.Duration
+}
+
 // ErrorToJSONAPIError returns the JSONAPI representation
 // of an error and the HTTP status code that will be associated with it.
 // This function knows about the models package and the errors from there
@@ -71,6 +80,10 @@ func ErrorToJSONAPIError(ctx context.Context, err error) (app.JSONAPIError, int)
 		code = ErrorCodeForbiddenError
 		title = "Forbidden error"
 		statusCode = http.StatusForbidden
+	case UnavailableError:
+		code = ErrorCodeUnavailableError
+		title = "Service unavailable error"
+		statusCode = http.StatusServiceUnavailable
 	default:
 		code = ErrorCodeUnknownError
 		title = "Unknown error"
@@ -138,6 +151,11 @@ type Conflict interface {
 	Conflict(*app.JSONAPIErrors) error
 }
 
+// ServiceUnavailable represent a Context that can return a ServiceUnavailable HTTP status
+type ServiceUnavailable interface {
+	ServiceUnavailable(*app.JSONAPIErrors) error
+}
+
 // JSONErrorResponse auto maps the provided error to the correct response type
 // If all else fails, InternalServerError is returned
 func JSONErrorResponse(obj interface{}, err error) error {
@@ -175,6 +193,14 @@ func JSONErrorResponse(obj interface{}, err error) error {
 		if ctx, ok := x.(Conflict); ok {
 			return errs.WithStack(ctx.Conflict(jsonErr))
 		}
+	case http.StatusServiceUnavailable:
+		if unavailable, ok := errs.Cause(err).(UnavailableError); ok && goa.ContextResponse(c) != nil {
+			goa.ContextResponse(c).Header().Set("Retry-After", strconv.Itoa(int(unavailable.RetryAfter().Seconds())))
+		}
+		if ctx, ok := x.(ServiceUnavailable); ok {
+			return errs.WithStack(ctx.ServiceUnavailable(jsonErr))
+		}
+		return errs.WithStack(x.InternalServerError(jsonErr))
 	default:
 		//sentry.Sentry().CaptureError(c, err)
 		return errs.WithStack(x.InternalServerError(jsonErr))
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/fabric8-services/fabric8-common/errors"
 	"github.com/fabric8-services/fabric8-common/resource"
@@ -72,6 +73,14 @@ func TestErrorToJSONAPIError(t *testing.T) {
 	require.NotNil(t, jerr.Status)
 	require.Equal(t, strconv.Itoa(httpStatus), *jerr.Status)
 
+	// test unavailable error
+	jerr, httpStatus = jsonapi.ErrorToJSONAPIError(nil, errs.Wrap(unavailableError{}, "foo"))
+	require.Equal(t, http.StatusServiceUnavailable, httpStatus)
+	require.NotNil(t, jerr.Code)
+	require.Equal(t, jsonapi.ErrorCodeUnavailableError, *jerr.Code)
+	require.NotNil(t, jerr.Status)
+	require.Equal(t, strconv.Itoa(httpStatus), *jerr.Status)
+
 	// test unspecified error
 	jerr, httpStatus = jsonapi.ErrorToJSONAPIError(nil, fmt.Errorf("foobar"))
 	require.Equal(t, http.StatusInternalServerError, httpStatus)
@@ -80,3 +89,13 @@ func TestErrorToJSONAPIError(t *testing.T) {
 	require.NotNil(t, jerr.Status)
 	require.Equal(t, strconv.Itoa(httpStatus), *jerr.Status)
 }
+
+type unavailableError struct{}
+
+func (unavailableError) Error() string {
+	return "unavailable"
+}
+
+func (unavailableError) RetryAfter() time.Duration {
+	return time.Second
+}
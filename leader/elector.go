@@ -0,0 +1,171 @@
+package leader
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"os"
+	"sync"
+	"time"
+)
+
+const leasesTableName = "leader_leases"
+
+// Elector takes part in the election of the leader among all replicas of the service. The leader is the holder
+// of a lease stored in the DB - it has to renew the lease periodically, otherwise any other replica can acquire it
+// as soon as the lease expires.
+type Elector struct {
+	db       *gorm.DB
+	config   *configuration.Data
+	name     string
+	identity string
+
+	mux       sync.RWMutex
+	isLeader  bool
+	onElected []func()
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+}
+
+// NewElector creates an elector competing for the lease with the given name. The identity of the replica is composed
+// of the host name (the pod name) and a random suffix so it is unique even after restarts.
+func NewElector(db *gorm.DB, config *configuration.Data, name string) *Elector {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return &Elector{
+		db:       db,
+		config:   config,
+		name:     name,
+		identity: fmt.Sprintf("%s-%s", hostname, uuid.NewV4().String()[:8]),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Identity returns the identity of this replica
+func (e *Elector) Identity() string {
+	if e == nil {
+		return ""
+	}
+	return e.identity
+}
+
+// IsLeader returns true if this replica holds the lease. A nil elector means there is only one replica - it is always the leader
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	return e.isLeader
+}
+
+// OnElected registers a function that is called every time this replica becomes the leader. The function shouldn't block
+func (e *Elector) OnElected(do func()) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.onElected = append(e.onElected, do)
+}
+
+// Start starts competing for the lease in the configured interval
+func (e *Elector) Start() {
+	e.stopped.Add(1)
+	go func() {
+		defer e.stopped.Done()
+		ticker := time.NewTicker(e.config.GetLeaderRenewInterval())
+		defer ticker.Stop()
+		for {
+			e.tryAcquire()
+			select {
+			case <-ticker.C:
+			case <-e.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops competing for the lease and releases it if this replica is the leader, so another replica can take over immediately
+func (e *Elector) Stop() {
+	close(e.stop)
+	e.stopped.Wait()
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if !e.isLeader {
+		return
+	}
+	e.isLeader = false
+	err := e.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE name = ? AND holder = ?", leasesTableName), e.name, e.identity).Error
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err":      err,
+			"lease":    e.name,
+			"identity": e.identity,
+		}, "unable to release the leadership lease")
+	}
+}
+
+func (e *Elector) tryAcquire() {
+	acquired, err := e.acquireOrRenew()
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err":      err,
+			"lease":    e.name,
+			"identity": e.identity,
+		}, "unable to acquire or renew the leadership lease")
+	}
+
+	e.mux.Lock()
+	wasLeader := e.isLeader
+	e.isLeader = acquired
+	onElected := e.onElected
+	e.mux.Unlock()
+
+	if acquired && !wasLeader {
+		log.Info(nil, map[string]interface{}{
+			"lease":    e.name,
+			"identity": e.identity,
+		}, "this replica has become the leader")
+		for _, do := range onElected {
+			do()
+		}
+	} else if !acquired && wasLeader {
+		log.Warn(nil, map[string]interface{}{
+			"lease":    e.name,
+			"identity": e.identity,
+		}, "this replica has lost the leadership")
+	}
+}
+
+// acquireOrRenew inserts the lease or takes it over if it is held by this replica or if it has already expired.
+// The DB time is used so the clocks of the replicas don't need to be synchronized.
+func (e *Elector) acquireOrRenew() (bool, error) {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES (?, ?, NOW(), NOW(), NOW() + make_interval(secs => ?))
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, renewed_at = NOW(), expires_at = EXCLUDED.expires_at,
+			acquired_at = CASE WHEN %[1]s.holder = EXCLUDED.holder THEN %[1]s.acquired_at ELSE NOW() END
+		WHERE %[1]s.holder = EXCLUDED.holder OR %[1]s.expires_at < NOW()`, leasesTableName)
+	result := e.db.Exec(query, e.name, e.identity, e.config.GetLeaderLeaseDuration().Seconds())
+	if result.Error != nil {
+		return false, errors.Wrapf(result.Error, "failed to acquire the lease %s", e.name)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetLeader returns the identity of the current holder of the lease with the given name or an empty string if there is none
+func GetLeader(db *gorm.DB, name string) (string, error) {
+	var holders []string
+	err := db.Table(leasesTableName).Where("name = ? AND expires_at > NOW()", name).Pluck("holder", &holders).Error
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the holder of the lease %s", name)
+	}
+	if len(holders) == 0 {
+		return "", nil
+	}
+	return holders[0], nil
+}
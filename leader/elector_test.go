@@ -0,0 +1,128 @@
+package leader_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	"github.com/fabric8-services/fabric8-tenant/test/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type ElectorTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestElector(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &ElectorTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func (s *ElectorTestSuite) TestOnlyOneReplicaIsLeader() {
+	// given
+	config, reset := s.prepareConfig()
+	defer reset()
+	var elected uint64
+	first := leader.NewElector(s.DB, config, "test-lease")
+	first.OnElected(func() {
+		atomic.AddUint64(&elected, 1)
+	})
+	second := leader.NewElector(s.DB, config, "test-lease")
+	second.OnElected(func() {
+		atomic.AddUint64(&elected, 1)
+	})
+
+	// when
+	first.Start()
+	defer first.Stop()
+	s.waitForLeader(first)
+	second.Start()
+	defer second.Stop()
+
+	// then
+	time.Sleep(time.Second)
+	assert.True(s.T(), first.IsLeader())
+	assert.False(s.T(), second.IsLeader())
+	assert.Equal(s.T(), uint64(1), atomic.LoadUint64(&elected))
+	holder, err := leader.GetLeader(s.DB, "test-lease")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), first.Identity(), holder)
+}
+
+func (s *ElectorTestSuite) TestLeadershipIsHandedOverWhenLeaderStops() {
+	// given
+	config, reset := s.prepareConfig()
+	defer reset()
+	first := leader.NewElector(s.DB, config, "test-lease")
+	first.Start()
+	s.waitForLeader(first)
+	second := leader.NewElector(s.DB, config, "test-lease")
+	second.Start()
+	defer second.Stop()
+
+	// when
+	first.Stop()
+
+	// then
+	s.waitForLeader(second)
+	assert.False(s.T(), first.IsLeader())
+	holder, err := leader.GetLeader(s.DB, "test-lease")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), second.Identity(), holder)
+}
+
+func (s *ElectorTestSuite) TestLeadershipIsTakenOverWhenLeaseExpires() {
+	// given
+	config, reset := s.prepareConfig()
+	defer reset()
+	err := s.DB.Exec(`INSERT INTO leader_leases (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES ('test-lease', 'dead-replica', NOW(), NOW(), NOW() + make_interval(secs => 1))`).Error
+	require.NoError(s.T(), err)
+	elector := leader.NewElector(s.DB, config, "test-lease")
+
+	// when
+	elector.Start()
+	defer elector.Stop()
+
+	// then
+	time.Sleep(500 * time.Millisecond)
+	assert.False(s.T(), elector.IsLeader())
+	s.waitForLeader(elector)
+}
+
+func (s *ElectorTestSuite) TestNilElectorIsAlwaysLeader() {
+	// given
+	var elector *leader.Elector
+
+	// when
+	isLeader := elector.IsLeader()
+
+	// then
+	assert.True(s.T(), isLeader)
+	assert.Empty(s.T(), elector.Identity())
+}
+
+func (s *ElectorTestSuite) waitForLeader(elector *leader.Elector) {
+	err := test.WaitWithTimeout(5 * time.Second).Until(func() error {
+		if !elector.IsLeader() {
+			return fmt.Errorf("the replica %s hasn't become the leader yet", elector.Identity())
+		}
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *ElectorTestSuite) prepareConfig() (*configuration.Data, func()) {
+	reset := test.SetEnvironments(
+		test.Env("F8_LEADER_LEASE_DURATION", "1s"),
+		test.Env("F8_LEADER_RENEW_INTERVAL", "100ms"))
+	config, err := configuration.GetData()
+	require.NoError(s.T(), err)
+	return config, reset
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fabric8-services/fabric8-tenant/metric"
@@ -18,10 +22,16 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/controller"
 	"github.com/fabric8-services/fabric8-tenant/environment"
 	"github.com/fabric8-services/fabric8-tenant/jsonapi"
+	"github.com/fabric8-services/fabric8-tenant/leader"
 	"github.com/fabric8-services/fabric8-tenant/migration"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/progress"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/fabric8-services/fabric8-tenant/toggles"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
 	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
 	witmiddleware "github.com/fabric8-services/fabric8-wit/goamiddleware"
 	"github.com/goadesign/goa"
 	"github.com/goadesign/goa/logging/logrus"
@@ -66,6 +76,14 @@ func main() {
 
 	toggles.Init("f8tenant", config.GetTogglesURL())
 
+	stopTracing, err := tracing.Init(config)
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to initialize tracing")
+	}
+	defer stopTracing()
+
 	authService, err := auth.NewAuthService(config)
 	if err != nil {
 		log.Panic(nil, map[string]interface{}{
@@ -73,6 +91,9 @@ func main() {
 		}, "failed to initialize the auth.Service component")
 	}
 
+	// the requests to the clusters that are down fail fast instead of waiting through all the retries
+	openshift.ConfigureCircuitBreakers(config.GetCircuitBreakerFailureThreshold(), config.GetCircuitBreakerOpenDuration())
+
 	publicKeys, err := authService.GetPublicKeys()
 	if err != nil {
 		log.Panic(nil, map[string]interface{}{
@@ -87,6 +108,7 @@ func main() {
 	// Mount middleware
 	service.WithLogger(goalogrus.New(log.Logger()))
 	service.Use(middleware.RequestID())
+	service.Use(tracing.Middleware())
 	service.Use(gzip.Middleware(9))
 	service.Use(jsonapi.ErrorHandler(service, true))
 	service.Use(middleware.Recover())
@@ -103,6 +125,8 @@ func main() {
 		}, "failed to initialize the cluster.Service component")
 	}
 	defer clusterService.Stop()
+	// the tokens rejected by the clusters are resolved again and the stale cluster entries are refreshed right away
+	openshift.HandleRejectedTokens(clusterService.InvalidateToken)
 
 	//haltSentry, err := sentry.InitializeLogger(config, configuration.Commit)
 	//if err != nil {
@@ -116,22 +140,107 @@ func main() {
 	metric.RegisterMetrics()
 
 	tenantService := tenant.NewDBService(db)
+	metric.RegisterNamespacesCollector(tenantService)
 
 	tenantUpdater := controller.TenantUpdater{
 		Config:         config,
 		TenantService:  tenantService,
 		ClusterService: clusterService,
 	}
+
+	// the tenant lifecycle events are stored in DB and every replica sends the pending ones to the webhooks
+	webhook.Init(db)
+	dispatcher := webhook.NewDispatcher(db, config, nil)
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	// the progress of the operations on the namespaces is relayed to the streams of the tenants connected to any replica
+	relay := progress.NewRelay(db, config.GetPostgresConfigString())
+	if err := relay.Start(); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to relay the tenant progress events - the streams will get only the events of this replica")
+	} else {
+		defer relay.Stop()
+	}
+
+	// only the leader among all replicas drives the updates; the requests received by the other replicas are handed over
+	// to the leader via DB
+	elector := leader.NewElector(db, config, update.LeaderLeaseName)
+	requestsWatcher := update.NewRequestsWatcher(db, config, clusterService, tenantUpdater, elector)
+	requestsWatcher.Start()
+	defer requestsWatcher.Stop()
+	// every new leader takes over the update that was left ongoing by the previous one, regardless of what started it
+	elector.OnElected(func() {
+		go update.NewTenantsUpdater(db, config, clusterService, tenantUpdater, update.AllTypes, "",
+			update.WithTrigger(update.TriggerTakeover), update.WithLeader(elector)).TakeOverOngoingUpdate()
+	})
+
+	// the leader repairs the namespaces stuck in the provisioning or updating state, e.g. after a pod died
+	if config.IsJanitorEnabled() {
+		janitor := update.NewJanitor(db, config, tenantUpdater, elector)
+		janitor.Start()
+		defer janitor.Stop()
+	}
+
+	// the leader permanently removes the tenants and namespaces deleted before the retention period
+	if config.IsPurgeEnabled() {
+		purger := update.NewPurger(db, config, elector)
+		purger.Start()
+		defer purger.Stop()
+	}
+
+	// the leader idles the namespaces of the tenants that haven't been active for the configured period
+	if config.IsIdlerEnabled() {
+		idler := update.NewIdler(db, config, tenantUpdater, elector)
+		idler.Start()
+		defer idler.Stop()
+	}
+
+	// every replica claims and updates the batches of tenants when the work is distributed
+	stopWorker := func() {}
+	if config.IsAutomatedUpdateWorkDistributed() {
+		worker := update.NewWorker(db, config, tenantUpdater, elector.Identity())
+		worker.Start()
+		stopWorker = worker.Stop
+		defer worker.Stop()
+	}
+
 	// Check & do all tenants update
 	if config.IsAutomatedUpdateEnabled() {
 		log.Info(nil, map[string]interface{}{}, "automated update is enabled")
-		go update.NewTenantsUpdater(db, config, clusterService, tenantUpdater, update.AllTypes, "").UpdateAllTenants()
+		schedule, err := update.NewSchedule(config)
+		if err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"err": err,
+			}, "failed to parse maintenance windows of the automated update")
+		}
+		if schedule.IsEmpty() {
+			// the startup update is triggered only once - the update left ongoing by the previous leader is taken over anyway
+			var startupUpdate sync.Once
+			elector.OnElected(func() {
+				startupUpdate.Do(func() {
+					go update.NewTenantsUpdater(db, config, clusterService, tenantUpdater, update.AllTypes, "",
+						update.WithTrigger(update.TriggerStartup), update.WithLeader(elector)).UpdateAllTenants()
+				})
+			})
+		} else {
+			log.Info(nil, map[string]interface{}{
+				"windows":         config.GetAutomatedUpdateWindows(),
+				"cluster_windows": config.GetAutomatedUpdateClusterWindows(),
+			}, "automated update is scheduled to the maintenance windows")
+			scheduler := update.NewScheduler(db, config, clusterService, tenantUpdater, schedule, elector)
+			scheduler.Start()
+			defer scheduler.Stop()
+		}
 	} else {
 		log.Info(nil, map[string]interface{}{}, "automated update is disabled")
 	}
+	elector.Start()
+	defer elector.Stop()
 
 	// Mount "status" controller
-	statusCtrl := controller.NewStatusController(service, db)
+	statusCtrl := controller.NewStatusController(service, db, config, authService, clusterService)
 	app.MountStatusController(service, statusCtrl)
 
 	// Mount "tenant" controller
@@ -142,9 +251,21 @@ func main() {
 	app.MountTenantsController(service, tenantsCtrl)
 
 	// Mount "update" controller
-	updateCtrl := controller.NewUpdateController(service, db, config, clusterService, tenantUpdater)
+	updateCtrl := controller.NewUpdateController(service, db, config, clusterService, tenantUpdater, elector)
 	app.MountUpdateController(service, updateCtrl)
 
+	// Mount "clusters" controller
+	clustersCtrl := controller.NewClustersController(service, tenantService, clusterService)
+	app.MountClustersController(service, clustersCtrl)
+
+	// Mount "operations" controller
+	operationsCtrl := controller.NewOperationsController(service, db, update.NewOperationRunner(db, config, tenantUpdater, elector.Identity()))
+	app.MountOperationsController(service, operationsCtrl)
+
+	// Mount "webhooks" controller
+	webhooksCtrl := controller.NewWebhooksController(service, db)
+	app.MountWebhooksController(service, webhooksCtrl)
+
 	log.Logger().Infoln("Git Commit SHA: ", configuration.Commit)
 	log.Logger().Infoln("UTC Build Time: ", configuration.BuildTime)
 	log.Logger().Infoln("UTC Start Time: ", configuration.StartTime)
@@ -154,31 +275,94 @@ func main() {
 	http.Handle("/favicon.ico", http.NotFoundHandler())
 	http.Handle("/", service.Mux)
 
+	server := &http.Server{Addr: config.GetHTTPAddress()}
+	servers := []*http.Server{server}
+
 	// Start/mount metrics http
 	if config.GetHTTPAddress() == config.GetMetricsHTTPAddress() {
 		http.Handle("/metrics", promhttp.Handler())
 	} else {
-		go func(metricAddress string) {
-			mx := http.NewServeMux()
-			mx.Handle("/metrics", promhttp.Handler())
-			if err := http.ListenAndServe(metricAddress, mx); err != nil {
+		mx := http.NewServeMux()
+		mx.Handle("/metrics", promhttp.Handler())
+		metricsServer := &http.Server{Addr: config.GetMetricsHTTPAddress(), Handler: mx}
+		servers = append(servers, metricsServer)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				log.Error(nil, map[string]interface{}{
-					"addr": metricAddress,
+					"addr": metricsServer.Addr,
 					"err":  err,
 				}, "unable to connect to metrics server")
 				service.LogError("startup", "err", err)
 			}
-		}(config.GetMetricsHTTPAddress())
+		}()
 	}
 
 	// Start http
-	if err := http.ListenAndServe(config.GetHTTPAddress(), nil); err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	select {
+	case err := <-serverErr:
 		log.Error(nil, map[string]interface{}{
 			"addr": config.GetHTTPAddress(),
 			"err":  err,
 		}, "unable to connect to server")
 		service.LogError("startup", "err", err)
+	case sig := <-signals:
+		log.Info(nil, map[string]interface{}{
+			"signal": sig.String(),
+		}, "received a signal to terminate")
+		shutdownGracefully(db, config, elector.Identity(), stopWorker, servers...)
+	}
+}
+
+// shutdownGracefully stops accepting new requests and tenant operations, waits until the requests and operations in flight
+// are finished and marks the ongoing update driven by this replica as interrupted so it can be resumed by another replica
+// or after the restart. The ongoing bulk operations of this replica are marked as interrupted too. Everything has to be done
+// within the configured timeout - the servers can use at most half of it, so there is always time left for the operations.
+func shutdownGracefully(db *gorm.DB, config *configuration.Data, driver string, stopWorker func(), servers ...*http.Server) {
+	timeout := config.GetShutdownTimeout()
+	log.Info(nil, map[string]interface{}{
+		"timeout": timeout.String(),
+	}, "shutting down the service gracefully")
+	shutdown.StartShutdown()
+	// the streams of the tenant events would keep the server from shutting down
+	progress.Close()
+
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout/2)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"addr": server.Addr,
+				"err":  err,
+			}, "unable to shut down the server gracefully")
+		}
+	}
+
+	if !shutdown.Drain(time.Until(deadline)) {
+		log.Warn(nil, map[string]interface{}{}, "some of the tenant operations were interrupted - their namespaces were marked as failed")
+	}
+	// the worker mustn't report any result of the batch after the update is marked as interrupted
+	stopWorker()
+	if err := update.MarkInterrupted(db, driver); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to mark the ongoing update as interrupted")
+	}
+	if err := update.MarkOperationsInterrupted(db, driver); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to mark the ongoing bulk operations as interrupted")
 	}
+	log.Info(nil, map[string]interface{}{}, "the service has been shut down")
 }
 
 func checkTemplateVersions() string {
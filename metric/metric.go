@@ -2,15 +2,32 @@ package metric
 
 import (
 	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/auth"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/prometheus/client_golang/prometheus"
 	"strconv"
+	"time"
 )
 
 const (
-	provisionedTenantsTotalName = "provisioned_tenants_total"
-	cleanedTenantsTotalName     = "cleaned_tenants_total"
-	updatedTenantsTotalName     = "updated_tenants_total"
-	deletedTenantsTotalName     = "deleted_tenants_total"
+	provisionedTenantsTotalName      = "provisioned_tenants_total"
+	cleanedTenantsTotalName          = "cleaned_tenants_total"
+	updatedTenantsTotalName          = "updated_tenants_total"
+	deletedTenantsTotalName          = "deleted_tenants_total"
+	openShiftRequestDurationName     = "openshift_request_duration_seconds"
+	applyRetriesTotalName            = "apply_retries_total"
+	selfHealingTotalName             = "self_healing_total"
+	namespacesName                   = "namespaces"
+	updateRemainingTenantsName       = "automated_update_remaining_tenants"
+	updateProcessedTenantsName       = "automated_update_processed_tenants"
+	updateFailedTenantsName          = "automated_update_failed_tenants"
+	janitorStuckNamespacesName       = "janitor_stuck_namespaces"
+	janitorRepairedNamespacesName    = "janitor_repaired_namespaces_total"
+	idledNamespacesName              = "idled_namespaces_total"
+	authTokenCacheLookupsName        = "auth_token_cache_lookups_total"
+	circuitBreakerStateName          = "openshift_circuit_breaker_state"
+	circuitBreakerRejectedName       = "openshift_circuit_breaker_rejected_requests_total"
+	requestFailedWithoutResponseCode = "none"
 )
 
 var (
@@ -25,11 +42,56 @@ var (
 	CleanedTenantsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: cleanedTenantsTotalName,
 		Help: "Total number of cleaned tenants",
-	}, []string{"successful", "nsBaseName"})
+	}, []string{"successful", "cluster"})
 	UpdatedTenantsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: updatedTenantsTotalName,
 		Help: "Total number of updated tenants",
-	}, []string{"successful", "nsBaseName"})
+	}, []string{"successful", "cluster"})
+	OpenShiftRequestDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    openShiftRequestDurationName,
+		Help:    "Duration of the requests applying objects to the OpenShift clusters",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"cluster", "kind", "method", "code"})
+	ApplyRetriesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: applyRetriesTotalName,
+		Help: "Total number of the retried requests done by the callbacks when applying objects to the OpenShift clusters",
+	}, []string{"callback", "cluster"})
+	SelfHealingCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: selfHealingTotalName,
+		Help: "Total number of the self-healing invocations",
+	}, []string{"strategy", "successful"})
+	UpdateRemainingTenantsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: updateRemainingTenantsName,
+		Help: "Number of the outdated tenants that are still waiting for the automated update",
+	}, []string{"cluster"})
+	UpdateProcessedTenantsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: updateProcessedTenantsName,
+		Help: "Number of the tenants processed by the latest automated update",
+	}, []string{"cluster"})
+	UpdateFailedTenantsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: updateFailedTenantsName,
+		Help: "Number of the tenants whose update failed in the latest automated update",
+	}, []string{"cluster"})
+	JanitorStuckNamespacesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: janitorStuckNamespacesName,
+		Help: "Number of the namespaces stuck in the provisioning or updating state found by the latest check of the janitor",
+	}, []string{"state"})
+	JanitorRepairedNamespacesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: janitorRepairedNamespacesName,
+		Help: "Total number of the stuck namespaces handled by the janitor",
+	}, []string{"state", "outcome"})
+	IdledNamespacesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: idledNamespacesName,
+		Help: "Total number of the namespaces idled or unidled",
+	}, []string{"action", "successful"})
+	CircuitBreakerStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: circuitBreakerStateName,
+		Help: "State of the circuit breaker of the requests sent to the OpenShift cluster - 0 closed, 1 half-open, 2 open",
+	}, []string{"cluster"})
+	CircuitBreakerRejectedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: circuitBreakerRejectedName,
+		Help: "Total number of the requests to the OpenShift clusters rejected by the open circuit breaker",
+	}, []string{"cluster"})
 )
 
 func RegisterMetrics() {
@@ -37,6 +99,18 @@ func RegisterMetrics() {
 	DeletedTenantsCounter = register(DeletedTenantsCounter, deletedTenantsTotalName).(*prometheus.CounterVec)
 	CleanedTenantsCounter = register(CleanedTenantsCounter, cleanedTenantsTotalName).(*prometheus.CounterVec)
 	UpdatedTenantsCounter = register(UpdatedTenantsCounter, updatedTenantsTotalName).(*prometheus.CounterVec)
+	OpenShiftRequestDurationHistogram = register(OpenShiftRequestDurationHistogram, openShiftRequestDurationName).(*prometheus.HistogramVec)
+	ApplyRetriesCounter = register(ApplyRetriesCounter, applyRetriesTotalName).(*prometheus.CounterVec)
+	SelfHealingCounter = register(SelfHealingCounter, selfHealingTotalName).(*prometheus.CounterVec)
+	UpdateRemainingTenantsGauge = register(UpdateRemainingTenantsGauge, updateRemainingTenantsName).(*prometheus.GaugeVec)
+	UpdateProcessedTenantsGauge = register(UpdateProcessedTenantsGauge, updateProcessedTenantsName).(*prometheus.GaugeVec)
+	UpdateFailedTenantsGauge = register(UpdateFailedTenantsGauge, updateFailedTenantsName).(*prometheus.GaugeVec)
+	JanitorStuckNamespacesGauge = register(JanitorStuckNamespacesGauge, janitorStuckNamespacesName).(*prometheus.GaugeVec)
+	JanitorRepairedNamespacesCounter = register(JanitorRepairedNamespacesCounter, janitorRepairedNamespacesName).(*prometheus.CounterVec)
+	IdledNamespacesCounter = register(IdledNamespacesCounter, idledNamespacesName).(*prometheus.CounterVec)
+	CircuitBreakerStateGauge = register(CircuitBreakerStateGauge, circuitBreakerStateName).(*prometheus.GaugeVec)
+	CircuitBreakerRejectedCounter = register(CircuitBreakerRejectedCounter, circuitBreakerRejectedName).(*prometheus.CounterVec)
+	auth.TokenCacheLookupsCounter = register(auth.TokenCacheLookupsCounter, authTokenCacheLookupsName).(*prometheus.CounterVec)
 	log.Info(nil, nil, "metrics registered successfully")
 }
 
@@ -69,12 +143,12 @@ func RecordProvisionedTenant(successful bool) {
 	}
 }
 
-func RecordCleanedTenant(successful bool, nsBaseName string) {
-	if counter, err := CleanedTenantsCounter.GetMetricWithLabelValues(strconv.FormatBool(successful), nsBaseName); err != nil {
+func RecordCleanedTenant(successful bool, cluster string) {
+	if counter, err := CleanedTenantsCounter.GetMetricWithLabelValues(strconv.FormatBool(successful), cluster); err != nil {
 		log.Error(nil, map[string]interface{}{
 			"metric_name": cleanedTenantsTotalName,
 			"successful":  successful,
-			"nsBaseName":  nsBaseName,
+			"cluster":     cluster,
 			"err":         err,
 		}, "Failed to get metric")
 	} else {
@@ -82,12 +156,12 @@ func RecordCleanedTenant(successful bool, nsBaseName string) {
 	}
 }
 
-func RecordUpdatedTenant(successful bool, nsBaseName string) {
-	if counter, err := UpdatedTenantsCounter.GetMetricWithLabelValues(strconv.FormatBool(successful), nsBaseName); err != nil {
+func RecordUpdatedTenant(successful bool, cluster string) {
+	if counter, err := UpdatedTenantsCounter.GetMetricWithLabelValues(strconv.FormatBool(successful), cluster); err != nil {
 		log.Error(nil, map[string]interface{}{
 			"metric_name": updatedTenantsTotalName,
 			"successful":  successful,
-			"nsBaseName":  nsBaseName,
+			"cluster":     cluster,
 			"err":         err,
 		}, "Failed to get metric")
 	} else {
@@ -106,3 +180,196 @@ func RecordDeletedTenant(successful bool) {
 		counter.Inc()
 	}
 }
+
+// RecordOpenShiftRequest observes the duration of the request applying an object of the given kind to the cluster.
+// The code is zero if the request failed without any response
+func RecordOpenShiftRequest(cluster, kind, method string, code int, duration time.Duration) {
+	codeLabel := requestFailedWithoutResponseCode
+	if code > 0 {
+		codeLabel = strconv.Itoa(code)
+	}
+	if histogram, err := OpenShiftRequestDurationHistogram.GetMetricWithLabelValues(cluster, kind, method, codeLabel); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": openShiftRequestDurationName,
+			"cluster":     cluster,
+			"kind":        kind,
+			"method":      method,
+			"code":        codeLabel,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		histogram.Observe(duration.Seconds())
+	}
+}
+
+// RecordApplyRetries adds the number of retries done by the given callback
+func RecordApplyRetries(callback, cluster string, retries int) {
+	if retries <= 0 {
+		return
+	}
+	if counter, err := ApplyRetriesCounter.GetMetricWithLabelValues(callback, cluster); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": applyRetriesTotalName,
+			"callback":    callback,
+			"cluster":     cluster,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		counter.Add(float64(retries))
+	}
+}
+
+// RecordSelfHealing counts an invocation of the given self-healing strategy
+func RecordSelfHealing(strategy string, successful bool) {
+	if counter, err := SelfHealingCounter.GetMetricWithLabelValues(strategy, strconv.FormatBool(successful)); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": selfHealingTotalName,
+			"strategy":    strategy,
+			"successful":  successful,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		counter.Inc()
+	}
+}
+
+// ResetUpdateProgress clears the progress of the previous automated update
+func ResetUpdateProgress() {
+	UpdateRemainingTenantsGauge.Reset()
+	UpdateProcessedTenantsGauge.Reset()
+	UpdateFailedTenantsGauge.Reset()
+}
+
+// SetUpdateRemainingTenants sets the number of the tenants of the cluster that are still waiting for the automated update
+func SetUpdateRemainingTenants(cluster string, remaining int) {
+	if gauge, err := UpdateRemainingTenantsGauge.GetMetricWithLabelValues(cluster); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": updateRemainingTenantsName,
+			"cluster":     cluster,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		gauge.Set(float64(remaining))
+	}
+}
+
+// RecordUpdateProgress counts the tenant of the cluster processed by the automated update
+func RecordUpdateProgress(cluster string, successful bool) {
+	gauges := map[string]*prometheus.GaugeVec{updateProcessedTenantsName: UpdateProcessedTenantsGauge}
+	if !successful {
+		gauges[updateFailedTenantsName] = UpdateFailedTenantsGauge
+	}
+	for name, gaugeVec := range gauges {
+		if gauge, err := gaugeVec.GetMetricWithLabelValues(cluster); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"metric_name": name,
+				"cluster":     cluster,
+				"err":         err,
+			}, "Failed to get metric")
+		} else {
+			gauge.Inc()
+		}
+	}
+}
+
+// SetStuckNamespaces sets the number of the namespaces found stuck in the given state
+func SetStuckNamespaces(state string, count int) {
+	if gauge, err := JanitorStuckNamespacesGauge.GetMetricWithLabelValues(state); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": janitorStuckNamespacesName,
+			"state":       state,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		gauge.Set(float64(count))
+	}
+}
+
+// RecordRepairedNamespace counts the namespace stuck in the given state that was handled by the janitor with the given outcome
+func RecordRepairedNamespace(state, outcome string) {
+	if counter, err := JanitorRepairedNamespacesCounter.GetMetricWithLabelValues(state, outcome); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": janitorRepairedNamespacesName,
+			"state":       state,
+			"outcome":     outcome,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		counter.Inc()
+	}
+}
+
+// RecordIdledNamespace counts the namespace that was idled or unidled - the action is either "idle" or "unidle"
+func RecordIdledNamespace(action string, successful bool) {
+	if counter, err := IdledNamespacesCounter.GetMetricWithLabelValues(action, strconv.FormatBool(successful)); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": idledNamespacesName,
+			"action":      action,
+			"successful":  successful,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		counter.Inc()
+	}
+}
+
+// SetCircuitBreakerState sets the state of the circuit breaker of the cluster - 0 closed, 1 half-open, 2 open
+func SetCircuitBreakerState(cluster string, state int) {
+	if gauge, err := CircuitBreakerStateGauge.GetMetricWithLabelValues(cluster); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": circuitBreakerStateName,
+			"cluster":     cluster,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		gauge.Set(float64(state))
+	}
+}
+
+// RecordCircuitBreakerRejection counts the request to the cluster that was rejected by its open circuit breaker
+func RecordCircuitBreakerRejection(cluster string) {
+	if counter, err := CircuitBreakerRejectedCounter.GetMetricWithLabelValues(cluster); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": circuitBreakerRejectedName,
+			"cluster":     cluster,
+			"err":         err,
+		}, "Failed to get metric")
+	} else {
+		counter.Inc()
+	}
+}
+
+// namespacesCollector counts the namespaces per state, version and cluster in DB every time the metrics are collected
+type namespacesCollector struct {
+	desc          *prometheus.Desc
+	tenantService tenant.Service
+}
+
+// RegisterNamespacesCollector registers the gauge of the numbers of the namespaces retrieved from the given service
+func RegisterNamespacesCollector(tenantService tenant.Service) prometheus.Collector {
+	collector := &namespacesCollector{
+		desc: prometheus.NewDesc(namespacesName, "Number of the namespaces per state, version and cluster",
+			[]string{"state", "version", "cluster"}, nil),
+		tenantService: tenantService,
+	}
+	return register(collector, namespacesName)
+}
+
+func (c *namespacesCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.desc
+}
+
+func (c *namespacesCollector) Collect(metrics chan<- prometheus.Metric) {
+	counts, err := c.tenantService.CountNamespaces()
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"metric_name": namespacesName,
+			"err":         err,
+		}, "Failed to count namespaces")
+		return
+	}
+	for _, count := range counts {
+		metrics <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count.Count),
+			count.State.String(), count.Version, count.MasterURL)
+	}
+}
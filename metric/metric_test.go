@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/auth"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
 	"github.com/fabric8-services/fabric8-tenant/controller"
 	"github.com/fabric8-services/fabric8-tenant/environment"
@@ -25,6 +26,7 @@ import (
 	"io/ioutil"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	apptest "github.com/fabric8-services/fabric8-tenant/app/test"
 	dto "github.com/prometheus/client_model/go"
@@ -39,6 +41,17 @@ func TestMetricsEndpointExposesTenantSpecificMetrics(t *testing.T) {
 	metric.RecordDeletedTenant(false)
 	metric.RecordCleanedTenant(true, "")
 	metric.RecordUpdatedTenant(true, "")
+	metric.RecordOpenShiftRequest(test.ClusterURL, "Namespace", "GET", 200, time.Second)
+	metric.RecordApplyRetries("GetObject", test.ClusterURL, 2)
+	metric.RecordSelfHealing("redo-patch", true)
+	metric.SetUpdateRemainingTenants(test.ClusterURL, 10)
+	metric.RecordUpdateProgress(test.ClusterURL, false)
+	metric.SetStuckNamespaces("updating", 2)
+	metric.RecordRepairedNamespace("updating", "rerun")
+	metric.RecordIdledNamespace("idle", true)
+	metric.SetCircuitBreakerState(test.ClusterURL, 2)
+	metric.RecordCircuitBreakerRejection(test.ClusterURL)
+	auth.TokenCacheLookupsCounter.WithLabelValues("token", "hit").Inc()
 
 	handler := promhttp.Handler()
 
@@ -56,6 +69,18 @@ func TestMetricsEndpointExposesTenantSpecificMetrics(t *testing.T) {
 	assert.Contains(t, string(body), "deleted_tenants_total")
 	assert.Contains(t, string(body), "cleaned_tenants_total")
 	assert.Contains(t, string(body), "updated_tenants_total")
+	assert.Contains(t, string(body), "openshift_request_duration_seconds")
+	assert.Contains(t, string(body), "apply_retries_total")
+	assert.Contains(t, string(body), "self_healing_total")
+	assert.Contains(t, string(body), "automated_update_remaining_tenants")
+	assert.Contains(t, string(body), "automated_update_processed_tenants")
+	assert.Contains(t, string(body), "automated_update_failed_tenants")
+	assert.Contains(t, string(body), "janitor_stuck_namespaces")
+	assert.Contains(t, string(body), "janitor_repaired_namespaces_total")
+	assert.Contains(t, string(body), "idled_namespaces_total")
+	assert.Contains(t, string(body), "auth_token_cache_lookups_total")
+	assert.Contains(t, string(body), "openshift_circuit_breaker_state")
+	assert.Contains(t, string(body), "openshift_circuit_breaker_rejected_requests_total")
 }
 
 type MetricTestSuite struct {
@@ -126,8 +151,8 @@ func (s *MetricTestSuite) TestFailedUpdatedTenantMetric() {
 	apptest.UpdateTenantInternalServerError(s.T(), testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl)
 
 	// then
-	s.verifyCount(metric.UpdatedTenantsCounter, 1, "false", "johny")
-	s.verifyCount(metric.UpdatedTenantsCounter, 0, "true", "johny")
+	s.verifyCount(metric.UpdatedTenantsCounter, 1, "false", test.Normalize(test.ClusterURL))
+	s.verifyCount(metric.UpdatedTenantsCounter, 0, "true", test.Normalize(test.ClusterURL))
 }
 
 func (s *MetricTestSuite) TestSuccessfulUpdatedTenantMetric() {
@@ -145,8 +170,39 @@ func (s *MetricTestSuite) TestSuccessfulUpdatedTenantMetric() {
 	apptest.UpdateTenantAccepted(s.T(), testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl)
 
 	// then
-	s.verifyCount(metric.UpdatedTenantsCounter, 0, "false", "johny")
-	s.verifyCount(metric.UpdatedTenantsCounter, 1, "true", "johny")
+	s.verifyCount(metric.UpdatedTenantsCounter, 0, "false", test.Normalize(test.ClusterURL))
+	s.verifyCount(metric.UpdatedTenantsCounter, 1, "true", test.Normalize(test.ClusterURL))
+	observed := make(chan prometheus.Metric, 1000)
+	metric.OpenShiftRequestDurationHistogram.Collect(observed)
+	close(observed)
+	assert.NotZero(s.T(), len(observed))
+}
+
+func (s *MetricTestSuite) TestNamespacesMetric() {
+	// given
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().State(tenant.Ready))
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().State(tenant.Failed))
+	collector := metric.RegisterNamespacesCollector(tenant.NewDBService(s.DB))
+	defer prometheus.Unregister(collector)
+
+	// when
+	collected := make(chan prometheus.Metric, 100)
+	collector.Collect(collected)
+	close(collected)
+
+	// then
+	counts := map[string]int{}
+	for collectedMetric := range collected {
+		gauge := &dto.Metric{}
+		require.NoError(s.T(), collectedMetric.Write(gauge))
+		for _, label := range gauge.Label {
+			if label.GetName() == "state" {
+				counts[label.GetValue()] += int(gauge.Gauge.GetValue())
+			}
+		}
+	}
+	assert.Equal(s.T(), 4, counts["ready"])
+	assert.Equal(s.T(), 2, counts["failed"])
 }
 
 func (s *MetricTestSuite) TestFailedCleanedTenantMetric() {
@@ -174,8 +230,8 @@ func (s *MetricTestSuite) TestFailedCleanedTenantMetric() {
 	apptest.CleanTenantInternalServerError(s.T(), testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl, false)
 
 	// then
-	s.verifyCount(metric.CleanedTenantsCounter, 1, "false", "johny")
-	s.verifyCount(metric.CleanedTenantsCounter, 0, "true", "johny")
+	s.verifyCount(metric.CleanedTenantsCounter, 1, "false", test.Normalize(test.ClusterURL))
+	s.verifyCount(metric.CleanedTenantsCounter, 0, "true", test.Normalize(test.ClusterURL))
 }
 
 func (s *MetricTestSuite) TestSuccessfulCleanedTenantMetric() {
@@ -193,8 +249,8 @@ func (s *MetricTestSuite) TestSuccessfulCleanedTenantMetric() {
 	apptest.UpdateTenantAccepted(s.T(), testdoubles.CreateAndMockUserAndToken(s.T(), id, false), svc, ctrl)
 
 	// then
-	s.verifyCount(metric.UpdatedTenantsCounter, 0, "false", "johny")
-	s.verifyCount(metric.UpdatedTenantsCounter, 1, "true", "johny")
+	s.verifyCount(metric.UpdatedTenantsCounter, 0, "false", test.Normalize(test.ClusterURL))
+	s.verifyCount(metric.UpdatedTenantsCounter, 1, "true", test.Normalize(test.ClusterURL))
 }
 
 func (s *MetricTestSuite) TestFailedDeletedTenantMetric() {
@@ -281,4 +337,26 @@ func unRegisterAndResetMetrics() {
 	metric.UpdatedTenantsCounter.Reset()
 	prometheus.Unregister(metric.DeletedTenantsCounter)
 	metric.DeletedTenantsCounter.Reset()
+	prometheus.Unregister(metric.OpenShiftRequestDurationHistogram)
+	metric.OpenShiftRequestDurationHistogram.Reset()
+	prometheus.Unregister(metric.ApplyRetriesCounter)
+	metric.ApplyRetriesCounter.Reset()
+	prometheus.Unregister(metric.SelfHealingCounter)
+	metric.SelfHealingCounter.Reset()
+	prometheus.Unregister(metric.UpdateRemainingTenantsGauge)
+	prometheus.Unregister(metric.UpdateProcessedTenantsGauge)
+	prometheus.Unregister(metric.UpdateFailedTenantsGauge)
+	metric.ResetUpdateProgress()
+	prometheus.Unregister(metric.JanitorStuckNamespacesGauge)
+	metric.JanitorStuckNamespacesGauge.Reset()
+	prometheus.Unregister(metric.JanitorRepairedNamespacesCounter)
+	metric.JanitorRepairedNamespacesCounter.Reset()
+	prometheus.Unregister(metric.IdledNamespacesCounter)
+	metric.IdledNamespacesCounter.Reset()
+	prometheus.Unregister(metric.CircuitBreakerStateGauge)
+	metric.CircuitBreakerStateGauge.Reset()
+	prometheus.Unregister(metric.CircuitBreakerRejectedCounter)
+	metric.CircuitBreakerRejectedCounter.Reset()
+	prometheus.Unregister(auth.TokenCacheLookupsCounter)
+	auth.TokenCacheLookupsCounter.Reset()
 }
@@ -111,6 +111,19 @@ func getMigrations() migrations {
 	m = append(m, steps{executeSQLFile("008-add-can-continue-column-to-tenants-update.sql")})
 	m = append(m, steps{executeSQLFile("009-index-namespace-name.sql")})
 	m = append(m, steps{executeSQLFile("010-delete-run-stage-jenkins.sql")})
+	m = append(m, steps{executeSQLFile("011-add-stage-column-to-tenants-update.sql")})
+	m = append(m, steps{executeSQLFile("012-add-halt-reason-column-to-tenants-update.sql")})
+	m = append(m, steps{executeSQLFile("013-create-tenants-update-runs-tables.sql")})
+	m = append(m, steps{executeSQLFile("014-add-namespaces-column-to-tenants-update-run-tenants.sql")})
+	m = append(m, steps{executeSQLFile("015-create-leader-leases-and-update-requests-tables.sql")})
+	m = append(m, steps{executeSQLFile("016-create-tenants-update-work-items-table.sql")})
+	m = append(m, steps{executeSQLFile("017-create-webhook-subscriptions-and-deliveries-tables.sql")})
+	m = append(m, steps{executeSQLFile("018-create-tenants-operations-tables.sql")})
+	m = append(m, steps{executeSQLFile("019-add-state-reason-column-to-namespaces.sql")})
+	m = append(m, steps{executeSQLFile("020-create-namespace-state-transitions-table.sql")})
+	m = append(m, steps{executeSQLFile("021-add-deleted-at-indexes.sql")})
+	m = append(m, steps{executeSQLFile("022-add-last-active-at-column-to-tenants.sql")})
+	m = append(m, steps{executeSQLFile("023-add-suspension-columns-to-tenants.sql")})
 
 	// Version N
 	//
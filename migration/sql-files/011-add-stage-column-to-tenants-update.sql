@@ -0,0 +1 @@
+ALTER TABLE tenants_update ADD COLUMN stage TEXT;
\ No newline at end of file
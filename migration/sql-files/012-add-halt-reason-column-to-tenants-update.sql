@@ -0,0 +1 @@
+ALTER TABLE tenants_update ADD COLUMN halt_reason TEXT;
\ No newline at end of file
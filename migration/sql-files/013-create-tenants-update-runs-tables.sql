@@ -0,0 +1,39 @@
+CREATE TABLE tenants_update_runs (
+    id uuid primary key NOT NULL,
+    triggered_by text,
+    env_type_filter text,
+    cluster_filter text,
+    status text,
+    stage text,
+    halt_reason text,
+    failed_count int DEFAULT 0,
+    versions_before text,
+    versions_after text,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone
+);
+
+CREATE INDEX idx_tenants_update_runs_started_at ON tenants_update_runs (started_at);
+
+CREATE TABLE tenants_update_run_clusters (
+    run_id uuid NOT NULL REFERENCES tenants_update_runs (id) ON DELETE CASCADE,
+    master_url text NOT NULL,
+    updated_count int DEFAULT 0,
+    failed_count int DEFAULT 0,
+    PRIMARY KEY (run_id, master_url)
+);
+
+CREATE TABLE tenants_update_run_tenants (
+    id uuid primary key NOT NULL,
+    run_id uuid NOT NULL REFERENCES tenants_update_runs (id) ON DELETE CASCADE,
+    tenant_id uuid NOT NULL,
+    master_url text,
+    env_types text,
+    status text,
+    error text,
+    created_at timestamp with time zone
+);
+
+CREATE INDEX idx_tenants_update_run_tenants_run_id ON tenants_update_run_tenants (run_id);
+
+ALTER TABLE tenants_update ADD COLUMN run_id uuid;
@@ -0,0 +1,3 @@
+ALTER TABLE tenants_update_run_tenants ADD COLUMN namespaces text;
+
+CREATE INDEX idx_tenants_update_run_tenants_tenant_id ON tenants_update_run_tenants (tenant_id, created_at);
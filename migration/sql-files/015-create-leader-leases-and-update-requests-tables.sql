@@ -0,0 +1,18 @@
+CREATE TABLE leader_leases (
+    name text primary key NOT NULL,
+    holder text NOT NULL,
+    acquired_at timestamp with time zone,
+    renewed_at timestamp with time zone,
+    expires_at timestamp with time zone
+);
+
+CREATE TABLE tenants_update_requests (
+    id uuid primary key NOT NULL,
+    action text,
+    env_type text,
+    cluster_url text,
+    tenant_ids text,
+    requested_at timestamp with time zone
+);
+
+ALTER TABLE tenants_update ADD COLUMN driver text;
@@ -0,0 +1,36 @@
+CREATE TABLE tenants_update_work_items (
+    id uuid primary key NOT NULL,
+    run_id uuid REFERENCES tenants_update_runs(id) ON DELETE CASCADE,
+    stage text,
+    driver text,
+    master_url text,
+    tenant_ids uuid[],
+    types_with_version text,
+    commit_sha text,
+    scheduled boolean DEFAULT false,
+    status text,
+    claimed_by text,
+    attempts integer DEFAULT 0,
+    heartbeat_at timestamp with time zone,
+    updated_ids uuid[],
+    failed_ids uuid[],
+    stopped boolean DEFAULT false,
+    postponed boolean DEFAULT false,
+    tripped boolean DEFAULT false,
+    error text,
+    created_at timestamp with time zone,
+    finished_at timestamp with time zone
+);
+
+CREATE INDEX idx_tenants_update_work_items_status ON tenants_update_work_items (status, created_at);
+CREATE INDEX idx_tenants_update_work_items_run_id ON tenants_update_work_items (run_id);
+
+CREATE TABLE tenants_update_run_breakers (
+    run_id uuid REFERENCES tenants_update_runs(id) ON DELETE CASCADE,
+    master_url text NOT NULL,
+    attempts integer DEFAULT 0,
+    failed integer DEFAULT 0,
+    consecutive integer DEFAULT 0,
+    tripped boolean DEFAULT false,
+    PRIMARY KEY (run_id, master_url)
+);
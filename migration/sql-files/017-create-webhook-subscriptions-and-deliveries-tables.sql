@@ -0,0 +1,29 @@
+CREATE TABLE webhook_subscriptions (
+    id uuid primary key NOT NULL,
+    owner text NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone
+);
+
+CREATE INDEX idx_webhook_subscriptions_owner ON webhook_subscriptions (owner);
+
+CREATE TABLE webhook_deliveries (
+    id uuid primary key NOT NULL,
+    subscription_id uuid REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id uuid NOT NULL,
+    event_type text NOT NULL,
+    payload text,
+    status text,
+    attempts integer DEFAULT 0,
+    last_status_code integer DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamp with time zone,
+    created_at timestamp with time zone,
+    delivered_at timestamp with time zone
+);
+
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_at);
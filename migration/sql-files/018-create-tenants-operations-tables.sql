@@ -0,0 +1,32 @@
+CREATE TABLE tenants_operations (
+    id uuid primary key NOT NULL,
+    action text NOT NULL,
+    env_types text,
+    selector text,
+    driver text,
+    status text,
+    can_continue boolean DEFAULT true,
+    halt_reason text,
+    total_count int DEFAULT 0,
+    finished_count int DEFAULT 0,
+    failed_count int DEFAULT 0,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone
+);
+
+CREATE INDEX idx_tenants_operations_started_at ON tenants_operations (started_at);
+
+CREATE TABLE tenants_operation_tenants (
+    id uuid primary key NOT NULL,
+    operation_id uuid NOT NULL REFERENCES tenants_operations (id) ON DELETE CASCADE,
+    tenant_id uuid NOT NULL,
+    master_url text,
+    env_types text,
+    namespaces text,
+    status text,
+    error text,
+    created_at timestamp with time zone,
+    finished_at timestamp with time zone
+);
+
+CREATE INDEX idx_tenants_operation_tenants_operation_id ON tenants_operation_tenants (operation_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE namespaces ADD COLUMN state_reason TEXT;
+CREATE INDEX idx_namespaces_state_updated_at ON namespaces USING btree (state, updated_at);
@@ -0,0 +1,14 @@
+CREATE TABLE namespace_state_transitions (
+    id uuid primary key NOT NULL,
+    namespace_id uuid NOT NULL,
+    tenant_id uuid NOT NULL,
+    name text,
+    type text,
+    from_state text,
+    to_state text NOT NULL,
+    actor text,
+    error text,
+    created_at timestamp with time zone
+);
+
+CREATE INDEX idx_namespace_state_transitions_tenant_id ON namespace_state_transitions (tenant_id, created_at);
@@ -0,0 +1,2 @@
+CREATE INDEX idx_tenants_deleted_at ON tenants (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_namespaces_deleted_at ON namespaces (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE tenants ADD COLUMN last_active_at timestamp with time zone;
+-- the activity of the existing tenants is unknown, so they are considered active since the column was added
+UPDATE tenants SET last_active_at = NOW() WHERE last_active_at IS NULL;
+CREATE INDEX idx_tenants_last_active_at ON tenants (last_active_at);
@@ -0,0 +1,2 @@
+ALTER TABLE tenants ADD COLUMN suspended_at timestamp with time zone;
+ALTER TABLE tenants ADD COLUMN suspension_reason TEXT;
@@ -5,21 +5,27 @@ import (
 	"github.com/fabric8-services/fabric8-common/log"
 	"github.com/fabric8-services/fabric8-tenant/cluster"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/metric"
 	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/fabric8-services/fabric8-tenant/utils"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 	"net/http"
 	"sort"
+	"strings"
 )
 
 // NamespaceAction represents the action that should be applied on the namespaces for the particular tenant - [post|update|delete].
 // It is mainly responsible for operation on DB and provides additional information specific to the action that is needed by other objects
 type NamespaceAction interface {
 	MethodName() string
+	Actor() tenant.Actor
 	GetNamespaceEntity(nsTypeService EnvironmentTypeService) (*tenant.Namespace, error)
-	UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, failed bool)
+	StartOperation(namespace *tenant.Namespace) error
+	UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, cause error)
 	GetOperationSets(envService EnvironmentTypeService, client Client) (*environment.EnvData, []OperationSet, error)
 	ForceMasterTokenGlobally() bool
 	HealingStrategy() HealingFuncGenerator
@@ -77,6 +83,8 @@ func DeleteOpts() *DeleteActionOption {
 	return &DeleteActionOption{ActionOptions: &ActionOptions{allowSelfHealing: false}, removeFromCluster: false, keepTenant: true}
 }
 
+const recreateStrategy = "recreate-with-new-nsBaseName"
+
 type HealingFuncGenerator func(openShiftService *ServiceBuilder) Healing
 type Healing func(originalError error) error
 
@@ -84,12 +92,54 @@ type commonNamespaceAction struct {
 	method        string
 	actionOptions *ActionOptions
 	tenantRepo    tenant.Repository
+	actor         tenant.Actor
 }
 
 func (c *commonNamespaceAction) MethodName() string {
 	return c.method
 }
 
+// Actor returns who performs the action - it is recorded in the history of the namespace states
+func (c *commonNamespaceAction) Actor() tenant.Actor {
+	return c.actor
+}
+
+// StartOperation moves the namespace to the state of the ongoing action
+func (c *commonNamespaceAction) StartOperation(namespace *tenant.Namespace) error {
+	state := startStates[c.method]
+	if namespace.State == state {
+		return nil
+	}
+	return c.tenantRepo.ChangeNamespaceState(namespace, state, c.actor, nil)
+}
+
+// finishOperation moves the namespace to the ready state or to the failed one if there is a cause of the failure
+func (c *commonNamespaceAction) finishOperation(namespace *tenant.Namespace, cause error) {
+	state := tenant.Ready
+	if cause != nil {
+		state = tenant.Failed
+	}
+	err := c.tenantRepo.ChangeNamespaceState(namespace, state, c.actor, cause)
+	if transitionErr, ok := err.(tenant.InvalidTransitionError); ok && transitionErr.From == tenant.Failed && shutdown.IsShuttingDown() {
+		// the operation outlived the shutdown deadline and the namespace has already been marked as interrupted
+		log.Warn(nil, map[string]interface{}{
+			"tenant":    namespace.TenantID,
+			"namespace": namespace.Name,
+			"method":    c.method,
+		}, "the operation was finished after it had been interrupted by the shutdown - the namespace stays failed")
+		return
+	}
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"env_type": namespace.Type,
+			"cluster":  namespace.MasterURL,
+			"tenant":   namespace.TenantID,
+			"method":   c.method,
+			"state":    state,
+		}, err, "changing state of namespace entity failed")
+	}
+}
+
 func (c *commonNamespaceAction) getOperationSets(envService EnvironmentTypeService, client Client, filterFunc FilterFunc) (*environment.EnvData, []OperationSet, error) {
 	env, objects, err := envService.GetEnvDataAndObjects(filterFunc)
 	if err != nil {
@@ -134,6 +184,15 @@ func (c *commonNamespaceAction) HealingStrategy() HealingFuncGenerator {
 	return NoHealing
 }
 
+// recordedHealing counts the invocations of the given self-healing strategy in the metrics
+func recordedHealing(strategy string, healing Healing) Healing {
+	return func(originalError error) error {
+		err := healing(originalError)
+		metric.RecordSelfHealing(strategy, err == nil)
+		return err
+	}
+}
+
 func (c *commonNamespaceAction) ManageAndUpdateResults(errorChan chan error, envTypes []environment.Type, healing Healing) error {
 	msg := utils.ListErrorsInMessage(errorChan, 100)
 	if len(msg) > 0 {
@@ -148,10 +207,10 @@ func (c *commonNamespaceAction) ManageAndUpdateResults(errorChan chan error, env
 
 func (c *CreateAction) HealingStrategy() HealingFuncGenerator {
 	return func(openShiftService *ServiceBuilder) Healing {
-		return func(originalError error) error {
+		return recordedHealing(recreateStrategy, func(originalError error) error {
 			log.Error(openShiftService.service.context.requestCtx, map[string]interface{}{
 				"err":                   originalError,
-				"self-healing-strategy": "recreate-with-new-nsBaseName",
+				"self-healing-strategy": recreateStrategy,
 			}, "the creation failed, starting self-healing logic")
 			openShiftUsername := openShiftService.service.context.openShiftUsername
 			tnnt, err := c.tenantRepo.GetTenant()
@@ -182,7 +241,7 @@ func (c *CreateAction) HealingStrategy() HealingFuncGenerator {
 				return errors.Wrapf(err, "unable to create new namespaces %s", errMsgSuffix)
 			}
 			return nil
-		}
+		})
 	}
 }
 
@@ -191,7 +250,8 @@ func NewCreateAction(tenantRepo tenant.Repository, actionOpts *ActionOptions) *C
 		commonNamespaceAction: &commonNamespaceAction{
 			method:        http.MethodPost,
 			tenantRepo:    tenantRepo,
-			actionOptions: actionOpts},
+			actionOptions: actionOpts,
+			actor:         tenant.ActorUser},
 	}
 }
 
@@ -202,24 +262,14 @@ type CreateAction struct {
 func (c *CreateAction) GetNamespaceEntity(nsTypeService EnvironmentTypeService) (*tenant.Namespace, error) {
 	namespace := c.tenantRepo.NewNamespace(
 		nsTypeService.GetType(), nsTypeService.GetNamespaceName(), nsTypeService.GetCluster().APIURL, tenant.Provisioning)
-	return c.tenantRepo.CreateNamespace(namespace)
+	return c.tenantRepo.CreateNamespace(namespace, c.actor)
 }
 
-func (c *CreateAction) UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, failed bool) {
-	state := tenant.Ready
-	if failed {
-		state = tenant.Failed
-	}
-	namespace.UpdateData(env, cluster, state)
-	err := c.tenantRepo.SaveNamespace(namespace)
-	if err != nil {
-		sentry.LogError(nil, map[string]interface{}{
-			"env_type": env.EnvType,
-			"cluster":  cluster.APIURL,
-			"tenant":   namespace.TenantID,
-			"state":    state,
-		}, err, "creation of namespace entity failed")
-	}
+func (c *CreateAction) UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, cause error) {
+	namespace.UpdateData(env, cluster)
+	c.finishOperation(namespace, cause)
+	reportNamespaceState(namespace, c.method, namespace.State.String())
+	publishNamespaceEvent(namespace, "create")
 }
 
 func (c *CreateAction) ForceMasterTokenGlobally() bool {
@@ -237,6 +287,7 @@ func NewDeleteAction(tenantRepo tenant.Repository, existingNamespaces []*tenant.
 				method:        http.MethodDelete,
 				tenantRepo:    tenantRepo,
 				actionOptions: deleteOpts.ActionOptions,
+				actor:         tenant.ActorUser,
 			},
 			existingNamespaces: existingNamespaces,
 		},
@@ -253,12 +304,13 @@ func (d *DeleteAction) GetNamespaceEntity(nsTypeService EnvironmentTypeService)
 	return d.getNamespaceFor(nsTypeService.GetType()), nil
 }
 
-func (d *DeleteAction) UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, failed bool) {
+func (d *DeleteAction) UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, cause error) {
 	var err error
-	if failed {
-		namespace.State = tenant.Failed
-		err = d.tenantRepo.SaveNamespace(namespace)
-	} else if d.deleteOptions.removeFromCluster {
+	failed := cause != nil
+	if failed || !d.deleteOptions.removeFromCluster {
+		// the cleaned namespace is kept and is ready to be used again
+		d.finishOperation(namespace, cause)
+	} else {
 		err = d.tenantRepo.DeleteNamespace(namespace)
 	}
 	if err != nil {
@@ -270,6 +322,30 @@ func (d *DeleteAction) UpdateNamespace(env *environment.EnvData, cluster *cluste
 			"remove_from_cluster": d.deleteOptions.removeFromCluster,
 		}, err, "deleting namespace entity failed")
 	}
+	if failed {
+		reportNamespaceState(namespace, d.method, tenant.Failed.String())
+		publishNamespaceEvent(namespace, "delete")
+	} else if d.deleteOptions.removeFromCluster {
+		reportNamespaceState(namespace, d.method, deletedState)
+	} else {
+		reportNamespaceState(namespace, d.method, cleanedState)
+	}
+}
+
+// publishNamespaceEvent notifies the webhooks about the result of the action performed on the namespace
+func publishNamespaceEvent(namespace *tenant.Namespace, action string) {
+	eventType := webhook.NamespaceReady
+	if namespace.State == tenant.Failed {
+		eventType = webhook.NamespaceFailed
+	}
+	webhook.Publish(nil, webhook.NewNamespaceEvent(eventType, namespace.TenantID, &webhook.Namespace{
+		Name:      namespace.Name,
+		Type:      namespace.Type.String(),
+		MasterURL: namespace.MasterURL,
+		Version:   namespace.Version,
+		State:     namespace.State.String(),
+		Action:    action,
+	}))
 }
 
 func (d *DeleteAction) Filter() FilterFunc {
@@ -435,7 +511,8 @@ func NewUpdateAction(tenantRepo tenant.Repository, existingNamespaces []*tenant.
 			commonNamespaceAction: &commonNamespaceAction{
 				method:        http.MethodPatch,
 				tenantRepo:    tenantRepo,
-				actionOptions: actionOpts},
+				actionOptions: actionOpts,
+				actor:         tenant.ActorUser},
 			existingNamespaces: existingNamespaces,
 		},
 	}
@@ -449,21 +526,11 @@ func (u *UpdateAction) GetNamespaceEntity(nsTypeService EnvironmentTypeService)
 	return u.getNamespaceFor(nsTypeService.GetType()), nil
 }
 
-func (u *UpdateAction) UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, failed bool) {
-	state := tenant.Ready
-	if failed {
-		state = tenant.Failed
-	}
-	namespace.UpdateData(env, cluster, state)
-	err := u.tenantRepo.SaveNamespace(namespace)
-	if err != nil {
-		sentry.LogError(nil, map[string]interface{}{
-			"env_type": env.EnvType,
-			"cluster":  cluster.APIURL,
-			"tenant":   namespace.TenantID,
-			"state":    state,
-		}, err, "updating namespace entity failed")
-	}
+func (u *UpdateAction) UpdateNamespace(env *environment.EnvData, cluster *cluster.Cluster, namespace *tenant.Namespace, cause error) {
+	namespace.UpdateData(env, cluster)
+	u.finishOperation(namespace, cause)
+	reportNamespaceState(namespace, u.method, namespace.State.String())
+	publishNamespaceEvent(namespace, "update")
 }
 
 func (u *UpdateAction) Filter() FilterFunc {
@@ -484,7 +551,7 @@ func (w *withExistingNamespacesAction) redoStrategy(
 	toRedo func(openShiftService *ServiceBuilder, nsTypes []environment.Type, existingNamespaces []*tenant.Namespace) error) HealingFuncGenerator {
 
 	return func(openShiftService *ServiceBuilder) Healing {
-		return func(originalError error) error {
+		return recordedHealing("redo-"+strings.ToLower(w.method), func(originalError error) error {
 			errMsgSuffix := fmt.Sprintf("while doing self-healing operations triggered by error: [%s]", originalError)
 			namespaces, err := w.tenantRepo.GetNamespaces()
 			if err != nil {
@@ -495,7 +562,7 @@ func (w *withExistingNamespacesAction) redoStrategy(
 				return errors.Wrapf(err, "unable to redo the given action for the existing namespaces %s", errMsgSuffix)
 			}
 			return nil
-		}
+		})
 	}
 }
 
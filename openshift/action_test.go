@@ -127,7 +127,7 @@ func (s *ActionTestSuite) TestCreateAction() {
 
 		s.T().Run("update namespace to ready", func(t *testing.T) {
 			// when
-			create.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, false)
+			create.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, nil)
 			// then
 			assertion.AssertTenant(t, repo).
 				HasNamespaceOfTypeThat(envType).
@@ -137,8 +137,10 @@ func (s *ActionTestSuite) TestCreateAction() {
 		})
 
 		s.T().Run("update namespace to failed", func(t *testing.T) {
+			// given - the namespace is being provisioned again
+			namespace.State = tenant.Provisioning
 			// when
-			create.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, true)
+			create.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, fmt.Errorf("creation failed"))
 			// then
 			assertion.AssertTenant(t, repo).
 				HasNamespaceOfTypeThat(envType).
@@ -491,7 +493,7 @@ func (s *ActionTestSuite) TestDeleteAction() {
 
 		s.T().Run("update namespace does nothing when ns is only cleaned", func(t *testing.T) {
 			// when
-			delete.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, false)
+			delete.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, nil)
 			// then
 			assertion.AssertTenant(t, repo).
 				HasNamespaceOfTypeThat(envType).
@@ -500,8 +502,10 @@ func (s *ActionTestSuite) TestDeleteAction() {
 		})
 
 		s.T().Run("update namespace set state to failed", func(t *testing.T) {
+			// given
+			require.NoError(t, delete.StartOperation(namespace))
 			// when
-			delete.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, true)
+			delete.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, fmt.Errorf("deletion failed"))
 			// then
 			assertion.AssertTenant(t, repo).
 				HasNamespaceOfTypeThat(envType).
@@ -510,7 +514,7 @@ func (s *ActionTestSuite) TestDeleteAction() {
 
 		s.T().Run("update namespace deletes entity when it should be removed from cluster", func(t *testing.T) {
 			// when
-			deleteFromCluster.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, false)
+			deleteFromCluster.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, nil)
 			// then
 			assertion.AssertTenant(t, repo).HasNotNamespaceOfType(envType)
 		})
@@ -831,7 +835,7 @@ func (s *ActionTestSuite) TestUpdateAction() {
 		// verify namespace update to ready
 		s.T().Run("update namespace to ready", func(t *testing.T) {
 			// when
-			update.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, false)
+			update.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, nil)
 			// then
 			assertion.AssertTenant(t, repo).
 				HasNumberOfNamespaces(2).
@@ -842,8 +846,10 @@ func (s *ActionTestSuite) TestUpdateAction() {
 
 		// verify namespace update to failed
 		s.T().Run("update namespace to failed", func(t *testing.T) {
+			// given
+			require.NoError(t, update.StartOperation(namespace))
 			// when
-			update.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, true)
+			update.UpdateNamespace(envData, &cluster.Cluster{APIURL: test.ClusterURL}, namespace, fmt.Errorf("update failed"))
 			// then
 
 			assertion.AssertTenant(t, repo).
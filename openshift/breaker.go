@@ -0,0 +1,194 @@
+package openshift
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/metric"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of the circuit breaker of the requests sent to a cluster
+type CircuitState string
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects all requests without sending them to the cluster
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single request through to check if the cluster is back
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// metricValue is the value of the state reported in the metrics
+func (s CircuitState) metricValue() int {
+	switch s {
+	case CircuitHalfOpen:
+		return 1
+	case CircuitOpen:
+		return 2
+	}
+	return 0
+}
+
+// ClusterUnavailableError is returned instead of sending the request when the circuit breaker of the cluster is open
+type ClusterUnavailableError struct {
+	ClusterURL string
+	retryAfter time.Duration
+}
+
+func (e ClusterUnavailableError) Error() string {
+	return fmt.Sprintf("the cluster %s is unavailable - no requests are sent to it for the next %s", e.ClusterURL, e.RetryAfter())
+}
+
+// RetryAfter says when the cluster is checked again
+func (e ClusterUnavailableError) RetryAfter() time.Duration {
+	if e.retryAfter < time.Second {
+		return time.Second
+	}
+	return e.retryAfter.Round(time.Second)
+}
+
+// IsClusterUnavailable says if the given error (or its cause) was returned because the circuit breaker of the cluster is open
+func IsClusterUnavailable(err error) bool {
+	_, ok := errors.Cause(err).(ClusterUnavailableError)
+	return ok
+}
+
+type circuitBreaker struct {
+	clusterURL string
+	state      CircuitState
+	failures   int
+	openedAt   time.Time
+	probing    bool
+}
+
+var breakers = struct {
+	sync.Mutex
+	failureThreshold int
+	openDuration     time.Duration
+	byCluster        map[string]*circuitBreaker
+}{byCluster: map[string]*circuitBreaker{}}
+
+// ConfigureCircuitBreakers sets after how many consecutive failed requests the circuit breaker of a cluster opens and how long it stays open.
+// The zero threshold disables the circuit breakers. The states of all circuit breakers are reset
+func ConfigureCircuitBreakers(failureThreshold int, openDuration time.Duration) {
+	breakers.Lock()
+	defer breakers.Unlock()
+	breakers.failureThreshold = failureThreshold
+	breakers.openDuration = openDuration
+	breakers.byCluster = map[string]*circuitBreaker{}
+}
+
+// GetCircuitState returns the state of the circuit breaker of the given cluster
+func GetCircuitState(clusterURL string) CircuitState {
+	breakers.Lock()
+	defer breakers.Unlock()
+	breaker, found := breakers.byCluster[breakerKey(clusterURL)]
+	if !found {
+		return CircuitClosed
+	}
+	breaker.halfOpenIfExpired()
+	return breaker.state
+}
+
+// CheckCluster returns ClusterUnavailableError if the circuit breaker of the given cluster is open, so the operation
+// can fail before it starts
+func CheckCluster(clusterURL string) error {
+	breakers.Lock()
+	defer breakers.Unlock()
+	breaker, found := breakers.byCluster[breakerKey(clusterURL)]
+	if !found {
+		return nil
+	}
+	breaker.halfOpenIfExpired()
+	if breaker.state == CircuitOpen {
+		return breaker.unavailableError()
+	}
+	return nil
+}
+
+// allowRequest returns ClusterUnavailableError if the request to the cluster shouldn't be sent.
+// Every allowed request has to be followed by recordResult
+func allowRequest(clusterURL string) error {
+	breakers.Lock()
+	defer breakers.Unlock()
+	if breakers.failureThreshold <= 0 {
+		return nil
+	}
+	breaker := breakerFor(clusterURL)
+	breaker.halfOpenIfExpired()
+	switch {
+	case breaker.state == CircuitOpen, breaker.state == CircuitHalfOpen && breaker.probing:
+		metric.RecordCircuitBreakerRejection(clusterURL)
+		return breaker.unavailableError()
+	case breaker.state == CircuitHalfOpen:
+		breaker.probing = true
+	}
+	return nil
+}
+
+// recordResult counts the result of the request sent to the cluster. The failed requests are the ones that didn't get
+// any response or got 5xx
+func recordResult(clusterURL string, failed bool) {
+	breakers.Lock()
+	defer breakers.Unlock()
+	if breakers.failureThreshold <= 0 {
+		return
+	}
+	breaker := breakerFor(clusterURL)
+	breaker.probing = false
+	if !failed {
+		breaker.failures = 0
+		breaker.setState(CircuitClosed)
+		return
+	}
+	breaker.failures++
+	if breaker.state == CircuitHalfOpen || breaker.failures >= breakers.failureThreshold {
+		breaker.openedAt = time.Now()
+		breaker.setState(CircuitOpen)
+	}
+}
+
+func breakerFor(clusterURL string) *circuitBreaker {
+	key := breakerKey(clusterURL)
+	breaker, found := breakers.byCluster[key]
+	if !found {
+		breaker = &circuitBreaker{clusterURL: clusterURL, state: CircuitClosed}
+		breakers.byCluster[key] = breaker
+	}
+	return breaker
+}
+
+func breakerKey(clusterURL string) string {
+	return strings.TrimSuffix(clusterURL, "/")
+}
+
+func (b *circuitBreaker) halfOpenIfExpired() {
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= breakers.openDuration {
+		b.setState(CircuitHalfOpen)
+	}
+}
+
+func (b *circuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	log.Warn(nil, map[string]interface{}{
+		"cluster_url": b.clusterURL,
+		"from":        b.state,
+		"to":          state,
+		"failures":    b.failures,
+	}, "the state of the circuit breaker of the cluster changed")
+	b.state = state
+	metric.SetCircuitBreakerState(b.clusterURL, state.metricValue())
+}
+
+func (b *circuitBreaker) unavailableError() ClusterUnavailableError {
+	return ClusterUnavailableError{
+		ClusterURL: b.clusterURL,
+		retryAfter: breakers.openDuration - time.Since(b.openedAt),
+	}
+}
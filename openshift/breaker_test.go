@@ -0,0 +1,87 @@
+package openshift_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	openshift.ConfigureCircuitBreakers(2, 200*time.Millisecond)
+	defer openshift.ConfigureCircuitBreakers(0, 0)
+	client := openshift.NewClient(nil, test.ClusterURL, func(forceMasterToken bool) string {
+		return "clusterToken"
+	})
+	getNamespace := func() error {
+		_, err := openshift.Apply(*client, http.MethodGet, openshift.NewObject(environment.ValKindNamespace, "john", "john"))
+		return err
+	}
+	gock.New(test.ClusterURL).
+		Get("/api/v1/namespaces/john").
+		Times(2).
+		Reply(503)
+
+	// when
+	firstErr := getNamespace()
+	stateAfterFirst := openshift.GetCircuitState(test.ClusterURL)
+	secondErr := getNamespace()
+	rejectedErr := getNamespace()
+
+	// then
+	assert.Error(t, firstErr)
+	assert.False(t, openshift.IsClusterUnavailable(firstErr))
+	assert.Equal(t, openshift.CircuitClosed, stateAfterFirst)
+	assert.Error(t, secondErr)
+	assert.False(t, openshift.IsClusterUnavailable(secondErr))
+	assert.Equal(t, openshift.CircuitOpen, openshift.GetCircuitState(test.ClusterURL+"/"))
+	assert.True(t, openshift.IsClusterUnavailable(rejectedErr))
+	assert.True(t, openshift.IsClusterUnavailable(openshift.CheckCluster(test.ClusterURL)))
+	assert.True(t, gock.IsDone(), "the rejected request shouldn't be sent: %v", gock.Pending())
+
+	// and when
+	time.Sleep(250 * time.Millisecond)
+	stateAfterOpenDuration := openshift.GetCircuitState(test.ClusterURL)
+	gock.New(test.ClusterURL).
+		Get("/api/v1/namespaces/john").
+		Reply(200).
+		BodyString(`{"metadata": {"name": "john"}}`)
+	probeErr := getNamespace()
+
+	// then
+	assert.Equal(t, openshift.CircuitHalfOpen, stateAfterOpenDuration)
+	require.NoError(t, probeErr)
+	assert.Equal(t, openshift.CircuitClosed, openshift.GetCircuitState(test.ClusterURL))
+	assert.NoError(t, openshift.CheckCluster(test.ClusterURL))
+}
+
+func TestClusterUnavailableErrorSaysWhenToRetry(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	openshift.ConfigureCircuitBreakers(1, time.Minute)
+	defer openshift.ConfigureCircuitBreakers(0, 0)
+	client := openshift.NewClient(nil, test.ClusterURL, func(forceMasterToken bool) string {
+		return "clusterToken"
+	})
+	gock.New(test.ClusterURL).
+		Get("/api/v1/namespaces/john").
+		Reply(500)
+	openshift.Apply(*client, http.MethodGet, openshift.NewObject(environment.ValKindNamespace, "john", "john"))
+
+	// when
+	_, err := openshift.Apply(*client, http.MethodGet, openshift.NewObject(environment.ValKindNamespace, "john", "john"))
+
+	// then
+	require.Error(t, err)
+	unavailable, ok := err.(openshift.ClusterUnavailableError)
+	require.True(t, ok)
+	assert.Equal(t, test.ClusterURL, unavailable.ClusterURL)
+	assert.True(t, unavailable.RetryAfter() > 50*time.Second && unavailable.RetryAfter() <= time.Minute)
+}
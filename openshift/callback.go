@@ -3,6 +3,7 @@ package openshift
 import (
 	"fmt"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/metric"
 	"github.com/fabric8-services/fabric8-tenant/retry"
 	"github.com/fabric8-services/fabric8-tenant/utils"
 	ghodssYaml "github.com/ghodss/yaml"
@@ -43,7 +44,7 @@ var GetObjectAndMerge = BeforeDoCallback{
 				return method, body, err
 			}
 			retries := 10
-			errorChan := retry.Do(retries, time.Second, func() error {
+			errorChan := doWithRetries(GetObjectAndMergeName, context, retries, time.Second, func() error {
 				result, err := context.ObjEndpoints.Apply(context.Client, context.Object, http.MethodGet)
 				if result != nil && isNotPresent(result.Response.StatusCode) {
 					method, err = context.ObjEndpoints.GetMethodDefinition(http.MethodPost, context.Object)
@@ -135,7 +136,7 @@ func WaitUntilIsRemoved(doCheckAndContinue bool) BeforeDoCallback {
 					return nil, nil, nil
 				}
 				retries := 30
-				msg := waitUntilIsGone(context, retries, false)
+				msg := waitUntilIsGone(WaitUntilIsRemovedName, context, retries, false)
 				if len(msg) > 0 {
 					logrus.WithFields(map[string]interface{}{
 						"action":         context.Method.action,
@@ -217,7 +218,7 @@ var GetObject = AfterDoCallback{
 				return result, err
 			}
 			retries := 50
-			errorChan := retry.Do(retries, time.Millisecond*100, func() error {
+			errorChan := doWithRetries(GetObjectName, context, retries, time.Millisecond*100, func() error {
 				getResponse, err := context.ObjEndpoints.Apply(context.Client, context.Object, http.MethodGet)
 				err = CheckHTTPCode(getResponse, err)
 				if err != nil {
@@ -277,7 +278,7 @@ var TryToWaitUntilIsGone = AfterDoCallback{
 				return result, err
 			}
 			retries := 60
-			msg := waitUntilIsGone(context, retries, true)
+			msg := waitUntilIsGone(TryToWaitUntilIsGoneName, context, retries, true)
 			if len(msg) > 0 {
 				// todo investigate why logging here ends with panic: runtime error: index out of range in common logic
 				logrus.WithFields(map[string]interface{}{
@@ -296,8 +297,8 @@ var TryToWaitUntilIsGone = AfterDoCallback{
 	Name: TryToWaitUntilIsGoneName,
 }
 
-func waitUntilIsGone(context CallbackContext, retries int, checkTerminating bool) string {
-	errorChan := retry.Do(retries, time.Millisecond*500, func() error {
+func waitUntilIsGone(callbackName string, context CallbackContext, retries int, checkTerminating bool) string {
+	errorChan := doWithRetries(callbackName, context, retries, time.Millisecond*500, func() error {
 		result, err := context.ObjEndpoints.Apply(context.Client, context.Object, http.MethodGet)
 		if result != nil && isNotPresent(result.Response.StatusCode) {
 			return nil
@@ -322,6 +323,23 @@ func waitUntilIsGone(context CallbackContext, retries int, checkTerminating bool
 	return utils.ListErrorsInMessage(errorChan, 5)
 }
 
+// doWithRetries calls retry.Do and counts the retries done by the given callback in the metrics. The retries are stopped
+// when the circuit breaker of the cluster opens
+func doWithRetries(callbackName string, context CallbackContext, retries int, sleep time.Duration, toRetry retry.ToRetry) chan error {
+	attempts := 0
+	errorChan := retry.Do(retries, sleep, func() error {
+		attempts++
+		err := toRetry()
+		if IsClusterUnavailable(err) {
+			// there is no point in retrying while the circuit breaker of the cluster is open
+			return retry.Abort(err)
+		}
+		return err
+	})
+	metric.RecordApplyRetries(callbackName, context.Client.MasterURL, attempts-1)
+	return errorChan
+}
+
 func isNotPresent(statusCode int) bool {
 	return statusCode == http.StatusNotFound || statusCode == http.StatusForbidden
 }
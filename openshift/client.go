@@ -2,6 +2,7 @@ package openshift
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,16 +10,30 @@ import (
 	tmpl "html/template"
 
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/metric"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"io/ioutil"
+	"time"
 )
 
 type Client struct {
 	client        *http.Client
 	MasterURL     string
 	TokenProducer TokenProducer
+	ctx           context.Context
 }
 type TokenProducer func(forceMasterToken bool) string
 
+// rejectedTokenHandler is notified about the tokens rejected by the clusters with 401, so they aren't cached anymore
+var rejectedTokenHandler = func(clusterURL, token string) {}
+
+// HandleRejectedTokens sets the function that is called with the cluster URL and the token whenever the cluster rejects
+// the token the request was sent with
+func HandleRejectedTokens(handler func(clusterURL, token string)) {
+	rejectedTokenHandler = handler
+}
+
 func NewClient(httpTransport http.RoundTripper, masterURL string, TokenProducer TokenProducer) *Client {
 	return &Client{
 		client:        createHTTPClient(httpTransport),
@@ -27,6 +42,21 @@ func NewClient(httpTransport http.RoundTripper, masterURL string, TokenProducer
 	}
 }
 
+// WithContext returns a copy of the client that traces the requests as children of the span stored in the given context
+func (c *Client) WithContext(ctx context.Context) *Client {
+	withCtx := *c
+	withCtx.ctx = ctx
+	return &withCtx
+}
+
+// Context returns the context the requests of the client are traced in
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // CreateHTTPClient returns an HTTP client with the options settings,
 // or a default HTTP client if nothing was specified
 func createHTTPClient(HTTPTransport http.RoundTripper) *http.Client {
@@ -51,12 +81,32 @@ func (c *Client) Do(requestCreator RequestCreator, object environment.Object, bo
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.TokenProducer(requestCreator.needMasterToken))
+	token := c.TokenProducer(requestCreator.needMasterToken)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// the request isn't sent at all when the cluster has been failing recently
+	if err := allowRequest(c.MasterURL); err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracing.StartRequest(c.Context(), fmt.Sprintf("%s %s", req.Method, environment.GetKind(object)), req,
+		attribute.String("cluster", c.MasterURL),
+		attribute.String("object.name", environment.GetName(object)),
+		attribute.String("object.namespace", environment.GetNamespace(object)))
+	req = req.WithContext(ctx)
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	tracing.EndRequest(span, resp, err)
+	recordResult(c.MasterURL, err != nil || resp.StatusCode >= http.StatusInternalServerError)
 	if err != nil {
+		metric.RecordOpenShiftRequest(c.MasterURL, environment.GetKind(object), req.Method, 0, time.Since(start))
 		return nil, err
 	}
+	metric.RecordOpenShiftRequest(c.MasterURL, environment.GetKind(object), req.Method, resp.StatusCode, time.Since(start))
+	if resp.StatusCode == http.StatusUnauthorized {
+		rejectedTokenHandler(c.MasterURL, token)
+	}
 
 	defer func() {
 		resp.Body.Close()
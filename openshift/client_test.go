@@ -0,0 +1,39 @@
+package openshift_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+	"net/http"
+	"testing"
+)
+
+func TestTokenRejectedByClusterIsHandled(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	var rejected []string
+	openshift.HandleRejectedTokens(func(clusterURL, token string) {
+		rejected = append(rejected, clusterURL, token)
+	})
+	defer openshift.HandleRejectedTokens(func(clusterURL, token string) {})
+	gock.New(test.ClusterURL).
+		Get("/api/v1/namespaces/john").
+		Reply(401)
+	gock.New(test.ClusterURL).
+		Get("/api/v1/namespaces/jane").
+		Reply(404)
+	client := openshift.NewClient(nil, test.ClusterURL, func(forceMasterToken bool) string {
+		return "clusterToken"
+	})
+
+	// when
+	_, rejectedErr := openshift.Apply(*client, http.MethodGet, openshift.NewObject(environment.ValKindNamespace, "john", "john"))
+	_, missingErr := openshift.Apply(*client, http.MethodGet, openshift.NewObject(environment.ValKindNamespace, "jane", "jane"))
+
+	// then
+	assert.Error(t, rejectedErr)
+	assert.Error(t, missingErr)
+	assert.Equal(t, []string{test.ClusterURL, "clusterToken"}, rejected)
+}
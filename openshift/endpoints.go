@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/fabric8-services/fabric8-common/log"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v2"
 )
 
@@ -191,12 +193,16 @@ func (e *ObjectEndpoints) Apply(client *Client, object environment.Object, actio
 	return e.apply(client, object, method)
 }
 
-func (e *ObjectEndpoints) apply(client *Client, object environment.Object, method *MethodDefinition) (*Result, error) {
-	var (
-		reqBody []byte
-		result  *Result
-		err     error
-	)
+func (e *ObjectEndpoints) apply(client *Client, object environment.Object, method *MethodDefinition) (result *Result, err error) {
+	var reqBody []byte
+
+	ctx, span := tracing.Start(client.Context(), fmt.Sprintf("apply %s %s", method.action, environment.GetKind(object)),
+		attribute.String("object.name", environment.GetName(object)),
+		attribute.String("object.namespace", environment.GetNamespace(object)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	client = client.WithContext(ctx)
 
 	// handle before callbacks if any defined (that could change the request Body)
 	method, reqBody, err = method.beforeDoCallbacks.call(NewCallbackContext(client, object, e, method))
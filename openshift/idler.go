@@ -0,0 +1,122 @@
+package openshift
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"strconv"
+)
+
+// IdledReplicasAnnotation is set to the scaled objects when the namespace is idled - it keeps the original number of replicas
+// the objects are scaled back to when the namespace is unidled
+const IdledReplicasAnnotation = "tenant.fabric8.io/idled-replicas"
+
+// scalableKinds are the kinds of the objects that are scaled to zero when the namespace is idled
+var scalableKinds = []string{
+	environment.ValKindDeploymentConfig,
+	environment.ValKindDeployment,
+	environment.ValKindStatefulSet,
+}
+
+type scalableObject struct {
+	Metadata struct {
+		Name        string            `yaml:"name"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Replicas *int `yaml:"replicas"`
+	} `yaml:"spec"`
+}
+
+type scalableObjectList struct {
+	Items []scalableObject `yaml:"items"`
+}
+
+// IdleNamespace scales all deployment configs, deployments and stateful sets of the namespace to zero using the cluster token.
+// The original number of replicas is stored in the IdledReplicasAnnotation of every scaled object
+func IdleNamespace(ctx context.Context, config *configuration.Data, cluster cluster.Cluster, name string) error {
+	client := newClusterClient(ctx, config, cluster)
+	return forEachScalableObject(client, name, func(kind string, object scalableObject) error {
+		if object.Spec.Replicas == nil || *object.Spec.Replicas == 0 {
+			return nil
+		}
+		return scale(client, kind, name, object.Metadata.Name, 0, strconv.Itoa(*object.Spec.Replicas))
+	})
+}
+
+// UnidleNamespace scales the objects of the idled namespace back to the number of replicas they had before the namespace was idled
+func UnidleNamespace(ctx context.Context, config *configuration.Data, cluster cluster.Cluster, name string) error {
+	client := newClusterClient(ctx, config, cluster)
+	return forEachScalableObject(client, name, func(kind string, object scalableObject) error {
+		idledReplicas, found := object.Metadata.Annotations[IdledReplicasAnnotation]
+		if !found {
+			return nil
+		}
+		replicas, err := strconv.Atoi(idledReplicas)
+		if err != nil {
+			return errors.Wrapf(err, "invalid number of idled replicas of the %s %s in namespace %s", kind, object.Metadata.Name, name)
+		}
+		return scale(client, kind, name, object.Metadata.Name, replicas, nil)
+	})
+}
+
+func newClusterClient(ctx context.Context, config *configuration.Data, cluster cluster.Cluster) *Client {
+	return NewClient(newTransport(config), cluster.APIURL, func(forceMasterToken bool) string {
+		return cluster.Token
+	}).WithContext(ctx)
+}
+
+func forEachScalableObject(client *Client, namespaceName string, do func(kind string, object scalableObject) error) error {
+	for _, kind := range scalableKinds {
+		result, err := Apply(*client, http.MethodGet, NewObject(kind, namespaceName, ""))
+		if err != nil {
+			if result != nil && result.Response != nil && isNotPresent(result.Response.StatusCode) {
+				// the kind isn't available in the cluster
+				continue
+			}
+			return errors.Wrapf(err, "unable to get list of objects of kind %s in namespace %s", kind, namespaceName)
+		}
+		var list scalableObjectList
+		if err := yaml.Unmarshal(result.Body, &list); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal list of objects of kind %s in namespace %s", kind, namespaceName)
+		}
+		for _, object := range list.Items {
+			if err := do(kind, object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scale sets the number of replicas of the object together with the idled replicas annotation - nil removes the annotation
+func scale(client *Client, kind, namespaceName, name string, replicas int, idledReplicas interface{}) error {
+	err := mergePatch(client, NewObject(kind, namespaceName, name), map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{IdledReplicasAnnotation: idledReplicas},
+		},
+		"spec": map[string]interface{}{"replicas": replicas},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to scale the %s %s in namespace %s to %d replicas", kind, name, namespaceName, replicas)
+	}
+	return nil
+}
+
+// mergePatch sends the merge patch of the object directly so the object isn't created when it has been removed in the meantime
+func mergePatch(client *Client, object environment.Object, patch map[string]interface{}) error {
+	method, err := AllObjectEndpoints[environment.GetKind(object)].GetMethodDefinition(http.MethodPatch, object)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	return CheckHTTPCode(client.Do(method.requestCreator, object, body))
+}
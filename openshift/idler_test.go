@@ -0,0 +1,101 @@
+package openshift_test
+
+import (
+	"context"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"testing"
+)
+
+var idlerCluster = cluster.Cluster{APIURL: test.ClusterURL, Token: "clusterToken"}
+
+func TestIdleNamespace(t *testing.T) {
+	t.Run("running objects are scaled to zero", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		config, reset := test.LoadTestConfig(t)
+		defer reset()
+		gock.New(test.ClusterURL).
+			Get("/apis/apps.openshift.io/v1/namespaces/john/deploymentconfigs").
+			Reply(200).
+			BodyString(`{"items": [{"metadata": {"name": "jenkins"}, "spec": {"replicas": 2}}]}`)
+		gock.New(test.ClusterURL).
+			Get("/apis/apps/v1/namespaces/john/deployments").
+			Reply(200).
+			BodyString(`{"items": [{"metadata": {"name": "content-repository"}, "spec": {"replicas": 0}}]}`)
+		// the kind isn't available in the cluster
+		gock.New(test.ClusterURL).
+			Get("/apis/apps/v1/namespaces/john/statefulsets").
+			Reply(404)
+		gock.New(test.ClusterURL).
+			Patch("/apis/apps.openshift.io/v1/namespaces/john/deploymentconfigs/jenkins").
+			SetMatcher(test.ExpectRequest(
+				test.HasBearerWithSub("clusterToken"),
+				test.HasJSONBody(`{"metadata": {"annotations": {"tenant.fabric8.io/idled-replicas": "2"}}, "spec": {"replicas": 0}}`))).
+			Reply(200)
+
+		// when
+		err := openshift.IdleNamespace(context.Background(), config, idlerCluster, "john")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, gock.IsDone(), "not all expected requests were sent: %v", gock.Pending())
+	})
+
+	t.Run("fails when the object cannot be scaled", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		config, reset := test.LoadTestConfig(t)
+		defer reset()
+		gock.New(test.ClusterURL).
+			Get("/apis/apps.openshift.io/v1/namespaces/john/deploymentconfigs").
+			Reply(200).
+			BodyString(`{"items": [{"metadata": {"name": "jenkins"}, "spec": {"replicas": 1}}]}`)
+		gock.New(test.ClusterURL).
+			Patch("/apis/apps.openshift.io/v1/namespaces/john/deploymentconfigs/jenkins").
+			Reply(500)
+
+		// when
+		err := openshift.IdleNamespace(context.Background(), config, idlerCluster, "john")
+
+		// then
+		test.AssertError(t, err, test.HasMessageContaining("unable to scale the DeploymentConfig jenkins in namespace john"))
+	})
+}
+
+func TestUnidleNamespace(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	config, reset := test.LoadTestConfig(t)
+	defer reset()
+	gock.New(test.ClusterURL).
+		Get("/apis/apps.openshift.io/v1/namespaces/john-che/deploymentconfigs").
+		Reply(200).
+		BodyString(`{"items": [{"metadata": {"name": "che", "annotations": {"tenant.fabric8.io/idled-replicas": "1"}}, "spec": {"replicas": 0}}]}`)
+	gock.New(test.ClusterURL).
+		Get("/apis/apps/v1/namespaces/john-che/deployments").
+		Reply(200).
+		BodyString(`{"items": []}`)
+	// objects that weren't scaled by the idler are left untouched
+	gock.New(test.ClusterURL).
+		Get("/apis/apps/v1/namespaces/john-che/statefulsets").
+		Reply(200).
+		BodyString(`{"items": [{"metadata": {"name": "postgres"}, "spec": {"replicas": 0}}]}`)
+	gock.New(test.ClusterURL).
+		Patch("/apis/apps.openshift.io/v1/namespaces/john-che/deploymentconfigs/che").
+		SetMatcher(test.ExpectRequest(
+			test.HasBearerWithSub("clusterToken"),
+			test.HasJSONBody(`{"metadata": {"annotations": {"tenant.fabric8.io/idled-replicas": null}}, "spec": {"replicas": 1}}`))).
+		Reply(200)
+
+	// when
+	err := openshift.UnidleNamespace(context.Background(), config, idlerCluster, "john-che")
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), "not all expected requests were sent: %v", gock.Pending())
+}
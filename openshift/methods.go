@@ -2,6 +2,7 @@ package openshift
 
 import (
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
 	"gopkg.in/yaml.v2"
 	"net/http"
 )
@@ -64,11 +65,22 @@ var DefaultBeforeDoCallBack = func(context CallbackContext) (*MethodDefinition,
 func (c BeforeDoCallbacksChain) call(context CallbackContext) (*MethodDefinition, []byte, error) {
 	callbackFunc := DefaultBeforeDoCallBack
 	for _, callback := range c {
-		callbackFunc = callback.Create(callbackFunc)
+		callbackFunc = tracedBeforeDoCallback(callback.Name, callback.Create(callbackFunc))
 	}
 	return callbackFunc(context)
 }
 
+// tracedBeforeDoCallback runs the callback in its own span so the requests sent by the callback are traced as its children
+func tracedBeforeDoCallback(name string, callbackFunc BeforeDoCallbackFunc) BeforeDoCallbackFunc {
+	return func(context CallbackContext) (*MethodDefinition, []byte, error) {
+		ctx, span := tracing.Start(context.Client.Context(), "before "+name)
+		context.Client = context.Client.WithContext(ctx)
+		method, body, err := callbackFunc(context)
+		tracing.End(span, err)
+		return method, body, err
+	}
+}
+
 func BeforeDo(beforeDoCallback ...BeforeDoCallback) MethodDefModifier {
 	return func(methodDefinition *MethodDefinition) *MethodDefinition {
 		methodDefinition.beforeDoCallbacks = append(methodDefinition.beforeDoCallbacks, beforeDoCallback...)
@@ -83,11 +95,22 @@ func (c AfterDoCallbacksChain) call(context CallbackContext, result *Result) err
 		return result, result.err
 	}
 	for _, callback := range c {
-		callbackFunc = callback.Create(callbackFunc)
+		callbackFunc = tracedAfterDoCallback(callback.Name, callback.Create(callbackFunc))
 	}
 	return CheckHTTPCode(callbackFunc(context))
 }
 
+// tracedAfterDoCallback runs the callback in its own span so the requests sent by the callback are traced as its children
+func tracedAfterDoCallback(name string, callbackFunc AfterDoCallbackFunc) AfterDoCallbackFunc {
+	return func(context CallbackContext) (*Result, error) {
+		ctx, span := tracing.Start(context.Client.Context(), "after "+name)
+		context.Client = context.Client.WithContext(ctx)
+		result, err := callbackFunc(context)
+		tracing.End(span, err)
+		return result, err
+	}
+}
+
 func AfterDo(afterDoCallbacks ...AfterDoCallback) MethodDefModifier {
 	return func(methodDefinition *MethodDefinition) *MethodDefinition {
 		methodDefinition.afterDoCallbacks = append(methodDefinition.afterDoCallbacks, afterDoCallbacks...)
@@ -0,0 +1,55 @@
+package openshift
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/progress"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"net/http"
+)
+
+// states of the namespaces reported to the streams of the tenant that are not stored in DB
+const (
+	cleanedState = "cleaned"
+	deletedState = "deleted"
+)
+
+var operationNames = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+// startStates are the states the namespaces are moved to when the operation starts
+var startStates = map[string]tenant.NamespaceState{
+	http.MethodPost:   tenant.Provisioning,
+	http.MethodPatch:  tenant.Updating,
+	http.MethodDelete: tenant.Deleting,
+}
+
+// reportNamespaceState sends the state of the namespace the action is performed on to the streams of the tenant
+func reportNamespaceState(namespace *tenant.Namespace, method, state string) {
+	progress.Publish(&progress.Event{
+		Type:      progress.NamespaceChanged,
+		TenantID:  namespace.TenantID,
+		Namespace: namespace.Name,
+		NsType:    namespace.Type.String(),
+		Action:    operationNames[method],
+		State:     state,
+	})
+}
+
+// reportObjectApplied sends the number of the objects that have been already applied to the namespace to the streams of the tenant
+func reportObjectApplied(namespace *tenant.Namespace, method string, object environment.Object, applied, total int, failed bool) {
+	progress.Publish(&progress.Event{
+		Type:      progress.ObjectApplied,
+		TenantID:  namespace.TenantID,
+		Namespace: namespace.Name,
+		NsType:    namespace.Type.String(),
+		Action:    operationNames[method],
+		Kind:      environment.GetKind(object),
+		Name:      environment.GetName(object),
+		Applied:   applied,
+		Total:     total,
+		Failed:    failed,
+	})
+}
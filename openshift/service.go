@@ -8,8 +8,13 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/cluster"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
 	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 	"sync"
 )
@@ -28,15 +33,18 @@ type Service struct {
 }
 
 func NewService(context *ServiceContext, repo tenant.Repository, envService *environment.Service) *ServiceBuilder {
-	transport := http.DefaultTransport
-	if context.config.APIServerUseTLS() {
-		transport = &http.Transport{
+	return NewBuilderWithTransport(context, repo, newTransport(context.config), envService)
+}
+
+func newTransport(config *configuration.Data) http.RoundTripper {
+	if config.APIServerUseTLS() {
+		return &http.Transport{
 			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: context.config.APIServerInsecureSkipTLSVerify(),
+				InsecureSkipVerify: config.APIServerInsecureSkipTLSVerify(),
 			},
 		}
 	}
-	return NewBuilderWithTransport(context, repo, transport, envService)
+	return http.DefaultTransport
 }
 
 func NewBuilderWithTransport(context *ServiceContext, namespaceRepository tenant.Repository, transport http.RoundTripper, envService *environment.Service) *ServiceBuilder {
@@ -69,25 +77,46 @@ func NewServiceContext(callerCtx context.Context, config *configuration.Data, cl
 }
 
 func (b *ServiceBuilder) Create(nsTypes []environment.Type, actionOpts *ActionOptions) error {
-	return b.service.processAndApplyAll(nsTypes, NewCreateAction(b.service.tenantRepository, actionOpts))
+	action := NewCreateAction(b.service.tenantRepository, actionOpts)
+	action.actor = tenant.ActorFromContext(b.service.context.requestCtx)
+	return b.service.processAndApplyAll(nsTypes, action)
 }
 
 func (b *ServiceBuilder) Update(nsTypes []environment.Type, existingNamespaces []*tenant.Namespace, actionOpts *ActionOptions) error {
-	return b.service.processAndApplyAll(nsTypes, NewUpdateAction(b.service.tenantRepository, existingNamespaces, actionOpts))
+	action := NewUpdateAction(b.service.tenantRepository, existingNamespaces, actionOpts)
+	action.actor = tenant.ActorFromContext(b.service.context.requestCtx)
+	return b.service.processAndApplyAll(nsTypes, action)
 }
 
 func (b *ServiceBuilder) Delete(nsTypes []environment.Type, existingNamespaces []*tenant.Namespace, deleteOpts *DeleteActionOption) error {
-	return b.service.processAndApplyAll(nsTypes, NewDeleteAction(b.service.tenantRepository, existingNamespaces, deleteOpts))
+	action := NewDeleteAction(b.service.tenantRepository, existingNamespaces, deleteOpts)
+	action.actor = tenant.ActorFromContext(b.service.context.requestCtx)
+	return b.service.processAndApplyAll(nsTypes, action)
 }
 
-func (s *Service) processAndApplyAll(nsTypes []environment.Type, action NamespaceAction) error {
+func (s *Service) processAndApplyAll(nsTypes []environment.Type, action NamespaceAction) (err error) {
+	if shutdown.IsShuttingDown() {
+		return shutdown.ErrShuttingDown
+	}
+	// fail fast before any namespace is touched when any of the clusters is unavailable
+	for _, nsType := range nsTypes {
+		if err := CheckCluster(s.context.clusterForType(nsType).APIURL); err != nil {
+			return err
+		}
+	}
+	ctx, span := tracing.Start(s.context.requestCtx, "ServiceBuilder."+action.MethodName(),
+		attribute.String("namespace.base.name", s.context.nsBaseName))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	var nsTypesWait sync.WaitGroup
 	nsTypesWait.Add(len(nsTypes))
 
 	errorChan := make(chan error, len(nsTypes)*2)
 	for _, nsType := range nsTypes {
 		nsTypeService := NewEnvironmentTypeService(nsType, s.context, s.envService)
-		go processAndApplyNs(&nsTypesWait, nsTypeService, action, s.httpTransport, errorChan)
+		go processAndApplyNs(ctx, &nsTypesWait, nsTypeService, action, s.httpTransport, s.tenantRepository, errorChan)
 	}
 	nsTypesWait.Wait()
 	close(errorChan)
@@ -107,35 +136,74 @@ func NewOperationSet(method string, objects environment.Objects) OperationSet {
 	return OperationSet{Method: method, Objects: objects}
 }
 
-func processAndApplyNs(nsTypeWait *sync.WaitGroup, nsTypeService EnvironmentTypeService, action NamespaceAction, transport http.RoundTripper, errorChan chan error) {
+func processAndApplyNs(ctx context.Context, nsTypeWait *sync.WaitGroup, nsTypeService EnvironmentTypeService, action NamespaceAction,
+	transport http.RoundTripper, tenantRepo tenant.Repository, errorChan chan error) {
 	defer nsTypeWait.Done()
 
+	ctx, span := tracing.Start(ctx, fmt.Sprintf("%s %s", action.MethodName(), nsTypeService.GetType()))
+	var spanErr error
+	defer func() {
+		tracing.End(span, spanErr)
+	}()
+
 	namespace, err := action.GetNamespaceEntity(nsTypeService)
 	if err != nil {
-		errorChan <- errors.Wrap(err, "getting the namespace failed")
+		spanErr = errors.Wrap(err, "getting the namespace failed")
+		errorChan <- spanErr
 		return
 	}
 	if namespace == nil {
 		return
 	}
 
+	// the operation is registered before the namespace is moved to its state, so a namespace whose operation
+	// is refused because of the shutdown stays in the original state. The interruption is called from another goroutine,
+	// so it mustn't touch the namespace entity that is changed by this one
+	namespaceID := namespace.ID
+	done, err := shutdown.Begin(fmt.Sprintf("%s of namespace %s", action.MethodName(), namespace.Name), func() {
+		markAsInterrupted(tenantRepo, namespaceID, action.Actor())
+	})
+	if err != nil {
+		spanErr = err
+		errorChan <- err
+		return
+	}
+	defer done()
+
+	if err := action.StartOperation(namespace); err != nil {
+		spanErr = errors.Wrapf(err, "the method %s cannot be started for the namespace %s", action.MethodName(), namespace.Name)
+		errorChan <- spanErr
+		return
+	}
+
 	cluster := nsTypeService.GetCluster()
-	client := NewClient(transport, cluster.APIURL, nsTypeService.GetTokenProducer(action.ForceMasterTokenGlobally()))
+	span.SetAttributes(attribute.String("namespace", nsTypeService.GetNamespaceName()), attribute.String("cluster", cluster.APIURL))
+	client := NewClient(transport, cluster.APIURL, nsTypeService.GetTokenProducer(action.ForceMasterTokenGlobally())).WithContext(ctx)
 
-	failed := false
+	reportNamespaceState(namespace, action.MethodName(), startStates[action.MethodName()].String())
+
+	// the first error the operation failed with
+	var cause error
 	env, operationSets, err := action.GetOperationSets(nsTypeService, *client)
 	if err != nil {
-		errorChan <- errors.Wrapf(err, "for the namespace [%s] the method %s failed for the cluster %s with following error while getting list of objects to apply",
+		cause = errors.Wrapf(err, "for the namespace [%s] the method %s failed for the cluster %s with following error while getting list of objects to apply",
 			nsTypeService.GetNamespaceName(), action.MethodName(), cluster.APIURL)
-		failed = true
+		errorChan <- cause
 	} else {
+		total := 0
+		for _, operationSet := range operationSets {
+			total += len(operationSet.Objects)
+		}
+		applied := 0
 		for _, operationSet := range operationSets {
 			for _, object := range operationSet.Objects {
 				_, err := Apply(*client, operationSet.Method, object)
+				applied++
+				reportObjectApplied(namespace, action.MethodName(), object, applied, total, err != nil)
 				if err != nil {
-					errorChan <- errors.Wrapf(err, "for the namespace [%s] the method %s failed for the cluster %s with following error",
+					cause = errors.Wrapf(err, "for the namespace [%s] the method %s failed for the cluster %s with following error",
 						nsTypeService.GetNamespaceName(), operationSet.Method, cluster.APIURL)
-					failed = true
+					errorChan <- cause
 					break
 				}
 			}
@@ -144,10 +212,42 @@ func processAndApplyNs(nsTypeWait *sync.WaitGroup, nsTypeService EnvironmentType
 
 	err = nsTypeService.AfterCallback(client, action.MethodName())
 	if err != nil {
-		errorChan <- errors.Wrapf(err, "the after callback of a namespace %s failed for the type %s", action.MethodName(), nsTypeService.GetNamespaceName())
+		err = errors.Wrapf(err, "the after callback of a namespace %s failed for the type %s", action.MethodName(), nsTypeService.GetNamespaceName())
+		errorChan <- err
+		if cause == nil {
+			cause = err
+		}
 	}
 	namespace.Version = env.Version()
-	action.UpdateNamespace(env, &cluster, namespace, failed || err != nil)
+	action.UpdateNamespace(env, &cluster, namespace, cause)
+	if cause != nil {
+		spanErr = fmt.Errorf("the method %s failed for the namespace %s", action.MethodName(), nsTypeService.GetNamespaceName())
+	}
+}
+
+// markAsInterrupted marks the namespace as failed so the operation is redone by the next setup or update of the tenant.
+// The current state is read from DB - the namespace is marked only if the operation has already moved it to a state
+// the failed one can follow, otherwise it is left as it is
+func markAsInterrupted(tenantRepo tenant.Repository, namespaceID uuid.UUID, actor tenant.Actor) {
+	namespaces, err := tenantRepo.GetNamespaces()
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"namespace_id": namespaceID,
+		}, err, "unable to get the interrupted namespace")
+		return
+	}
+	for _, namespace := range namespaces {
+		if namespace.ID != namespaceID || !tenant.CanTransition(namespace.State, tenant.Failed) {
+			continue
+		}
+		cause := fmt.Errorf("the operation was interrupted by the shutdown of the service")
+		if err := tenantRepo.ChangeNamespaceState(namespace, tenant.Failed, actor, cause); err != nil {
+			sentry.LogError(nil, map[string]interface{}{
+				"tenant":    namespace.TenantID,
+				"namespace": namespace.Name,
+			}, err, "unable to mark the interrupted namespace as failed")
+		}
+	}
 }
 
 func Apply(client Client, action string, object environment.Object) (*Result, error) {
@@ -162,6 +262,23 @@ func Apply(client Client, action string, object environment.Object) (*Result, er
 	return result, err
 }
 
+// NamespaceExists checks using the cluster token if the project of the given name exists in the cluster
+func NamespaceExists(ctx context.Context, config *configuration.Data, cluster cluster.Cluster, name string) (bool, error) {
+	client := newClusterClient(ctx, config, cluster)
+	result, err := Apply(*client, http.MethodGet, NewObject(environment.ValKindProject, name, name))
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to get the project %s from the cluster %s", name, cluster.APIURL)
+	}
+	switch result.Response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unable to get the project %s from the cluster %s - the server responded with %s",
+		name, cluster.APIURL, result.Response.Status)
+}
+
 type UserTokenResolver func(cluster cluster.Cluster) string
 
 func TokenResolverForUser(user *auth.User) UserTokenResolver {
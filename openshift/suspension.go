@@ -0,0 +1,161 @@
+package openshift
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"net/http"
+)
+
+// SuspendedRoleBindingsAnnotation is set to the namespace of the suspended tenant - it keeps the roles of the removed role
+// bindings of the user (as a JSON map of the binding names to the role names), so the bindings are created again when
+// the tenant is resumed
+const SuspendedRoleBindingsAnnotation = "tenant.fabric8.io/suspended-role-bindings"
+
+// suspendedRoles are the roles the user loses in the namespaces of the suspended tenant
+var suspendedRoles = map[string]bool{"edit": true, "admin": true}
+
+type roleBinding struct {
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	RoleRef struct {
+		Name string `yaml:"name"`
+	} `yaml:"roleRef"`
+	Subjects []struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	} `yaml:"subjects"`
+	UserNames []string `yaml:"userNames"`
+}
+
+func (b roleBinding) isBoundTo(username string) bool {
+	for _, subject := range b.Subjects {
+		if subject.Kind == "User" && subject.Name == username {
+			return true
+		}
+	}
+	for _, userName := range b.UserNames {
+		if userName == username {
+			return true
+		}
+	}
+	return false
+}
+
+type roleBindingList struct {
+	Items []roleBinding `yaml:"items"`
+}
+
+type namespaceObject struct {
+	Metadata struct {
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+}
+
+// SuspendNamespace removes the role bindings granting the edit or admin role to the user in the namespace and scales
+// the workloads of the namespace down to zero. The removed bindings are remembered in the SuspendedRoleBindingsAnnotation
+// of the namespace before they are removed, so the suspension can be safely repeated when it is interrupted
+func SuspendNamespace(ctx context.Context, config *configuration.Data, cluster cluster.Cluster, name, username string) error {
+	client := newClusterClient(ctx, config, cluster)
+	result, err := Apply(*client, http.MethodGet, NewObject(environment.ValKindRoleBinding, name, ""))
+	if err != nil {
+		return errors.Wrapf(err, "unable to get list of role bindings in namespace %s", name)
+	}
+	var list roleBindingList
+	if err := yaml.Unmarshal(result.Body, &list); err != nil {
+		return errors.Wrapf(err, "unable to unmarshal list of role bindings in namespace %s", name)
+	}
+	toRemove := map[string]string{}
+	for _, binding := range list.Items {
+		if suspendedRoles[binding.RoleRef.Name] && binding.isBoundTo(username) {
+			toRemove[binding.Metadata.Name] = binding.RoleRef.Name
+		}
+	}
+
+	if len(toRemove) > 0 {
+		suspended, err := getSuspendedRoleBindings(client, name)
+		if err != nil {
+			return err
+		}
+		for bindingName, role := range toRemove {
+			suspended[bindingName] = role
+		}
+		if err := setSuspendedRoleBindings(client, name, suspended); err != nil {
+			return err
+		}
+		for bindingName := range toRemove {
+			_, err := Apply(*client, http.MethodDelete, NewObject(environment.ValKindRoleBinding, name, bindingName))
+			if err != nil {
+				return errors.Wrapf(err, "unable to remove the role binding %s in namespace %s", bindingName, name)
+			}
+		}
+	}
+	return IdleNamespace(ctx, config, cluster, name)
+}
+
+// ResumeNamespace scales the workloads of the suspended namespace back to their original number of replicas and creates
+// again the role bindings of the user that were removed by the suspension
+func ResumeNamespace(ctx context.Context, config *configuration.Data, cluster cluster.Cluster, name, username string) error {
+	if err := UnidleNamespace(ctx, config, cluster, name); err != nil {
+		return err
+	}
+	client := newClusterClient(ctx, config, cluster)
+	suspended, err := getSuspendedRoleBindings(client, name)
+	if err != nil {
+		return err
+	}
+	if len(suspended) == 0 {
+		return nil
+	}
+	for bindingName, role := range suspended {
+		_, err := Apply(*client, http.MethodPost, newUserRoleBinding(name, bindingName, role, username))
+		if err != nil {
+			return errors.Wrapf(err, "unable to restore the role binding %s in namespace %s", bindingName, name)
+		}
+	}
+	return setSuspendedRoleBindings(client, name, nil)
+}
+
+func getSuspendedRoleBindings(client *Client, name string) (map[string]string, error) {
+	result, err := Apply(*client, http.MethodGet, NewObject(environment.ValKindNamespace, name, name))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get the namespace %s", name)
+	}
+	var namespace namespaceObject
+	if err := yaml.Unmarshal(result.Body, &namespace); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal the namespace %s", name)
+	}
+	suspended := map[string]string{}
+	if value, found := namespace.Metadata.Annotations[SuspendedRoleBindingsAnnotation]; found && value != "" {
+		if err := json.Unmarshal([]byte(value), &suspended); err != nil {
+			return nil, errors.Wrapf(err, "invalid suspended role bindings of the namespace %s", name)
+		}
+	}
+	return suspended, nil
+}
+
+// setSuspendedRoleBindings stores the suspended role bindings in the annotation of the namespace - nil removes the annotation
+func setSuspendedRoleBindings(client *Client, name string, suspended map[string]string) error {
+	var value interface{}
+	if suspended != nil {
+		bytes, err := json.Marshal(suspended)
+		if err != nil {
+			return err
+		}
+		value = string(bytes)
+	}
+	err := mergePatch(client, NewObject(environment.ValKindNamespace, name, name), map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{SuspendedRoleBindingsAnnotation: value},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to store the suspended role bindings to the namespace %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package openshift_test
+
+import (
+	"context"
+	"github.com/fabric8-services/fabric8-tenant/openshift"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"testing"
+)
+
+func TestSuspendNamespace(t *testing.T) {
+	t.Run("rights of the user are removed and workloads scaled down", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		config, reset := test.LoadTestConfig(t)
+		defer reset()
+		gock.New(test.ClusterURL).
+			Get("/oapi/v1/namespaces/john-che/rolebindings").
+			Reply(200).
+			BodyString(`{"items": [
+{"metadata": {"name": "user-edit"}, "roleRef": {"name": "edit"}, "subjects": [{"kind": "User", "name": "john"}]},
+{"metadata": {"name": "user-view"}, "roleRef": {"name": "view"}, "subjects": [{"kind": "User", "name": "john"}]},
+{"metadata": {"name": "admin"}, "roleRef": {"name": "admin"}, "userNames": ["devtools-sre"]}]}`)
+		gock.New(test.ClusterURL).
+			Get("/api/v1/namespaces/john-che").
+			Reply(200).
+			BodyString(`{"metadata": {"name": "john-che"}}`)
+		gock.New(test.ClusterURL).
+			Patch("/api/v1/namespaces/john-che").
+			SetMatcher(test.ExpectRequest(
+				test.HasBearerWithSub("clusterToken"),
+				test.HasJSONBody(`{"metadata": {"annotations": {"tenant.fabric8.io/suspended-role-bindings": "{\"user-edit\":\"edit\"}"}}}`))).
+			Reply(200)
+		gock.New(test.ClusterURL).
+			Delete("/oapi/v1/namespaces/john-che/rolebindings/user-edit").
+			Reply(200)
+		gock.New(test.ClusterURL).
+			Get("/apis/apps.openshift.io/v1/namespaces/john-che/deploymentconfigs").
+			Reply(200).
+			BodyString(`{"items": [{"metadata": {"name": "che"}, "spec": {"replicas": 1}}]}`)
+		gock.New(test.ClusterURL).
+			Get("/apis/apps/v1/namespaces/john-che/deployments").
+			Reply(200).
+			BodyString(`{"items": []}`)
+		gock.New(test.ClusterURL).
+			Get("/apis/apps/v1/namespaces/john-che/statefulsets").
+			Reply(404)
+		gock.New(test.ClusterURL).
+			Patch("/apis/apps.openshift.io/v1/namespaces/john-che/deploymentconfigs/che").
+			SetMatcher(test.ExpectRequest(
+				test.HasJSONBody(`{"metadata": {"annotations": {"tenant.fabric8.io/idled-replicas": "1"}}, "spec": {"replicas": 0}}`))).
+			Reply(200)
+
+		// when
+		err := openshift.SuspendNamespace(context.Background(), config, idlerCluster, "john-che", "john")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, gock.IsDone(), "not all expected requests were sent: %v", gock.Pending())
+	})
+
+	t.Run("fails when the role bindings cannot be listed", func(t *testing.T) {
+		// given
+		defer gock.OffAll()
+		config, reset := test.LoadTestConfig(t)
+		defer reset()
+		gock.New(test.ClusterURL).
+			Get("/oapi/v1/namespaces/john/rolebindings").
+			Reply(500)
+
+		// when
+		err := openshift.SuspendNamespace(context.Background(), config, idlerCluster, "john", "john")
+
+		// then
+		test.AssertError(t, err, test.HasMessageContaining("unable to get list of role bindings in namespace john"))
+	})
+}
+
+func TestResumeNamespace(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	config, reset := test.LoadTestConfig(t)
+	defer reset()
+	gock.New(test.ClusterURL).
+		Get("/apis/apps.openshift.io/v1/namespaces/john-che/deploymentconfigs").
+		Reply(200).
+		BodyString(`{"items": [{"metadata": {"name": "che", "annotations": {"tenant.fabric8.io/idled-replicas": "1"}}, "spec": {"replicas": 0}}]}`)
+	gock.New(test.ClusterURL).
+		Get("/apis/apps/v1/namespaces/john-che/deployments").
+		Reply(200).
+		BodyString(`{"items": []}`)
+	gock.New(test.ClusterURL).
+		Get("/apis/apps/v1/namespaces/john-che/statefulsets").
+		Reply(200).
+		BodyString(`{"items": []}`)
+	gock.New(test.ClusterURL).
+		Patch("/apis/apps.openshift.io/v1/namespaces/john-che/deploymentconfigs/che").
+		SetMatcher(test.ExpectRequest(
+			test.HasJSONBody(`{"metadata": {"annotations": {"tenant.fabric8.io/idled-replicas": null}}, "spec": {"replicas": 1}}`))).
+		Reply(200)
+	gock.New(test.ClusterURL).
+		Get("/api/v1/namespaces/john-che").
+		Reply(200).
+		BodyString(`{"metadata": {"name": "john-che", "annotations": {"tenant.fabric8.io/suspended-role-bindings": "{\"user-edit\":\"edit\"}"}}}`)
+	gock.New(test.ClusterURL).
+		Post("/oapi/v1/namespaces/john-che/rolebindings").
+		SetMatcher(test.ExpectRequest(test.HasBearerWithSub("clusterToken"))).
+		Reply(201)
+	gock.New(test.ClusterURL).
+		Patch("/api/v1/namespaces/john-che").
+		SetMatcher(test.ExpectRequest(
+			test.HasJSONBody(`{"metadata": {"annotations": {"tenant.fabric8.io/suspended-role-bindings": null}}}`))).
+		Reply(200)
+
+	// when
+	err := openshift.ResumeNamespace(context.Background(), config, idlerCluster, "john-che", "john")
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone(), "not all expected requests were sent: %v", gock.Pending())
+}
@@ -118,13 +118,18 @@ func (t *CheNamespaceTypeService) AdditionalObject() (environment.Object, bool)
 }
 
 func (t *CheNamespaceTypeService) newEditRightsObject() environment.Object {
-	adminRb := NewObject(environment.ValKindRoleBinding, t.GetNamespaceName(), "user-edit")
-	adminRb["roleRef"] = environment.Object{"name": "edit"}
-	adminRb["subjects"] = environment.Objects{{
+	return newUserRoleBinding(t.GetNamespaceName(), "user-edit", "edit", t.context.openShiftUsername)
+}
+
+// newUserRoleBinding creates the role binding granting the role to the user in the namespace
+func newUserRoleBinding(namespace, name, role, username string) environment.Object {
+	roleBinding := NewObject(environment.ValKindRoleBinding, namespace, name)
+	roleBinding["roleRef"] = environment.Object{"name": role}
+	roleBinding["subjects"] = environment.Objects{{
 		"kind": "User",
-		"name": t.context.openShiftUsername}}
-	adminRb["userNames"] = []string{t.context.openShiftUsername}
-	return adminRb
+		"name": username}}
+	roleBinding["userNames"] = []string{username}
+	return roleBinding
 }
 
 type UserNamespaceTypeService struct {
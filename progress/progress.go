@@ -0,0 +1,139 @@
+package progress
+
+import (
+	"github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+// EventType is a type of the event sent to the streams of the tenant - it is used as the name of the server-sent event
+type EventType string
+
+const (
+	// NamespaceChanged is sent when the state of a namespace of the tenant has changed
+	NamespaceChanged EventType = "namespace"
+	// ObjectApplied is sent when an object of a namespace was applied (or failed to be applied) on the cluster
+	ObjectApplied EventType = "progress"
+)
+
+// Event is a change of a namespace of the tenant or a progress of an operation performed on it
+type Event struct {
+	Type      EventType `json:"-"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Namespace string    `json:"namespace"`
+	NsType    string    `json:"type"`
+	Action    string    `json:"action,omitempty"`
+	State     string    `json:"state,omitempty"`
+	Kind      string    `json:"kind,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Applied   int       `json:"applied,omitempty"`
+	Total     int       `json:"total,omitempty"`
+	Failed    bool      `json:"failed,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Broker passes the events to the streams subscribed to the tenant in this replica
+type Broker struct {
+	mux         sync.RWMutex
+	closed      bool
+	subscribers map[uuid.UUID]map[chan *Event]struct{}
+}
+
+// NewBroker creates a broker without any subscriber
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[uuid.UUID]map[chan *Event]struct{}{},
+	}
+}
+
+// Subscribe registers a new stream of the events of the given tenant. The returned function has to be called when
+// the stream is not read anymore. The channel is closed when the broker is closed.
+func (b *Broker) Subscribe(tenantID uuid.UUID, buffer int) (<-chan *Event, func()) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	events := make(chan *Event, buffer)
+	if b.closed {
+		close(events)
+		return events, func() {}
+	}
+	if b.subscribers[tenantID] == nil {
+		b.subscribers[tenantID] = map[chan *Event]struct{}{}
+	}
+	b.subscribers[tenantID][events] = struct{}{}
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			b.mux.Lock()
+			defer b.mux.Unlock()
+			if _, subscribed := b.subscribers[tenantID][events]; !subscribed {
+				return
+			}
+			delete(b.subscribers[tenantID], events)
+			if len(b.subscribers[tenantID]) == 0 {
+				delete(b.subscribers, tenantID)
+			}
+			close(events)
+		})
+	}
+}
+
+// Publish passes the event to all streams of the tenant. The provisioning is never blocked by a slow reader - if the buffer
+// of the stream is full, then the event is dropped for that stream.
+func (b *Broker) Publish(event *Event) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	for events := range b.subscribers[event.TenantID] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// Close closes all streams so the long-running requests reading them can finish; no new stream can be subscribed then
+func (b *Broker) Close() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.closed = true
+	for _, streams := range b.subscribers {
+		for events := range streams {
+			close(events)
+		}
+	}
+	b.subscribers = map[uuid.UUID]map[chan *Event]struct{}{}
+}
+
+var (
+	defaultBroker = NewBroker()
+	publisherMux  sync.RWMutex
+	// publisher sends the event to the subscribed streams - it is replaced by the relay so the events reach the streams in all replicas
+	publisher = defaultBroker.Publish
+)
+
+// Subscribe registers a new stream of the events of the given tenant in the default broker
+func Subscribe(tenantID uuid.UUID, buffer int) (<-chan *Event, func()) {
+	return defaultBroker.Subscribe(tenantID, buffer)
+}
+
+// Publish sends the event to all streams subscribed to the tenant
+func Publish(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	publisherMux.RLock()
+	publish := publisher
+	publisherMux.RUnlock()
+	publish(event)
+}
+
+// Close closes all streams of the default broker
+func Close() {
+	defaultBroker.Close()
+}
+
+func setPublisher(publish func(event *Event)) {
+	publisherMux.Lock()
+	defer publisherMux.Unlock()
+	publisher = publish
+}
@@ -0,0 +1,147 @@
+package progress_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/progress"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+func TestEventIsPassedOnlyToStreamsOfTheTenant(t *testing.T) {
+	// given
+	broker := progress.NewBroker()
+	johnID, aliceID := uuid.NewV4(), uuid.NewV4()
+	john, unsubscribeJohn := broker.Subscribe(johnID, 10)
+	defer unsubscribeJohn()
+	johnsSecond, unsubscribeJohnsSecond := broker.Subscribe(johnID, 10)
+	defer unsubscribeJohnsSecond()
+	alice, unsubscribeAlice := broker.Subscribe(aliceID, 10)
+	defer unsubscribeAlice()
+
+	// when
+	broker.Publish(&progress.Event{Type: progress.ObjectApplied, TenantID: johnID, Namespace: "john-che", Applied: 1, Total: 2})
+
+	// then
+	for _, events := range []<-chan *progress.Event{john, johnsSecond} {
+		require.Len(t, events, 1)
+		event := <-events
+		assert.Equal(t, progress.ObjectApplied, event.Type)
+		assert.Equal(t, "john-che", event.Namespace)
+	}
+	assert.Len(t, alice, 0)
+}
+
+func TestSlowStreamDoesNotBlockPublishing(t *testing.T) {
+	// given
+	broker := progress.NewBroker()
+	tenantID := uuid.NewV4()
+	events, unsubscribe := broker.Subscribe(tenantID, 1)
+	defer unsubscribe()
+
+	// when
+	for i := 1; i <= 3; i++ {
+		broker.Publish(&progress.Event{Type: progress.ObjectApplied, TenantID: tenantID, Applied: i, Total: 3})
+	}
+
+	// then
+	require.Len(t, events, 1)
+	assert.Equal(t, 1, (<-events).Applied)
+}
+
+func TestUnsubscribeAndCloseEndTheStreams(t *testing.T) {
+	// given
+	broker := progress.NewBroker()
+	tenantID := uuid.NewV4()
+	unsubscribed, unsubscribe := broker.Subscribe(tenantID, 1)
+	closed, _ := broker.Subscribe(tenantID, 1)
+
+	// when
+	unsubscribe()
+	unsubscribe()
+	broker.Close()
+
+	// then
+	_, open := <-unsubscribed
+	assert.False(t, open)
+	_, open = <-closed
+	assert.False(t, open)
+	afterClose, _ := broker.Subscribe(tenantID, 1)
+	_, open = <-afterClose
+	assert.False(t, open)
+	broker.Publish(&progress.Event{TenantID: tenantID})
+}
+
+type RelayTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestRelay(t *testing.T) {
+	suite.Run(t, &RelayTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func (s *RelayTestSuite) TestEventIsRelayedThroughDB() {
+	// given
+	relay := progress.NewRelay(s.DB, s.Configuration.GetPostgresConfigString())
+	require.NoError(s.T(), relay.Start())
+	defer relay.Stop()
+	tenantID := uuid.NewV4()
+	events, unsubscribe := progress.Subscribe(tenantID, 10)
+	defer unsubscribe()
+
+	// when
+	progress.Publish(&progress.Event{Type: progress.NamespaceChanged, TenantID: tenantID, Namespace: "john", NsType: "user",
+		Action: "create", State: "ready"})
+
+	// then
+	select {
+	case event := <-events:
+		assert.Equal(s.T(), progress.NamespaceChanged, event.Type)
+		assert.Equal(s.T(), tenantID, event.TenantID)
+		assert.Equal(s.T(), "john", event.Namespace)
+		assert.Equal(s.T(), "ready", event.State)
+		assert.False(s.T(), event.Time.IsZero())
+	case <-time.After(5 * time.Second):
+		assert.Fail(s.T(), "the event wasn't relayed")
+	}
+}
+
+func (s *RelayTestSuite) TestProgressOfNamespaceIsCoalesced() {
+	// given
+	relay := progress.NewRelay(s.DB, s.Configuration.GetPostgresConfigString())
+	require.NoError(s.T(), relay.Start())
+	defer relay.Stop()
+	tenantID := uuid.NewV4()
+	events, unsubscribe := progress.Subscribe(tenantID, 10)
+	defer unsubscribe()
+
+	// when
+	for i := 1; i <= 3; i++ {
+		progress.Publish(&progress.Event{Type: progress.ObjectApplied, TenantID: tenantID, Namespace: "john", Applied: i, Total: 3})
+	}
+	progress.Publish(&progress.Event{Type: progress.NamespaceChanged, TenantID: tenantID, Namespace: "john", NsType: "user",
+		Action: "update", State: "ready"})
+
+	// then
+	var received []*progress.Event
+	timeout := time.After(5 * time.Second)
+	for len(received) < 2 {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-timeout:
+			require.FailNow(s.T(), "the events weren't relayed", "received %d events", len(received))
+		}
+	}
+	assert.Equal(s.T(), progress.ObjectApplied, received[0].Type)
+	assert.Equal(s.T(), 3, received[0].Applied)
+	assert.Equal(s.T(), progress.NamespaceChanged, received[1].Type)
+	select {
+	case event := <-events:
+		assert.Fail(s.T(), "no other event should be relayed", "%+v", event)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,178 @@
+package progress
+
+import (
+	"encoding/json"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+const (
+	// Channel is the name of the Postgres channel the events are relayed through
+	Channel = "tenant_progress"
+	// progressInterval is how often the progress of the operations is relayed - all progress events of a namespace
+	// published within the interval are coalesced into the latest one
+	progressInterval = time.Second
+)
+
+// Relay passes the events among all replicas - the operation on the namespaces can run in a different replica than the one
+// the stream of the tenant is connected to. The events are sent via Postgres NOTIFY and every replica listens to them
+// and passes them to its local streams. The changes of the namespaces are sent immediately, the progress of the operations
+// is throttled so the applied objects of a bulk operation or an update don't flood the DB with notifications.
+type Relay struct {
+	db       *gorm.DB
+	listener *pq.Listener
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+
+	mux             sync.Mutex
+	pendingProgress map[progressKey]*Event
+}
+
+type progressKey struct {
+	tenantID  uuid.UUID
+	namespace string
+}
+
+// NewRelay creates a relay sending the events via the given DB and listening to them on a separate connection
+func NewRelay(db *gorm.DB, connectionString string) *Relay {
+	listener := pq.NewListener(connectionString, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err":     err,
+				"channel": Channel,
+			}, "the listener of the tenant progress events has a connection problem")
+		}
+	})
+	return &Relay{
+		db:              db,
+		listener:        listener,
+		stop:            make(chan struct{}),
+		pendingProgress: map[progressKey]*Event{},
+	}
+}
+
+// Start starts listening to the events and makes Publish send them to all replicas
+func (r *Relay) Start() error {
+	if err := r.listener.Listen(Channel); err != nil {
+		return errors.Wrapf(err, "unable to listen to the %s channel", Channel)
+	}
+	setPublisher(r.notify)
+	r.stopped.Add(2)
+	go r.listen()
+	go r.relayProgress()
+	return nil
+}
+
+// Stop makes Publish pass the events only to the streams of this replica and stops listening to the other replicas
+func (r *Relay) Stop() {
+	setPublisher(defaultBroker.Publish)
+	close(r.stop)
+	r.stopped.Wait()
+	if err := r.listener.Close(); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to close the listener of the tenant progress events")
+	}
+}
+
+func (r *Relay) listen() {
+	defer r.stopped.Done()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case notification := <-r.listener.Notify:
+			// nil is sent after the connection was re-established
+			if notification == nil {
+				continue
+			}
+			relayed := relayedEvent{Event: &Event{}}
+			if err := json.Unmarshal([]byte(notification.Extra), &relayed); err != nil {
+				log.Error(nil, map[string]interface{}{
+					"err":     err,
+					"payload": notification.Extra,
+				}, "unable to parse the tenant progress event")
+				continue
+			}
+			relayed.Event.Type = relayed.Type
+			defaultBroker.Publish(relayed.Event)
+		case <-time.After(90 * time.Second):
+			go r.listener.Ping()
+		}
+	}
+}
+
+// relayProgress sends the latest progress of the namespaces in the configured interval until the relay is stopped
+func (r *Relay) relayProgress() {
+	defer r.stopped.Done()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flushProgress()
+		case <-r.stop:
+			r.flushProgress()
+			return
+		}
+	}
+}
+
+func (r *Relay) flushProgress() {
+	r.mux.Lock()
+	pending := r.pendingProgress
+	r.pendingProgress = map[progressKey]*Event{}
+	r.mux.Unlock()
+	for _, event := range pending {
+		r.send(event)
+	}
+}
+
+// notify sends the change of the namespace to all replicas including this one. The progress of the operation is only
+// stored to be sent with the next relayed progress, unless the namespace changes before - then it is sent first
+// so the streams get the events in order
+func (r *Relay) notify(event *Event) {
+	key := progressKey{tenantID: event.TenantID, namespace: event.Namespace}
+	r.mux.Lock()
+	if event.Type == ObjectApplied {
+		r.pendingProgress[key] = event
+		r.mux.Unlock()
+		return
+	}
+	pending := r.pendingProgress[key]
+	delete(r.pendingProgress, key)
+	r.mux.Unlock()
+
+	if pending != nil {
+		r.send(pending)
+	}
+	r.send(event)
+}
+
+// send sends the event to all replicas including this one. If it fails, then the event is dropped for all streams,
+// so the streams of the tenant get the same events regardless of the replica they are connected to
+func (r *Relay) send(event *Event) {
+	payload, err := json.Marshal(relayedEvent{Event: event, Type: event.Type})
+	if err == nil {
+		err = r.db.Exec("SELECT pg_notify(?, ?)", Channel, string(payload)).Error
+	}
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"tenant":     event.TenantID,
+			"namespace":  event.Namespace,
+			"event_type": event.Type,
+		}, err, "unable to relay the tenant progress event - it is dropped for all streams of the tenant")
+	}
+}
+
+// relayedEvent carries the type of the event that is not part of its JSON representation sent to the streams
+type relayedEvent struct {
+	*Event
+	Type EventType `json:"event"`
+}
@@ -7,8 +7,23 @@ import (
 // ToRetry is a function type which wraps actual logic to be retried and returns error if that needs to happen
 type ToRetry func() error // nolint: golint
 
+// abortError stops the retries of Do
+type abortError struct {
+	err error
+}
+
+func (e abortError) Error() string {
+	return e.err.Error()
+}
+
+// Abort wraps the error returned by ToRetry so Do stops retrying - the wrapped error is the last accumulated one
+func Abort(err error) error {
+	return abortError{err: err}
+}
+
 // Do invokes a function and if invocation fails retries defined amount of time with sleep in between
-// Returns accumulated errors if all attempts failed or empty slice otherwise
+// Returns accumulated errors if all attempts failed or empty slice otherwise. The retries are stopped when the function returns
+// an error wrapped by Abort
 func Do(retries int, sleep time.Duration, toRetry ToRetry) chan error {
 	iteration := 1
 	errs := make(chan error, retries)
@@ -18,6 +33,10 @@ func Do(retries int, sleep time.Duration, toRetry ToRetry) chan error {
 	if err == nil {
 		return errs
 	}
+	if aborted, ok := err.(abortError); ok {
+		errs <- aborted.err
+		return errs
+	}
 	errs <- err
 
 	for {
@@ -27,6 +46,10 @@ func Do(retries int, sleep time.Duration, toRetry ToRetry) chan error {
 				return errs
 			}
 			err := toRetry()
+			if aborted, ok := err.(abortError); ok {
+				errs <- aborted.err
+				return errs
+			}
 			if err != nil {
 				errs <- err
 			} else {
@@ -61,3 +61,24 @@ func TestStopRetryingWhenSuccessful(t *testing.T) {
 	require.Empty(t, err)
 	require.Equal(t, executions, 3)
 }
+
+func TestStopRetryingWhenAborted(t *testing.T) {
+	// given
+	executions := 0
+	toRetry := func() error {
+		executions++
+		if executions == 2 {
+			return retry.Abort(errors.New("unavailable"))
+		}
+		return errors.New("not found")
+	}
+
+	// when
+	errs := retry.Do(10, time.Millisecond*50, toRetry)
+
+	// then
+	require.Len(t, errs, 2)
+	require.Equal(t, executions, 2)
+	<-errs
+	require.EqualError(t, <-errs, "unavailable")
+}
@@ -0,0 +1,135 @@
+package shutdown
+
+import (
+	"errors"
+	"github.com/fabric8-services/fabric8-common/log"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned when a new operation is started while the service is shutting down
+var ErrShuttingDown = errors.New("the service is shutting down - no new operation can be started")
+
+// Tracker keeps track of the operations in flight (setup, update or clean of tenant namespaces) so the service can wait
+// for them to finish before it exits. Once the shutdown is started, no new operation can be begun.
+type Tracker struct {
+	mux          sync.Mutex
+	shuttingDown bool
+	lastID       uint64
+	inFlight     map[uint64]*operation
+	finished     *sync.Cond
+}
+
+type operation struct {
+	name        string
+	onInterrupt func()
+}
+
+// NewTracker creates a tracker of operations in flight
+func NewTracker() *Tracker {
+	tracker := &Tracker{
+		inFlight: map[uint64]*operation{},
+	}
+	tracker.finished = sync.NewCond(&tracker.mux)
+	return tracker
+}
+
+// Begin registers a new operation in flight. The returned function has to be called when the operation is finished.
+// The onInterrupt function (if not nil) is called when the operation doesn't finish before the shutdown deadline,
+// so it can leave a mark that the operation should be resumed.
+func (t *Tracker) Begin(name string, onInterrupt func()) (func(), error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+	t.lastID++
+	id := t.lastID
+	t.inFlight[id] = &operation{name: name, onInterrupt: onInterrupt}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mux.Lock()
+			defer t.mux.Unlock()
+			delete(t.inFlight, id)
+			t.finished.Broadcast()
+		})
+	}, nil
+}
+
+// StartShutdown stops accepting new operations
+func (t *Tracker) StartShutdown() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.shuttingDown = true
+}
+
+// IsShuttingDown returns true if the shutdown was started
+func (t *Tracker) IsShuttingDown() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.shuttingDown
+}
+
+// Drain stops accepting new operations and waits until all operations in flight are finished or the timeout elapses.
+// The operations that are not finished in time are interrupted. Returns true if all operations were finished.
+func (t *Tracker) Drain(timeout time.Duration) bool {
+	t.StartShutdown()
+
+	deadline := time.AfterFunc(timeout, func() {
+		t.mux.Lock()
+		defer t.mux.Unlock()
+		t.finished.Broadcast()
+	})
+	defer deadline.Stop()
+	start := time.Now()
+
+	t.mux.Lock()
+	for len(t.inFlight) > 0 && time.Since(start) < timeout {
+		t.finished.Wait()
+	}
+	unfinished := t.inFlight
+	t.inFlight = map[uint64]*operation{}
+	t.mux.Unlock()
+
+	for _, op := range unfinished {
+		log.Warn(nil, map[string]interface{}{
+			"operation": op.name,
+			"timeout":   timeout.String(),
+		}, "the operation hasn't been finished before the shutdown deadline - interrupting it")
+		if op.onInterrupt != nil {
+			op.onInterrupt()
+		}
+	}
+	return len(unfinished) == 0
+}
+
+// InFlight returns the number of operations in flight
+func (t *Tracker) InFlight() int {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return len(t.inFlight)
+}
+
+var defaultTracker = NewTracker()
+
+// Begin registers a new operation in flight using the tracker of the service
+func Begin(name string, onInterrupt func()) (func(), error) {
+	return defaultTracker.Begin(name, onInterrupt)
+}
+
+// StartShutdown stops accepting new operations in the tracker of the service
+func StartShutdown() {
+	defaultTracker.StartShutdown()
+}
+
+// IsShuttingDown returns true if the shutdown of the service was started
+func IsShuttingDown() bool {
+	return defaultTracker.IsShuttingDown()
+}
+
+// Drain waits for the operations in flight of the service - see Tracker.Drain
+func Drain(timeout time.Duration) bool {
+	return defaultTracker.Drain(timeout)
+}
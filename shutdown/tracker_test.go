@@ -0,0 +1,74 @@
+package shutdown_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrainWaitsForOperationsInFlight(t *testing.T) {
+	// given
+	tracker := shutdown.NewTracker()
+	var interrupted uint64
+	done, err := tracker.Begin("update of namespace john-che", func() {
+		atomic.AddUint64(&interrupted, 1)
+	})
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		done()
+	}()
+	start := time.Now()
+
+	// when
+	finished := tracker.Drain(5 * time.Second)
+
+	// then
+	assert.True(t, finished)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	assert.True(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&interrupted))
+	assert.Equal(t, 0, tracker.InFlight())
+}
+
+func TestUnfinishedOperationsAreInterruptedAfterTimeout(t *testing.T) {
+	// given
+	tracker := shutdown.NewTracker()
+	var interrupted uint64
+	_, err := tracker.Begin("update of namespace john-che", func() {
+		atomic.AddUint64(&interrupted, 1)
+	})
+	require.NoError(t, err)
+	done, err := tracker.Begin("setup of namespace john", func() {
+		atomic.AddUint64(&interrupted, 1)
+	})
+	require.NoError(t, err)
+	done()
+
+	// when
+	finished := tracker.Drain(100 * time.Millisecond)
+
+	// then
+	assert.False(t, finished)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&interrupted))
+	assert.Equal(t, 0, tracker.InFlight())
+}
+
+func TestNoOperationCanBeBegunWhenShuttingDown(t *testing.T) {
+	// given
+	tracker := shutdown.NewTracker()
+	assert.False(t, tracker.IsShuttingDown())
+
+	// when
+	tracker.StartShutdown()
+
+	// then
+	assert.True(t, tracker.IsShuttingDown())
+	done, err := tracker.Begin("setup of namespace john", nil)
+	assert.Equal(t, shutdown.ErrShuttingDown, err)
+	assert.Nil(t, done)
+	assert.True(t, tracker.Drain(time.Second))
+}
@@ -9,18 +9,28 @@ import (
 	errs "github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 	"strings"
+	"time"
 )
 
 type Service interface {
 	CreateTenant(tenant *Tenant) error
 	SaveTenant(tenant *Tenant) error
 	LookupTenantByClusterAndNamespace(masterURL, namespace string) (*Tenant, error)
+	SearchTenants(query *TenantsQuery) (*TenantsPage, error)
 	NewTenantRepository(tenantID uuid.UUID) Repository
 	NamespaceExists(nsName string) (bool, error)
 	ExistsWithNsBaseName(nsBaseName string) (bool, error)
 	GetTenantsToUpdate(typeWithVersion map[environment.Type]string, count int, commit string, masterURL string) ([]*Tenant, error)
+	GetTenantsToUpdateAfter(typeWithVersion map[environment.Type]string, count int, commit string, masterURL string, after uuid.UUID) ([]*Tenant, error)
 	GetClustersToUpdate(typeWithVersion map[environment.Type]string, commit string) ([]string, error)
 	GetNumberOfOutdatedTenants(typeWithVersion map[environment.Type]string, commit string, masterURL string) (int, error)
+	CountNotReadyTenants(tenantIDs []uuid.UUID, typeWithVersion map[environment.Type]string) (int, error)
+	CountNamespaces() ([]*NamespacesCount, error)
+	CountTenantsPerCluster() ([]*TenantsCount, error)
+	GetStuckNamespaces(stuckBefore time.Time, count int) ([]*Namespace, error)
+	MarkStuckNamespaceFailed(namespace *Namespace, reason string) (bool, error)
+	PurgeDeleted(deletedBefore time.Time, count int) (*PurgedCount, error)
+	GetNamespacesToIdle(inactiveSince time.Time, count int) ([]*Namespace, error)
 }
 
 func NewDBService(db *gorm.DB) Service {
@@ -45,9 +55,11 @@ func (s *DBService) CreateTenant(tenant *Tenant) error {
 	return s.db.Create(tenant).Error
 }
 
+// ExistsWithNsBaseName checks if there is a tenant with the given base name - including the soft-deleted ones,
+// so the name isn't reused before the deleted tenant is purged
 func (s *DBService) ExistsWithNsBaseName(nsBaseName string) (bool, error) {
 	var t Tenant
-	err := s.db.Table(t.TableName()).Where("ns_base_name = ?", nsBaseName).Find(&t).Error
+	err := s.db.Unscoped().Table(t.TableName()).Where("ns_base_name = ?", nsBaseName).Find(&t).Error
 	if err != nil {
 		if gorm.ErrRecordNotFound == err {
 			return false, nil
@@ -57,9 +69,11 @@ func (s *DBService) ExistsWithNsBaseName(nsBaseName string) (bool, error) {
 	return true, nil
 }
 
+// NamespaceExists checks if there is a namespace with the given name - including the soft-deleted ones,
+// so the name isn't reused before the deleted namespace is purged
 func (s *DBService) NamespaceExists(nsName string) (bool, error) {
 	var ns Namespace
-	err := s.db.Table(Namespace{}.TableName()).Where("name = ?", nsName).Find(&ns).Error
+	err := s.db.Unscoped().Table(Namespace{}.TableName()).Where("name = ?", nsName).Find(&ns).Error
 	if err != nil {
 		if gorm.ErrRecordNotFound == err {
 			return false, nil
@@ -71,7 +85,7 @@ func (s *DBService) NamespaceExists(nsName string) (bool, error) {
 
 func (s *DBService) LookupTenantByClusterAndNamespace(masterURL, namespace string) (*Tenant, error) {
 	// select t.id from tenant t, namespaces n where t.id = n.tenant_id and n.master_url = ? and n.name = ?
-	query := fmt.Sprintf("select t.* from %[1]s t, %[2]s n where t.id = n.tenant_id and n.master_url = ? and n.name = ?", Tenant{}.TableName(), Namespace{}.TableName())
+	query := fmt.Sprintf("select t.* from %[1]s t, %[2]s n where t.id = n.tenant_id and n.master_url = ? and n.name = ? and t.deleted_at is null and n.deleted_at is null", Tenant{}.TableName(), Namespace{}.TableName())
 	var result Tenant
 	err := s.db.Raw(query, masterURL, namespace).Scan(&result).Error
 	if err == gorm.ErrRecordNotFound {
@@ -84,8 +98,18 @@ func (s *DBService) LookupTenantByClusterAndNamespace(masterURL, namespace strin
 }
 
 func (s *DBService) GetTenantsToUpdate(typeWithVersion map[environment.Type]string, count int, commit string, masterURL string) ([]*Tenant, error) {
+	return s.GetTenantsToUpdateAfter(typeWithVersion, count, commit, masterURL, uuid.Nil)
+}
+
+// GetTenantsToUpdateAfter returns the next batch of outdated tenants ordered by their IDs starting after the given ID.
+// It can be used for iterating over outdated tenants where some of them are skipped and thus stay outdated.
+func (s *DBService) GetTenantsToUpdateAfter(typeWithVersion map[environment.Type]string, count int, commit string, masterURL string, after uuid.UUID) ([]*Tenant, error) {
 	var tenants []*Tenant
-	err := s.newGetOutdatedTenantsQuery(typeWithVersion, commit, masterURL).Limit(count).Scan(&tenants).Error
+	err := s.newGetOutdatedTenantsQuery(typeWithVersion, commit, masterURL).
+		Where("tenants.id > ?", after).
+		Order("tenants.id").
+		Limit(count).
+		Scan(&tenants).Error
 
 	return tenants, err
 }
@@ -111,8 +135,200 @@ func (s *DBService) GetNumberOfOutdatedTenants(typeWithVersion map[environment.T
 	return count, err
 }
 
+// CountNotReadyTenants returns how many of the given tenants have a namespace of any of the given types that either isn't ready
+// or doesn't have the given version. The unknown states are considered as ready, the same way as they are scanned
+func (s *DBService) CountNotReadyTenants(tenantIDs []uuid.UUID, typeWithVersion map[environment.Type]string) (int, error) {
+	if len(tenantIDs) == 0 || len(typeWithVersion) == 0 {
+		return 0, nil
+	}
+	var conditions []string
+	var params []interface{}
+	for envType, version := range typeWithVersion {
+		conditions = append(conditions, "(type = ? AND ((CASE WHEN state IN (?) THEN state ELSE ? END) != ? OR version != ?))")
+		params = append(params, envType, knownStateValues(), Ready, Ready, version)
+	}
+	var count int
+	err := s.db.Table(namespaceTableName).
+		Select("count(DISTINCT tenant_id)").
+		Where("deleted_at IS NULL AND tenant_id IN (?)", tenantIDs).
+		Where(strings.Join(conditions, " OR "), params...).
+		Count(&count).Error
+	if err != nil {
+		return 0, errs.Wrapf(err, "unable to count tenants with namespaces that are not ready")
+	}
+	return count, nil
+}
+
+// NamespacesCount is the number of namespaces in the same state with the same version located in the same cluster
+type NamespacesCount struct {
+	State     NamespaceState
+	Version   string
+	MasterURL string
+	Count     int
+}
+
+// CountNamespaces returns the numbers of namespaces grouped by state, version and cluster
+func (s *DBService) CountNamespaces() ([]*NamespacesCount, error) {
+	var counts []*NamespacesCount
+	err := s.db.Table(namespaceTableName).
+		Select("state, version, master_url, count(*) AS count").
+		Where("deleted_at IS NULL").
+		Group("state, version, master_url").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to count namespaces")
+	}
+	return counts, nil
+}
+
+// TenantsCount is the number of tenants that have namespaces located in the same cluster
+type TenantsCount struct {
+	MasterURL string
+	Count     int
+}
+
+// CountTenantsPerCluster returns the numbers of tenants grouped by the clusters their namespaces are located in
+func (s *DBService) CountTenantsPerCluster() ([]*TenantsCount, error) {
+	var counts []*TenantsCount
+	err := s.db.Table(namespaceTableName).
+		Select("namespaces.master_url, count(DISTINCT namespaces.tenant_id) AS count").
+		Joins("INNER JOIN tenants t ON t.id = namespaces.tenant_id AND t.deleted_at IS NULL").
+		Where("namespaces.deleted_at IS NULL").
+		Group("namespaces.master_url").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to count tenants per cluster")
+	}
+	return counts, nil
+}
+
+// GetStuckNamespaces returns the namespaces that have been in the provisioning or updating state since before the given time
+// starting from the oldest ones
+func (s *DBService) GetStuckNamespaces(stuckBefore time.Time, count int) ([]*Namespace, error) {
+	var namespaces []*Namespace
+	err := s.db.Table(namespaceTableName).
+		Where("state IN (?) AND updated_at < ?", []NamespaceState{Provisioning, Updating}, stuckBefore).
+		Order("updated_at").
+		Limit(count).
+		Find(&namespaces).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to get namespaces stuck since %s", stuckBefore)
+	}
+	return namespaces, nil
+}
+
+// MarkStuckNamespaceFailed sets the failed state with the given reason to the namespace unless its state has been changed
+// since it was retrieved. The transition is recorded as done by the janitor. Returns true if the namespace was marked as failed
+func (s *DBService) MarkStuckNamespaceFailed(namespace *Namespace, reason string) (bool, error) {
+	from := namespace.State
+	if !CanTransition(from, Failed) {
+		return false, InvalidTransitionError{Namespace: namespace.Name, From: from, To: Failed}
+	}
+	marked := false
+	err := dbsupport.Transaction(s.db, func(tx *gorm.DB) error {
+		result := tx.Model(&Namespace{}).
+			Where("id = ? AND state = ? AND updated_at = ?", namespace.ID, from, namespace.UpdatedAt).
+			Updates(map[string]interface{}{"state": Failed, "state_reason": reason})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		marked = true
+		namespace.State = Failed
+		namespace.StateReason = reason
+		return tx.Create(NewStateTransition(namespace, from, ActorJanitor, errs.New(reason))).Error
+	})
+	if err != nil {
+		namespace.State = from
+		return false, errs.Wrapf(err, "unable to mark the namespace %s as failed", namespace.Name)
+	}
+	return marked, nil
+}
+
+// GetNamespacesToIdle returns the ready namespaces of the tenants that haven't been active since the given time
+// starting from the least active ones. Tenants without any recorded activity are considered active since their creation.
+// The namespaces of the suspended tenants are already scaled down so they are skipped
+func (s *DBService) GetNamespacesToIdle(inactiveSince time.Time, count int) ([]*Namespace, error) {
+	var namespaces []*Namespace
+	err := s.db.Table(namespaceTableName).
+		Select("namespaces.*").
+		Joins("INNER JOIN tenants t ON t.id = namespaces.tenant_id AND t.deleted_at IS NULL AND t.suspended_at IS NULL").
+		Where("namespaces.state = ? AND COALESCE(t.last_active_at, t.created_at) < ?", Ready, inactiveSince).
+		Order("COALESCE(t.last_active_at, t.created_at), namespaces.tenant_id").
+		Limit(count).
+		Find(&namespaces).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to get namespaces of the tenants inactive since %s", inactiveSince)
+	}
+	return namespaces, nil
+}
+
+// PurgedCount is the number of records removed from DB by the purge of the soft-deleted entities
+type PurgedCount struct {
+	Tenants    int
+	Namespaces int
+}
+
+// PurgeDeleted permanently removes at most count tenants and count namespaces that were soft-deleted before the given time.
+// The namespaces and the history of the purged tenants are removed together with them
+func (s *DBService) PurgeDeleted(deletedBefore time.Time, count int) (*PurgedCount, error) {
+	purged := &PurgedCount{}
+	err := dbsupport.Transaction(s.db, func(tx *gorm.DB) error {
+		var tenants []*Tenant
+		err := tx.Unscoped().Table(tenantTableName).Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Order("deleted_at").
+			Limit(count).
+			Find(&tenants).Error
+		if err != nil {
+			return err
+		}
+		var tenantIDs []uuid.UUID
+		for _, t := range tenants {
+			tenantIDs = append(tenantIDs, t.ID)
+		}
+		if len(tenantIDs) > 0 {
+			namespaces, err := purgeTenants(tx, tenantIDs)
+			if err != nil {
+				return err
+			}
+			purged.Tenants = len(tenantIDs)
+			purged.Namespaces = namespaces
+		}
+
+		result := tx.Unscoped().
+			Where("id IN (?)", tx.Unscoped().Table(namespaceTableName).Select("id").
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+				Order("deleted_at").
+				Limit(count).
+				SubQuery()).
+			Delete(&Namespace{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.Namespaces += int(result.RowsAffected)
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to purge the entities deleted before %s", deletedBefore)
+	}
+	return purged, nil
+}
+
+// purgeTenants permanently removes the tenants with all their namespaces and the history of the namespace states.
+// Returns the number of the removed namespaces
+func purgeTenants(tx *gorm.DB, tenantIDs []uuid.UUID) (int, error) {
+	result := tx.Unscoped().Where("tenant_id IN (?)", tenantIDs).Delete(&Namespace{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := tx.Where("tenant_id IN (?)", tenantIDs).Delete(&StateTransition{}).Error; err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected), tx.Unscoped().Where("id IN (?)", tenantIDs).Delete(&Tenant{}).Error
+}
+
 func (s *DBService) newGetOutdatedNamespacesQuery(typeWithVersion map[environment.Type]string, toSelect, commit, masterURL string) *gorm.DB {
-	nsSubQuery := s.db.Table(Namespace{}.TableName()).Select(toSelect)
+	nsSubQuery := s.db.Table(Namespace{}.TableName()).Select(toSelect).Where("deleted_at IS NULL")
 	nsSubQuery = nsSubQuery.Where("state != 'failed' OR (state = 'failed' AND updated_by != ?)", commit)
 	if masterURL != "" {
 		nsSubQuery = nsSubQuery.Where("master_url = ?", masterURL)
@@ -131,7 +347,8 @@ func (s *DBService) newGetOutdatedNamespacesQuery(typeWithVersion map[environmen
 func (s *DBService) newGetOutdatedTenantsQuery(typeWithVersion map[environment.Type]string, commit string, masterURL string) *gorm.DB {
 	nsSubQuery := s.newGetOutdatedNamespacesQuery(typeWithVersion, "tenant_id", commit, masterURL)
 	return s.db.Table(Tenant{}.TableName()).
-		Joins("INNER JOIN ? n ON tenants.id = n.tenant_id", nsSubQuery.SubQuery())
+		Joins("INNER JOIN ? n ON tenants.id = n.tenant_id", nsSubQuery.SubQuery()).
+		Where("tenants.deleted_at IS NULL AND tenants.suspended_at IS NULL")
 }
 
 func (s *DBService) NewTenantRepository(tenantID uuid.UUID) Repository {
@@ -149,10 +366,20 @@ type Repository interface {
 	NewNamespace(envType environment.Type, nsName, masterURL string, state NamespaceState) *Namespace
 	GetNamespaces() ([]*Namespace, error)
 	SaveNamespace(namespace *Namespace) error
-	CreateNamespace(namespace *Namespace) (*Namespace, error)
+	CreateNamespace(namespace *Namespace, actor Actor) (*Namespace, error)
+	ChangeNamespaceState(namespace *Namespace, state NamespaceState, actor Actor, cause error) error
+	GetStateTransitions() ([]*StateTransition, error)
 	DeleteNamespace(namespace *Namespace) error
+	PurgeNamespace(namespace *Namespace) error
 	DeleteNamespaces() error
 	DeleteTenant() error
+	GetDeletedTenant() (*Tenant, error)
+	GetDeletedNamespaces() ([]*Namespace, error)
+	RestoreTenant() error
+	PurgeDeletedTenant() error
+	RecordActivity(at time.Time) error
+	SuspendTenant(reason string) error
+	ResumeTenant() error
 }
 
 type DBTenantRepository struct {
@@ -210,7 +437,8 @@ func (r *DBTenantRepository) SaveNamespace(namespace *Namespace) error {
 	return r.db.Save(namespace).Error
 }
 
-func (r *DBTenantRepository) CreateNamespace(namespace *Namespace) (*Namespace, error) {
+// CreateNamespace stores the namespace unless the same one already exists and records its initial state in the history
+func (r *DBTenantRepository) CreateNamespace(namespace *Namespace, actor Actor) (*Namespace, error) {
 	if namespace.TenantID == uuid.Nil {
 		namespace.TenantID = r.tenantID
 	}
@@ -232,7 +460,10 @@ func (r *DBTenantRepository) CreateNamespace(namespace *Namespace) (*Namespace,
 			return nil
 		}
 		created = true
-		return tx.Create(namespace).Error
+		if err := tx.Create(namespace).Error; err != nil {
+			return err
+		}
+		return tx.Create(NewStateTransition(namespace, noState, actor, nil)).Error
 	}))
 	if err != nil {
 		return nil, err
@@ -243,22 +474,207 @@ func (r *DBTenantRepository) CreateNamespace(namespace *Namespace) (*Namespace,
 	return namespace, nil
 }
 
+// ChangeNamespaceState moves the namespace to the given state if the transition is allowed, saves it and records
+// the transition in the history. The cause is the error that made the namespace end up in the state, if any.
+// The namespace is changed only if its state hasn't been changed since it was retrieved - otherwise
+// InvalidTransitionError with the current state is returned, so concurrent changes don't overwrite each other
+func (r *DBTenantRepository) ChangeNamespaceState(namespace *Namespace, state NamespaceState, actor Actor, cause error) error {
+	from, fromReason, fromUpdatedAt := namespace.State, namespace.StateReason, namespace.UpdatedAt
+	if !CanTransition(from, state) {
+		return InvalidTransitionError{Namespace: namespace.Name, From: from, To: state}
+	}
+	if namespace.TenantID == uuid.Nil {
+		namespace.TenantID = r.tenantID
+	}
+	namespace.State = state
+	namespace.StateReason = ""
+	if cause != nil {
+		namespace.StateReason = cause.Error()
+	}
+	namespace.UpdatedAt = time.Now()
+	conflict := false
+	var current []*Namespace
+	err := dbsupport.Transaction(r.db, func(tx *gorm.DB) error {
+		result := tx.Model(&Namespace{}).
+			// NULL and unknown states are read as ready
+			Where("id = ? AND (CASE WHEN state IN (?) THEN state ELSE ? END) = ?", namespace.ID, knownStateValues(), Ready, from).
+			Updates(map[string]interface{}{
+				"tenant_id":    namespace.TenantID,
+				"name":         namespace.Name,
+				"master_url":   namespace.MasterURL,
+				"type":         namespace.Type,
+				"version":      namespace.Version,
+				"updated_by":   namespace.UpdatedBy,
+				"state":        state,
+				"state_reason": namespace.StateReason,
+				"updated_at":   namespace.UpdatedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			conflict = true
+			return tx.Table(namespaceTableName).Where("id = ?", namespace.ID).Find(&current).Error
+		}
+		if from == state {
+			return nil
+		}
+		return tx.Create(NewStateTransition(namespace, from, actor, cause)).Error
+	})
+	if err == nil && conflict {
+		if len(current) == 0 {
+			err = errors.NewNotFoundError("namespace", namespace.ID.String())
+		} else {
+			err = InvalidTransitionError{Namespace: namespace.Name, From: current[0].State, To: state}
+		}
+	}
+	if err != nil {
+		namespace.State, namespace.StateReason, namespace.UpdatedAt = from, fromReason, fromUpdatedAt
+		if _, invalid := err.(InvalidTransitionError); invalid {
+			return err
+		}
+		return errs.Wrapf(err, "unable to change the state of the namespace %s from '%s' to '%s'", namespace.Name, from, state)
+	}
+	return nil
+}
+
+// GetStateTransitions returns the history of the states of all namespaces of the tenant starting from the latest change
+func (r *DBTenantRepository) GetStateTransitions() ([]*StateTransition, error) {
+	var transitions []*StateTransition
+	err := r.db.Table(stateTransitionsTableName).
+		Where("tenant_id = ?", r.tenantID).
+		Order("created_at DESC").
+		Find(&transitions).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to get the history of the namespace states of the tenant %s", r.tenantID)
+	}
+	return transitions, nil
+}
+
+// DeleteNamespace soft-deletes the namespace - the record is kept until it is purged after the retention period
 func (r *DBTenantRepository) DeleteNamespace(namespace *Namespace) error {
+	return r.db.Delete(namespace).Error
+}
+
+// PurgeNamespace permanently removes the entity of the namespace, so it can be stored again with the same ID.
+// The history of its states is kept
+func (r *DBTenantRepository) PurgeNamespace(namespace *Namespace) error {
 	return r.db.Unscoped().Delete(namespace).Error
 }
 
+// DeleteNamespaces soft-deletes all namespaces of the tenant
 func (r *DBTenantRepository) DeleteNamespaces() error {
 	if r.tenantID == uuid.Nil {
 		return nil
 	}
-	return r.db.Unscoped().Delete(&Namespace{}, "tenant_id = ?", r.tenantID).Error
+	return r.db.Delete(&Namespace{}, "tenant_id = ?", r.tenantID).Error
 }
 
+// DeleteTenant soft-deletes the tenant so it can be restored until it is purged after the retention period
 func (r *DBTenantRepository) DeleteTenant() error {
 	if r.tenantID == uuid.Nil {
 		return nil
 	}
-	return r.db.Unscoped().Delete(&Tenant{ID: r.tenantID}).Error
+	return r.db.Delete(&Tenant{ID: r.tenantID}).Error
+}
+
+// GetDeletedTenant returns the tenant if it is soft-deleted
+func (r *DBTenantRepository) GetDeletedTenant() (*Tenant, error) {
+	var t Tenant
+	err := r.db.Unscoped().Table(t.TableName()).Where("id = ? AND deleted_at IS NOT NULL", r.tenantID).Find(&t).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("deleted tenant", r.tenantID.String())
+	} else if err != nil {
+		return nil, errs.Wrapf(err, "unable to lookup deleted tenant by id")
+	}
+	return &t, nil
+}
+
+// GetDeletedNamespaces returns the soft-deleted namespaces of the tenant starting from the latest deleted one
+func (r *DBTenantRepository) GetDeletedNamespaces() ([]*Namespace, error) {
+	var namespaces []*Namespace
+	err := r.db.Unscoped().Table(namespaceTableName).
+		Where("tenant_id = ? AND deleted_at IS NOT NULL", r.tenantID).
+		Order("deleted_at DESC").
+		Find(&namespaces).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to get the deleted namespaces of the tenant %s", r.tenantID)
+	}
+	return namespaces, nil
+}
+
+// RestoreTenant revives the soft-deleted tenant. The deleted namespaces are kept deleted as they were removed from the cluster
+func (r *DBTenantRepository) RestoreTenant() error {
+	result := r.db.Unscoped().Table(tenantTableName).
+		Where("id = ? AND deleted_at IS NOT NULL", r.tenantID).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		return errs.Wrapf(result.Error, "unable to restore the tenant %s", r.tenantID)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("deleted tenant", r.tenantID.String())
+	}
+	return nil
+}
+
+// RecordActivity stores the time of the activity of the user unless a later activity has already been recorded
+func (r *DBTenantRepository) RecordActivity(at time.Time) error {
+	err := r.db.Model(&Tenant{ID: r.tenantID}).
+		UpdateColumn("last_active_at", gorm.Expr("GREATEST(COALESCE(last_active_at, ?), ?)", at, at)).Error
+	if err != nil {
+		return errs.Wrapf(err, "unable to record the activity of the tenant %s", r.tenantID)
+	}
+	return nil
+}
+
+// SuspendTenant marks the tenant as suspended for the given reason. The time of the suspension is kept if the tenant is already suspended
+func (r *DBTenantRepository) SuspendTenant(reason string) error {
+	result := r.db.Model(&Tenant{}).Where("id = ?", r.tenantID).
+		Updates(map[string]interface{}{
+			"suspended_at":      gorm.Expr("COALESCE(suspended_at, ?)", time.Now()),
+			"suspension_reason": reason,
+		})
+	if result.Error != nil {
+		return errs.Wrapf(result.Error, "unable to suspend the tenant %s", r.tenantID)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("tenant", r.tenantID.String())
+	}
+	return nil
+}
+
+// ResumeTenant clears the suspension of the tenant
+func (r *DBTenantRepository) ResumeTenant() error {
+	result := r.db.Model(&Tenant{}).Where("id = ?", r.tenantID).
+		Updates(map[string]interface{}{"suspended_at": nil, "suspension_reason": ""})
+	if result.Error != nil {
+		return errs.Wrapf(result.Error, "unable to resume the tenant %s", r.tenantID)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("tenant", r.tenantID.String())
+	}
+	return nil
+}
+
+// PurgeDeletedTenant removes the soft-deleted tenant together with its namespaces and their history from DB.
+// It does nothing if the tenant isn't soft-deleted
+func (r *DBTenantRepository) PurgeDeletedTenant() error {
+	var deleted []*Tenant
+	err := r.db.Unscoped().Table(tenantTableName).Where("id = ? AND deleted_at IS NOT NULL", r.tenantID).Find(&deleted).Error
+	if err != nil {
+		return errs.Wrapf(err, "unable to lookup deleted tenant by id")
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	err = dbsupport.Transaction(r.db, func(tx *gorm.DB) error {
+		_, err := purgeTenants(tx, []uuid.UUID{r.tenantID})
+		return err
+	})
+	if err != nil {
+		return errs.Wrapf(err, "unable to purge the deleted tenant %s", r.tenantID)
+	}
+	return nil
 }
 
 func ConstructNsBaseName(repo Service, username string) (string, error) {
@@ -4,6 +4,7 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/test/assertion"
 	"sync"
 	"testing"
+	"time"
 
 	"fmt"
 	"github.com/fabric8-services/fabric8-common/errors"
@@ -232,6 +233,26 @@ func (s *TenantServiceTestSuite) TestGetNoClusterToUpdate() {
 	assert.Empty(s.T(), clusters)
 }
 
+func (s *TenantServiceTestSuite) TestCountTenantsPerCluster() {
+	// given
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces())
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().MasterURL("http://cool-cluster.com"))
+	deleted := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().MasterURL("http://cool-cluster.com"))
+	require.NoError(s.T(), tenant.NewTenantRepository(s.DB, deleted.Tenants[0].ID).DeleteTenant())
+	svc := tenant.NewDBService(s.DB)
+
+	// when
+	counts, err := svc.CountTenantsPerCluster()
+
+	// then
+	require.NoError(s.T(), err)
+	perCluster := map[string]int{}
+	for _, count := range counts {
+		perCluster[count.MasterURL] = count.Count
+	}
+	assert.Equal(s.T(), map[string]int{test.Normalize(test.ClusterURL): 3, "http://cool-cluster.com": 2}, perCluster)
+}
+
 func (s *TenantServiceTestSuite) TestGetAllTenantsToUpdateBatchByBatch() {
 	s.T().Run("will need to call GetTenantsToUpdate three times to get all tenants to update", func(t *testing.T) {
 		// given
@@ -280,6 +301,35 @@ func (s *TenantServiceTestSuite) TestGetAllTenantsToUpdateBatchByBatch() {
 	})
 }
 
+func (s *TenantServiceTestSuite) TestGetTenantsToUpdateAfter() {
+	s.T().Run("returns tenants with higher IDs in ascending order", func(t *testing.T) {
+		// given
+		configuration.Commit = "123abc"
+		testdoubles.SetTemplateVersions()
+		tf.FillDB(t, s.DB, tf.AddTenants(7), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+		svc := tenant.NewDBService(s.DB)
+		mappedVersions := testdoubles.GetMappedVersions(environment.DefaultEnvTypes...)
+
+		// when
+		firstBatch, err := svc.GetTenantsToUpdateAfter(mappedVersions, 4, "xyz", "", uuid.Nil)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, firstBatch, 4)
+
+		// and when - without updating the first batch
+		secondBatch, err := svc.GetTenantsToUpdateAfter(mappedVersions, 4, "xyz", "", firstBatch[3].ID)
+
+		// then
+		require.NoError(t, err)
+		assert.Len(t, secondBatch, 3)
+		assertContentOfTenants(t, secondBatch, firstBatch, false)
+		for _, tnnt := range secondBatch {
+			assert.True(t, tnnt.ID.String() > firstBatch[3].ID.String())
+		}
+	})
+}
+
 func updateAllTenants(t *testing.T, toUpdate []*tenant.Tenant, svc tenant.Service, failed bool) {
 	mappedVersions := testdoubles.GetMappedVersions(environment.DefaultEnvTypes...)
 	for _, tnnt := range toUpdate {
@@ -321,7 +371,7 @@ func (s *TenantServiceTestSuite) TestCreateNamespaceInParallel() {
 
 				// when
 				run.Wait()
-				_, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).CreateNamespace(ns)
+				_, err := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).CreateNamespace(ns, tenant.ActorUser)
 
 				require.NoError(s.T(), err)
 			}
@@ -502,6 +552,94 @@ func (s *TenantServiceTestSuite) TestGetNumberOfOutdatedTenantsLimitedToEnvType(
 	assert.Equal(s.T(), 10, count)
 }
 
+func (s *TenantServiceTestSuite) TestCountNotReadyTenants() {
+	// given
+	testdoubles.SetTemplateVersions()
+	ready := tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().State(tenant.Ready))
+	outdated := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	failed := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().State(tenant.Failed))
+	failedChe := tf.FillDB(s.T(), s.DB, tf.AddTenants(1),
+		tf.AddNamespaces(environment.TypeUser).State(tenant.Ready), tf.AddNamespaces(environment.TypeChe).State(tenant.Failed))
+	// the tenant that isn't counted as it wasn't updated
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().State(tenant.Failed))
+	svc := tenant.NewDBService(s.DB)
+	tenantIDs := []uuid.UUID{ready.Tenants[0].ID, ready.Tenants[1].ID, outdated.Tenants[0].ID, failed.Tenants[0].ID, failedChe.Tenants[0].ID}
+
+	s.T().Run("all types", func(t *testing.T) {
+		// when
+		count, err := svc.CountNotReadyTenants(tenantIDs, testdoubles.GetMappedVersions(environment.DefaultEnvTypes...))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	s.T().Run("limited to env type", func(t *testing.T) {
+		// when
+		count, err := svc.CountNotReadyTenants(tenantIDs, testdoubles.GetMappedVersions(environment.TypeUser))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	s.T().Run("no tenant", func(t *testing.T) {
+		// when
+		count, err := svc.CountNotReadyTenants(nil, testdoubles.GetMappedVersions(environment.DefaultEnvTypes...))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+}
+
+func (s *TenantServiceTestSuite) TestMarkStuckNamespaceFailed() {
+	// given
+	svc := tenant.NewDBService(s.DB)
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Updating))
+	err := s.DB.Model(fxt.Namespaces[0]).UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error
+	require.NoError(s.T(), err)
+
+	// when
+	stuck, err := svc.GetStuckNamespaces(time.Now().Add(-30*time.Minute), 1000)
+
+	// then
+	require.NoError(s.T(), err)
+	var found *tenant.Namespace
+	for _, ns := range stuck {
+		assert.Contains(s.T(), []tenant.NamespaceState{tenant.Provisioning, tenant.Updating}, ns.State)
+		if ns.ID == fxt.Namespaces[0].ID {
+			found = ns
+		}
+	}
+	require.NotNil(s.T(), found)
+
+	s.T().Run("namespace is marked as failed", func(t *testing.T) {
+		// when
+		marked, err := svc.MarkStuckNamespaceFailed(found, "interrupted")
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, marked)
+		namespaces, err := svc.NewTenantRepository(fxt.Tenants[0].ID).GetNamespaces()
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Failed, namespaces[0].State)
+		assert.Equal(t, "interrupted", namespaces[0].StateReason)
+	})
+
+	s.T().Run("namespace whose state was changed is not marked", func(t *testing.T) {
+		// given
+		outdated := *fxt.Namespaces[0]
+
+		// when
+		marked, err := svc.MarkStuckNamespaceFailed(&outdated, "interrupted again")
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, marked)
+	})
+}
+
 func (s *TenantServiceTestSuite) TestDeleteNamespaces() {
 	s.T().Run("all info", func(t *testing.T) {
 		// given
@@ -550,6 +688,268 @@ func (s *TenantServiceTestSuite) TestDeleteTenant() {
 	})
 }
 
+func (s *TenantServiceTestSuite) TestRestoreTenant() {
+	s.T().Run("deleted tenant is restored", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		require.NoError(t, repo.DeleteNamespaces())
+		require.NoError(t, repo.DeleteTenant())
+		deleted, err := repo.GetDeletedTenant()
+		require.NoError(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+
+		// when
+		err = repo.RestoreTenant()
+
+		// then
+		require.NoError(t, err)
+		assertion.AssertTenant(t, repo).
+			Exists().
+			HasNoNamespace()
+		deletedNamespaces, err := repo.GetDeletedNamespaces()
+		require.NoError(t, err)
+		assert.Len(t, deletedNamespaces, len(environment.DefaultEnvTypes))
+	})
+
+	s.T().Run("tenant that is not deleted cannot be restored", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+
+		// when
+		err := repo.RestoreTenant()
+
+		// then
+		test.AssertError(t, err, test.IsOfType(errors.NotFoundError{}))
+		_, err = repo.GetDeletedTenant()
+		test.AssertError(t, err, test.IsOfType(errors.NotFoundError{}))
+	})
+}
+
+func (s *TenantServiceTestSuite) TestPurgeDeleted() {
+	// given
+	svc := tenant.NewDBService(s.DB)
+	old := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	recent := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	cleaned := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	for _, fxt := range []*tf.TestFixture{old, recent} {
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		require.NoError(s.T(), repo.DeleteNamespaces())
+		require.NoError(s.T(), repo.DeleteTenant())
+	}
+	require.NoError(s.T(), tenant.NewTenantRepository(s.DB, cleaned.Tenants[0].ID).DeleteNamespace(cleaned.Namespaces[0]))
+	s.makeDeletedBefore(2*time.Hour, old.Tenants, old.Namespaces)
+	s.makeDeletedBefore(2*time.Hour, nil, cleaned.Namespaces[:1])
+
+	// when
+	purged, err := svc.PurgeDeleted(time.Now().Add(-time.Hour), 100)
+
+	// then
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, purged.Tenants)
+	assert.Equal(s.T(), len(environment.DefaultEnvTypes)+1, purged.Namespaces)
+	var count int
+	require.NoError(s.T(), s.DB.Unscoped().Table("tenants").Where("id = ?", old.Tenants[0].ID).Count(&count).Error)
+	assert.Equal(s.T(), 0, count)
+	require.NoError(s.T(), s.DB.Unscoped().Table("namespaces").Where("tenant_id = ?", old.Tenants[0].ID).Count(&count).Error)
+	assert.Equal(s.T(), 0, count)
+	// the recently deleted tenant can still be restored
+	_, err = tenant.NewTenantRepository(s.DB, recent.Tenants[0].ID).GetDeletedTenant()
+	require.NoError(s.T(), err)
+	assertion.AssertTenant(s.T(), tenant.NewTenantRepository(s.DB, cleaned.Tenants[0].ID)).
+		Exists().
+		HasNumberOfNamespaces(len(environment.DefaultEnvTypes) - 1)
+}
+
+func (s *TenantServiceTestSuite) TestPurgeDeletedTenant() {
+	// given
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+	require.NoError(s.T(), repo.DeleteNamespaces())
+	require.NoError(s.T(), repo.DeleteTenant())
+
+	// when
+	err := repo.PurgeDeletedTenant()
+
+	// then
+	require.NoError(s.T(), err)
+	_, err = repo.GetDeletedTenant()
+	test.AssertError(s.T(), err, test.IsOfType(errors.NotFoundError{}))
+	exists, err := repo.ExistsWithNsBaseName(fxt.Tenants[0].NsBaseName)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), exists)
+}
+
+func (s *TenantServiceTestSuite) TestRecordActivity() {
+	s.T().Run("activity is recorded", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		activeAt := time.Now().Add(-time.Hour)
+
+		// when
+		err := repo.RecordActivity(activeAt)
+
+		// then
+		require.NoError(t, err)
+		tnnt, err := repo.GetTenant()
+		require.NoError(t, err)
+		require.NotNil(t, tnnt.LastActiveAt)
+		assert.WithinDuration(t, activeAt, *tnnt.LastActiveAt, time.Millisecond)
+	})
+
+	s.T().Run("older activity does not override the latest one", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		activeAt := time.Now()
+		require.NoError(t, repo.RecordActivity(activeAt))
+
+		// when
+		err := repo.RecordActivity(activeAt.Add(-24 * time.Hour))
+
+		// then
+		require.NoError(t, err)
+		tnnt, err := repo.GetTenant()
+		require.NoError(t, err)
+		require.NotNil(t, tnnt.LastActiveAt)
+		assert.WithinDuration(t, activeAt, *tnnt.LastActiveAt, time.Millisecond)
+	})
+}
+
+func (s *TenantServiceTestSuite) TestGetNamespacesToIdle() {
+	// given
+	inactive := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	inactiveFailed := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Failed))
+	neverActive := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	active := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	for _, fxt := range []*tf.TestFixture{inactive, inactiveFailed} {
+		require.NoError(s.T(), tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID).RecordActivity(time.Now().Add(-48*time.Hour)))
+	}
+	require.NoError(s.T(), tenant.NewTenantRepository(s.DB, active.Tenants[0].ID).RecordActivity(time.Now()))
+	err := s.DB.Table("tenants").Where("id = ?", neverActive.Tenants[0].ID).
+		UpdateColumn("created_at", time.Now().Add(-72*time.Hour)).Error
+	require.NoError(s.T(), err)
+	svc := tenant.NewDBService(s.DB)
+
+	// when
+	namespaces, err := svc.GetNamespacesToIdle(time.Now().Add(-24*time.Hour), 100)
+
+	// then
+	require.NoError(s.T(), err)
+	var tenantIDs []uuid.UUID
+	for _, ns := range namespaces {
+		assert.Equal(s.T(), tenant.Ready, ns.State)
+		tenantIDs = append(tenantIDs, ns.TenantID)
+	}
+	assert.Len(s.T(), namespaces, 2*len(environment.DefaultEnvTypes))
+	assert.Contains(s.T(), tenantIDs, inactive.Tenants[0].ID)
+	assert.Contains(s.T(), tenantIDs, neverActive.Tenants[0].ID)
+	assert.NotContains(s.T(), tenantIDs, inactiveFailed.Tenants[0].ID)
+	assert.NotContains(s.T(), tenantIDs, active.Tenants[0].ID)
+	// the least active tenant goes first
+	assert.Equal(s.T(), neverActive.Tenants[0].ID, namespaces[0].TenantID)
+}
+
+func (s *TenantServiceTestSuite) TestSuspendTenant() {
+	s.T().Run("tenant is suspended", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+
+		// when
+		err := repo.SuspendTenant("unpaid subscription")
+
+		// then
+		require.NoError(t, err)
+		tnnt, err := repo.GetTenant()
+		require.NoError(t, err)
+		assert.True(t, tnnt.IsSuspended())
+		assert.Equal(t, "unpaid subscription", tnnt.SuspensionReason)
+	})
+
+	s.T().Run("time of the suspension is kept when suspended again", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		require.NoError(t, repo.SuspendTenant("unpaid subscription"))
+		suspended, err := repo.GetTenant()
+		require.NoError(t, err)
+
+		// when
+		err = repo.SuspendTenant("banned")
+
+		// then
+		require.NoError(t, err)
+		tnnt, err := repo.GetTenant()
+		require.NoError(t, err)
+		require.NotNil(t, tnnt.SuspendedAt)
+		assert.WithinDuration(t, *suspended.SuspendedAt, *tnnt.SuspendedAt, time.Millisecond)
+		assert.Equal(t, "banned", tnnt.SuspensionReason)
+	})
+
+	s.T().Run("fails when the tenant does not exist", func(t *testing.T) {
+		// when
+		err := tenant.NewTenantRepository(s.DB, uuid.NewV4()).SuspendTenant("banned")
+
+		// then
+		test.AssertError(t, err, test.IsOfType(errors.NotFoundError{}))
+	})
+}
+
+func (s *TenantServiceTestSuite) TestResumeTenant() {
+	// given
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1))
+	repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+	require.NoError(s.T(), repo.SuspendTenant("banned"))
+
+	// when
+	err := repo.ResumeTenant()
+
+	// then
+	require.NoError(s.T(), err)
+	tnnt, err := repo.GetTenant()
+	require.NoError(s.T(), err)
+	assert.False(s.T(), tnnt.IsSuspended())
+	assert.Nil(s.T(), tnnt.SuspendedAt)
+	assert.Empty(s.T(), tnnt.SuspensionReason)
+}
+
+func (s *TenantServiceTestSuite) TestSuspendedTenantsAreNeitherUpdatedNorIdled() {
+	// given
+	configuration.Commit = "123abc"
+	testdoubles.SetTemplateVersions()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+	require.NoError(s.T(), repo.RecordActivity(time.Now().Add(-48*time.Hour)))
+	require.NoError(s.T(), repo.SuspendTenant("banned"))
+	svc := tenant.NewDBService(s.DB)
+
+	// when
+	toUpdate, err := svc.GetTenantsToUpdate(testdoubles.GetMappedVersions(environment.DefaultEnvTypes...), 10, "xyz", "")
+	require.NoError(s.T(), err)
+	toIdle, err := svc.GetNamespacesToIdle(time.Now().Add(-24*time.Hour), 100)
+	require.NoError(s.T(), err)
+
+	// then
+	assert.Empty(s.T(), toUpdate)
+	assert.Empty(s.T(), toIdle)
+}
+
+// makeDeletedBefore moves the time of the deletion of the tenant and namespaces to the past
+func (s *TenantServiceTestSuite) makeDeletedBefore(before time.Duration, tenants []*tenant.Tenant, namespaces []*tenant.Namespace) {
+	deletedAt := time.Now().Add(-before)
+	for _, tnnt := range tenants {
+		err := s.DB.Unscoped().Table("tenants").Where("id = ?", tnnt.ID).UpdateColumn("deleted_at", deletedAt).Error
+		require.NoError(s.T(), err)
+	}
+	for _, ns := range namespaces {
+		err := s.DB.Unscoped().Table("namespaces").Where("id = ?", ns.ID).UpdateColumn("deleted_at", deletedAt).Error
+		require.NoError(s.T(), err)
+	}
+}
+
 func (s *TenantServiceTestSuite) TestNsBaseNameConstruction() {
 
 	s.T().Run("is first tenant", func(t *testing.T) {
@@ -594,6 +994,20 @@ func (s *TenantServiceTestSuite) TestNsBaseNameConstruction() {
 		assert.Equal(t, "johny10", nsBaseName)
 	})
 
+	s.T().Run("name of the deleted tenant is not reused", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddSpecificTenants(tf.SingleWithName("deletedjohny")), tf.AddDefaultNamespaces())
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		require.NoError(t, repo.DeleteNamespaces())
+		require.NoError(t, repo.DeleteTenant())
+		svc := tenant.NewDBService(s.DB)
+		// when
+		nsBaseName, err := tenant.ConstructNsBaseName(svc, "deletedjohny")
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, "deletedjohny2", nsBaseName)
+	})
+
 	s.T().Run("repo returns a failure while getting tenants", func(t *testing.T) {
 		// given
 		svc := serviceWithFailures{
@@ -0,0 +1,218 @@
+package tenant
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+// the columns the tenants can be sorted by - a minus sign prefix means the descending order. The nullable columns
+// are coalesced to an empty string, so the tenants without the value are sorted and compared with the cursor the same way
+var sortColumns = map[string]string{
+	"created-at": "tenants.created_at",
+	"updated-at": "tenants.updated_at",
+	"email":      "COALESCE(tenants.email, '')",
+	"username":   "COALESCE(tenants.os_username, '')",
+}
+
+// TenantsQuery filters and sorts the tenants. The namespace filters (cluster, namespace, type, state and version)
+// match the tenants that have at least one namespace satisfying all of them. Empty values don't filter anything.
+type TenantsQuery struct {
+	MasterURL      string
+	Namespace      string
+	EnvType        environment.Type
+	State          NamespaceState
+	Version        string
+	Profile        string
+	EmailPrefix    string
+	UsernamePrefix string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	UpdatedAfter   *time.Time
+	UpdatedBefore  *time.Time
+	// Sort is one of created-at, updated-at, email and username optionally prefixed by minus sign for the descending order
+	Sort string
+	// After is the cursor returned as the Next value of the previous page
+	After string
+	Limit int
+}
+
+// TenantsPage is one page of the tenants matching the query together with their namespaces
+type TenantsPage struct {
+	Tenants    []*Tenant
+	Namespaces map[uuid.UUID][]*Namespace
+	TotalCount int
+	// Next is the cursor of the next page - it is empty if this is the last page
+	Next string
+}
+
+// cursor is the position of the last tenant of the page in the sorted list of tenants
+type cursor struct {
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// SearchTenants returns the page of the tenants matching the query. The pages are paginated using the keyset of the sorted
+// column and the ID of the tenant, so the tenants that are created or removed in the meantime don't shift the next pages.
+func (s *DBService) SearchTenants(query *TenantsQuery) (*TenantsPage, error) {
+	sort := query.Sort
+	if sort == "" {
+		sort = "created-at"
+	}
+	descending := strings.HasPrefix(sort, "-")
+	column, ok := sortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, errors.NewBadParameterError("sort", query.Sort)
+	}
+
+	filtered := s.newSearchTenantsQuery(query)
+	page := &TenantsPage{Namespaces: map[uuid.UUID][]*Namespace{}}
+	if err := filtered.Count(&page.TotalCount).Error; err != nil {
+		return nil, errs.Wrap(err, "unable to count the tenants")
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+	if query.After != "" {
+		after, err := decodeCursor(query.After)
+		if err != nil {
+			return nil, errors.NewBadParameterError("page[after]", query.After)
+		}
+		value, err := after.value(column)
+		if err != nil {
+			return nil, errors.NewBadParameterError("page[after]", query.After)
+		}
+		filtered = filtered.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND tenants.id %[2]s ?))", column, comparison),
+			value, value, after.ID)
+	}
+
+	// one more tenant is fetched to find out if there is a next page
+	var tenants []*Tenant
+	err := filtered.Order(fmt.Sprintf("%s %s, tenants.id %s", column, direction, direction)).
+		Limit(query.Limit + 1).
+		Find(&tenants).Error
+	if err != nil {
+		return nil, errs.Wrap(err, "unable to search the tenants")
+	}
+	if len(tenants) > query.Limit {
+		tenants = tenants[:query.Limit]
+		page.Next = encodeCursor(tenants[len(tenants)-1], column)
+	}
+	page.Tenants = tenants
+
+	if len(tenants) > 0 {
+		var ids []uuid.UUID
+		for _, tnnt := range tenants {
+			ids = append(ids, tnnt.ID)
+		}
+		var namespaces []*Namespace
+		err := s.db.Table(namespaceTableName).Where("tenant_id IN (?) AND deleted_at IS NULL", ids).Find(&namespaces).Error
+		if err != nil {
+			return nil, errs.Wrap(err, "unable to get the namespaces of the found tenants")
+		}
+		for _, ns := range namespaces {
+			page.Namespaces[ns.TenantID] = append(page.Namespaces[ns.TenantID], ns)
+		}
+	}
+	return page, nil
+}
+
+func (s *DBService) newSearchTenantsQuery(query *TenantsQuery) *gorm.DB {
+	db := s.db.Table(tenantTableName).Where("tenants.deleted_at IS NULL")
+	if query.Profile != "" {
+		db = db.Where("tenants.profile = ?", query.Profile)
+	}
+	if query.EmailPrefix != "" {
+		db = db.Where("tenants.email ILIKE ?", escapeLike(query.EmailPrefix)+"%")
+	}
+	if query.UsernamePrefix != "" {
+		db = db.Where("tenants.os_username LIKE ?", escapeLike(query.UsernamePrefix)+"%")
+	}
+	if query.CreatedAfter != nil {
+		db = db.Where("tenants.created_at >= ?", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		db = db.Where("tenants.created_at < ?", *query.CreatedBefore)
+	}
+	if query.UpdatedAfter != nil {
+		db = db.Where("tenants.updated_at >= ?", *query.UpdatedAfter)
+	}
+	if query.UpdatedBefore != nil {
+		db = db.Where("tenants.updated_at < ?", *query.UpdatedBefore)
+	}
+
+	conditions := []string{"n.tenant_id = tenants.id", "n.deleted_at IS NULL"}
+	var params []interface{}
+	addCondition := func(condition string, value interface{}) {
+		conditions = append(conditions, condition)
+		params = append(params, value)
+	}
+	if query.MasterURL != "" {
+		addCondition("n.master_url = ?", query.MasterURL)
+	}
+	if query.Namespace != "" {
+		addCondition("n.name = ?", query.Namespace)
+	}
+	if query.EnvType != "" {
+		addCondition("n.type = ?", query.EnvType)
+	}
+	if query.State != "" {
+		addCondition("n.state = ?", query.State)
+	}
+	if query.Version != "" {
+		addCondition("n.version = ?", query.Version)
+	}
+	if len(params) > 0 {
+		db = db.Where(fmt.Sprintf("EXISTS (SELECT 1 FROM %s n WHERE %s)", namespaceTableName, strings.Join(conditions, " AND ")), params...)
+	}
+	return db
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func encodeCursor(last *Tenant, column string) string {
+	position := cursor{ID: last.ID}
+	switch column {
+	case "tenants.created_at":
+		position.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case "tenants.updated_at":
+		position.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	case sortColumns["email"]:
+		position.Value = last.Email
+	case sortColumns["username"]:
+		position.Value = last.OSUsername
+	}
+	encoded, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	position := &cursor{}
+	if err := json.Unmarshal(decoded, position); err != nil {
+		return nil, err
+	}
+	return position, nil
+}
+
+// value returns the value of the sorted column the page ends with
+func (c *cursor) value(column string) (interface{}, error) {
+	if column == "tenants.created_at" || column == "tenants.updated_at" {
+		return time.Parse(time.RFC3339Nano, c.Value)
+	}
+	return c.Value, nil
+}
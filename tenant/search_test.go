@@ -0,0 +1,167 @@
+package tenant_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func (s *TenantServiceTestSuite) TestSearchTenants() {
+	// given
+	svc := tenant.NewDBService(s.DB)
+	clusterURL := "http://api." + uuid.NewV4().String() + ".example.com/"
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddSpecificTenants(
+		withEmail("alice@redhat.com"), withEmail("bob@redhat.com"), withEmail("carol@example.com")),
+		tf.AddNamespaces(environment.TypeUser).MasterURL(clusterURL),
+		tf.AddNamespaces(environment.TypeChe).MasterURL(clusterURL))
+	failed := fxt.Tenants[1]
+	require.NoError(s.T(), s.DB.Table("namespaces").Where("tenant_id = ? AND type = ?", failed.ID, environment.TypeChe).
+		UpdateColumn("state", tenant.Failed).Error)
+
+	s.T().Run("filters by the state of the namespace in the cluster", func(t *testing.T) {
+		// when
+		page, err := svc.SearchTenants(&tenant.TenantsQuery{MasterURL: clusterURL, State: tenant.Failed, Limit: 10})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, page.Tenants, 1)
+		assert.Equal(t, failed.ID, page.Tenants[0].ID)
+		assert.Equal(t, 1, page.TotalCount)
+		assert.Len(t, page.Namespaces[failed.ID], 2)
+		assert.Empty(t, page.Next)
+	})
+
+	s.T().Run("filters by the type and state of the same namespace", func(t *testing.T) {
+		// when
+		page, err := svc.SearchTenants(&tenant.TenantsQuery{MasterURL: clusterURL, EnvType: environment.TypeUser, State: tenant.Failed, Limit: 10})
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, page.Tenants)
+		assert.Equal(t, 0, page.TotalCount)
+	})
+
+	s.T().Run("filters by the email prefix case insensitively", func(t *testing.T) {
+		// when
+		page, err := svc.SearchTenants(&tenant.TenantsQuery{MasterURL: clusterURL, EmailPrefix: "CAROL@", Limit: 10})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, page.Tenants, 1)
+		assert.Equal(t, "carol@example.com", page.Tenants[0].Email)
+	})
+
+	s.T().Run("filters by the creation time", func(t *testing.T) {
+		// given
+		future := time.Now().Add(time.Hour)
+
+		// when
+		page, err := svc.SearchTenants(&tenant.TenantsQuery{MasterURL: clusterURL, CreatedAfter: &future, Limit: 10})
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, page.Tenants)
+	})
+
+	s.T().Run("pages through the sorted tenants", func(t *testing.T) {
+		// given
+		query := &tenant.TenantsQuery{MasterURL: clusterURL, Sort: "-email", Limit: 2}
+
+		// when
+		first, err := svc.SearchTenants(query)
+		require.NoError(t, err)
+		query.After = first.Next
+		second, err := svc.SearchTenants(query)
+		require.NoError(t, err)
+
+		// then
+		require.Len(t, first.Tenants, 2)
+		assert.Equal(t, "carol@example.com", first.Tenants[0].Email)
+		assert.Equal(t, "bob@redhat.com", first.Tenants[1].Email)
+		assert.Equal(t, 3, first.TotalCount)
+		assert.NotEmpty(t, first.Next)
+		require.Len(t, second.Tenants, 1)
+		assert.Equal(t, "alice@redhat.com", second.Tenants[0].Email)
+		assert.Equal(t, 3, second.TotalCount)
+		assert.Empty(t, second.Next)
+	})
+
+	s.T().Run("pages through the tenants without email", func(t *testing.T) {
+		// given
+		withoutEmail := tf.FillDB(t, s.DB, tf.AddSpecificTenants(withEmail("dave@redhat.com"), withEmail("erin@redhat.com")),
+			tf.AddNamespaces(environment.TypeUser).MasterURL(clusterURL))
+		for _, tnnt := range withoutEmail.Tenants {
+			require.NoError(t, s.DB.Table("tenants").Where("id = ?", tnnt.ID).UpdateColumn("email", gorm.Expr("NULL")).Error)
+		}
+		defer func() {
+			for _, tnnt := range withoutEmail.Tenants {
+				require.NoError(t, tenant.NewTenantRepository(s.DB, tnnt.ID).DeleteTenant())
+			}
+		}()
+		var emails []string
+
+		// when
+		for _, sort := range []string{"email", "-email"} {
+			query := &tenant.TenantsQuery{MasterURL: clusterURL, Sort: sort, Limit: 1}
+			for {
+				page, err := svc.SearchTenants(query)
+				require.NoError(t, err)
+				require.Len(t, page.Tenants, 1)
+				emails = append(emails, page.Tenants[0].Email)
+				if page.Next == "" {
+					break
+				}
+				query.After = page.Next
+			}
+		}
+
+		// then
+		assert.Equal(t, []string{"", "", "alice@redhat.com", "bob@redhat.com", "carol@example.com",
+			"carol@example.com", "bob@redhat.com", "alice@redhat.com", "", ""}, emails)
+	})
+
+	s.T().Run("pages through the tenants sorted by creation time", func(t *testing.T) {
+		// given
+		query := &tenant.TenantsQuery{MasterURL: clusterURL, Limit: 1}
+		var emails []string
+
+		// when
+		for i := 0; i < 3; i++ {
+			page, err := svc.SearchTenants(query)
+			require.NoError(t, err)
+			require.Len(t, page.Tenants, 1)
+			emails = append(emails, page.Tenants[0].Email)
+			query.After = page.Next
+		}
+
+		// then
+		assert.Equal(t, []string{"alice@redhat.com", "bob@redhat.com", "carol@example.com"}, emails)
+		assert.Empty(t, query.After)
+	})
+
+	s.T().Run("fails for wrong sort and cursor", func(t *testing.T) {
+		// when
+		_, sortErr := svc.SearchTenants(&tenant.TenantsQuery{Sort: "profile", Limit: 10})
+		_, cursorErr := svc.SearchTenants(&tenant.TenantsQuery{After: "not-a-cursor", Limit: 10})
+
+		// then
+		assert.IsType(t, errors.BadParameterError{}, sortErr)
+		assert.IsType(t, errors.BadParameterError{}, cursorErr)
+	})
+}
+
+func withEmail(email string) tf.TenantModifier {
+	return func(tnnt *tenant.Tenant) {
+		tnnt.Email = email
+		tnnt.OSUsername = fmt.Sprintf("user-%s", uuid.NewV4().String()[:8])
+		tnnt.NsBaseName = tnnt.OSUsername
+	}
+}
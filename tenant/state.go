@@ -0,0 +1,122 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/satori/go.uuid"
+	"time"
+)
+
+const stateTransitionsTableName = "namespace_state_transitions"
+
+// Actor says who caused the change of the state of a namespace
+type Actor string
+
+const (
+	// ActorUser is the user performing an operation on their own tenant
+	ActorUser Actor = "user"
+	// ActorUpdater is the automated update of the tenants
+	ActorUpdater Actor = "updater"
+	// ActorAdmin is a service account managing the tenants via the admin endpoints
+	ActorAdmin Actor = "admin"
+	// ActorJanitor is the janitor repairing the stuck namespaces
+	ActorJanitor Actor = "janitor"
+	// ActorIdler is the idler scaling down the namespaces of the inactive tenants
+	ActorIdler Actor = "idler"
+)
+
+type actorKey struct{}
+
+// WithActor returns a copy of the context carrying the actor of the operations performed within the context
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context - the user if there is none
+func ActorFromContext(ctx context.Context) Actor {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+			return actor
+		}
+	}
+	return ActorUser
+}
+
+// noState is the state of the namespace before it is stored for the first time
+const noState NamespaceState = ""
+
+var allowedTransitions = map[NamespaceState][]NamespaceState{
+	noState:      {Provisioning},
+	Provisioning: {Ready, Failed, Deleting},
+	Ready:        {Updating, Deleting, Idled},
+	Updating:     {Ready, Failed, Deleting},
+	Failed:       {Updating, Deleting},
+	Deleting:     {Ready, Failed},
+	Idled:        {Ready, Updating, Deleting},
+}
+
+// CanTransition says if the namespace in the "from" state can be moved to the "to" state. Staying in the same state is always allowed
+func CanTransition(from, to NamespaceState) bool {
+	if from == to {
+		return true
+	}
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidTransitionError is returned when the requested change of the state of the namespace isn't allowed
+type InvalidTransitionError struct {
+	Namespace string
+	From      NamespaceState
+	To        NamespaceState
+}
+
+func (e InvalidTransitionError) Error() string {
+	return fmt.Sprintf("the namespace %s cannot be moved from the state '%s' to '%s'", e.Namespace, e.From, e.To)
+}
+
+// StateTransition is a record of the change of the state of a namespace. The records are kept even when the namespace is removed
+type StateTransition struct {
+	ID          uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"`
+	NamespaceID uuid.UUID `sql:"type:uuid"`
+	TenantID    uuid.UUID `sql:"type:uuid"`
+	Name        string
+	Type        environment.Type
+	FromState   NamespaceState
+	ToState     NamespaceState
+	Actor       Actor
+	Error       string
+	CreatedAt   time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (t StateTransition) TableName() string {
+	return stateTransitionsTableName
+}
+
+// NewStateTransition creates a record of the change of the namespace from the given state to its current one
+func NewStateTransition(namespace *Namespace, from NamespaceState, actor Actor, cause error) *StateTransition {
+	transition := &StateTransition{
+		ID:          uuid.NewV4(),
+		NamespaceID: namespace.ID,
+		TenantID:    namespace.TenantID,
+		Name:        namespace.Name,
+		Type:        namespace.Type,
+		FromState:   from,
+		ToState:     namespace.State,
+		Actor:       actor,
+	}
+	if cause != nil {
+		transition.Error = cause.Error()
+	}
+	return transition
+}
@@ -0,0 +1,204 @@
+package tenant_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	allowed := map[tenant.NamespaceState][]tenant.NamespaceState{
+		tenant.Provisioning: {tenant.Ready, tenant.Failed, tenant.Deleting},
+		tenant.Ready:        {tenant.Updating, tenant.Deleting, tenant.Idled},
+		tenant.Updating:     {tenant.Ready, tenant.Failed, tenant.Deleting},
+		tenant.Failed:       {tenant.Updating, tenant.Deleting},
+		tenant.Deleting:     {tenant.Ready, tenant.Failed},
+		tenant.Idled:        {tenant.Ready, tenant.Updating, tenant.Deleting},
+	}
+	rejected := map[tenant.NamespaceState][]tenant.NamespaceState{
+		tenant.Provisioning: {tenant.Updating},
+		tenant.Ready:        {tenant.Provisioning, tenant.Failed},
+		tenant.Updating:     {tenant.Provisioning, tenant.Idled},
+		tenant.Failed:       {tenant.Provisioning, tenant.Ready, tenant.Idled},
+		tenant.Deleting:     {tenant.Provisioning, tenant.Updating},
+		tenant.Idled:        {tenant.Provisioning, tenant.Failed},
+	}
+
+	for from, states := range allowed {
+		for _, to := range states {
+			assert.True(t, tenant.CanTransition(from, to), "%s -> %s should be allowed", from, to)
+		}
+		assert.True(t, tenant.CanTransition(from, from), "%s -> %s should be allowed", from, from)
+	}
+	for from, states := range rejected {
+		for _, to := range states {
+			assert.False(t, tenant.CanTransition(from, to), "%s -> %s should be rejected", from, to)
+		}
+	}
+}
+
+func TestNamespaceStateScan(t *testing.T) {
+	t.Run("known state", func(t *testing.T) {
+		// given
+		var state tenant.NamespaceState
+
+		// when
+		err := state.Scan([]byte("deleting"))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Deleting, state)
+	})
+
+	t.Run("unknown state is read as ready", func(t *testing.T) {
+		// given
+		var state tenant.NamespaceState
+
+		// when
+		err := state.Scan([]byte("archived"))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Ready, state)
+	})
+
+	t.Run("null state is read as ready", func(t *testing.T) {
+		// given
+		var state tenant.NamespaceState
+
+		// when
+		err := state.Scan(nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Ready, state)
+	})
+}
+
+func TestActorFromContext(t *testing.T) {
+	assert.Equal(t, tenant.ActorUser, tenant.ActorFromContext(context.Background()))
+	assert.Equal(t, tenant.ActorAdmin, tenant.ActorFromContext(tenant.WithActor(nil, tenant.ActorAdmin)))
+}
+
+func (s *TenantServiceTestSuite) TestChangeNamespaceState() {
+	s.T().Run("valid transitions are recorded in the history", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Ready))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		namespace := fxt.Namespaces[0]
+
+		// when
+		require.NoError(t, repo.ChangeNamespaceState(namespace, tenant.Updating, tenant.ActorUpdater, nil))
+		require.NoError(t, repo.ChangeNamespaceState(namespace, tenant.Failed, tenant.ActorUpdater, fmt.Errorf("quota exceeded")))
+
+		// then
+		namespaces, err := repo.GetNamespaces()
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Failed, namespaces[0].State)
+		assert.Equal(t, "quota exceeded", namespaces[0].StateReason)
+
+		transitions, err := repo.GetStateTransitions()
+		require.NoError(t, err)
+		require.Len(t, transitions, 2)
+		assert.Equal(t, tenant.Updating, transitions[0].FromState)
+		assert.Equal(t, tenant.Failed, transitions[0].ToState)
+		assert.Equal(t, tenant.ActorUpdater, transitions[0].Actor)
+		assert.Equal(t, "quota exceeded", transitions[0].Error)
+		assert.Equal(t, namespace.ID, transitions[0].NamespaceID)
+		assert.Equal(t, tenant.Ready, transitions[1].FromState)
+		assert.Equal(t, tenant.Updating, transitions[1].ToState)
+		assert.Empty(t, transitions[1].Error)
+	})
+
+	s.T().Run("invalid transition is rejected", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Ready))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+
+		// when
+		err := repo.ChangeNamespaceState(fxt.Namespaces[0], tenant.Provisioning, tenant.ActorAdmin, nil)
+
+		// then
+		require.Error(t, err)
+		assert.IsType(t, tenant.InvalidTransitionError{}, err)
+		namespaces, err := repo.GetNamespaces()
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Ready, namespaces[0].State)
+		transitions, err := repo.GetStateTransitions()
+		require.NoError(t, err)
+		assert.Empty(t, transitions)
+	})
+
+	s.T().Run("change of stale state is rejected", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Ready))
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		stale := *fxt.Namespaces[0]
+		require.NoError(t, repo.ChangeNamespaceState(fxt.Namespaces[0], tenant.Updating, tenant.ActorUpdater, nil))
+
+		// when
+		err := repo.ChangeNamespaceState(&stale, tenant.Idled, tenant.ActorIdler, nil)
+
+		// then
+		require.Error(t, err)
+		require.IsType(t, tenant.InvalidTransitionError{}, err)
+		assert.Equal(t, tenant.Updating, err.(tenant.InvalidTransitionError).From)
+		assert.Equal(t, tenant.Ready, stale.State)
+		namespaces, err := repo.GetNamespaces()
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Updating, namespaces[0].State)
+		transitions, err := repo.GetStateTransitions()
+		require.NoError(t, err)
+		require.Len(t, transitions, 1)
+		assert.Equal(t, tenant.Updating, transitions[0].ToState)
+	})
+
+	s.T().Run("state stored as null is changed as ready", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Ready))
+		err := s.DB.Table("namespaces").Where("id = ?", fxt.Namespaces[0].ID).UpdateColumn("state", gorm.Expr("NULL")).Error
+		require.NoError(t, err)
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		namespaces, err := repo.GetNamespaces()
+		require.NoError(t, err)
+		require.Equal(t, tenant.Ready, namespaces[0].State)
+
+		// when
+		err = repo.ChangeNamespaceState(namespaces[0], tenant.Updating, tenant.ActorUpdater, nil)
+
+		// then
+		require.NoError(t, err)
+		namespaces, err = repo.GetNamespaces()
+		require.NoError(t, err)
+		assert.Equal(t, tenant.Updating, namespaces[0].State)
+	})
+
+	s.T().Run("history is kept when the namespace is removed", func(t *testing.T) {
+		// given
+		fxt := tf.FillDB(t, s.DB, tf.AddTenants(1), tf.AddNamespaces())
+		repo := tenant.NewTenantRepository(s.DB, fxt.Tenants[0].ID)
+		namespace := repo.NewNamespace(environment.TypeUser, "johny", "http://my.cluster", tenant.Provisioning)
+		_, err := repo.CreateNamespace(namespace, tenant.ActorUser)
+		require.NoError(t, err)
+		require.NoError(t, repo.ChangeNamespaceState(namespace, tenant.Deleting, tenant.ActorAdmin, nil))
+
+		// when
+		require.NoError(t, repo.DeleteNamespace(namespace))
+
+		// then
+		transitions, err := repo.GetStateTransitions()
+		require.NoError(t, err)
+		require.Len(t, transitions, 2)
+		assert.Equal(t, tenant.Deleting, transitions[0].ToState)
+		assert.Equal(t, tenant.ActorAdmin, transitions[0].Actor)
+		assert.Empty(t, transitions[1].FromState)
+		assert.Equal(t, tenant.Provisioning, transitions[1].ToState)
+		assert.Equal(t, tenant.ActorUser, transitions[1].Actor)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"database/sql/driver"
+	"github.com/fabric8-services/fabric8-common/log"
 	"github.com/fabric8-services/fabric8-tenant/cluster"
 	"github.com/fabric8-services/fabric8-tenant/configuration"
 	"github.com/fabric8-services/fabric8-tenant/environment"
@@ -25,6 +26,13 @@ type Tenant struct {
 	Profile    string
 	OSUsername string
 	NsBaseName string
+	// LastActiveAt is the time of the last known activity of the user - either an access to the tenant API
+	// or the activity reported by auth. Used for idling of the inactive tenants
+	LastActiveAt *time.Time
+	// SuspendedAt is set when the tenant is suspended - the user has no access to the namespaces and the namespaces
+	// cannot be provisioned or updated until the tenant is resumed
+	SuspendedAt      *time.Time
+	SuspensionReason string
 }
 
 // TableName overrides the table name settings in Gorm to force a specific table name
@@ -33,6 +41,11 @@ func (m Tenant) TableName() string {
 	return tenantTableName
 }
 
+// IsSuspended says if the tenant is suspended
+func (m Tenant) IsSuspended() bool {
+	return m.SuspendedAt != nil
+}
+
 // Namespace represent a single namespace owned by an Tenant
 type Namespace struct {
 	ID        uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"`
@@ -45,7 +58,9 @@ type Namespace struct {
 	Type      environment.Type
 	Version   string
 	State     NamespaceState
-	UpdatedBy string
+	// StateReason explains why the namespace ended up in the current state - set only when it isn't obvious
+	StateReason string
+	UpdatedBy   string
 }
 
 func ConstructNamespaceName(envType environment.Type, nsBaseName string) string {
@@ -62,11 +77,12 @@ func (m Namespace) TableName() string {
 	return namespaceTableName
 }
 
-func (n *Namespace) UpdateData(env *environment.EnvData, cluster *cluster.Cluster, state NamespaceState) {
+// UpdateData sets the data of the environment the namespace was provisioned or updated with. The state is changed
+// separately via Repository.ChangeNamespaceState so the transition is validated and recorded
+func (n *Namespace) UpdateData(env *environment.EnvData, cluster *cluster.Cluster) {
 	if n.Name == "" {
 		n.Name = string(env.EnvType)
 	}
-	n.State = state
 	n.Version = env.Version()
 	n.MasterURL = cluster.APIURL
 	n.Type = env.EnvType
@@ -80,8 +96,24 @@ const (
 	Updating     NamespaceState = "updating"
 	Ready        NamespaceState = "ready"
 	Failed       NamespaceState = "failed"
+	Deleting     NamespaceState = "deleting"
+	Idled        NamespaceState = "idled"
 )
 
+var knownStates = map[NamespaceState]bool{
+	noState: true, Provisioning: true, Updating: true, Ready: true, Failed: true, Deleting: true, Idled: true,
+}
+
+// knownStateValues returns the values of all known states, so the stored state can be interpreted in the queries
+// the same way it is read by Scan
+func knownStateValues() []string {
+	var values []string
+	for state := range knownStates {
+		values = append(values, string(state))
+	}
+	return values
+}
+
 func (s NamespaceState) String() string {
 	return string(s)
 }
@@ -98,14 +130,15 @@ func (ns *NamespaceState) Scan(value interface{}) error {
 		return nil
 	}
 	if bv, err := driver.String.ConvertValue(value); err == nil {
-		// if this is a bool type
-		if v, ok := bv.(string); ok {
-			// set the value of the pointer yne to YesNoEnum(v)
+		if v, ok := bv.(string); ok && knownStates[NamespaceState(v)] {
 			*ns = NamespaceState(v)
 			return nil
 		}
 	}
-	// otherwise, set ready state
+	// otherwise, set ready state so the namespace can still be loaded
+	log.Warn(nil, map[string]interface{}{
+		"state": value,
+	}, "unknown namespace state - using the ready state instead")
 	*ns = Ready
 	return nil
 }
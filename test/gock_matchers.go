@@ -1,6 +1,7 @@
 package test
 
 import (
+	"encoding/json"
 	"github.com/dgrijalva/jwt-go"
 	jwtrequest "github.com/dgrijalva/jwt-go/request"
 	"github.com/fabric8-services/fabric8-common/log"
@@ -8,6 +9,7 @@ import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strings"
 )
 
@@ -93,6 +95,24 @@ func HasBodyContainingObject(object map[interface{}]interface{}) gock.MatchFunc
 	}
 }
 
+// HasJSONBody checks that the body of the request is a JSON equal to the expected one
+func HasJSONBody(expected string) gock.MatchFunc {
+	return func(req *http.Request, gockReq *gock.Request) (bool, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return false, err
+		}
+		var actualObj, expectedObj interface{}
+		if err := json.Unmarshal(body, &actualObj); err != nil {
+			return false, nil
+		}
+		if err := json.Unmarshal([]byte(expected), &expectedObj); err != nil {
+			return false, err
+		}
+		return reflect.DeepEqual(actualObj, expectedObj), nil
+	}
+}
+
 // SpyOnCalls checks the number of calls
 func SpyOnCalls(counter *int) gock.Matcher {
 	matcher := gock.NewBasicMatcher()
@@ -9,6 +9,7 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/cluster"
 	"github.com/fabric8-services/fabric8-tenant/environment"
 	"github.com/satori/go.uuid"
+	"time"
 )
 
 type ClusterService struct {
@@ -42,6 +43,17 @@ func (s *ClusterService) GetUserClusterForType(ctx context.Context, user *auth.U
 	}, nil
 }
 
+func (s *ClusterService) GetCacheStatus() cluster.CacheStatus {
+	return cluster.CacheStatus{LastRefresh: time.Now()}
+}
+
+func (s *ClusterService) GetClustersHealth() []cluster.Health {
+	return []cluster.Health{{APIURL: s.APIURL, Cached: true, TokenValid: true, LastSuccess: time.Now()}}
+}
+
+func (s *ClusterService) InvalidateToken(clusterURL, token string) {
+}
+
 func (s *ClusterService) Stop() {
 }
 
@@ -85,3 +97,6 @@ func (s *AuthService) ResolveSaToken(ctx context.Context, target string) (userna
 func (s *AuthService) GetPublicKeys() ([]*rsa.PublicKey, error) {
 	return nil, nil
 }
+
+func (s *AuthService) InvalidateToken(target, accessToken string) {
+}
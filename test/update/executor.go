@@ -40,9 +40,28 @@ func (e *DummyUpdateExecutor) Update(ctx context.Context, dbTenant *tenant.Tenan
 	if e.ClusterService == nil {
 		return fmt.Errorf("cluster service is not set")
 	}
-	tenantUpdater := controller.TenantUpdater{TenantService: tenant.NewDBService(e.db),
+	return e.tenantUpdater().Update(ctx, dbTenant, user, envTypes, allowSelfHealing)
+}
+
+func (e *DummyUpdateExecutor) Clean(ctx context.Context, dbTenant *tenant.Tenant, removeFromCluster bool) error {
+	atomic.AddUint64(e.NumberOfCalls, 1)
+	if e.ClusterService == nil {
+		return fmt.Errorf("cluster service is not set")
+	}
+	return e.tenantUpdater().Clean(ctx, dbTenant, removeFromCluster)
+}
+
+func (e *DummyUpdateExecutor) Create(ctx context.Context, dbTenant *tenant.Tenant, envTypes []environment.Type) error {
+	atomic.AddUint64(e.NumberOfCalls, 1)
+	if e.ClusterService == nil {
+		return fmt.Errorf("cluster service is not set")
+	}
+	return e.tenantUpdater().Create(ctx, dbTenant, envTypes)
+}
+
+func (e *DummyUpdateExecutor) tenantUpdater() controller.TenantUpdater {
+	return controller.TenantUpdater{TenantService: tenant.NewDBService(e.db),
 		Config:         e.config,
 		ClusterService: e.ClusterService,
 	}
-	return tenantUpdater.Update(ctx, dbTenant, user, envTypes, allowSelfHealing)
 }
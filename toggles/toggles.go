@@ -47,6 +47,11 @@ func toString(value interface{}) string {
 	return fmt.Sprint(value)
 }
 
+// IsReady returns true if the toggles client has already fetched the features from the toggles service
+func IsReady() bool {
+	return ready
+}
+
 type IsToggleEnabled func(ctx context.Context, feature string, fallback bool) bool
 
 // IsEnabled wraps unleash for a simpler API
@@ -57,6 +62,14 @@ func IsEnabled(ctx context.Context, feature string, fallback bool) bool {
 	return unleash.IsEnabled(feature, WithContext(ctx), unleash.WithFallback(fallback))
 }
 
+// IsEnabledForUser checks the feature for the given user ID - it can be used when there is no user token available
+func IsEnabledForUser(userID string, feature string, fallback bool) bool {
+	if !ready {
+		return fallback
+	}
+	return unleash.IsEnabled(feature, unleash.WithContext(ucontext.Context{UserId: userID}), unleash.WithFallback(fallback))
+}
+
 type listener struct{}
 
 // OnError prints out errors.
@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/goadesign/goa"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	serviceName = "fabric8-tenant"
+	tracerName  = "github.com/fabric8-services/fabric8-tenant"
+
+	// RequestIDHeader is the header the ID of the incoming request is propagated in to the outgoing requests
+	RequestIDHeader = "X-Request-Id"
+
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// Init sets up the global tracer provider with the exporter set in the configuration. When no exporter is set,
+// the no-op provider stays in place so the spans cost nothing. The returned function flushes the remaining spans
+// and should be called when the service is stopped.
+func Init(config *configuration.Data) (func(), error) {
+	exporterName := config.GetTracingExporter()
+	if exporterName == "" {
+		log.Info(nil, map[string]interface{}{}, "tracing is disabled")
+		return func() {}, nil
+	}
+
+	var closer io.Closer
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpoint(config.GetTracingOTLPEndpoint()), otlptracehttp.WithInsecure())
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		var file *os.File
+		file, err = os.OpenFile(config.GetTracingFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to open the file %s for the spans", config.GetTracingFile())
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %s", exporterName)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create the %s tracing exporter", exporterName)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", configuration.Commit))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.GetTracingSampleRatio()))))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	log.Info(nil, map[string]interface{}{
+		"exporter": exporterName,
+	}, "tracing is enabled")
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err": err,
+			}, "unable to flush the remaining spans")
+		}
+		if closer != nil {
+			closer.Close()
+		}
+	}, nil
+}
+
+// Start starts a new span that is a child of the span stored in the given context (if any)
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the given error (if any) in the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartRequest starts a client span for the given outgoing request and propagates both the span and the ID of the incoming request
+// the context belongs to via the request headers
+func StartRequest(ctx context.Context, name string, req *http.Request, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, attribute.String("http.method", req.Method), attribute.String("http.url", req.URL.String()))
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...), trace.WithSpanKind(trace.SpanKindClient))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if requestID := log.ExtractRequestID(ctx); requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	return ctx, span
+}
+
+// EndRequest records the status code of the response or the error of the outgoing request and ends the span
+func EndRequest(span trace.Span, resp *http.Response, err error) {
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+		if err == nil && resp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	End(span, err)
+}
+
+// Middleware starts a server span for every incoming request named after the controller and its action. The span continues
+// the trace propagated by the caller via the request headers
+func Middleware() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+			name := fmt.Sprintf("%s.%s", goa.ContextController(ctx), goa.ContextAction(ctx))
+			ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				attribute.String("http.method", req.Method),
+				attribute.String("http.target", req.URL.Path),
+				attribute.String("request.id", log.ExtractRequestID(ctx))))
+
+			err := h(ctx, rw, req)
+			if resp := goa.ContextResponse(ctx); resp != nil {
+				span.SetAttributes(attribute.Int("http.status_code", resp.Status))
+				if resp.Status >= 500 {
+					span.SetStatus(codes.Error, http.StatusText(resp.Status))
+				}
+			}
+			End(span, err)
+			return err
+		}
+	}
+}
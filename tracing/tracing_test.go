@@ -0,0 +1,109 @@
+package tracing_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/tracing"
+	"github.com/goadesign/goa/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitWhenDisabled(t *testing.T) {
+	// given
+	reset := test.SetEnvironments(test.Env("F8_TRACING_EXPORTER", ""))
+	defer reset()
+	config, err := configuration.GetData()
+	require.NoError(t, err)
+
+	// when
+	stop, err := tracing.Init(config)
+
+	// then
+	require.NoError(t, err)
+	stop()
+}
+
+func TestInitFailsForUnknownExporter(t *testing.T) {
+	// given
+	reset := test.SetEnvironments(test.Env("F8_TRACING_EXPORTER", "unknown"))
+	defer reset()
+	config, err := configuration.GetData()
+	require.NoError(t, err)
+
+	// when
+	_, err = tracing.Init(config)
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown tracing exporter unknown")
+}
+
+func TestFileExporter(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "tracing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "traces.json")
+	stop := initWithFileExporter(t, file)
+
+	ctx, parent := tracing.Start(nil, "parent-span")
+	_, child := tracing.Start(ctx, "child-span")
+
+	// when
+	tracing.End(child, fmt.Errorf("child failed"))
+	tracing.End(parent, nil)
+	stop()
+
+	// then
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "parent-span")
+	assert.Contains(t, string(content), "child-span")
+	assert.Contains(t, string(content), "child failed")
+	assert.Contains(t, string(content), parent.SpanContext().TraceID().String())
+}
+
+func TestStartRequestPropagatesTraceAndRequestID(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "tracing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	stop := initWithFileExporter(t, filepath.Join(dir, "traces.json"))
+	defer stop()
+
+	ctx, requestID := client.ContextWithRequestID(context.Background())
+	ctx, parent := tracing.Start(ctx, "parent-span")
+	defer parent.End()
+	req, err := http.NewRequest("GET", test.ClusterURL, nil)
+	require.NoError(t, err)
+
+	// when
+	_, span := tracing.StartRequest(ctx, "GET Namespace", req)
+	defer span.End()
+
+	// then
+	assert.Equal(t, requestID, req.Header.Get(tracing.RequestIDHeader))
+	traceParent := req.Header.Get("traceparent")
+	assert.Contains(t, traceParent, parent.SpanContext().TraceID().String())
+	assert.Contains(t, traceParent, span.SpanContext().SpanID().String())
+}
+
+func initWithFileExporter(t *testing.T, file string) func() {
+	reset := test.SetEnvironments(
+		test.Env("F8_TRACING_EXPORTER", tracing.ExporterFile),
+		test.Env("F8_TRACING_FILE", file))
+	defer reset()
+	config, err := configuration.GetData()
+	require.NoError(t, err)
+	stop, err := tracing.Init(config)
+	require.NoError(t, err)
+	return stop
+}
@@ -0,0 +1,256 @@
+package update
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/dbsupport"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"sync"
+)
+
+const RunBreakersTableName = "tenants_update_run_breakers"
+
+// updateBreaker counts the results of the tenant updates within a halt scope and trips when too many of them failed
+type updateBreaker interface {
+	// record registers the result of a tenant update. It returns a non-empty reason when the breaker has just tripped.
+	record(err error) string
+	isTripped() bool
+}
+
+// haltThresholds are the configured limits of the failed tenant updates that halt the update
+type haltThresholds struct {
+	maxConsecutive int
+	maxPercentage  int
+	minAttempts    int
+}
+
+func newHaltThresholds(config *configuration.Data) haltThresholds {
+	return haltThresholds{
+		maxConsecutive: config.GetAutomatedUpdateHaltConsecutiveFailures(),
+		maxPercentage:  config.GetAutomatedUpdateHaltFailedPercentage(),
+		minAttempts:    config.GetAutomatedUpdateHaltMinAttempts(),
+	}
+}
+
+// tripReason returns a non-empty reason if the given counts reached any of the thresholds
+func (t haltThresholds) tripReason(consecutive, attempts, failed int) string {
+	if t.maxConsecutive > 0 && consecutive >= t.maxConsecutive {
+		return fmt.Sprintf("%d consecutive tenant updates failed", consecutive)
+	}
+	if t.maxPercentage > 0 && attempts >= t.minAttempts && failed*100 >= attempts*t.maxPercentage {
+		return fmt.Sprintf("%d out of %d tenant updates failed which reached the limit of %d%%", failed, attempts, t.maxPercentage)
+	}
+	return ""
+}
+
+// failureBreaker trips when the number of consecutive failed tenant updates or the percentage of failed tenant updates
+// reaches the configured threshold. It is shared by all goroutines updating tenants within the same halt scope.
+type failureBreaker struct {
+	mux         sync.Mutex
+	thresholds  haltThresholds
+	consecutive int
+	attempts    int
+	failed      int
+	tripped     bool
+}
+
+func newFailureBreaker(config *configuration.Data) *failureBreaker {
+	return &failureBreaker{thresholds: newHaltThresholds(config)}
+}
+
+func (b *failureBreaker) record(err error) string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.attempts++
+	if err == nil {
+		b.consecutive = 0
+		return ""
+	}
+	b.failed++
+	b.consecutive++
+
+	if b.tripped {
+		return ""
+	}
+	reason := b.thresholds.tripReason(b.consecutive, b.attempts, b.failed)
+	b.tripped = reason != ""
+	return reason
+}
+
+func (b *failureBreaker) isTripped() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.tripped
+}
+
+// RunBreaker is the state of the failure breaker of a cluster within an update run. When all clusters share the same
+// breaker, then the master URL is empty.
+type RunBreaker struct {
+	RunID       uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	MasterURL   string    `gorm:"primary_key"`
+	Attempts    int
+	Failed      int
+	Consecutive int
+	Tripped     bool
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (b RunBreaker) TableName() string {
+	return RunBreakersTableName
+}
+
+// RecordBreakerResult counts the result of a tenant update in the breaker of the run and returns its new state.
+// The row of the breaker stays locked until the end of the transaction
+func (r *GormRepository) RecordBreakerResult(runID uuid.UUID, clusterURL string, failed bool) (*RunBreaker, error) {
+	failedCount := 0
+	if failed {
+		failedCount = 1
+	}
+	query := fmt.Sprintf(`INSERT INTO %[1]s (run_id, master_url, attempts, failed, consecutive) VALUES (?, ?, 1, ?, ?)
+		ON CONFLICT (run_id, master_url) DO UPDATE SET attempts = %[1]s.attempts + 1,
+		failed = %[1]s.failed + EXCLUDED.failed,
+		consecutive = CASE WHEN EXCLUDED.failed > 0 THEN %[1]s.consecutive + 1 ELSE 0 END
+		RETURNING *`, RunBreakersTableName)
+	var breakers []*RunBreaker
+	if err := r.tx.Raw(query, runID, clusterURL, failedCount, failedCount).Scan(&breakers).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed to record result in %s table", RunBreakersTableName)
+	}
+	if len(breakers) == 0 {
+		return nil, fmt.Errorf("no breaker of the run %s and cluster '%s' was stored", runID, clusterURL)
+	}
+	return breakers[0], nil
+}
+
+// TripBreaker trips the breaker of the run. Returns false if it has already been tripped
+func (r *GormRepository) TripBreaker(runID uuid.UUID, clusterURL string) (bool, error) {
+	result := r.tx.Table(RunBreakersTableName).Where("run_id = ? AND master_url = ? AND NOT tripped", runID, clusterURL).
+		UpdateColumn("tripped", true)
+	if result.Error != nil {
+		return false, errs.Wrapf(result.Error, "failed to trip breaker of the run %s and cluster '%s'", runID, clusterURL)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// IsBreakerTripped says if the breaker of the run has been tripped
+func (r *GormRepository) IsBreakerTripped(runID uuid.UUID, clusterURL string) (bool, error) {
+	var breakers []*RunBreaker
+	err := r.tx.Table(RunBreakersTableName).Where("run_id = ? AND master_url = ?", runID, clusterURL).Find(&breakers).Error
+	if err != nil {
+		return false, errs.Wrapf(err, "failed to get breaker of the run %s and cluster '%s'", runID, clusterURL)
+	}
+	return len(breakers) > 0 && breakers[0].Tripped, nil
+}
+
+// runBreaker keeps its state with the update run, so it is shared by all replicas updating the batches of the run
+type runBreaker struct {
+	db         *gorm.DB
+	runID      uuid.UUID
+	clusterURL string
+	thresholds haltThresholds
+}
+
+func (b *runBreaker) record(updateErr error) string {
+	var reason string
+	err := dbsupport.Transaction(b.db, func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		state, err := repo.RecordBreakerResult(b.runID, b.clusterURL, updateErr != nil)
+		if err != nil || updateErr == nil || state.Tripped {
+			return err
+		}
+		candidate := b.thresholds.tripReason(state.Consecutive, state.Attempts, state.Failed)
+		if candidate == "" {
+			return nil
+		}
+		tripped, err := repo.TripBreaker(b.runID, b.clusterURL)
+		if tripped {
+			reason = candidate
+		}
+		return err
+	})
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"run_id":      b.runID,
+			"cluster_url": b.clusterURL,
+		}, err, "unable to record the result of the tenant update in the breaker of the run")
+		return ""
+	}
+	return reason
+}
+
+func (b *runBreaker) isTripped() bool {
+	var tripped bool
+	err := dbsupport.Transaction(b.db, func(tx *gorm.DB) error {
+		var err error
+		tripped, err = NewRepository(tx).IsBreakerTripped(b.runID, b.clusterURL)
+		return err
+	})
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"run_id":      b.runID,
+			"cluster_url": b.clusterURL,
+		}, err, "unable to get the state of the breaker of the run")
+	}
+	return tripped
+}
+
+// clusterBreakers provides failure breakers for the clusters according to the configured halt scope -
+// either every cluster has its own breaker or all of them share the same one
+type clusterBreakers struct {
+	mux        sync.Mutex
+	config     *configuration.Data
+	shared     *failureBreaker
+	perCluster map[string]*failureBreaker
+	db         *gorm.DB
+	runID      uuid.UUID
+}
+
+func newClusterBreakers(config *configuration.Data) *clusterBreakers {
+	breakers := &clusterBreakers{
+		config:     config,
+		perCluster: map[string]*failureBreaker{},
+	}
+	if breakers.haltsAll() {
+		breakers.shared = newFailureBreaker(config)
+	}
+	return breakers
+}
+
+// newRunBreakers creates breakers whose state is stored with the update run, so the thresholds apply to all batches
+// of the run regardless of the replica that updates them
+func newRunBreakers(db *gorm.DB, config *configuration.Data, runID uuid.UUID) *clusterBreakers {
+	return &clusterBreakers{
+		config: config,
+		db:     db,
+		runID:  runID,
+	}
+}
+
+func (b *clusterBreakers) haltsAll() bool {
+	return b.config.GetAutomatedUpdateHaltScope() == configuration.HaltScopeAll
+}
+
+func (b *clusterBreakers) forCluster(clusterURL string) updateBreaker {
+	if b.db != nil {
+		scope := clusterURL
+		if b.haltsAll() {
+			scope = ""
+		}
+		return &runBreaker{db: b.db, runID: b.runID, clusterURL: scope, thresholds: newHaltThresholds(b.config)}
+	}
+	if b.shared != nil {
+		return b.shared
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	breaker, found := b.perCluster[clusterURL]
+	if !found {
+		breaker = newFailureBreaker(b.config)
+		b.perCluster[clusterURL] = breaker
+	}
+	return breaker
+}
@@ -0,0 +1,57 @@
+package update_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"testing"
+)
+
+func (s *TenantsUpdaterTestSuite) TestUpdateIsHaltedAfterConsecutiveFailures() {
+	for _, scope := range []string{configuration.HaltScopeCluster, configuration.HaltScopeAll} {
+		s.T().Run("with halt scope "+scope, func(t *testing.T) {
+			// given
+			defer gock.OffAll()
+			resetHalt := test.SetEnvironments(
+				test.Env("F8_AUTOMATED_UPDATE_HALT_CONSECUTIVE_FAILURES", "3"),
+				test.Env("F8_AUTOMATED_UPDATE_HALT_SCOPE", scope))
+			defer resetHalt()
+			testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+			gock.New(test.ClusterURL).
+				Get("").
+				Persist().
+				Reply(200).
+				BodyString(`{"status": {"phase":"Active"}}`)
+			updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+			tenantsUpdater, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+			defer reset()
+			testdoubles.SetTemplateVersions()
+
+			configuration.Commit = "124abcd"
+			tf.FillDB(t, s.DB, tf.AddTenants(10), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+			s.tx(t, func(repo update.Repository) error {
+				return testupdate.UpdateVersionsTo(repo, "0")
+			})
+			configuration.Commit = "xyz"
+
+			// when
+			tenantsUpdater.UpdateAllTenants()
+
+			// then
+			assert.Equal(t, 3, int(*updateExecutor.NumberOfCalls))
+			tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+			require.NoError(t, err)
+			assert.Equal(t, update.Halted, tenantsUpdate.Status)
+			assert.Equal(t, 3, tenantsUpdate.FailedCount)
+			assert.Contains(t, tenantsUpdate.HaltReason, "3 consecutive tenant updates failed")
+			assert.Equal(t, scope == configuration.HaltScopeCluster, tenantsUpdate.CanContinue)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package update
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/toggles"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// tenantSelector decides if the given tenant should be updated in the current stage
+type tenantSelector func(tnnt *tenant.Tenant) bool
+
+func allTenants(tnnt *tenant.Tenant) bool {
+	return true
+}
+
+// canaryCohort selects tenants that are updated in the canary stage. A tenant is part of the cohort if:
+// - it has an email address in the configured (internal) domain, or
+// - the configured feature toggle is enabled for the tenant, or
+// - the hash of its ID falls into the configured percentage of tenants
+type canaryCohort struct {
+	percentage       int
+	toggle           string
+	emailDomain      string
+	isEnabledForUser func(userID, feature string, fallback bool) bool
+}
+
+func newCanaryCohort(config *configuration.Data) *canaryCohort {
+	return &canaryCohort{
+		percentage:       config.GetAutomatedUpdateCanaryPercentage(),
+		toggle:           config.GetAutomatedUpdateCanaryToggle(),
+		emailDomain:      strings.ToLower(config.GetAutomatedUpdateCanaryEmailDomain()),
+		isEnabledForUser: toggles.IsEnabledForUser,
+	}
+}
+
+func (c *canaryCohort) isSelected(tnnt *tenant.Tenant) bool {
+	if c.emailDomain != "" && strings.HasSuffix(strings.ToLower(tnnt.Email), "@"+c.emailDomain) {
+		return true
+	}
+	if c.toggle != "" && c.isEnabledForUser(tnnt.ID.String(), c.toggle, false) {
+		return true
+	}
+	return c.percentage > 0 && bucketOf(tnnt.ID) < c.percentage
+}
+
+// bucketOf returns a stable number in range 0-99 computed from the tenant ID, so the same tenants
+// are selected for the same percentage in every run
+func bucketOf(tenantID uuid.UUID) int {
+	hash := fnv.New32a()
+	hash.Write(tenantID.Bytes())
+	return int(hash.Sum32() % 100)
+}
+
+// stageStats collects results of tenant updates done in one stage by all cluster goroutines
+type stageStats struct {
+	mux       sync.Mutex
+	updated   []uuid.UUID
+	failed    []uuid.UUID
+	stopped   bool
+	postponed bool
+}
+
+func (s *stageStats) record(tenantID uuid.UUID, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err != nil {
+		s.failed = append(s.failed, tenantID)
+	} else {
+		s.updated = append(s.updated, tenantID)
+	}
+}
+
+func (s *stageStats) markStopped() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.stopped = true
+}
+
+func (s *stageStats) markPostponed() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.postponed = true
+}
+
+// evaluateCanary checks the failure rate of the canary stage as well as the readiness of the updated namespaces.
+// It returns an error describing the reason when the update shouldn't proceed with the rest of the tenants.
+func evaluateCanary(db *gorm.DB, config *configuration.Data, stats *stageStats, typesWithVersion map[environment.Type]string) error {
+	total := len(stats.updated) + len(stats.failed)
+	if total == 0 {
+		log.Info(nil, map[string]interface{}{}, "there was no outdated tenant selected for the canary stage")
+		return nil
+	}
+
+	notReady, err := tenant.NewDBService(db).CountNotReadyTenants(stats.updated, typesWithVersion)
+	if err != nil {
+		return err
+	}
+
+	maxFailed := config.GetAutomatedUpdateCanaryMaxFailedPercentage()
+	log.Info(nil, map[string]interface{}{
+		"number_of_canary_tenants": total,
+		"number_of_failed":         len(stats.failed),
+		"number_of_not_ready":      notReady,
+		"max_failed_percentage":    maxFailed,
+	}, "evaluating canary stage of the tenants update")
+
+	if (len(stats.failed)+notReady)*100 > total*maxFailed {
+		return fmt.Errorf("%d failed and %d not ready tenants out of %d updated in the canary stage exceed the limit of %d%%",
+			len(stats.failed), notReady, total, maxFailed)
+	}
+	return nil
+}
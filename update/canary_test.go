@@ -0,0 +1,113 @@
+package update_test
+
+import (
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/assertion"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"time"
+)
+
+func withInternalEmail(tnnt *tenant.Tenant) {
+	tnnt.Email = "johndoe-" + tnnt.ID.String() + "@redhat.com"
+}
+
+func (s *TenantsUpdaterTestSuite) TestCanaryStageProceedsWithRolloutWhenSuccessful() {
+	// given
+	defer gock.OffAll()
+	resetCanary := test.SetEnvironments(test.Env("F8_AUTOMATED_UPDATE_CANARY_EMAIL_DOMAIN", "redhat.com"))
+	defer resetCanary()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	tenantsUpdater, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddSpecificTenants(withInternalEmail, withInternalEmail),
+		tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		return testupdate.UpdateVersionsTo(repo, "0")
+	})
+	configuration.Commit = "xyz"
+
+	// when
+	tenantsUpdater.UpdateAllTenants()
+
+	// then
+	assert.Equal(s.T(), 5, int(*updateExecutor.NumberOfCalls))
+	s.assertStatusAndAllVersionAreUpToDate(s.T(), update.Finished)
+	tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.Rollout, tenantsUpdate.Stage)
+	for _, tnnt := range fxt.Tenants {
+		assertion.AssertTenantFromDB(s.T(), s.DB, tnnt.ID).
+			HasNamespacesThat(func(assertion *assertion.NamespaceAssertion) {
+				assertion.
+					HasCurrentCompleteVersion().
+					HasUpdatedBy("xyz").
+					HasState(tenant.Ready)
+			})
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestCanaryStageHaltsUpdateWhenFailing() {
+	// given
+	defer gock.OffAll()
+	resetCanary := test.SetEnvironments(test.Env("F8_AUTOMATED_UPDATE_CANARY_EMAIL_DOMAIN", "redhat.com"))
+	defer resetCanary()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	gock.New(test.ClusterURL).
+		Get("").
+		Persist().
+		Reply(200).
+		BodyString(`{"status": {"phase":"Active"}}`)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	tenantsUpdater, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddSpecificTenants(withInternalEmail, withInternalEmail),
+		tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		return testupdate.UpdateVersionsTo(repo, "0")
+	})
+	configuration.Commit = "xyz"
+	before := time.Now()
+
+	// when
+	tenantsUpdater.UpdateAllTenants()
+
+	// then
+	assert.Equal(s.T(), 2, int(*updateExecutor.NumberOfCalls))
+	tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.Halted, tenantsUpdate.Status)
+	assert.Equal(s.T(), update.Canary, tenantsUpdate.Stage)
+	assert.Contains(s.T(), tenantsUpdate.HaltReason, "canary stage")
+	assert.Equal(s.T(), 2, tenantsUpdate.FailedCount)
+	for _, versionManager := range update.RetrieveVersionManagers() {
+		assert.False(s.T(), versionManager.IsVersionUpToDate(tenantsUpdate))
+	}
+	for _, tnnt := range fxt.Tenants {
+		assertion.AssertTenantFromDB(s.T(), s.DB, tnnt.ID).
+			HasNamespacesThat(func(assertion *assertion.NamespaceAssertion) {
+				assertion.
+					HasVersion("0000").
+					HasUpdatedBy("124abcd").
+					HasState(tenant.Ready).
+					WasUpdatedBefore(before)
+			})
+	}
+}
@@ -0,0 +1,138 @@
+package update
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard 5-field cron expression: minute, hour, day of month, month and day of week.
+// Each field supports "*", single values, ranges "a-b", steps "*/n" or "a-b/n" and comma-separated lists of them.
+type cronSchedule struct {
+	minutes    map[int]bool
+	hours      map[int]bool
+	daysOfMon  map[int]bool
+	months     map[int]bool
+	daysOfWeek map[int]bool
+	anyDom     bool
+	anyDow     bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("the cron expression '%s' has to have %d fields", expression, len(cronFields))
+	}
+	values := make([]map[int]bool, len(cronFields))
+	for idx, field := range fields {
+		parsed, err := parseCronField(field, cronFields[idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %s", expression, err)
+		}
+		values[idx] = parsed
+	}
+	// both 0 and 7 mean Sunday
+	if values[4][7] {
+		values[4][0] = true
+	}
+	return &cronSchedule{
+		minutes:    values[0],
+		hours:      values[1],
+		daysOfMon:  values[2],
+		months:     values[3],
+		daysOfWeek: values[4],
+		anyDom:     fields[2] == "*",
+		anyDow:     fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, def cronField) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %s field '%s'", def.name, field)
+			}
+			part = part[:idx]
+		}
+		from, to := def.min, def.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value in %s field '%s'", def.name, field)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range in %s field '%s'", def.name, field)
+				}
+			} else if step > 1 {
+				to = def.max
+			}
+		}
+		if from < def.min || to > def.max || from > to {
+			return nil, fmt.Errorf("%s field '%s' is out of range %d-%d", def.name, field, def.min, def.max)
+		}
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// next returns the first time matching the cron expression that is strictly after the given time
+func (c *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// the cron expression has to match at least once within a couple of years (eg. Feb 29)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay follows the cron convention: when both day of month and day of week are restricted,
+// the day matches if any of them matches
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	domMatches := c.daysOfMon[t.Day()]
+	dowMatches := c.daysOfWeek[int(t.Weekday())]
+	if c.anyDom || c.anyDow {
+		return domMatches && dowMatches
+	}
+	return domMatches || dowMatches
+}
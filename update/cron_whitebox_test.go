@@ -0,0 +1,106 @@
+package update
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCronFailures(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8",
+		"*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		t.Run(expression, func(t *testing.T) {
+			// when
+			_, err := parseCron(expression)
+
+			// then
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	testCases := []struct {
+		expression string
+		after      string
+		expected   string
+	}{
+		{expression: "* * * * *", after: "2019-03-05 10:15", expected: "2019-03-05 10:16"},
+		{expression: "0 22 * * *", after: "2019-03-05 10:15", expected: "2019-03-05 22:00"},
+		{expression: "0 22 * * *", after: "2019-03-05 22:00", expected: "2019-03-06 22:00"},
+		{expression: "*/20 1-2 * * *", after: "2019-03-05 01:45", expected: "2019-03-05 02:00"},
+		{expression: "30 2 * * 6,0", after: "2019-03-05 10:15", expected: "2019-03-09 02:30"},
+		{expression: "30 2 * * 7", after: "2019-03-05 10:15", expected: "2019-03-10 02:30"},
+		{expression: "0 0 29 2 *", after: "2019-03-05 10:15", expected: "2020-02-29 00:00"},
+		{expression: "0 0 1 * 1", after: "2019-03-05 10:15", expected: "2019-03-11 00:00"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.expression+" after "+testCase.after, func(t *testing.T) {
+			// given
+			cron, err := parseCron(testCase.expression)
+			require.NoError(t, err)
+
+			// when
+			next := cron.next(date(testCase.after))
+
+			// then
+			assert.Equal(t, date(testCase.expected), next)
+		})
+	}
+}
+
+func TestWindowCurrentAndNext(t *testing.T) {
+	// given
+	window, err := ParseWindow("0 22 * * 1-5 6h")
+	require.NoError(t, err)
+
+	t.Run("inside of the window", func(t *testing.T) {
+		// when
+		start, end, open := window.Current(date("2019-03-06 02:15"))
+
+		// then
+		assert.True(t, open)
+		assert.Equal(t, date("2019-03-05 22:00"), start)
+		assert.Equal(t, date("2019-03-06 04:00"), end)
+	})
+
+	t.Run("outside of the window", func(t *testing.T) {
+		// when
+		_, _, open := window.Current(date("2019-03-06 04:00"))
+		start, end := window.Next(date("2019-03-06 04:00"))
+
+		// then
+		assert.False(t, open)
+		assert.Equal(t, date("2019-03-06 22:00"), start)
+		assert.Equal(t, date("2019-03-07 04:00"), end)
+	})
+
+	t.Run("weekend is skipped", func(t *testing.T) {
+		// when
+		start, _ := window.Next(date("2019-03-09 12:00"))
+
+		// then
+		assert.Equal(t, date("2019-03-11 22:00"), start)
+	})
+}
+
+func TestParseWindowFailures(t *testing.T) {
+	for _, spec := range []string{"0 22 * * 1-5", "0 22 * * 1-5 abc", "0 22 * * 1-5 -1h", "0 25 * * * 1h"} {
+		t.Run(spec, func(t *testing.T) {
+			// when
+			_, err := ParseWindow(spec)
+
+			// then
+			assert.Error(t, err)
+		})
+	}
+}
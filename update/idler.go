@@ -0,0 +1,96 @@
+package update
+
+import (
+	"context"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// IdlerExecutor scales the workloads of the namespace down to zero and marks the namespace as idled
+type IdlerExecutor interface {
+	Idle(ctx context.Context, namespace *tenant.Namespace) error
+}
+
+// Idler periodically looks up the ready namespaces of the tenants whose users haven't been active for the configured period
+// and idles them. The namespaces are unidled when the user accesses the tenant again.
+// Only the leader idles the namespaces so the same namespace isn't idled by several replicas at once.
+type Idler struct {
+	db       *gorm.DB
+	config   *configuration.Data
+	executor IdlerExecutor
+	elector  *leader.Elector
+	stop     chan struct{}
+}
+
+// NewIdler creates an idler of the namespaces of the inactive tenants
+func NewIdler(db *gorm.DB, config *configuration.Data, executor IdlerExecutor, elector *leader.Elector) *Idler {
+	return &Idler{
+		db:       db,
+		config:   config,
+		executor: executor,
+		elector:  elector,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start starts idling the namespaces of the inactive tenants in the configured interval
+func (i *Idler) Start() {
+	go func() {
+		ticker := time.NewTicker(i.config.GetIdlerCheckInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-i.stop:
+				return
+			}
+			if i.elector.IsLeader() && !shutdown.IsShuttingDown() {
+				i.IdleInactiveNamespaces()
+			}
+		}
+	}()
+}
+
+// Stop stops the idler - the namespace that is being idled is finished
+func (i *Idler) Stop() {
+	close(i.stop)
+}
+
+// IdleInactiveNamespaces idles the ready namespaces of the tenants that haven't been active for the configured period
+func (i *Idler) IdleInactiveNamespaces() {
+	inactiveSince := time.Now().Add(-i.config.GetIdlerInactivityPeriod())
+	namespaces, err := tenant.NewDBService(i.db).GetNamespacesToIdle(inactiveSince, i.config.GetIdlerBatchSize())
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"inactive_since": inactiveSince,
+		}, err, "unable to get the namespaces of the inactive tenants")
+		return
+	}
+
+	ctx := tenant.WithActor(nil, tenant.ActorIdler)
+	for _, namespace := range namespaces {
+		if shutdown.IsShuttingDown() {
+			return
+		}
+		if err := i.executor.Idle(ctx, namespace); err != nil {
+			// the namespace is picked up again by the next check
+			sentry.LogError(nil, map[string]interface{}{
+				"tenant":    namespace.TenantID,
+				"namespace": namespace.Name,
+				"cluster":   namespace.MasterURL,
+			}, err, "unable to idle the namespace of the inactive tenant")
+			continue
+		}
+		log.Info(nil, map[string]interface{}{
+			"tenant":    namespace.TenantID,
+			"namespace": namespace.Name,
+			"cluster":   namespace.MasterURL,
+		}, "namespace of the inactive tenant idled")
+	}
+}
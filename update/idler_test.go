@@ -0,0 +1,92 @@
+package update_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestIdlerIdlesNamespacesOfInactiveTenants() {
+	// given
+	config, reset := s.newIdlerConfig()
+	defer reset()
+	inactive := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	active := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	s.makeInactive(inactive.Tenants...)
+	require.NoError(s.T(), tenant.NewTenantRepository(s.DB, active.Tenants[0].ID).RecordActivity(time.Now()))
+	executor := newOperationExecutor()
+
+	// when
+	update.NewIdler(s.DB, config, executor, nil).IdleInactiveNamespaces()
+
+	// then
+	require.Len(s.T(), executor.idled, len(inactive.Namespaces))
+	for _, ns := range inactive.Namespaces {
+		assert.Contains(s.T(), executor.idled, ns.Name)
+	}
+	for _, ns := range active.Namespaces {
+		assert.NotContains(s.T(), executor.idled, ns.Name)
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestIdlerIgnoresNamespacesThatAreNotReady() {
+	// given
+	config, reset := s.newIdlerConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1),
+		tf.AddNamespaces(environment.TypeUser).State(tenant.Idled), tf.AddNamespaces(environment.TypeChe).State(tenant.Failed))
+	s.makeInactive(fxt.Tenants...)
+	executor := newOperationExecutor()
+
+	// when
+	update.NewIdler(s.DB, config, executor, nil).IdleInactiveNamespaces()
+
+	// then
+	for _, ns := range fxt.Namespaces {
+		assert.NotContains(s.T(), executor.idled, ns.Name)
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestIdlerContinuesWhenIdlingOfNamespaceFails() {
+	// given
+	config, reset := s.newIdlerConfig()
+	defer reset()
+	failing := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser))
+	ok := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser))
+	s.makeInactive(failing.Tenants...)
+	s.makeInactive(ok.Tenants...)
+	executor := newOperationExecutor()
+	executor.failFor[failing.Tenants[0].ID] = true
+
+	// when
+	update.NewIdler(s.DB, config, executor, nil).IdleInactiveNamespaces()
+
+	// then
+	assert.NotContains(s.T(), executor.idled, failing.Namespaces[0].Name)
+	assert.Contains(s.T(), executor.idled, ok.Namespaces[0].Name)
+}
+
+func (s *TenantsUpdaterTestSuite) newIdlerConfig() (*configuration.Data, func()) {
+	resetEnvs := test.SetEnvironments(
+		test.Env("F8_IDLER_INACTIVITY_PERIOD", "24h"),
+		test.Env("F8_IDLER_BATCH_SIZE", "1000"))
+	config, reset := test.LoadTestConfig(s.T())
+	return config, func() {
+		reset()
+		resetEnvs()
+	}
+}
+
+// makeInactive moves the last activity of the tenants before the inactivity period
+func (s *TenantsUpdaterTestSuite) makeInactive(tenants ...*tenant.Tenant) {
+	for _, tnnt := range tenants {
+		err := tenant.NewTenantRepository(s.DB, tnnt.ID).RecordActivity(time.Now().Add(-48 * time.Hour))
+		require.NoError(s.T(), err)
+	}
+}
@@ -0,0 +1,223 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/fabric8-services/fabric8-tenant/metric"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// Outcomes of the handling of a stuck namespace reported in the metrics
+const (
+	// OutcomeRerun means that the interrupted operation was successfully performed again
+	OutcomeRerun = "rerun"
+	// OutcomeRerunFailed means that the interrupted operation was performed again, but it failed
+	OutcomeRerunFailed = "rerun-failed"
+	// OutcomeMarkedFailed means that the operation cannot be redone so the namespace was marked as failed
+	OutcomeMarkedFailed = "marked-failed"
+	// OutcomeSkipped means that the namespace couldn't be inspected - it is handled by the next check
+	OutcomeSkipped = "skipped"
+)
+
+// JanitorExecutor performs the interrupted operations again and inspects the live status of the namespaces
+type JanitorExecutor interface {
+	OperationExecutor
+	NamespaceExists(ctx context.Context, namespace *tenant.Namespace) (bool, error)
+}
+
+// Janitor periodically looks up the namespaces that have been in the provisioning or updating state for too long,
+// typically because the pod performing the operation died. Depending on the live status of the namespace in the cluster
+// the operation is performed again or the namespace is marked as failed, so it is picked up by the next update.
+// Only the leader checks the namespaces so the same namespace isn't repaired by several replicas at once.
+type Janitor struct {
+	db       *gorm.DB
+	config   *configuration.Data
+	executor JanitorExecutor
+	elector  *leader.Elector
+	stop     chan struct{}
+}
+
+// NewJanitor creates a janitor of the stuck namespaces
+func NewJanitor(db *gorm.DB, config *configuration.Data, executor JanitorExecutor, elector *leader.Elector) *Janitor {
+	return &Janitor{
+		db:       db,
+		config:   config,
+		executor: executor,
+		elector:  elector,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start starts checking the stuck namespaces in the configured interval
+func (j *Janitor) Start() {
+	go func() {
+		ticker := time.NewTicker(j.config.GetJanitorCheckInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+			if j.elector.IsLeader() && !shutdown.IsShuttingDown() {
+				j.RepairStuckNamespaces()
+			}
+		}
+	}()
+}
+
+// Stop stops the janitor - the namespace that is being repaired is finished
+func (j *Janitor) Stop() {
+	close(j.stop)
+}
+
+// RepairStuckNamespaces handles the namespaces whose state hasn't been changed within the configured timeout
+func (j *Janitor) RepairStuckNamespaces() {
+	tenantService := tenant.NewDBService(j.db)
+	stuckBefore := time.Now().Add(-j.config.GetJanitorStuckTimeout())
+	namespaces, err := tenantService.GetStuckNamespaces(stuckBefore, j.config.GetJanitorBatchSize())
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"stuck_before": stuckBefore,
+		}, err, "unable to get the stuck namespaces")
+		return
+	}
+
+	counts := map[tenant.NamespaceState]int{tenant.Provisioning: 0, tenant.Updating: 0}
+	for _, namespace := range namespaces {
+		counts[namespace.State]++
+	}
+	for state, count := range counts {
+		metric.SetStuckNamespaces(state.String(), count)
+	}
+
+	for _, namespace := range namespaces {
+		if shutdown.IsShuttingDown() {
+			return
+		}
+		state := namespace.State
+		outcome := j.repair(tenantService, namespace)
+		metric.RecordRepairedNamespace(state.String(), outcome)
+		log.Info(nil, map[string]interface{}{
+			"tenant":     namespace.TenantID,
+			"namespace":  namespace.Name,
+			"cluster":    namespace.MasterURL,
+			"state":      state,
+			"updated_at": namespace.UpdatedAt,
+			"outcome":    outcome,
+		}, "stuck namespace handled")
+	}
+}
+
+func (j *Janitor) repair(tenantService tenant.Service, namespace *tenant.Namespace) string {
+	tenantRepo := tenantService.NewTenantRepository(namespace.TenantID)
+	dbTenant, err := tenantRepo.GetTenant()
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"tenant":    namespace.TenantID,
+			"namespace": namespace.Name,
+		}, err, "unable to get the tenant of the stuck namespace")
+		return OutcomeSkipped
+	}
+	exists, err := j.executor.NamespaceExists(context.Background(), namespace)
+	if err != nil {
+		// the cluster can be temporarily unavailable - the namespace is inspected again by the next check
+		sentry.LogError(nil, map[string]interface{}{
+			"tenant":    namespace.TenantID,
+			"namespace": namespace.Name,
+			"cluster":   namespace.MasterURL,
+		}, err, "unable to inspect the stuck namespace in the cluster")
+		return OutcomeSkipped
+	}
+	envTypes := []environment.Type{namespace.Type}
+	ctx := tenant.WithActor(nil, tenant.ActorJanitor)
+
+	switch {
+	case namespace.State == tenant.Updating && exists:
+		if err := j.executor.Update(ctx, dbTenant, nil, envTypes, true); err != nil {
+			sentry.LogError(nil, map[string]interface{}{
+				"tenant":    namespace.TenantID,
+				"namespace": namespace.Name,
+			}, err, "the re-run of the interrupted update of the namespace failed")
+			return OutcomeRerunFailed
+		}
+		return OutcomeRerun
+
+	case namespace.State == tenant.Provisioning && !exists:
+		// the creation stores a new entity of the namespace, so the stuck one has to be removed first. It is removed
+		// permanently so it can be restored with the same ID when the creation fails
+		if err := tenantRepo.PurgeNamespace(namespace); err != nil {
+			sentry.LogError(nil, map[string]interface{}{
+				"tenant":    namespace.TenantID,
+				"namespace": namespace.Name,
+			}, err, "unable to remove the entity of the stuck namespace")
+			return OutcomeSkipped
+		}
+		if err := j.executor.Create(ctx, dbTenant, envTypes); err != nil {
+			sentry.LogError(nil, map[string]interface{}{
+				"tenant":    namespace.TenantID,
+				"namespace": namespace.Name,
+			}, err, "the re-run of the interrupted provisioning of the namespace failed")
+			// keep the record of the namespace when the creation failed before the new entity was stored
+			if err := j.restoreAsFailed(tenantRepo, namespace, err); err != nil {
+				sentry.LogError(nil, map[string]interface{}{
+					"tenant":    namespace.TenantID,
+					"namespace": namespace.Name,
+				}, err, "unable to restore the entity of the stuck namespace")
+			}
+			return OutcomeRerunFailed
+		}
+		return OutcomeRerun
+
+	case namespace.State == tenant.Provisioning:
+		return j.markAsFailed(tenantService, namespace, "create",
+			"the provisioning was interrupted - the project exists in the cluster, but it may be incomplete")
+
+	default:
+		return j.markAsFailed(tenantService, namespace, "update",
+			"the update was interrupted and the project doesn't exist in the cluster")
+	}
+}
+
+func (j *Janitor) restoreAsFailed(tenantRepo tenant.Repository, namespace *tenant.Namespace, cause error) error {
+	restored, err := tenantRepo.CreateNamespace(namespace, tenant.ActorJanitor)
+	if err != nil || restored == nil {
+		// either failed or the creation stored the new entity
+		return err
+	}
+	return tenantRepo.ChangeNamespaceState(restored, tenant.Failed, tenant.ActorJanitor,
+		fmt.Errorf("the re-run of the interrupted provisioning failed: %s", cause))
+}
+
+func (j *Janitor) markAsFailed(tenantService tenant.Service, namespace *tenant.Namespace, action, reason string) string {
+	marked, err := tenantService.MarkStuckNamespaceFailed(namespace, reason)
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"tenant":    namespace.TenantID,
+			"namespace": namespace.Name,
+		}, err, "unable to mark the stuck namespace as failed")
+		return OutcomeSkipped
+	}
+	if !marked {
+		// the state has been changed in the meantime so the namespace isn't stuck anymore
+		return OutcomeSkipped
+	}
+	webhook.Publish(nil, webhook.NewNamespaceEvent(webhook.NamespaceFailed, namespace.TenantID, &webhook.Namespace{
+		Name:      namespace.Name,
+		Type:      namespace.Type.String(),
+		MasterURL: namespace.MasterURL,
+		Version:   namespace.Version,
+		State:     namespace.State.String(),
+		Action:    action,
+	}))
+	return OutcomeMarkedFailed
+}
@@ -0,0 +1,161 @@
+package update_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestJanitorRerunsInterruptedOperations() {
+	// given
+	config, reset := s.newJanitorConfig()
+	defer reset()
+	updating := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Updating))
+	provisioning := tf.FillDB(s.T(), s.DB, tf.AddTenants(1),
+		tf.AddNamespaces(environment.TypeUser), tf.AddNamespaces(environment.TypeChe).State(tenant.Provisioning))
+	s.makeStuck(updating.Namespaces...)
+	s.makeStuck(provisioning.Namespaces...)
+	executor := newOperationExecutor()
+	stuckChe := namespaceOfType(provisioning.Namespaces, environment.TypeChe)
+	executor.missing[stuckChe.Name] = true
+
+	// when
+	update.NewJanitor(s.DB, config, executor, nil).RepairStuckNamespaces()
+
+	// then
+	assert.Equal(s.T(), "update:user", executor.calls[updating.Tenants[0].ID])
+	assert.Equal(s.T(), "create:che", executor.calls[provisioning.Tenants[0].ID])
+	// the entity of the stuck namespace is replaced by the one stored by the creation
+	namespaces, err := tenant.NewDBService(s.DB).NewTenantRepository(provisioning.Tenants[0].ID).GetNamespaces()
+	require.NoError(s.T(), err)
+	for _, ns := range namespaces {
+		assert.NotEqual(s.T(), stuckChe.ID, ns.ID)
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestJanitorRestoresNamespaceAsFailedWhenProvisioningRerunFails() {
+	// given
+	config, reset := s.newJanitorConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Provisioning))
+	s.makeStuck(fxt.Namespaces...)
+	executor := newOperationExecutor()
+	executor.missing[fxt.Namespaces[0].Name] = true
+	executor.failFor[fxt.Tenants[0].ID] = true
+
+	// when
+	update.NewJanitor(s.DB, config, executor, nil).RepairStuckNamespaces()
+
+	// then
+	assert.Equal(s.T(), "create:user", executor.calls[fxt.Tenants[0].ID])
+	repo := tenant.NewDBService(s.DB).NewTenantRepository(fxt.Tenants[0].ID)
+	namespaces, err := repo.GetNamespaces()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), namespaces, 1)
+	assert.Equal(s.T(), fxt.Namespaces[0].ID, namespaces[0].ID)
+	assert.Equal(s.T(), tenant.Failed, namespaces[0].State)
+	assert.Contains(s.T(), namespaces[0].StateReason, "the re-run of the interrupted provisioning failed")
+	deleted, err := repo.GetDeletedNamespaces()
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), deleted)
+}
+
+func (s *TenantsUpdaterTestSuite) TestJanitorMarksNamespacesAsFailedWhenOperationCannotBeRerun() {
+	// given
+	config, reset := s.newJanitorConfig()
+	defer reset()
+	provisioning := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Provisioning))
+	updating := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Updating))
+	s.makeStuck(provisioning.Namespaces...)
+	s.makeStuck(updating.Namespaces...)
+	executor := newOperationExecutor()
+	executor.missing[updating.Namespaces[0].Name] = true
+
+	// when
+	update.NewJanitor(s.DB, config, executor, nil).RepairStuckNamespaces()
+
+	// then
+	assert.Empty(s.T(), executor.calls)
+	for _, fxt := range []*tf.TestFixture{provisioning, updating} {
+		namespaces, err := tenant.NewDBService(s.DB).NewTenantRepository(fxt.Tenants[0].ID).GetNamespaces()
+		require.NoError(s.T(), err)
+		require.Len(s.T(), namespaces, 1)
+		assert.Equal(s.T(), tenant.Failed, namespaces[0].State)
+		assert.Contains(s.T(), namespaces[0].StateReason, "was interrupted")
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestJanitorIgnoresNamespacesThatAreNotStuck() {
+	// given
+	config, reset := s.newJanitorConfig()
+	defer reset()
+	recent := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Updating))
+	ready := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Ready))
+	s.makeStuck(ready.Namespaces...)
+	executor := newOperationExecutor()
+
+	// when
+	update.NewJanitor(s.DB, config, executor, nil).RepairStuckNamespaces()
+
+	// then
+	assert.NotContains(s.T(), executor.calls, recent.Tenants[0].ID)
+	assert.NotContains(s.T(), executor.calls, ready.Tenants[0].ID)
+	namespaces, err := tenant.NewDBService(s.DB).NewTenantRepository(recent.Tenants[0].ID).GetNamespaces()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), tenant.Updating, namespaces[0].State)
+}
+
+func (s *TenantsUpdaterTestSuite) TestJanitorSkipsNamespacesThatCannotBeInspected() {
+	// given
+	config, reset := s.newJanitorConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).State(tenant.Updating))
+	s.makeStuck(fxt.Namespaces...)
+	executor := newOperationExecutor()
+	executor.inspectionErr = fmt.Errorf("cluster unavailable")
+
+	// when
+	update.NewJanitor(s.DB, config, executor, nil).RepairStuckNamespaces()
+
+	// then
+	assert.NotContains(s.T(), executor.calls, fxt.Tenants[0].ID)
+	namespaces, err := tenant.NewDBService(s.DB).NewTenantRepository(fxt.Tenants[0].ID).GetNamespaces()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), tenant.Updating, namespaces[0].State)
+	assert.Empty(s.T(), namespaces[0].StateReason)
+}
+
+func (s *TenantsUpdaterTestSuite) newJanitorConfig() (*configuration.Data, func()) {
+	resetEnvs := test.SetEnvironments(
+		test.Env("F8_JANITOR_STUCK_TIMEOUT", "1h"),
+		test.Env("F8_JANITOR_BATCH_SIZE", "1000"))
+	config, reset := test.LoadTestConfig(s.T())
+	return config, func() {
+		reset()
+		resetEnvs()
+	}
+}
+
+// makeStuck moves the last change of the namespaces before the stuck timeout
+func (s *TenantsUpdaterTestSuite) makeStuck(namespaces ...*tenant.Namespace) {
+	for _, ns := range namespaces {
+		err := s.DB.Model(ns).UpdateColumn("updated_at", time.Now().Add(-2*time.Hour)).Error
+		require.NoError(s.T(), err)
+	}
+}
+
+func namespaceOfType(namespaces []*tenant.Namespace, envType environment.Type) *tenant.Namespace {
+	for _, ns := range namespaces {
+		if ns.Type == envType {
+			return ns
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package update_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestLeaderTakesOverUpdateDrivenByAnotherReplica() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, time.Hour, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0"); err != nil {
+			return err
+		}
+		if err := repo.PrepareForUpdating(); err != nil {
+			return err
+		}
+		return repo.SetDriver("dead-replica")
+	})
+	elector := s.startElector()
+	defer elector.Stop()
+
+	// when
+	update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "",
+		update.WithLeader(elector)).UpdateAllTenants()
+
+	// then
+	assert.Equal(s.T(), 3, int(*updateExecutor.NumberOfCalls))
+	s.assertStatusAndAllVersionAreUpToDate(s.T(), update.Finished)
+	tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), elector.Identity(), tenantsUpdate.Driver)
+}
+
+func (s *TenantsUpdaterTestSuite) TestNewLeaderResumesUpdateOrphanedByPreviousLeader() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, time.Hour, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0"); err != nil {
+			return err
+		}
+		if err := repo.PrepareForUpdating(); err != nil {
+			return err
+		}
+		return repo.SetDriver("dead-replica")
+	})
+	elector := s.startElector()
+	defer elector.Stop()
+
+	// when
+	update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "",
+		update.WithTrigger(update.TriggerTakeover), update.WithLeader(elector)).TakeOverOngoingUpdate()
+
+	// then
+	assert.Equal(s.T(), 3, int(*updateExecutor.NumberOfCalls))
+	s.assertStatusAndAllVersionAreUpToDate(s.T(), update.Finished)
+	tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), elector.Identity(), tenantsUpdate.Driver)
+}
+
+func (s *TenantsUpdaterTestSuite) TestNewLeaderResumesUpdateInterruptedByShutdown() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, time.Hour, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0"); err != nil {
+			return err
+		}
+		if err := repo.PrepareForUpdating(); err != nil {
+			return err
+		}
+		if err := repo.SetDriver("stopped-replica"); err != nil {
+			return err
+		}
+		return repo.UpdateStatus(update.Interrupted)
+	})
+	elector := s.startElector()
+	defer elector.Stop()
+
+	// when
+	update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "",
+		update.WithTrigger(update.TriggerTakeover), update.WithLeader(elector)).TakeOverOngoingUpdate()
+
+	// then
+	assert.Equal(s.T(), 3, int(*updateExecutor.NumberOfCalls))
+	s.assertStatusAndAllVersionAreUpToDate(s.T(), update.Finished)
+}
+
+func (s *TenantsUpdaterTestSuite) TestNewLeaderDoesNotStartUpdateWhenNoneIsOngoing() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, time.Hour, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0"); err != nil {
+			return err
+		}
+		return repo.UpdateStatus(update.Finished)
+	})
+	elector := s.startElector()
+	defer elector.Stop()
+
+	// when
+	update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "",
+		update.WithTrigger(update.TriggerTakeover), update.WithLeader(elector)).TakeOverOngoingUpdate()
+
+	// then
+	assert.Equal(s.T(), 0, int(*updateExecutor.NumberOfCalls))
+	tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.Finished, tenantsUpdate.Status)
+}
+
+func (s *TenantsUpdaterTestSuite) TestUpdateRequestIsExecutedByLeader() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		if err := testupdate.UpdateVersionsTo(repo, "0"); err != nil {
+			return err
+		}
+		if err := repo.UpdateStatus(update.Finished); err != nil {
+			return err
+		}
+		return repo.AddRequest(update.NewStartRequest("", ""))
+	})
+	elector := s.startElector()
+	defer elector.Stop()
+	watcher := update.NewRequestsWatcher(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, elector)
+
+	// when
+	watcher.Start()
+	defer watcher.Stop()
+
+	// then
+	err := test.WaitWithTimeout(10 * time.Second).Until(func() error {
+		tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+		if err != nil {
+			return err
+		}
+		if tenantsUpdate.Status != update.Finished || tenantsUpdate.Driver != elector.Identity() {
+			return fmt.Errorf("the requested update hasn't been finished by the leader yet")
+		}
+		return nil
+	})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, int(*updateExecutor.NumberOfCalls))
+	requests, err := update.NewRepository(s.DB).TakeRequests()
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), requests)
+}
+
+func (s *TenantsUpdaterTestSuite) startElector() *leader.Elector {
+	elector := leader.NewElector(s.DB, s.Configuration, update.LeaderLeaseName)
+	elector.Start()
+	err := test.WaitWithTimeout(5 * time.Second).Until(func() error {
+		if !elector.IsLeader() {
+			return fmt.Errorf("the elector hasn't become the leader yet")
+		}
+		return nil
+	})
+	require.NoError(s.T(), err)
+	return elector
+}
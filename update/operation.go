@@ -0,0 +1,713 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/dbsupport"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OperationsTableName       = "tenants_operations"
+	OperationTenantsTableName = "tenants_operation_tenants"
+)
+
+// Pending is used for the tenants of a bulk operation that haven't been processed yet
+const Pending Status = "pending"
+
+// Action is an action a bulk operation performs on every selected tenant
+type Action string
+
+const (
+	// ActionUpdate updates the existing namespaces of the requested types
+	ActionUpdate Action = "update"
+	// ActionClean removes all objects from the namespaces, but keeps the namespaces and the tenant
+	ActionClean Action = "clean"
+	// ActionRecreate creates the namespaces of the requested types that are missing
+	ActionRecreate Action = "recreate-missing"
+	// ActionDelete removes the namespaces from the cluster and the tenant from DB
+	ActionDelete Action = "delete"
+)
+
+// OperationExecutor performs the actions of the bulk operations on the tenants
+type OperationExecutor interface {
+	Executor
+	// Clean removes the objects of all namespaces of the tenant - the namespaces and the tenant itself are removed too
+	// if removeFromCluster is true
+	Clean(ctx context.Context, dbTenant *tenant.Tenant, removeFromCluster bool) error
+	// Create creates the namespaces of the given types for the existing tenant
+	Create(ctx context.Context, dbTenant *tenant.Tenant, envTypes []environment.Type) error
+}
+
+// Selector selects the tenants of a bulk operation either by their IDs or by the same filters as the tenants search
+type Selector struct {
+	TenantIDs []uuid.UUID          `json:"tenant_ids,omitempty"`
+	Query     *tenant.TenantsQuery `json:"query,omitempty"`
+}
+
+// Operation is a record of a bulk operation performed on the selected tenants
+type Operation struct {
+	ID            uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	Action        Action
+	EnvTypes      string
+	Selector      string
+	Driver        string
+	Status        Status
+	CanContinue   bool
+	HaltReason    string
+	TotalCount    int
+	FinishedCount int
+	FailedCount   int
+	StartedAt     time.Time
+	FinishedAt    *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (o Operation) TableName() string {
+	return OperationsTableName
+}
+
+// GetEnvTypes returns the types of the namespaces the operation is limited to
+func (o *Operation) GetEnvTypes() []environment.Type {
+	var envTypes []environment.Type
+	for _, envType := range splitList(o.EnvTypes) {
+		envTypes = append(envTypes, environment.Type(envType))
+	}
+	return envTypes
+}
+
+// IsOngoing returns true if the tenants of the operation are still being processed
+func (o *Operation) IsOngoing() bool {
+	return o.Status == Updating
+}
+
+// OperationTenant is a result of the operation performed on one tenant
+type OperationTenant struct {
+	ID          uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	OperationID uuid.UUID `sql:"type:uuid"`
+	TenantID    uuid.UUID `sql:"type:uuid"`
+	MasterURL   string
+	EnvTypes    string
+	Namespaces  string
+	Status      Status
+	Error       string
+	CreatedAt   time.Time
+	FinishedAt  *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (t OperationTenant) TableName() string {
+	return OperationTenantsTableName
+}
+
+// GetEnvTypes returns the types of the namespaces the operation was performed on
+func (t *OperationTenant) GetEnvTypes() []string {
+	return splitList(t.EnvTypes)
+}
+
+// GetNamespaces returns the names of the namespaces the operation was performed on
+func (t *OperationTenant) GetNamespaces() []string {
+	return splitList(t.Namespaces)
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// CreateOperation stores the record of a new operation together with the results of all its tenants. The results that
+// are already failed are counted as failed, the rest of them is pending
+func (r *GormRepository) CreateOperation(operation *Operation, tenants []*OperationTenant) error {
+	if uuid.Equal(operation.ID, uuid.Nil) {
+		operation.ID = uuid.NewV4()
+	}
+	operation.Status = Updating
+	operation.CanContinue = true
+	operation.TotalCount = len(tenants)
+	operation.StartedAt = time.Now()
+	for _, result := range tenants {
+		if result.Status == Failed {
+			operation.FailedCount++
+		}
+	}
+	if err := r.tx.Create(operation).Error; err != nil {
+		return errs.Wrapf(err, "failed to create a record of the operation in %s table", OperationsTableName)
+	}
+	for _, result := range tenants {
+		result.ID = uuid.NewV4()
+		result.OperationID = operation.ID
+		result.CreatedAt = operation.StartedAt
+		if result.Status == Failed {
+			result.FinishedAt = &operation.StartedAt
+		} else {
+			result.Status = Pending
+		}
+		if err := r.tx.Create(result).Error; err != nil {
+			return errs.Wrapf(err, "failed to store tenant %s of the operation in %s table", result.TenantID, OperationTenantsTableName)
+		}
+	}
+	return nil
+}
+
+// GetOperation returns the operation with the given ID
+func (r *GormRepository) GetOperation(operationID uuid.UUID) (*Operation, error) {
+	var operation Operation
+	err := r.tx.Table(OperationsTableName).Where("id = ?", operationID).Find(&operation).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("operation", operationID.String())
+	} else if err != nil {
+		return nil, errs.Wrapf(err, "unable to lookup operation by id")
+	}
+	return &operation, nil
+}
+
+// GetOperations returns the page of the operations ordered from the latest one together with the total number of operations
+func (r *GormRepository) GetOperations(offset, limit int) ([]*Operation, int, error) {
+	var operations []*Operation
+	var count int
+	if err := r.tx.Table(OperationsTableName).Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed to count operations in %s table", OperationsTableName)
+	}
+	err := r.tx.Table(OperationsTableName).Order("started_at DESC").Offset(offset).Limit(limit).Find(&operations).Error
+	if err != nil {
+		return nil, 0, errs.Wrapf(err, "failed to get operations from %s table", OperationsTableName)
+	}
+	return operations, count, nil
+}
+
+// GetOperationTenants returns the results of all tenants of the given operation
+func (r *GormRepository) GetOperationTenants(operationID uuid.UUID) ([]*OperationTenant, error) {
+	var tenants []*OperationTenant
+	err := r.tx.Table(OperationTenantsTableName).Where("operation_id = ?", operationID).Order("created_at, id").Find(&tenants).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to get tenant results of the operation %s", operationID)
+	}
+	return tenants, nil
+}
+
+// RecordOperationResult stores the result of the operation performed on the tenant and counts it to the numbers of the operation
+func (r *GormRepository) RecordOperationResult(result *OperationTenant) error {
+	now := time.Now()
+	result.FinishedAt = &now
+	err := r.tx.Table(OperationTenantsTableName).Where("id = ?", result.ID).Updates(map[string]interface{}{
+		"master_url":  result.MasterURL,
+		"env_types":   result.EnvTypes,
+		"namespaces":  result.Namespaces,
+		"status":      result.Status,
+		"error":       result.Error,
+		"finished_at": result.FinishedAt,
+	}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to store result of tenant %s in %s table", result.TenantID, OperationTenantsTableName)
+	}
+
+	counter := "finished_count"
+	if result.Status == Failed {
+		counter = "failed_count"
+	}
+	query := fmt.Sprintf("UPDATE %[1]s SET %[2]s = %[2]s + 1 WHERE id = ?", OperationsTableName, counter)
+	if err := r.tx.Exec(query, result.OperationID).Error; err != nil {
+		return errs.Wrapf(err, "failed to increment %s of the operation %s", counter, result.OperationID)
+	}
+	return nil
+}
+
+// HasOngoingOperation returns true if there is an ongoing operation that was started or finished any of its tenants
+// after the given time. The ongoing operations without any progress since then are considered as abandoned by their driver
+func (r *GormRepository) HasOngoingOperation(activeSince time.Time) (bool, error) {
+	var count int
+	condition := fmt.Sprintf(`status = ? AND GREATEST(started_at,
+		(SELECT MAX(finished_at) FROM %[1]s WHERE %[1]s.operation_id = %[2]s.id)) > ?`, OperationTenantsTableName, OperationsTableName)
+	err := r.tx.Table(OperationsTableName).Where(condition, Updating, activeSince).Count(&count).Error
+	if err != nil {
+		return false, errs.Wrapf(err, "failed to count ongoing operations in %s table", OperationsTableName)
+	}
+	return count > 0, nil
+}
+
+// CanOperationContinue returns false if the operation was stopped
+func (r *GormRepository) CanOperationContinue(operationID uuid.UUID) (bool, error) {
+	operation, err := r.GetOperation(operationID)
+	if err != nil {
+		return false, err
+	}
+	return operation.CanContinue, nil
+}
+
+// StopOperation stops the ongoing operation - the tenants that are being processed are finished, the rest of them is skipped.
+// It returns false if the operation is not ongoing.
+func (r *GormRepository) StopOperation(operationID uuid.UUID) (bool, error) {
+	result := r.tx.Table(OperationsTableName).Where("id = ? AND status = ? AND can_continue", operationID, Updating).
+		UpdateColumn("can_continue", false)
+	if result.Error != nil {
+		return false, errs.Wrapf(result.Error, "failed to stop the operation %s", operationID)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AddOperationHaltReason appends the given reason to the list of reasons why (a part of) the operation was halted
+func (r *GormRepository) AddOperationHaltReason(operationID uuid.UUID, reason string) error {
+	query := fmt.Sprintf("UPDATE %s SET halt_reason = CONCAT_WS('; ', NULLIF(halt_reason, ''), ?) WHERE id = ?", OperationsTableName)
+	if err := r.tx.Exec(query, reason, operationID).Error; err != nil {
+		return errs.Wrapf(err, "failed to add halt_reason of the operation %s", operationID)
+	}
+	return nil
+}
+
+// FinishOperation sets the final status of the operation
+func (r *GormRepository) FinishOperation(operationID uuid.UUID, status Status) error {
+	err := r.tx.Table(OperationsTableName).Where("id = ?", operationID).
+		Updates(map[string]interface{}{"status": status, "finished_at": time.Now()}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to finish the operation %s", operationID)
+	}
+	return nil
+}
+
+// InterruptOperations marks the ongoing operations driven by the given replica as interrupted
+func (r *GormRepository) InterruptOperations(driver string) error {
+	err := r.tx.Table(OperationsTableName).Where("status = ? AND driver = ?", Updating, driver).
+		Updates(map[string]interface{}{"status": Interrupted, "finished_at": time.Now()}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to mark operations driven by %s as interrupted", driver)
+	}
+	return nil
+}
+
+// isOperationOngoing returns true if there is a bulk operation that still makes progress. Neither another operation nor
+// the automated update is started while there is any, so the tenants are never processed by both of them at the same time
+// and the number of workers per cluster doesn't exceed the configured concurrency
+func isOperationOngoing(repo Repository, config *configuration.Data) (bool, error) {
+	return repo.HasOngoingOperation(time.Now().Add(-config.GetAutomatedUpdateRetrySleep()))
+}
+
+// OperationRunner performs the bulk operations on the selected tenants. The tenants of every cluster are processed by the same
+// number of workers and with the same pacing as the automated update; the operation is halted by the same failure limits.
+// The operation is refused when the automated update or another operation is ongoing.
+type OperationRunner struct {
+	db       *gorm.DB
+	config   *configuration.Data
+	executor OperationExecutor
+	driver   string
+}
+
+// NewOperationRunner creates a runner that performs the operations in this replica identified by the given driver
+func NewOperationRunner(db *gorm.DB, config *configuration.Data, executor OperationExecutor, driver string) *OperationRunner {
+	return &OperationRunner{
+		db:       db,
+		config:   config,
+		executor: executor,
+		driver:   driver,
+	}
+}
+
+// Start selects the tenants, stores the record of the operation and performs the operation in background.
+// If no env type is given, then the operation is performed on all default env types. The selected tenants that don't
+// exist are reported as failed. It fails with a conflict when the automated update or another operation is ongoing.
+func (r *OperationRunner) Start(action Action, envTypes []environment.Type, selector *Selector) (*Operation, error) {
+	if len(envTypes) == 0 {
+		envTypes = environment.DefaultEnvTypes
+	}
+	results, err := r.selectTenants(selector)
+	if err != nil {
+		return nil, err
+	}
+	var types []string
+	for _, envType := range envTypes {
+		types = append(types, envType.String())
+	}
+	encodedSelector, err := json.Marshal(selector)
+	if err != nil {
+		return nil, err
+	}
+	operation := &Operation{
+		Action:   action,
+		EnvTypes: strings.Join(types, ","),
+		Selector: string(encodedSelector),
+		Driver:   r.driver,
+	}
+	// the check and the creation are done under the same lock as the start of the automated update
+	err = dbsupport.Transaction(r.db, lock(func(repo Repository) error {
+		tenantUpdate, err := repo.GetTenantsUpdate()
+		if err != nil {
+			return err
+		}
+		if tenantUpdate.IsOngoing() && !IsOlderThanTimeout(tenantUpdate.LastTimeUpdated, r.config) {
+			return errors.NewDataConflictError("the automated update of tenants is ongoing - the operation can be started when it is finished")
+		}
+		ongoing, err := isOperationOngoing(repo, r.config)
+		if err != nil {
+			return err
+		}
+		if ongoing {
+			return errors.NewDataConflictError("another bulk operation is ongoing - the operation can be started when it is finished")
+		}
+		return repo.CreateOperation(operation, results)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	log.Info(nil, map[string]interface{}{
+		"operation_id": operation.ID,
+		"action":       action,
+		"env_types":    envTypes,
+		"tenants":      operation.TotalCount,
+	}, "starting bulk operation")
+
+	go r.run(operation, results)
+	return operation, nil
+}
+
+// selectTenants returns the results of the selected tenants - only their IDs and clusters are kept, the tenants are loaded
+// when they are processed. The explicitly selected tenants that don't exist are returned as failed.
+func (r *OperationRunner) selectTenants(selector *Selector) ([]*OperationTenant, error) {
+	var results []*OperationTenant
+	add := func(tenantID uuid.UUID, namespaces []*tenant.Namespace) {
+		result := &OperationTenant{TenantID: tenantID}
+		if len(namespaces) > 0 {
+			result.MasterURL = namespaces[0].MasterURL
+		}
+		results = append(results, result)
+	}
+
+	if len(selector.TenantIDs) > 0 {
+		for _, tenantID := range selector.TenantIDs {
+			repo := tenant.NewTenantRepository(r.db, tenantID)
+			_, err := repo.GetTenant()
+			if _, notFound := errs.Cause(err).(errors.NotFoundError); notFound {
+				results = append(results, &OperationTenant{
+					TenantID: tenantID,
+					Status:   Failed,
+					Error:    fmt.Sprintf("the tenant %s doesn't exist", tenantID),
+				})
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			namespaces, err := repo.GetNamespaces()
+			if err != nil {
+				return nil, err
+			}
+			add(tenantID, namespaces)
+		}
+		return results, nil
+	}
+
+	if selector.Query == nil {
+		return nil, errors.NewBadParameterError("selector", "either tenant IDs or a filter has to be specified")
+	}
+	query := *selector.Query
+	query.After = ""
+	query.Limit = 100
+	for {
+		page, err := tenant.NewDBService(r.db).SearchTenants(&query)
+		if err != nil {
+			return nil, err
+		}
+		for _, tnnt := range page.Tenants {
+			add(tnnt.ID, page.Namespaces[tnnt.ID])
+		}
+		if page.Next == "" {
+			return results, nil
+		}
+		query.After = page.Next
+	}
+}
+
+// run processes the pending tenants of every cluster in a separate goroutine and sets the final status of the operation
+func (r *OperationRunner) run(operation *Operation, results []*OperationTenant) {
+	perCluster := map[string][]*OperationTenant{}
+	for _, result := range results {
+		if result.Status == Pending {
+			perCluster[result.MasterURL] = append(perCluster[result.MasterURL], result)
+		}
+	}
+
+	breakers := newClusterBreakers(r.config)
+	wg := sync.WaitGroup{}
+	wg.Add(len(perCluster))
+	for clusterURL, pending := range perCluster {
+		go func(clusterURL string, pending []*OperationTenant) {
+			defer wg.Done()
+			batch := &operationBatch{
+				runner:     r,
+				operation:  operation,
+				clusterURL: clusterURL,
+				breakers:   breakers,
+				pacer:      newPacer(r.config),
+			}
+			batch.process(pending)
+		}(clusterURL, pending)
+	}
+	wg.Wait()
+
+	err := dbsupport.Transaction(r.db, func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		finished, err := repo.GetOperation(operation.ID)
+		if err != nil {
+			return err
+		}
+		if !finished.IsOngoing() {
+			return nil
+		}
+		status := Finished
+		if shutdown.IsShuttingDown() {
+			status = Interrupted
+		} else if finished.HaltReason != "" {
+			status = Halted
+		} else if !finished.CanContinue {
+			status = Killed
+		} else if finished.FailedCount > 0 {
+			status = Failed
+		}
+		log.Info(nil, map[string]interface{}{
+			"operation_id":   operation.ID,
+			"status":         status,
+			"finished_count": finished.FinishedCount,
+			"failed_count":   finished.FailedCount,
+		}, "bulk operation has been finished")
+		return repo.FinishOperation(operation.ID, status)
+	})
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"operation_id": operation.ID,
+		}, err, "unable to set the final status of the bulk operation")
+	}
+}
+
+// operationBatch holds the tenants of one cluster that are being processed by a pool of workers
+type operationBatch struct {
+	runner     *OperationRunner
+	operation  *Operation
+	clusterURL string
+	breakers   *clusterBreakers
+	pacer      *pacer
+
+	mux      sync.Mutex
+	finished bool
+}
+
+func (b *operationBatch) process(results []*OperationTenant) {
+	concurrency := b.runner.config.GetAutomatedUpdateConcurrency()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	resultsChan := make(chan *OperationTenant)
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for result := range resultsChan {
+				b.processTenant(result)
+			}
+		}()
+	}
+
+	for _, result := range results {
+		if b.isFinished() {
+			break
+		}
+		resultsChan <- result
+	}
+	close(resultsChan)
+	wg.Wait()
+}
+
+func (b *operationBatch) processTenant(result *OperationTenant) {
+	if b.isFinished() {
+		return
+	}
+	if shutdown.IsShuttingDown() {
+		log.Info(nil, map[string]interface{}{}, "the service is shutting down - stopping the bulk operation")
+		b.finish()
+		return
+	}
+	db := b.runner.db
+	var canContinue bool
+	err := dbsupport.Transaction(db, func(tx *gorm.DB) error {
+		var err error
+		canContinue, err = NewRepository(tx).CanOperationContinue(b.operation.ID)
+		return err
+	})
+	if !canContinue || err != nil {
+		log.Info(nil, map[string]interface{}{
+			"operation_id": b.operation.ID,
+			"err":          err,
+		}, "stopping the bulk operation")
+		b.finish()
+		return
+	}
+
+	breaker := b.breakers.forCluster(b.clusterURL)
+	start := time.Now()
+	// the tenant could have been removed since the operation was started
+	tnnt, execErr := tenant.NewTenantRepository(db, result.TenantID).GetTenant()
+	if execErr == nil {
+		execErr = b.runner.execute(b.operation, tnnt, result)
+	}
+	b.pacer.record(time.Since(start), execErr)
+
+	result.Status = Finished
+	if execErr != nil {
+		result.Status = Failed
+		result.Error = execErr.Error()
+		sentry.LogError(nil, map[string]interface{}{
+			"operation_id": b.operation.ID,
+			"action":       b.operation.Action,
+			"tenant_id":    result.TenantID,
+		}, execErr, "bulk operation failed for the tenant")
+	}
+	err = dbsupport.Transaction(db, func(tx *gorm.DB) error {
+		return NewRepository(tx).RecordOperationResult(result)
+	})
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"operation_id": b.operation.ID,
+			"tenant_id":    result.TenantID,
+		}, err, "unable to store the result of the bulk operation for the tenant")
+	}
+
+	if reason := breaker.record(execErr); reason != "" {
+		b.finish()
+		b.halt(reason, execErr)
+		return
+	}
+	if breaker.isTripped() {
+		b.finish()
+		return
+	}
+	b.pacer.wait()
+}
+
+// halt records why the operation was halted for the cluster and stops the whole operation if the halt scope covers all clusters
+func (b *operationBatch) halt(reason string, lastErr error) {
+	haltAll := b.breakers.haltsAll()
+	sentry.LogError(nil, map[string]interface{}{
+		"operation_id": b.operation.ID,
+		"cluster_url":  b.clusterURL,
+		"halt_all":     haltAll,
+		"reason":       reason,
+	}, lastErr, "bulk operation was halted because of too many failed tenants")
+
+	err := dbsupport.Transaction(b.runner.db, func(tx *gorm.DB) error {
+		repo := NewRepository(tx)
+		if err := repo.AddOperationHaltReason(b.operation.ID, fmt.Sprintf("%s: %s", b.clusterURL, reason)); err != nil {
+			return err
+		}
+		if haltAll {
+			_, err := repo.StopOperation(b.operation.ID)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"operation_id": b.operation.ID,
+		}, err, "unable to halt the bulk operation")
+	}
+}
+
+func (b *operationBatch) finish() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.finished = true
+}
+
+func (b *operationBatch) isFinished() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.finished
+}
+
+// execute performs the action of the operation on the tenant and fills the types and names of the affected namespaces in the result
+func (r *OperationRunner) execute(operation *Operation, tnnt *tenant.Tenant, result *OperationTenant) error {
+	namespaces, err := tenant.NewTenantRepository(r.db, tnnt.ID).GetNamespaces()
+	if err != nil {
+		return err
+	}
+	existing := map[environment.Type]*tenant.Namespace{}
+	for _, ns := range namespaces {
+		existing[ns.Type] = ns
+	}
+
+	var envTypes []environment.Type
+	var nsNames []string
+	switch operation.Action {
+	case ActionUpdate:
+		for _, envType := range operation.GetEnvTypes() {
+			if ns, found := existing[envType]; found {
+				envTypes = append(envTypes, envType)
+				nsNames = append(nsNames, ns.Name)
+			}
+		}
+	case ActionRecreate:
+		for _, envType := range operation.GetEnvTypes() {
+			if _, found := existing[envType]; !found {
+				envTypes = append(envTypes, envType)
+			}
+		}
+	default:
+		for _, ns := range namespaces {
+			envTypes = append(envTypes, ns.Type)
+			nsNames = append(nsNames, ns.Name)
+		}
+	}
+	var types []string
+	for _, envType := range envTypes {
+		types = append(types, envType.String())
+	}
+	result.EnvTypes = strings.Join(types, ",")
+	result.Namespaces = strings.Join(nsNames, ",")
+
+	log.Info(nil, map[string]interface{}{
+		"operation_id": operation.ID,
+		"action":       operation.Action,
+		"tenant_id":    tnnt.ID,
+		"env_types":    envTypes,
+	}, "performing bulk operation on the tenant")
+
+	ctx := tenant.WithActor(nil, tenant.ActorAdmin)
+	switch operation.Action {
+	case ActionUpdate:
+		if len(envTypes) == 0 {
+			return nil
+		}
+		return r.executor.Update(ctx, tnnt, nil, envTypes, true)
+	case ActionRecreate:
+		if len(envTypes) == 0 {
+			return nil
+		}
+		return r.executor.Create(ctx, tnnt, envTypes)
+	case ActionClean:
+		return r.executor.Clean(ctx, tnnt, false)
+	case ActionDelete:
+		return r.executor.Clean(ctx, tnnt, true)
+	}
+	return fmt.Errorf("unknown action %s", operation.Action)
+}
+
+// MarkOperationsInterrupted marks the ongoing operations driven by the given replica as interrupted. It is called when
+// the replica is shut down.
+func MarkOperationsInterrupted(db *gorm.DB, driver string) error {
+	return dbsupport.Transaction(db, func(tx *gorm.DB) error {
+		return NewRepository(tx).InterruptOperations(driver)
+	})
+}
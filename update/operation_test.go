@@ -0,0 +1,373 @@
+package update_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-tenant/auth"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	testupdate "github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestOperationIsPerformedOnSelectedTenants() {
+	// given
+	config, reset := s.newOperationConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().MasterURL(newClusterURL()))
+	executor := newOperationExecutor()
+	executor.failFor[fxt.Tenants[1].ID] = true
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+
+	// when
+	operation, err := runner.Start(update.ActionClean, nil, &update.Selector{
+		TenantIDs: []uuid.UUID{fxt.Tenants[0].ID, fxt.Tenants[1].ID, fxt.Tenants[2].ID}})
+
+	// then
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, operation.TotalCount)
+	finished := s.waitForOperation(operation.ID)
+	assert.Equal(s.T(), update.Failed, finished.Status)
+	assert.Equal(s.T(), 2, finished.FinishedCount)
+	assert.Equal(s.T(), 1, finished.FailedCount)
+	assert.Equal(s.T(), "replica-1", finished.Driver)
+	assert.NotNil(s.T(), finished.FinishedAt)
+
+	results, err := update.NewRepository(s.DB).GetOperationTenants(operation.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 3)
+	for _, result := range results {
+		assert.Len(s.T(), result.GetEnvTypes(), len(environment.DefaultEnvTypes))
+		assert.Len(s.T(), result.GetNamespaces(), len(environment.DefaultEnvTypes))
+		assert.NotNil(s.T(), result.FinishedAt)
+		if result.TenantID == fxt.Tenants[1].ID {
+			assert.Equal(s.T(), update.Failed, result.Status)
+			assert.Contains(s.T(), result.Error, "unable to clean")
+		} else {
+			assert.Equal(s.T(), update.Finished, result.Status)
+			assert.Empty(s.T(), result.Error)
+		}
+		assert.Equal(s.T(), "clean", executor.calls[result.TenantID])
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestOperationReportsMissingTenantsAsFailed() {
+	// given
+	config, reset := s.newOperationConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().MasterURL(newClusterURL()))
+	missingID := uuid.NewV4()
+	executor := newOperationExecutor()
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+
+	// when
+	operation, err := runner.Start(update.ActionClean, nil, &update.Selector{TenantIDs: []uuid.UUID{missingID, fxt.Tenants[0].ID}})
+
+	// then
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2, operation.TotalCount)
+	finished := s.waitForOperation(operation.ID)
+	assert.Equal(s.T(), update.Failed, finished.Status)
+	assert.Equal(s.T(), 1, finished.FinishedCount)
+	assert.Equal(s.T(), 1, finished.FailedCount)
+	assert.Equal(s.T(), "clean", executor.calls[fxt.Tenants[0].ID])
+	assert.NotContains(s.T(), executor.calls, missingID)
+
+	results, err := update.NewRepository(s.DB).GetOperationTenants(operation.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 2)
+	for _, result := range results {
+		assert.NotNil(s.T(), result.FinishedAt)
+		if result.TenantID == missingID {
+			assert.Equal(s.T(), update.Failed, result.Status)
+			assert.Contains(s.T(), result.Error, "doesn't exist")
+		} else {
+			assert.Equal(s.T(), update.Finished, result.Status)
+		}
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestOperationAndUpdateExcludeEachOther() {
+	// given
+	config, reset := s.newOperationConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().MasterURL(newClusterURL()))
+	executor := newOperationExecutor()
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+	selector := &update.Selector{TenantIDs: []uuid.UUID{fxt.Tenants[0].ID}}
+
+	s.T().Run("operation is refused while update is ongoing", func(t *testing.T) {
+		// given
+		s.tx(t, func(repo update.Repository) error {
+			return repo.PrepareForUpdating()
+		})
+		defer s.tx(t, func(repo update.Repository) error {
+			return repo.UpdateStatus(update.Finished)
+		})
+
+		// when
+		_, err := runner.Start(update.ActionClean, nil, selector)
+
+		// then
+		test.AssertError(t, err, test.IsOfType(errors.DataConflictError{}), test.HasMessageContaining("automated update"))
+		assert.Empty(t, executor.calls)
+	})
+
+	s.T().Run("neither update nor another operation is started while operation is ongoing", func(t *testing.T) {
+		// given
+		executor.release = make(chan struct{})
+		operation, err := runner.Start(update.ActionClean, nil, selector)
+		require.NoError(t, err)
+		defer func() {
+			close(executor.release)
+			s.waitForOperation(operation.ID)
+		}()
+		s.tx(t, func(repo update.Repository) error {
+			return repo.UpdateStatus(update.Failed)
+		})
+		defer s.tx(t, func(repo update.Repository) error {
+			return repo.UpdateStatus(update.Finished)
+		})
+		updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+		tenantsUpdater, resetUpdater := s.newTenantsUpdater(updateExecutor, 10*time.Minute, update.AllTypes, "")
+		defer resetUpdater()
+
+		// when
+		tenantsUpdater.UpdateAllTenants()
+		_, err = runner.Start(update.ActionClean, nil, selector)
+
+		// then
+		test.AssertError(t, err, test.IsOfType(errors.DataConflictError{}), test.HasMessageContaining("another bulk operation"))
+		assert.Equal(t, 0, int(*updateExecutor.NumberOfCalls))
+		tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+		require.NoError(t, err)
+		assert.Equal(t, update.Failed, tenantsUpdate.Status)
+	})
+}
+
+func (s *TenantsUpdaterTestSuite) TestOperationSelectsTenantsByFilter() {
+	// given
+	config, reset := s.newOperationConfig()
+	defer reset()
+	clusterURL := newClusterURL()
+	selected := tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().MasterURL(clusterURL))
+	other := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces().MasterURL(newClusterURL()))
+	executor := newOperationExecutor()
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+
+	// when
+	operation, err := runner.Start(update.ActionUpdate, []environment.Type{environment.TypeUser},
+		&update.Selector{Query: &tenant.TenantsQuery{MasterURL: clusterURL}})
+
+	// then
+	require.NoError(s.T(), err)
+	finished := s.waitForOperation(operation.ID)
+	assert.Equal(s.T(), update.Finished, finished.Status)
+	assert.Equal(s.T(), 2, finished.TotalCount)
+	assert.Equal(s.T(), 2, finished.FinishedCount)
+	assert.Equal(s.T(), []environment.Type{environment.TypeUser}, finished.GetEnvTypes())
+	for _, tnnt := range selected.Tenants {
+		assert.Equal(s.T(), "update:user", executor.calls[tnnt.ID])
+	}
+	assert.NotContains(s.T(), executor.calls, other.Tenants[0].ID)
+
+	results, err := update.NewRepository(s.DB).GetOperationTenants(operation.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 2)
+	for _, result := range results {
+		assert.Equal(s.T(), clusterURL, result.MasterURL)
+		assert.Equal(s.T(), []string{"user"}, result.GetEnvTypes())
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestOperationRecreatesOnlyMissingNamespaces() {
+	// given
+	config, reset := s.newOperationConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddNamespaces(environment.TypeUser).MasterURL(newClusterURL()))
+	executor := newOperationExecutor()
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+
+	// when
+	operation, err := runner.Start(update.ActionRecreate, nil, &update.Selector{TenantIDs: []uuid.UUID{fxt.Tenants[0].ID}})
+
+	// then
+	require.NoError(s.T(), err)
+	finished := s.waitForOperation(operation.ID)
+	assert.Equal(s.T(), update.Finished, finished.Status)
+	assert.Equal(s.T(), "create:che", executor.calls[fxt.Tenants[0].ID])
+}
+
+func (s *TenantsUpdaterTestSuite) TestStoppedOperationSkipsPendingTenants() {
+	// given
+	config, reset := s.newOperationConfig()
+	defer reset()
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces().MasterURL(newClusterURL()))
+	executor := newOperationExecutor()
+	executor.release = make(chan struct{})
+	runner := update.NewOperationRunner(s.DB, config, executor, "replica-1")
+	operation, err := runner.Start(update.ActionDelete, nil, &update.Selector{
+		TenantIDs: []uuid.UUID{fxt.Tenants[0].ID, fxt.Tenants[1].ID, fxt.Tenants[2].ID}})
+	require.NoError(s.T(), err)
+
+	// when
+	stopped, err := update.NewRepository(s.DB).StopOperation(operation.ID)
+	require.NoError(s.T(), err)
+	close(executor.release)
+
+	// then
+	assert.True(s.T(), stopped)
+	finished := s.waitForOperation(operation.ID)
+	assert.Equal(s.T(), update.Killed, finished.Status)
+	assert.Equal(s.T(), 3, finished.TotalCount)
+	assert.True(s.T(), finished.FinishedCount < 3)
+	results, err := update.NewRepository(s.DB).GetOperationTenants(operation.ID)
+	require.NoError(s.T(), err)
+	pending := 0
+	for _, result := range results {
+		if result.Status == update.Pending {
+			pending++
+		}
+	}
+	assert.Equal(s.T(), 3-finished.FinishedCount, pending)
+
+	s.T().Run("finished operation cannot be stopped", func(t *testing.T) {
+		// when
+		stopped, err := update.NewRepository(s.DB).StopOperation(operation.ID)
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, stopped)
+	})
+}
+
+func (s *TenantsUpdaterTestSuite) TestMarkOperationsInterrupted() {
+	// given
+	repo := update.NewRepository(s.DB)
+	ongoing := &update.Operation{Action: update.ActionClean, Driver: "replica-1"}
+	require.NoError(s.T(), repo.CreateOperation(ongoing, nil))
+	ofAnother := &update.Operation{Action: update.ActionClean, Driver: "replica-2"}
+	require.NoError(s.T(), repo.CreateOperation(ofAnother, nil))
+
+	// when
+	err := update.MarkOperationsInterrupted(s.DB, "replica-1")
+
+	// then
+	require.NoError(s.T(), err)
+	interrupted, err := repo.GetOperation(ongoing.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.Interrupted, interrupted.Status)
+	assert.NotNil(s.T(), interrupted.FinishedAt)
+	running, err := repo.GetOperation(ofAnother.ID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.Updating, running.Status)
+}
+
+func (s *TenantsUpdaterTestSuite) newOperationConfig() (*configuration.Data, func()) {
+	resetEnvs := test.SetEnvironments(
+		test.Env("F8_AUTOMATED_UPDATE_TIME_GAP", "0"),
+		test.Env("F8_AUTOMATED_UPDATE_PACING_MAX_DELAY", "0"))
+	config, reset := test.LoadTestConfig(s.T())
+	return config, func() {
+		reset()
+		resetEnvs()
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) waitForOperation(operationID uuid.UUID) *update.Operation {
+	for i := 0; i < 100; i++ {
+		operation, err := update.NewRepository(s.DB).GetOperation(operationID)
+		require.NoError(s.T(), err)
+		if !operation.IsOngoing() {
+			return operation
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.FailNow(s.T(), "the operation hasn't been finished in time")
+	return nil
+}
+
+func newClusterURL() string {
+	return fmt.Sprintf("http://api.%s.example.com/", uuid.NewV4())
+}
+
+// operationExecutor records the actions performed on the tenants instead of calling the cluster
+type operationExecutor struct {
+	mux           sync.Mutex
+	calls         map[uuid.UUID]string
+	failFor       map[uuid.UUID]bool
+	release       chan struct{}
+	missing       map[string]bool
+	inspectionErr error
+	idled         []string
+}
+
+func newOperationExecutor() *operationExecutor {
+	return &operationExecutor{
+		calls:   map[uuid.UUID]string{},
+		failFor: map[uuid.UUID]bool{},
+		missing: map[string]bool{},
+	}
+}
+
+func (e *operationExecutor) NamespaceExists(ctx context.Context, namespace *tenant.Namespace) (bool, error) {
+	if e.inspectionErr != nil {
+		return false, e.inspectionErr
+	}
+	return !e.missing[namespace.Name], nil
+}
+
+func (e *operationExecutor) Update(ctx context.Context, dbTenant *tenant.Tenant, user *auth.User, envTypes []environment.Type, allowSelfHealing bool) error {
+	return e.record(dbTenant, "update", envTypes)
+}
+
+func (e *operationExecutor) Clean(ctx context.Context, dbTenant *tenant.Tenant, removeFromCluster bool) error {
+	if removeFromCluster {
+		return e.record(dbTenant, "delete", nil)
+	}
+	return e.record(dbTenant, "clean", nil)
+}
+
+func (e *operationExecutor) Create(ctx context.Context, dbTenant *tenant.Tenant, envTypes []environment.Type) error {
+	return e.record(dbTenant, "create", envTypes)
+}
+
+func (e *operationExecutor) Idle(ctx context.Context, namespace *tenant.Namespace) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if e.failFor[namespace.TenantID] {
+		return fmt.Errorf("unable to idle the namespace %s", namespace.Name)
+	}
+	e.idled = append(e.idled, namespace.Name)
+	return nil
+}
+
+func (e *operationExecutor) record(dbTenant *tenant.Tenant, action string, envTypes []environment.Type) error {
+	if e.release != nil {
+		<-e.release
+	}
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	for idx, envType := range envTypes {
+		separator := ","
+		if idx == 0 {
+			separator = ":"
+		}
+		action += separator + envType.String()
+	}
+	e.calls[dbTenant.ID] = action
+	if e.failFor[dbTenant.ID] {
+		return fmt.Errorf("unable to %s the tenant", action)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package update
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"sync"
+	"time"
+)
+
+const minBackoffDelay = time.Second
+
+// pacer adapts the delay between tenant updates on one cluster to the latency and error rate of the cluster API.
+// The delay is doubled when an update fails or takes longer than the target latency and it is gradually
+// decreased back to the minimal delay when the updates are fast and successful.
+type pacer struct {
+	mux           sync.Mutex
+	minDelay      time.Duration
+	maxDelay      time.Duration
+	targetLatency time.Duration
+	delay         time.Duration
+}
+
+func newPacer(config *configuration.Data) *pacer {
+	minDelay := config.GetAutomatedUpdateTimeGap()
+	maxDelay := config.GetAutomatedUpdatePacingMaxDelay()
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	return &pacer{
+		minDelay:      minDelay,
+		maxDelay:      maxDelay,
+		targetLatency: config.GetAutomatedUpdatePacingTargetLatency(),
+		delay:         minDelay,
+	}
+}
+
+func (p *pacer) record(latency time.Duration, err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if err != nil || (p.targetLatency > 0 && latency > p.targetLatency) {
+		p.delay = p.delay * 2
+		if p.delay < minBackoffDelay {
+			p.delay = minBackoffDelay
+		}
+	} else {
+		p.delay = p.delay - p.delay/4
+	}
+
+	if p.delay > p.maxDelay {
+		p.delay = p.maxDelay
+	}
+	if p.delay < p.minDelay {
+		p.delay = p.minDelay
+	}
+}
+
+func (p *pacer) currentDelay() time.Duration {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.delay
+}
+
+func (p *pacer) wait() {
+	time.Sleep(p.currentDelay())
+}
@@ -0,0 +1,75 @@
+package update
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestPacer(minDelay, maxDelay time.Duration) *pacer {
+	return &pacer{
+		minDelay:      minDelay,
+		maxDelay:      maxDelay,
+		targetLatency: time.Second,
+		delay:         minDelay,
+	}
+}
+
+func TestPacerIncreasesDelayWhenUpdateFails(t *testing.T) {
+	// given
+	p := newTestPacer(0, 10*time.Second)
+
+	// when
+	p.record(time.Millisecond, fmt.Errorf("failed"))
+	p.record(time.Millisecond, fmt.Errorf("failed"))
+
+	// then
+	assert.Equal(t, 2*time.Second, p.currentDelay())
+}
+
+func TestPacerIncreasesDelayWhenUpdateIsSlow(t *testing.T) {
+	// given
+	p := newTestPacer(2*time.Second, 10*time.Second)
+
+	// when
+	p.record(2*time.Second, nil)
+
+	// then
+	assert.Equal(t, 4*time.Second, p.currentDelay())
+}
+
+func TestPacerDoesNotExceedMaxDelay(t *testing.T) {
+	// given
+	p := newTestPacer(time.Second, 5*time.Second)
+
+	// when
+	for i := 0; i < 10; i++ {
+		p.record(time.Millisecond, fmt.Errorf("failed"))
+	}
+
+	// then
+	assert.Equal(t, 5*time.Second, p.currentDelay())
+}
+
+func TestPacerDecreasesDelayBackToMinDelay(t *testing.T) {
+	// given
+	p := newTestPacer(time.Second, 8*time.Second)
+	p.record(time.Millisecond, fmt.Errorf("failed"))
+	p.record(time.Millisecond, fmt.Errorf("failed"))
+	assert.Equal(t, 4*time.Second, p.currentDelay())
+
+	// when
+	p.record(time.Millisecond, nil)
+
+	// then
+	assert.Equal(t, 3*time.Second, p.currentDelay())
+
+	// and when
+	for i := 0; i < 10; i++ {
+		p.record(time.Millisecond, nil)
+	}
+
+	// then
+	assert.Equal(t, time.Second, p.currentDelay())
+}
@@ -0,0 +1,82 @@
+package update
+
+import (
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// Purger periodically removes the soft-deleted tenants and namespaces from DB when the retention period expires.
+// Until then the deleted tenant can be restored and the names of its namespaces aren't used by any other tenant.
+// Only the leader purges the entities so the same records aren't removed by several replicas at once.
+type Purger struct {
+	db      *gorm.DB
+	config  *configuration.Data
+	elector *leader.Elector
+	stop    chan struct{}
+}
+
+// NewPurger creates a purger of the soft-deleted entities
+func NewPurger(db *gorm.DB, config *configuration.Data, elector *leader.Elector) *Purger {
+	return &Purger{
+		db:      db,
+		config:  config,
+		elector: elector,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start starts purging the soft-deleted entities in the configured interval
+func (p *Purger) Start() {
+	go func() {
+		ticker := time.NewTicker(p.config.GetPurgeCheckInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+			if p.elector.IsLeader() && !shutdown.IsShuttingDown() {
+				p.PurgeDeleted()
+			}
+		}
+	}()
+}
+
+// Stop stops the purger
+func (p *Purger) Stop() {
+	close(p.stop)
+}
+
+// PurgeDeleted removes the tenants and namespaces that were deleted before the retention period - batch by batch
+// until there is nothing left to purge
+func (p *Purger) PurgeDeleted() {
+	tenantService := tenant.NewDBService(p.db)
+	deletedBefore := time.Now().Add(-p.config.GetPurgeRetention())
+	batchSize := p.config.GetPurgeBatchSize()
+	for !shutdown.IsShuttingDown() {
+		purged, err := tenantService.PurgeDeleted(deletedBefore, batchSize)
+		if err != nil {
+			sentry.LogError(nil, map[string]interface{}{
+				"deleted_before": deletedBefore,
+			}, err, "unable to purge the deleted tenants and namespaces")
+			return
+		}
+		if purged.Tenants > 0 || purged.Namespaces > 0 {
+			log.Info(nil, map[string]interface{}{
+				"deleted_before": deletedBefore,
+				"tenants":        purged.Tenants,
+				"namespaces":     purged.Namespaces,
+			}, "deleted tenants and namespaces purged")
+		}
+		if purged.Tenants < batchSize && purged.Namespaces < batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package update_test
+
+import (
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestPurgerRemovesTenantsDeletedBeforeRetention() {
+	// given
+	resetEnvs := test.SetEnvironments(
+		test.Env("F8_PURGE_RETENTION", "1h"),
+		test.Env("F8_PURGE_BATCH_SIZE", "1"))
+	defer resetEnvs()
+	config, reset := test.LoadTestConfig(s.T())
+	defer reset()
+	expired := tf.FillDB(s.T(), s.DB, tf.AddTenants(3), tf.AddDefaultNamespaces())
+	retained := tf.FillDB(s.T(), s.DB, tf.AddTenants(1), tf.AddDefaultNamespaces())
+	for _, fxt := range []*tf.TestFixture{expired, retained} {
+		for _, tnnt := range fxt.Tenants {
+			repo := tenant.NewTenantRepository(s.DB, tnnt.ID)
+			require.NoError(s.T(), repo.DeleteNamespaces())
+			require.NoError(s.T(), repo.DeleteTenant())
+		}
+	}
+	for _, tnnt := range expired.Tenants {
+		err := s.DB.Unscoped().Model(tnnt).UpdateColumn("deleted_at", time.Now().Add(-2*time.Hour)).Error
+		require.NoError(s.T(), err)
+	}
+
+	// when
+	update.NewPurger(s.DB, config, nil).PurgeDeleted()
+
+	// then
+	for _, tnnt := range expired.Tenants {
+		_, err := tenant.NewTenantRepository(s.DB, tnnt.ID).GetDeletedTenant()
+		assert.Error(s.T(), err)
+	}
+	_, err := tenant.NewTenantRepository(s.DB, retained.Tenants[0].ID).GetDeletedTenant()
+	assert.NoError(s.T(), err)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/dbsupport"
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
 	"time"
 )
 
@@ -19,6 +20,11 @@ const (
 	Failed     Status = "failed"
 	Killed     Status = "killed"
 	Incomplete Status = "incomplete"
+	Halted     Status = "halted"
+	Postponed  Status = "postponed"
+	Paused     Status = "paused"
+	// Interrupted is used for updates that were stopped because the replica driving the update was shut down
+	Interrupted Status = "interrupted"
 )
 
 // Value - Implementation of valuer for database/sql
@@ -48,6 +54,39 @@ func (s Status) String() string {
 	return string(s)
 }
 
+type Stage string
+
+const (
+	// Canary is a stage when only the selected cohort of tenants is being updated
+	Canary Stage = "canary"
+	// Rollout is a stage when the rest of the tenants is being updated
+	Rollout Stage = "rollout"
+)
+
+// Value - Implementation of valuer for database/sql
+func (s Stage) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+// Scan - Implement the database/sql scanner interface
+func (s *Stage) Scan(value interface{}) error {
+	if value == nil {
+		*s = Stage("")
+		return nil
+	}
+	if bv, err := driver.String.ConvertValue(value); err == nil {
+		if v, ok := bv.(string); ok {
+			*s = Stage(v)
+			return nil
+		}
+	}
+	return errors.New("failed to scan stage")
+}
+
+func (s Stage) String() string {
+	return string(s)
+}
+
 type TenantsUpdate struct {
 	LastVersionFabric8TenantUserFile          string
 	LastVersionFabric8TenantCheMtFile         string
@@ -59,17 +98,63 @@ type TenantsUpdate struct {
 	FailedCount                               int
 	LastTimeUpdated                           time.Time
 	CanContinue                               bool
+	Stage                                     Stage
+	HaltReason                                string
+	RunID                                     *uuid.UUID `sql:"type:uuid"`
+	Driver                                    string
+}
+
+// IsOngoing returns true if the update is either running or paused
+func (tu *TenantsUpdate) IsOngoing() bool {
+	return tu.Status == Updating || tu.Status == Paused
 }
 
 type Repository interface {
 	GetTenantsUpdate() (*TenantsUpdate, error)
 	SaveTenantsUpdate(tenantUpdate *TenantsUpdate) error
 	UpdateStatus(status Status) error
+	UpdateStage(stage Stage) error
 	UpdateLastTimeUpdated() error
 	PrepareForUpdating() error
 	IncrementFailedCount() error
 	CanContinue() (bool, error)
 	Stop() error
+	SetDriver(driver string) error
+	Pause() (bool, error)
+	Resume() (bool, error)
+	AddHaltReason(reason string) error
+	StartRun(run *Run) error
+	SyncRun() error
+	RecordTenantResult(result *RunTenant) error
+	GetRuns(offset, limit int) ([]*Run, int, error)
+	GetRun(runID uuid.UUID) (*Run, error)
+	GetRunClusters(runID uuid.UUID) ([]*RunCluster, error)
+	GetRunTenants(runID uuid.UUID) ([]*RunTenant, error)
+	GetFailedTenants(tenantIDs []uuid.UUID) ([]*RunTenant, error)
+	AddRequest(request *Request) error
+	TakeRequests() ([]*Request, error)
+	AddWorkItem(item *WorkItem) error
+	ClaimWorkItem(worker, commit string) (*WorkItem, error)
+	HeartbeatWorkItem(itemID uuid.UUID, worker string) (bool, error)
+	FinishWorkItem(item *WorkItem) error
+	ReleaseWorkItem(item *WorkItem) error
+	ReassignStaleWorkItems(runID uuid.UUID, staleTimeout time.Duration, maxAttempts int) error
+	CancelWorkItems(runID uuid.UUID, clusterURL string) error
+	GetWorkItems(runID uuid.UUID) ([]*WorkItem, error)
+	RecordBreakerResult(runID uuid.UUID, clusterURL string, failed bool) (*RunBreaker, error)
+	TripBreaker(runID uuid.UUID, clusterURL string) (bool, error)
+	IsBreakerTripped(runID uuid.UUID, clusterURL string) (bool, error)
+	CreateOperation(operation *Operation, tenants []*OperationTenant) error
+	GetOperation(operationID uuid.UUID) (*Operation, error)
+	GetOperations(offset, limit int) ([]*Operation, int, error)
+	GetOperationTenants(operationID uuid.UUID) ([]*OperationTenant, error)
+	RecordOperationResult(result *OperationTenant) error
+	HasOngoingOperation(activeSince time.Time) (bool, error)
+	CanOperationContinue(operationID uuid.UUID) (bool, error)
+	StopOperation(operationID uuid.UUID) (bool, error)
+	AddOperationHaltReason(operationID uuid.UUID, reason string) error
+	FinishOperation(operationID uuid.UUID, status Status) error
+	InterruptOperations(driver string) error
 }
 
 type GormRepository struct {
@@ -115,9 +200,19 @@ func (r *GormRepository) UpdateStatus(status Status) error {
 	return nil
 }
 
+func (r *GormRepository) UpdateStage(stage Stage) error {
+	err := r.tx.Table(TenantsUpdateTableName).UpdateColumn("stage", stage).Error
+	if err != nil {
+		return errors.Wrapf(err, "failed to update stage in %s table", TenantsUpdateTableName)
+	}
+	return nil
+}
+
 func (r *GormRepository) PrepareForUpdating() error {
 	err := r.tx.Table(TenantsUpdateTableName).
 		UpdateColumn("status", Updating).
+		UpdateColumn("stage", "").
+		UpdateColumn("halt_reason", "").
 		UpdateColumn("failed_count", 0).
 		UpdateColumn("last_time_updated", time.Now()).
 		UpdateColumn("can_continue", true).Error
@@ -152,6 +247,43 @@ func (r *GormRepository) Stop() error {
 	return nil
 }
 
+// SetDriver sets the identity of the replica that drives the update
+func (r *GormRepository) SetDriver(driver string) error {
+	err := r.tx.Table(TenantsUpdateTableName).UpdateColumn("driver", driver).Error
+	if err != nil {
+		return errors.Wrapf(err, "failed to update driver in %s table", TenantsUpdateTableName)
+	}
+	return nil
+}
+
+// Pause pauses the ongoing update. It returns false if there is no running update that could be paused
+func (r *GormRepository) Pause() (bool, error) {
+	result := r.tx.Table(TenantsUpdateTableName).Where("status = ? AND can_continue", Updating).UpdateColumn("status", Paused)
+	if result.Error != nil {
+		return false, errors.Wrapf(result.Error, "failed to set status to paused in %s table", TenantsUpdateTableName)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Resume resumes the paused update. It returns false if there is no paused update
+func (r *GormRepository) Resume() (bool, error) {
+	result := r.tx.Table(TenantsUpdateTableName).Where("status = ?", Paused).
+		UpdateColumns(map[string]interface{}{"status": Updating, "last_time_updated": time.Now()})
+	if result.Error != nil {
+		return false, errors.Wrapf(result.Error, "failed to set status to updating in %s table", TenantsUpdateTableName)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AddHaltReason appends the given reason to the list of reasons why (a part of) the update was halted
+func (r *GormRepository) AddHaltReason(reason string) error {
+	query := fmt.Sprintf("UPDATE %s SET halt_reason = CONCAT_WS('; ', NULLIF(halt_reason, ''), ?)", TenantsUpdateTableName)
+	if err := r.tx.Exec(query, reason).Error; err != nil {
+		return errors.Wrapf(err, "failed to add halt_reason in %s table", TenantsUpdateTableName)
+	}
+	return nil
+}
+
 const TenantsUpdateAdvisoryLockID = 4242
 
 func lock(do func(repo Repository) error) dbsupport.LockAndDo {
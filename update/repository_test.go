@@ -322,3 +322,59 @@ func (s *UpdateRepoTestSuite) TestStopAndCanContinue() {
 		assert.False(t, canContinue)
 	})
 }
+
+func (s *UpdateRepoTestSuite) TestPauseAndResume() {
+	// given
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		return update.NewRepository(tx).PrepareForUpdating()
+	})
+	require.NoError(s.T(), err)
+	repo := update.NewRepository(s.DB)
+
+	s.T().Run("running update is paused", func(t *testing.T) {
+		// when
+		paused, err := repo.Pause()
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, paused)
+		tenantsUpdate, err := repo.GetTenantsUpdate()
+		require.NoError(t, err)
+		assert.Equal(t, update.Paused, tenantsUpdate.Status)
+		assert.True(t, tenantsUpdate.IsOngoing())
+	})
+
+	s.T().Run("paused update cannot be paused again", func(t *testing.T) {
+		// when
+		paused, err := repo.Pause()
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, paused)
+	})
+
+	s.T().Run("paused update is resumed", func(t *testing.T) {
+		// given
+		before := time.Now()
+
+		// when
+		resumed, err := repo.Resume()
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, resumed)
+		tenantsUpdate, err := repo.GetTenantsUpdate()
+		require.NoError(t, err)
+		assert.Equal(t, update.Updating, tenantsUpdate.Status)
+		assert.True(t, before.Before(tenantsUpdate.LastTimeUpdated))
+	})
+
+	s.T().Run("running update cannot be resumed", func(t *testing.T) {
+		// when
+		resumed, err := repo.Resume()
+
+		// then
+		require.NoError(t, err)
+		assert.False(t, resumed)
+	})
+}
@@ -0,0 +1,186 @@
+package update
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+const (
+	RequestsTableName = "tenants_update_requests"
+	// LeaderLeaseName is the name of the lease held by the replica that drives the automated updates
+	LeaderLeaseName = "automated-update"
+)
+
+// RequestAction says what should be done by the leader when it takes the request
+type RequestAction string
+
+const (
+	// RequestStart requests an update of all outdated tenants
+	RequestStart RequestAction = "start"
+	// RequestRetry requests a retry of the tenants that failed in the latest update run
+	RequestRetry RequestAction = "retry"
+)
+
+// Request is an update requested via the REST endpoint of a replica that is not the leader. The leader takes
+// the request from the DB and executes it
+type Request struct {
+	ID          uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	Action      RequestAction
+	EnvType     string
+	ClusterURL  string
+	TenantIDs   string
+	RequestedAt time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (r Request) TableName() string {
+	return RequestsTableName
+}
+
+// NewStartRequest creates a request of an update of all outdated tenants limited to the given env type and cluster
+func NewStartRequest(envType, clusterURL string) *Request {
+	return &Request{
+		Action:     RequestStart,
+		EnvType:    envType,
+		ClusterURL: clusterURL,
+	}
+}
+
+// NewRetryRequest creates a request of a retry of the given failed tenants
+func NewRetryRequest(tenantIDs []uuid.UUID) *Request {
+	var ids []string
+	for _, id := range tenantIDs {
+		ids = append(ids, id.String())
+	}
+	return &Request{
+		Action:    RequestRetry,
+		TenantIDs: strings.Join(ids, ","),
+	}
+}
+
+// GetTenantIDs returns the IDs of the tenants that should be retried
+func (r *Request) GetTenantIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	if r.TenantIDs == "" {
+		return ids
+	}
+	for _, id := range strings.Split(r.TenantIDs, ",") {
+		if tenantID, err := uuid.FromString(id); err == nil {
+			ids = append(ids, tenantID)
+		}
+	}
+	return ids
+}
+
+// AddRequest stores the request so it can be taken by the leader
+func (r *GormRepository) AddRequest(request *Request) error {
+	request.ID = uuid.NewV4()
+	request.RequestedAt = time.Now()
+	if err := r.tx.Create(request).Error; err != nil {
+		return errs.Wrapf(err, "failed to store update request in %s table", RequestsTableName)
+	}
+	return nil
+}
+
+// TakeRequests removes all stored requests and returns them ordered from the oldest one
+func (r *GormRepository) TakeRequests() ([]*Request, error) {
+	var requests []*Request
+	query := fmt.Sprintf("WITH taken AS (DELETE FROM %s RETURNING *) SELECT * FROM taken ORDER BY requested_at", RequestsTableName)
+	if err := r.tx.Raw(query).Scan(&requests).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed to take update requests from %s table", RequestsTableName)
+	}
+	return requests, nil
+}
+
+// RequestsWatcher periodically takes the update requests stored by other replicas and executes them. Only the leader
+// takes the requests, so the update is always driven by the leader no matter which replica received the REST call.
+type RequestsWatcher struct {
+	db             *gorm.DB
+	config         *configuration.Data
+	clusterService cluster.Service
+	updateExecutor Executor
+	elector        *leader.Elector
+	stop           chan struct{}
+}
+
+// NewRequestsWatcher creates a watcher of the update requests
+func NewRequestsWatcher(db *gorm.DB, config *configuration.Data, clusterService cluster.Service, updateExecutor Executor,
+	elector *leader.Elector) *RequestsWatcher {
+	return &RequestsWatcher{
+		db:             db,
+		config:         config,
+		clusterService: clusterService,
+		updateExecutor: updateExecutor,
+		elector:        elector,
+		stop:           make(chan struct{}),
+	}
+}
+
+// Start starts checking the update requests in the configured interval
+func (w *RequestsWatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(w.config.GetAutomatedUpdateRequestsCheckInterval())
+		defer ticker.Stop()
+		for {
+			if w.elector.IsLeader() {
+				w.executeRequests()
+			}
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the watcher - an ongoing update is not interrupted
+func (w *RequestsWatcher) Stop() {
+	close(w.stop)
+}
+
+func (w *RequestsWatcher) executeRequests() {
+	requests, err := NewRepository(w.db).TakeRequests()
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to take update requests")
+		return
+	}
+	for _, request := range requests {
+		log.Info(nil, map[string]interface{}{
+			"action":       request.Action,
+			"env_type":     request.EnvType,
+			"cluster_url":  request.ClusterURL,
+			"tenant_ids":   request.TenantIDs,
+			"requested_at": request.RequestedAt,
+		}, "executing update request received by another replica")
+
+		switch request.Action {
+		case RequestStart:
+			var envTypesFilter FilterEnvType = AllTypes
+			if request.EnvType != "" {
+				envTypesFilter = OneType(environment.Type(request.EnvType))
+			}
+			go NewTenantsUpdater(w.db, w.config, w.clusterService, w.updateExecutor, envTypesFilter, request.ClusterURL,
+				WithTrigger(TriggerAPI), WithLeader(w.elector)).UpdateAllTenants()
+		case RequestRetry:
+			go NewTenantsUpdater(w.db, w.config, w.clusterService, w.updateExecutor, AllTypes, "",
+				WithLeader(w.elector)).RetryFailedTenants(request.GetTenantIDs())
+		default:
+			log.Warn(nil, map[string]interface{}{
+				"action": request.Action,
+			}, "unknown action of the update request - skipping it")
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package update_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/assertion"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"testing"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestRetryFailedTenants() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	s.T().Run("all failed tenants are retried even if they were updated by the current commit", func(t *testing.T) {
+		*updateExecutor.NumberOfCalls = 0
+		fxt := s.failedUpdateOfTenants(t, 3, 2)
+		before := time.Now()
+
+		// when
+		update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "").
+			RetryFailedTenants(nil)
+
+		// then
+		assert.Equal(t, 2, int(*updateExecutor.NumberOfCalls))
+		s.assertLatestRun(t, update.Finished)
+		for idx, tnnt := range fxt.Tenants {
+			assertion.AssertTenantFromDB(t, s.DB, tnnt.ID).HasNamespacesThat(func(nsAssertion *assertion.NamespaceAssertion) {
+				if idx < 2 {
+					nsAssertion.HasCurrentCompleteVersion().HasState(tenant.Ready).WasUpdatedAfter(before)
+				} else {
+					nsAssertion.HasVersion("0000").HasState(tenant.Failed).WasUpdatedBefore(before)
+				}
+			})
+		}
+		failed, err := update.NewRepository(s.DB).GetFailedTenants(nil)
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+	})
+
+	s.T().Run("only selected failed tenants are retried", func(t *testing.T) {
+		*updateExecutor.NumberOfCalls = 0
+		fxt := s.failedUpdateOfTenants(t, 2, 2)
+
+		// when
+		update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "").
+			RetryFailedTenants([]uuid.UUID{fxt.Tenants[0].ID})
+
+		// then
+		assert.Equal(t, 1, int(*updateExecutor.NumberOfCalls))
+		s.assertLatestRun(t, update.Incomplete)
+		failed, err := update.NewRepository(s.DB).GetFailedTenants(nil)
+		require.NoError(t, err)
+		require.Len(t, failed, 1)
+		assert.Equal(t, fxt.Tenants[1].ID, failed[0].TenantID)
+		assert.Equal(t, "the update failed", failed[0].Error)
+	})
+}
+
+// failedUpdateOfTenants creates tenants with failed namespaces updated by the current commit and records a failed update run
+// of the given number of them
+func (s *TenantsUpdaterTestSuite) failedUpdateOfTenants(t *testing.T, numberOfTenants, numberOfFailed int) *tf.TestFixture {
+	configuration.Commit = "xyz"
+	fxt := tf.FillDB(t, s.DB, tf.AddTenants(numberOfTenants), tf.AddDefaultNamespaces().State(tenant.Failed).Outdated())
+	run := &update.Run{TriggeredBy: update.TriggerAPI}
+	s.tx(t, func(repo update.Repository) error {
+		if err := repo.PrepareForUpdating(); err != nil {
+			return err
+		}
+		if err := repo.StartRun(run); err != nil {
+			return err
+		}
+		for _, tnnt := range fxt.Tenants[:numberOfFailed] {
+			err := repo.RecordTenantResult(update.NewRunTenant(run.ID, tnnt.ID, test.ClusterURL, environment.DefaultEnvTypes, nil,
+				fmt.Errorf("the update failed")))
+			if err != nil {
+				return err
+			}
+		}
+		if err := repo.UpdateStatus(update.Failed); err != nil {
+			return err
+		}
+		return repo.SyncRun()
+	})
+	return fxt
+}
+
+func (s *TenantsUpdaterTestSuite) assertLatestRun(t *testing.T, status update.Status) {
+	repo := update.NewRepository(s.DB)
+	tenantsUpdate, err := repo.GetTenantsUpdate()
+	require.NoError(t, err)
+	assert.Equal(t, status, tenantsUpdate.Status)
+	run, err := repo.GetRun(*tenantsUpdate.RunID)
+	require.NoError(t, err)
+	assert.Equal(t, update.TriggerRetry, run.TriggeredBy)
+	assert.Equal(t, status, run.Status)
+}
@@ -0,0 +1,309 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+const (
+	RunsTableName        = "tenants_update_runs"
+	RunClustersTableName = "tenants_update_run_clusters"
+	RunTenantsTableName  = "tenants_update_run_tenants"
+)
+
+// Trigger says what started an update run
+type Trigger string
+
+const (
+	// TriggerStartup is used for runs started when the service starts
+	TriggerStartup Trigger = "startup"
+	// TriggerSchedule is used for runs started when a maintenance window is opened
+	TriggerSchedule Trigger = "schedule"
+	// TriggerAPI is used for runs started via the REST endpoint
+	TriggerAPI Trigger = "api"
+	// TriggerRetry is used for runs that retry the tenants that failed in the previous runs
+	TriggerRetry Trigger = "retry"
+	// TriggerTakeover is used for runs resuming an update that was left ongoing by the previous leader
+	TriggerTakeover Trigger = "takeover"
+)
+
+// Run is a record of one update run. The tenants_update row is a view of the latest run
+type Run struct {
+	ID             uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	TriggeredBy    Trigger
+	EnvTypeFilter  string
+	ClusterFilter  string
+	Status         Status
+	Stage          Stage
+	HaltReason     string
+	FailedCount    int
+	VersionsBefore string
+	VersionsAfter  string
+	StartedAt      time.Time
+	FinishedAt     *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (r Run) TableName() string {
+	return RunsTableName
+}
+
+// RunCluster holds the numbers of updated and failed tenants of one cluster within a run
+type RunCluster struct {
+	RunID        uuid.UUID `sql:"type:uuid"`
+	MasterURL    string
+	UpdatedCount int
+	FailedCount  int
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (r RunCluster) TableName() string {
+	return RunClustersTableName
+}
+
+// RunTenant is a result of an update of one tenant within a run
+type RunTenant struct {
+	ID         uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	RunID      uuid.UUID `sql:"type:uuid"`
+	TenantID   uuid.UUID `sql:"type:uuid"`
+	MasterURL  string
+	EnvTypes   string
+	Namespaces string
+	Status     Status
+	Error      string
+	CreatedAt  time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (r RunTenant) TableName() string {
+	return RunTenantsTableName
+}
+
+// NewRunTenant creates a result of the update of the given tenant namespaces
+func NewRunTenant(runID, tenantID uuid.UUID, masterURL string, envTypes []environment.Type, nsNames []string, err error) *RunTenant {
+	var types []string
+	for _, envType := range envTypes {
+		types = append(types, envType.String())
+	}
+	result := &RunTenant{
+		RunID:      runID,
+		TenantID:   tenantID,
+		MasterURL:  masterURL,
+		EnvTypes:   strings.Join(types, ","),
+		Namespaces: strings.Join(nsNames, ","),
+		Status:     Finished,
+	}
+	if err != nil {
+		result.Status = Failed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// GetEnvTypes returns the list of environment types that were updated
+func (r *RunTenant) GetEnvTypes() []string {
+	if r.EnvTypes == "" {
+		return nil
+	}
+	return strings.Split(r.EnvTypes, ",")
+}
+
+// GetNamespaces returns the names of the namespaces that were updated
+func (r *RunTenant) GetNamespaces() []string {
+	if r.Namespaces == "" {
+		return nil
+	}
+	return strings.Split(r.Namespaces, ",")
+}
+
+// FileVersions maps names of the template files to the versions stored in the given tenants update
+func FileVersions(tu *TenantsUpdate) map[string]string {
+	versions := map[string]string{}
+	for _, versionManager := range RetrieveVersionManagers() {
+		versions[versionManager.FileName] = versionManager.GetStoredVersion(tu)
+	}
+	return versions
+}
+
+// GetVersionsBefore returns the versions of the template files used when the run was started
+func (r *Run) GetVersionsBefore() map[string]string {
+	return unmarshalVersions(r.VersionsBefore)
+}
+
+// GetVersionsAfter returns the versions of the template files stored when the run was finished
+func (r *Run) GetVersionsAfter() map[string]string {
+	return unmarshalVersions(r.VersionsAfter)
+}
+
+func marshalVersions(versions map[string]string) string {
+	bytes, err := json.Marshal(versions)
+	if err != nil {
+		return ""
+	}
+	return string(bytes)
+}
+
+func unmarshalVersions(versions string) map[string]string {
+	result := map[string]string{}
+	if versions != "" {
+		json.Unmarshal([]byte(versions), &result)
+	}
+	return result
+}
+
+// StartRun creates a record of a new run and makes it the latest one. Runs that were not finished are marked as failed
+func (r *GormRepository) StartRun(run *Run) error {
+	tenantsUpdate, err := r.GetTenantsUpdate()
+	if err != nil {
+		return err
+	}
+	err = r.tx.Table(RunsTableName).Where("finished_at IS NULL").
+		Updates(map[string]interface{}{"status": Failed, "finished_at": time.Now()}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to finish previous runs in %s table", RunsTableName)
+	}
+
+	if uuid.Equal(run.ID, uuid.Nil) {
+		run.ID = uuid.NewV4()
+	}
+	run.Status = Updating
+	run.StartedAt = time.Now()
+	run.VersionsBefore = marshalVersions(FileVersions(tenantsUpdate))
+	if err := r.tx.Create(run).Error; err != nil {
+		return errs.Wrapf(err, "failed to create a record of the update run in %s table", RunsTableName)
+	}
+	err = r.tx.Table(TenantsUpdateTableName).UpdateColumn("run_id", run.ID).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to update run_id in %s table", TenantsUpdateTableName)
+	}
+	return nil
+}
+
+// SyncRun copies the current state of the tenants update to the record of the latest run
+func (r *GormRepository) SyncRun() error {
+	tenantsUpdate, err := r.GetTenantsUpdate()
+	if err != nil {
+		return err
+	}
+	if tenantsUpdate.RunID == nil {
+		return nil
+	}
+	values := map[string]interface{}{
+		"status":         tenantsUpdate.Status,
+		"stage":          tenantsUpdate.Stage,
+		"halt_reason":    tenantsUpdate.HaltReason,
+		"failed_count":   tenantsUpdate.FailedCount,
+		"versions_after": marshalVersions(FileVersions(tenantsUpdate)),
+		"finished_at":    nil,
+	}
+	if !tenantsUpdate.IsOngoing() {
+		values["finished_at"] = time.Now()
+	}
+	err = r.tx.Table(RunsTableName).Where("id = ?", *tenantsUpdate.RunID).Updates(values).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to update the latest run in %s table", RunsTableName)
+	}
+	return nil
+}
+
+// RecordTenantResult stores the result of a tenant update and counts it to the numbers of its cluster
+func (r *GormRepository) RecordTenantResult(result *RunTenant) error {
+	result.ID = uuid.NewV4()
+	result.CreatedAt = time.Now()
+	if err := r.tx.Create(result).Error; err != nil {
+		return errs.Wrapf(err, "failed to store result of tenant update in %s table", RunTenantsTableName)
+	}
+
+	updated, failed := 1, 0
+	if result.Status == Failed {
+		updated, failed = 0, 1
+	}
+	query := fmt.Sprintf(`INSERT INTO %[1]s (run_id, master_url, updated_count, failed_count) VALUES (?, ?, ?, ?)
+		ON CONFLICT (run_id, master_url) DO UPDATE SET updated_count = %[1]s.updated_count + EXCLUDED.updated_count,
+		failed_count = %[1]s.failed_count + EXCLUDED.failed_count`, RunClustersTableName)
+	if err := r.tx.Exec(query, result.RunID, result.MasterURL, updated, failed).Error; err != nil {
+		return errs.Wrapf(err, "failed to update counts of cluster %s in %s table", result.MasterURL, RunClustersTableName)
+	}
+	return nil
+}
+
+// GetRuns returns the page of the runs ordered from the latest one together with the total number of runs
+func (r *GormRepository) GetRuns(offset, limit int) ([]*Run, int, error) {
+	var runs []*Run
+	var count int
+	if err := r.tx.Table(RunsTableName).Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed to count update runs in %s table", RunsTableName)
+	}
+	err := r.tx.Table(RunsTableName).Order("started_at DESC").Offset(offset).Limit(limit).Find(&runs).Error
+	if err != nil {
+		return nil, 0, errs.Wrapf(err, "failed to get update runs from %s table", RunsTableName)
+	}
+	return runs, count, nil
+}
+
+// GetRun returns the run with the given ID
+func (r *GormRepository) GetRun(runID uuid.UUID) (*Run, error) {
+	var run Run
+	err := r.tx.Table(RunsTableName).Where("id = ?", runID).Find(&run).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("update run", runID.String())
+	} else if err != nil {
+		return nil, errs.Wrapf(err, "unable to lookup update run by id")
+	}
+	return &run, nil
+}
+
+// GetRunClusters returns the numbers of updated and failed tenants per cluster of the given run
+func (r *GormRepository) GetRunClusters(runID uuid.UUID) ([]*RunCluster, error) {
+	var clusters []*RunCluster
+	err := r.tx.Table(RunClustersTableName).Where("run_id = ?", runID).Order("master_url").Find(&clusters).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to get clusters of the update run %s", runID)
+	}
+	return clusters, nil
+}
+
+// GetRunTenants returns results of all tenant updates of the given run
+func (r *GormRepository) GetRunTenants(runID uuid.UUID) ([]*RunTenant, error) {
+	var tenants []*RunTenant
+	err := r.tx.Table(RunTenantsTableName).Where("run_id = ?", runID).Order("created_at").Find(&tenants).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to get tenant results of the update run %s", runID)
+	}
+	return tenants, nil
+}
+
+// GetFailedTenants returns the tenants that failed in the latest update run and haven't been successfully retried since then.
+// If the list of tenant IDs is not empty, then only the results of these tenants are returned.
+func (r *GormRepository) GetFailedTenants(tenantIDs []uuid.UUID) ([]*RunTenant, error) {
+	params := []interface{}{TriggerRetry}
+	tenantsCondition := ""
+	if len(tenantIDs) > 0 {
+		tenantsCondition = "AND t.tenant_id IN (?)"
+		params = append(params, tenantIDs)
+	}
+	params = append(params, Failed)
+
+	// the latest result of every tenant since the start of the last run that wasn't a retry
+	query := fmt.Sprintf(`SELECT * FROM (
+		SELECT DISTINCT ON (t.tenant_id) t.* FROM %[1]s t JOIN %[2]s r ON r.id = t.run_id
+		WHERE r.started_at >= (SELECT COALESCE(MAX(started_at), to_timestamp(0)) FROM %[2]s WHERE triggered_by <> ?) %[3]s
+		ORDER BY t.tenant_id, t.created_at DESC) latest
+		WHERE latest.status = ? ORDER BY latest.created_at`, RunTenantsTableName, RunsTableName, tenantsCondition)
+
+	var tenants []*RunTenant
+	if err := r.tx.Raw(query, params...).Scan(&tenants).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed to get tenants that failed in the latest update run")
+	}
+	return tenants, nil
+}
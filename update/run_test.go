@@ -0,0 +1,184 @@
+package update_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-common/errors"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/dbsupport"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"testing"
+)
+
+func (s *TenantsUpdaterTestSuite) TestUpdateRunIsRecordedInHistory() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		return testupdate.UpdateVersionsTo(repo, "0")
+	})
+	configuration.Commit = "xyz"
+	tenantsUpdater := update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "",
+		update.WithTrigger(update.TriggerAPI))
+
+	// when
+	tenantsUpdater.UpdateAllTenants()
+
+	// then
+	repo := update.NewRepository(s.DB)
+	tenantsUpdate, err := repo.GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), tenantsUpdate.RunID)
+
+	run, err := repo.GetRun(*tenantsUpdate.RunID)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.TriggerAPI, run.TriggeredBy)
+	assert.Equal(s.T(), update.Finished, run.Status)
+	assert.Equal(s.T(), update.Rollout, run.Stage)
+	assert.Empty(s.T(), run.EnvTypeFilter)
+	assert.Empty(s.T(), run.ClusterFilter)
+	assert.NotNil(s.T(), run.FinishedAt)
+	for _, versionManager := range update.RetrieveVersionManagers() {
+		assert.Equal(s.T(), "0", run.GetVersionsBefore()[versionManager.FileName])
+		assert.Equal(s.T(), versionManager.Version, run.GetVersionsAfter()[versionManager.FileName])
+	}
+
+	clusters, err := repo.GetRunClusters(run.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), clusters, 1)
+	assert.Equal(s.T(), test.ClusterURL, clusters[0].MasterURL)
+	assert.Equal(s.T(), 2, clusters[0].UpdatedCount)
+	assert.Equal(s.T(), 0, clusters[0].FailedCount)
+
+	tenants, err := repo.GetRunTenants(run.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), tenants, 2)
+	for _, result := range tenants {
+		assert.Equal(s.T(), update.Finished, result.Status)
+		assert.Empty(s.T(), result.Error)
+		assert.Len(s.T(), result.GetEnvTypes(), len(environment.DefaultEnvTypes))
+		assert.Len(s.T(), result.GetNamespaces(), len(environment.DefaultEnvTypes))
+		assert.Contains(s.T(), []uuid.UUID{fxt.Tenants[0].ID, fxt.Tenants[1].ID}, result.TenantID)
+	}
+}
+
+func (s *TenantsUpdaterTestSuite) TestNewRunFinishesThePreviousOne() {
+	// given
+	var first, second update.Run
+	s.tx(s.T(), func(repo update.Repository) error {
+		return repo.StartRun(&first)
+	})
+
+	// when
+	s.tx(s.T(), func(repo update.Repository) error {
+		return repo.StartRun(&second)
+	})
+
+	// then
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		repo := update.NewRepository(tx)
+		previous, err := repo.GetRun(first.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), update.Failed, previous.Status)
+		assert.NotNil(s.T(), previous.FinishedAt)
+
+		latest, err := repo.GetRun(second.ID)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), update.Updating, latest.Status)
+		assert.Nil(s.T(), latest.FinishedAt)
+
+		tenantsUpdate, err := repo.GetTenantsUpdate()
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), second.ID, *tenantsUpdate.RunID)
+		return nil
+	})
+	require.NoError(s.T(), err)
+}
+
+func (s *UpdateRepoTestSuite) TestRecordTenantResultsAndGetRuns() {
+	// given
+	run := &update.Run{TriggeredBy: update.TriggerSchedule, ClusterFilter: "http://api.cluster1/"}
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		return update.NewRepository(tx).StartRun(run)
+	})
+	require.NoError(s.T(), err)
+	tenantIDs := []uuid.UUID{uuid.NewV4(), uuid.NewV4(), uuid.NewV4()}
+	updateErr := fmt.Errorf("the update failed")
+
+	// when
+	err = dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		repo := update.NewRepository(tx)
+		results := []*update.RunTenant{
+			update.NewRunTenant(run.ID, tenantIDs[0], "http://api.cluster1/", environment.DefaultEnvTypes, nil, nil),
+			update.NewRunTenant(run.ID, tenantIDs[1], "http://api.cluster1/", []environment.Type{environment.TypeChe}, []string{"john-che"}, updateErr),
+			update.NewRunTenant(run.ID, tenantIDs[2], "http://api.cluster2/", []environment.Type{environment.TypeUser}, nil, nil),
+		}
+		for _, result := range results {
+			if err := repo.RecordTenantResult(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// then
+	require.NoError(s.T(), err)
+	repo := update.NewRepository(s.DB)
+
+	s.T().Run("clusters have correct counts", func(t *testing.T) {
+		clusters, err := repo.GetRunClusters(run.ID)
+		require.NoError(t, err)
+		require.Len(t, clusters, 2)
+		assert.Equal(t, "http://api.cluster1/", clusters[0].MasterURL)
+		assert.Equal(t, 1, clusters[0].UpdatedCount)
+		assert.Equal(t, 1, clusters[0].FailedCount)
+		assert.Equal(t, "http://api.cluster2/", clusters[1].MasterURL)
+		assert.Equal(t, 1, clusters[1].UpdatedCount)
+		assert.Equal(t, 0, clusters[1].FailedCount)
+	})
+
+	s.T().Run("tenant results are stored", func(t *testing.T) {
+		tenants, err := repo.GetRunTenants(run.ID)
+		require.NoError(t, err)
+		require.Len(t, tenants, 3)
+		assert.Equal(t, tenantIDs[1], tenants[1].TenantID)
+		assert.Equal(t, update.Failed, tenants[1].Status)
+		assert.Equal(t, updateErr.Error(), tenants[1].Error)
+		assert.Equal(t, []string{"che"}, tenants[1].GetEnvTypes())
+		assert.Equal(t, []string{"john-che"}, tenants[1].GetNamespaces())
+	})
+
+	s.T().Run("the latest run is listed first", func(t *testing.T) {
+		runs, count, err := repo.GetRuns(0, 1)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.True(t, count >= 1)
+		assert.Equal(t, run.ID, runs[0].ID)
+		assert.Equal(t, update.TriggerSchedule, runs[0].TriggeredBy)
+		assert.Equal(t, "http://api.cluster1/", runs[0].ClusterFilter)
+	})
+
+	s.T().Run("unknown run is not found", func(t *testing.T) {
+		_, err := repo.GetRun(uuid.NewV4())
+		test.AssertError(t, err, test.IsOfType(errors.NotFoundError{}))
+	})
+}
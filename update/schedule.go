@@ -0,0 +1,171 @@
+package update
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"strings"
+	"time"
+)
+
+// Window is a recurring maintenance window - it starts at every time matching the cron expression and lasts
+// for the given duration
+type Window struct {
+	spec     string
+	cron     *cronSchedule
+	duration time.Duration
+}
+
+// ParseWindow parses a window definition in the format "<cron expression> <duration>", eg. "0 22 * * 1-5 6h"
+func ParseWindow(spec string) (*Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields)+1 {
+		return nil, fmt.Errorf("the maintenance window '%s' has to consist of a cron expression and a duration", spec)
+	}
+	duration, err := time.ParseDuration(fields[len(fields)-1])
+	if err != nil || duration <= 0 {
+		return nil, fmt.Errorf("invalid duration of the maintenance window '%s'", spec)
+	}
+	cron, err := parseCron(strings.Join(fields[:len(fields)-1], " "))
+	if err != nil {
+		return nil, err
+	}
+	return &Window{spec: spec, cron: cron, duration: duration}, nil
+}
+
+// Current returns the start and the end of the window occurrence the given time falls into
+func (w *Window) Current(t time.Time) (time.Time, time.Time, bool) {
+	start := w.cron.next(t.Add(-w.duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.Add(w.duration), true
+}
+
+// Next returns the start and the end of the current or the next occurrence of the window
+func (w *Window) Next(t time.Time) (time.Time, time.Time) {
+	if start, end, open := w.Current(t); open {
+		return start, end
+	}
+	start := w.cron.next(t)
+	return start, start.Add(w.duration)
+}
+
+func (w *Window) String() string {
+	return w.spec
+}
+
+// Schedule holds the global maintenance windows as well as the windows specific for clusters. When a cluster has its own
+// windows defined, then these are used instead of the global ones. An empty schedule means that updates can run anytime.
+type Schedule struct {
+	global   []*Window
+	clusters map[string][]*Window
+	location *time.Location
+}
+
+// NewSchedule creates a schedule from the maintenance windows set in the configuration
+func NewSchedule(config *configuration.Data) (*Schedule, error) {
+	location, err := time.LoadLocation(config.GetAutomatedUpdateWindowsLocation())
+	if err != nil {
+		return nil, fmt.Errorf("invalid location of the maintenance windows: %s", err)
+	}
+	schedule := &Schedule{
+		clusters: map[string][]*Window{},
+		location: location,
+	}
+	for _, spec := range splitSpecs(config.GetAutomatedUpdateWindows()) {
+		window, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		schedule.global = append(schedule.global, window)
+	}
+	for _, clusterSpec := range splitSpecs(config.GetAutomatedUpdateClusterWindows()) {
+		parts := strings.SplitN(clusterSpec, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("the cluster maintenance window '%s' has to be in the format <cluster-url>=<window>", clusterSpec)
+		}
+		window, err := ParseWindow(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		clusterURL := clusterKey(parts[0])
+		schedule.clusters[clusterURL] = append(schedule.clusters[clusterURL], window)
+	}
+	return schedule, nil
+}
+
+func splitSpecs(specs string) []string {
+	var result []string
+	for _, spec := range strings.Split(specs, ";") {
+		if strings.TrimSpace(spec) != "" {
+			result = append(result, strings.TrimSpace(spec))
+		}
+	}
+	return result
+}
+
+// IsEmpty returns true if there is no maintenance window defined
+func (s *Schedule) IsEmpty() bool {
+	return s == nil || (len(s.global) == 0 && len(s.clusters) == 0)
+}
+
+func clusterKey(clusterURL string) string {
+	return strings.TrimSuffix(strings.TrimSpace(clusterURL), "/")
+}
+
+func (s *Schedule) windowsFor(clusterURL string) []*Window {
+	if windows, found := s.clusters[clusterKey(clusterURL)]; found {
+		return windows
+	}
+	return s.global
+}
+
+// IsOpen returns true if the update of the given cluster is allowed at the given time
+func (s *Schedule) IsOpen(clusterURL string, t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	windows := s.windowsFor(clusterURL)
+	if len(windows) == 0 {
+		return true
+	}
+	for _, window := range windows {
+		if _, _, open := window.Current(t.In(s.location)); open {
+			return true
+		}
+	}
+	return false
+}
+
+// NextWindow returns the start and the end of the current or the next window the given cluster can be updated in.
+// If the cluster URL is empty, then the global windows are used. The returned flag is false when there is no window defined.
+func (s *Schedule) NextWindow(clusterURL string, t time.Time) (time.Time, time.Time, bool) {
+	if s == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	var nextStart, nextEnd time.Time
+	for _, window := range s.windowsFor(clusterURL) {
+		start, end := window.Next(t.In(s.location))
+		if !start.IsZero() && (nextStart.IsZero() || start.Before(nextStart)) {
+			nextStart, nextEnd = start, end
+		}
+	}
+	return nextStart, nextEnd, !nextStart.IsZero()
+}
+
+// openWindows returns the set of all windows (global as well as cluster-specific ones) that are open at the given time
+func (s *Schedule) openWindows(t time.Time) map[string]bool {
+	open := map[string]bool{}
+	check := func(key string, windows []*Window) {
+		for _, window := range windows {
+			if start, _, isOpen := window.Current(t.In(s.location)); isOpen {
+				open[fmt.Sprintf("%s|%s|%s", key, window, start)] = true
+			}
+		}
+	}
+	check("", s.global)
+	for clusterURL, windows := range s.clusters {
+		check(clusterURL, windows)
+	}
+	return open
+}
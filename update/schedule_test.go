@@ -0,0 +1,83 @@
+package update_test
+
+import (
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/assertion"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestUpdateIsPostponedWhenWindowOfClusterIsClosed() {
+	// given
+	defer gock.OffAll()
+	now := time.Now().UTC()
+	closedWindow := "0 0 1 1 * 1m"
+	if now.Month() == time.January && now.Day() == 1 && now.Hour() == 0 {
+		closedWindow = "0 0 1 7 * 1m"
+	}
+	resetWindows := test.SetEnvironments(
+		test.Env("F8_AUTOMATED_UPDATE_WINDOWS", "* * * * * 1h"),
+		test.Env("F8_AUTOMATED_UPDATE_CLUSTER_WINDOWS", "http://api.cluster2="+closedWindow))
+	defer resetWindows()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	fxtOpen := tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	fxtClosed := tf.FillDB(s.T(), s.DB, tf.AddTenants(2),
+		tf.AddDefaultNamespaces().State(tenant.Ready).MasterURL("http://api.cluster2/").Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		return testupdate.UpdateVersionsTo(repo, "0")
+	})
+	configuration.Commit = "xyz"
+	before := time.Now()
+
+	config, resetConfig := test.LoadTestConfig(s.T())
+	defer resetConfig()
+	schedule, err := update.NewSchedule(config)
+	require.NoError(s.T(), err)
+	tenantsUpdater := update.NewTenantsUpdater(s.DB, config, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "",
+		update.WithSchedule(schedule))
+
+	// when
+	tenantsUpdater.UpdateAllTenants()
+
+	// then
+	assert.Equal(s.T(), 2, int(*updateExecutor.NumberOfCalls))
+	tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), update.Postponed, tenantsUpdate.Status)
+	for _, versionManager := range update.RetrieveVersionManagers() {
+		assert.False(s.T(), versionManager.IsVersionUpToDate(tenantsUpdate))
+	}
+	s.assertNamespaces(fxtOpen, func(assertion *assertion.NamespaceAssertion) {
+		assertion.HasCurrentCompleteVersion().HasUpdatedBy("xyz").WasUpdatedAfter(before)
+	})
+	s.assertNamespaces(fxtClosed, func(assertion *assertion.NamespaceAssertion) {
+		assertion.HasVersion("0000").HasUpdatedBy("124abcd").WasUpdatedBefore(before)
+	})
+
+	start, end, found := schedule.NextWindow("http://api.cluster2/", time.Now())
+	assert.True(s.T(), found)
+	assert.True(s.T(), start.After(time.Now()))
+	assert.Equal(s.T(), time.Minute, end.Sub(start))
+}
+
+func (s *TenantsUpdaterTestSuite) assertNamespaces(fxt *tf.TestFixture, check func(assertion *assertion.NamespaceAssertion)) {
+	for _, tnnt := range fxt.Tenants {
+		assertion.AssertTenantFromDB(s.T(), s.DB, tnnt.ID).HasNamespacesThat(check)
+	}
+}
@@ -0,0 +1,103 @@
+package update
+
+import (
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/cluster"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/jinzhu/gorm"
+	"sync/atomic"
+	"time"
+)
+
+// Scheduler triggers the automated update of all tenants every time a maintenance window is opened. The update itself
+// checks the windows between tenant updates so when the window is closed the update is postponed to the next one.
+// When there are more replicas of the service, only the leader triggers the updates.
+type Scheduler struct {
+	db             *gorm.DB
+	config         *configuration.Data
+	clusterService cluster.Service
+	updateExecutor Executor
+	schedule       *Schedule
+	elector        *leader.Elector
+	stop           chan struct{}
+	// running is 1 while the triggered update is running
+	running int32
+}
+
+// NewScheduler creates a scheduler of the automated updates using the given maintenance windows. The elector may be nil
+// if there is only one replica of the service.
+func NewScheduler(db *gorm.DB, config *configuration.Data, clusterService cluster.Service, updateExecutor Executor, schedule *Schedule,
+	elector *leader.Elector) *Scheduler {
+	return &Scheduler{
+		db:             db,
+		config:         config,
+		clusterService: clusterService,
+		updateExecutor: updateExecutor,
+		schedule:       schedule,
+		elector:        elector,
+		stop:           make(chan struct{}),
+	}
+}
+
+// Start starts checking the maintenance windows in the configured interval
+func (s *Scheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.config.GetAutomatedUpdateSchedulerInterval())
+		defer ticker.Stop()
+
+		var lastOpen map[string]bool
+		for {
+			open := s.schedule.openWindows(time.Now())
+			if !s.elector.IsLeader() {
+				// when this replica becomes the leader, it triggers the update for the windows that are already open
+				open = nil
+			} else if hasNewlyOpened(lastOpen, open) && !s.trigger(len(open)) {
+				// the newly opened windows are triggered again once the previous update is finished
+				open = lastOpen
+			}
+			lastOpen = open
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// trigger starts the update in the background, so the windows are checked and the scheduler can be stopped while
+// the update is running. Returns false if the update wasn't triggered because the previous one is still running
+func (s *Scheduler) trigger(openWindows int) bool {
+	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		log.Info(nil, map[string]interface{}{
+			"open_windows": openWindows,
+		}, "a maintenance window has been opened, but the previous tenants update is still running")
+		return false
+	}
+	log.Info(nil, map[string]interface{}{
+		"open_windows": openWindows,
+	}, "a maintenance window has been opened - triggering tenants update")
+	go func() {
+		defer atomic.StoreInt32(&s.running, 0)
+		NewTenantsUpdater(s.db, s.config, s.clusterService, s.updateExecutor, AllTypes, "",
+			WithSchedule(s.schedule), WithTrigger(TriggerSchedule), WithLeader(s.elector)).
+			UpdateAllTenants()
+	}()
+	return true
+}
+
+// Stop stops the scheduler - an ongoing update is not interrupted
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+func hasNewlyOpened(lastOpen, open map[string]bool) bool {
+	for window := range open {
+		if !lastOpen[window] {
+			return true
+		}
+	}
+	return false
+}
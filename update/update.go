@@ -9,10 +9,14 @@ import (
 	"github.com/fabric8-services/fabric8-tenant/configuration"
 	"github.com/fabric8-services/fabric8-tenant/dbsupport"
 	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/leader"
+	"github.com/fabric8-services/fabric8-tenant/metric"
 	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
 	"github.com/fabric8-services/fabric8-tenant/tenant"
 	"github.com/fabric8-services/fabric8-tenant/utils"
 	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
 	"sync"
 	"time"
 )
@@ -29,9 +33,10 @@ func NewTenantsUpdater(
 	clusterService cluster.Service,
 	updateExecutor Executor,
 	filterEnvType FilterEnvType,
-	limitToCluster string) *TenantsUpdater {
+	limitToCluster string,
+	opts ...UpdaterOption) *TenantsUpdater {
 
-	return &TenantsUpdater{
+	updater := &TenantsUpdater{
 		db:             db,
 		config:         config,
 		clusterService: clusterService,
@@ -39,6 +44,10 @@ func NewTenantsUpdater(
 		filterEnvType:  filterEnvType,
 		limitToCluster: limitToCluster,
 	}
+	for _, opt := range opts {
+		opt(updater)
+	}
+	return updater
 }
 
 type TenantsUpdater struct {
@@ -48,6 +57,36 @@ type TenantsUpdater struct {
 	updateExecutor Executor
 	filterEnvType  FilterEnvType
 	limitToCluster string
+	schedule       *Schedule
+	trigger        Trigger
+	runID          uuid.UUID
+	retryOf        []uuid.UUID
+	elector        *leader.Elector
+}
+
+// UpdaterOption customizes the TenantsUpdater
+type UpdaterOption func(updater *TenantsUpdater)
+
+// WithSchedule limits the update of the clusters to the maintenance windows of the given schedule
+func WithSchedule(schedule *Schedule) UpdaterOption {
+	return func(updater *TenantsUpdater) {
+		updater.schedule = schedule
+	}
+}
+
+// WithLeader makes the updater drive the update on behalf of the replica elected by the given elector. When the leadership
+// is handed over to another replica, the new leader takes over the ongoing update immediately.
+func WithLeader(elector *leader.Elector) UpdaterOption {
+	return func(updater *TenantsUpdater) {
+		updater.elector = elector
+	}
+}
+
+// WithTrigger sets what started the update so it can be recorded in the run history
+func WithTrigger(trigger Trigger) UpdaterOption {
+	return func(updater *TenantsUpdater) {
+		updater.trigger = trigger
+	}
 }
 
 type FilterEnvType interface {
@@ -87,10 +126,13 @@ func (u *TenantsUpdater) UpdateAllTenants() {
 	var followUp followUpFunc = func() error { return nil }
 
 	prepareAndAssignStart := func(repo Repository, envTypes []environment.Type) error {
+		if postponed, err := u.postponedByOperation(repo); postponed || err != nil {
+			return err
+		}
 		log.Info(nil, map[string]interface{}{
 			"env_types": envTypes,
 		}, "starting update for outdated types")
-		err := repo.PrepareForUpdating()
+		err := u.prepareForUpdating(repo)
 		if err != nil {
 			return err
 		}
@@ -115,15 +157,22 @@ func (u *TenantsUpdater) UpdateAllTenants() {
 			}
 			log.Info(nil, map[string]interface{}{}, "there is nothing to be updated")
 
-		} else if tenantUpdate.Status == Failed || tenantUpdate.Status == Killed || tenantUpdate.Status == Incomplete {
+		} else if tenantUpdate.Status == Failed || tenantUpdate.Status == Killed || tenantUpdate.Status == Incomplete ||
+			tenantUpdate.Status == Halted || tenantUpdate.Status == Postponed || tenantUpdate.Status == Interrupted {
 			log.Info(nil, map[string]interface{}{
 				"failed_count": tenantUpdate.FailedCount,
 			}, "last update has status \"%s\" - going to check failed or incomplete updates", tenantUpdate.Status)
 			return prepareAndAssignStart(repo, environment.DefaultEnvTypes)
 
-		} else if tenantUpdate.Status == Updating {
+		} else if tenantUpdate.IsOngoing() {
 			if IsOlderThanTimeout(tenantUpdate.LastTimeUpdated, u.config) {
 				return prepareAndAssignStart(repo, environment.DefaultEnvTypes)
+			} else if u.takesOver(tenantUpdate) {
+				log.Info(nil, map[string]interface{}{
+					"previous_driver": tenantUpdate.Driver,
+					"driver":          u.elector.Identity(),
+				}, "the replica driving the ongoing update is not the leader anymore - taking over the update")
+				return prepareAndAssignStart(repo, environment.DefaultEnvTypes)
 			} else {
 				log.Info(nil, map[string]interface{}{
 					"automated_update_retry_sleep": u.config.GetAutomatedUpdateRetrySleep().String(),
@@ -146,13 +195,190 @@ func (u *TenantsUpdater) UpdateAllTenants() {
 	}
 }
 
+// TakeOverOngoingUpdate resumes the ongoing update if its driver is not alive anymore - either it is another replica
+// that isn't the leader or it hasn't reported any progress within the timeout. The update interrupted by the shutdown
+// of its driver is resumed as well. It is meant to be called every time this replica becomes the leader, so an update
+// orphaned by a killed leader is resumed without waiting for another trigger. If there is no such update, then nothing
+// is started.
+func (u *TenantsUpdater) TakeOverOngoingUpdate() {
+	var followUp followUpFunc = func() error { return nil }
+
+	err := dbsupport.Transaction(u.db, lock(func(repo Repository) error {
+		tenantUpdate, err := repo.GetTenantsUpdate()
+		if err != nil {
+			return err
+		}
+		orphaned := tenantUpdate.IsOngoing() &&
+			(u.takesOver(tenantUpdate) || IsOlderThanTimeout(tenantUpdate.LastTimeUpdated, u.config))
+		if !orphaned && tenantUpdate.Status != Interrupted {
+			return nil
+		}
+		if postponed, err := u.postponedByOperation(repo); postponed || err != nil {
+			return err
+		}
+		log.Info(nil, map[string]interface{}{
+			"previous_driver":   tenantUpdate.Driver,
+			"driver":            u.elector.Identity(),
+			"last_time_updated": tenantUpdate.LastTimeUpdated,
+		}, "the ongoing update isn't driven by any alive replica - taking over the update")
+		if err := u.prepareForUpdating(repo); err != nil {
+			return err
+		}
+		followUp = u.updateTenantsForTypes(environment.DefaultEnvTypes)
+		return nil
+	}))
+
+	if err == nil {
+		err = followUp()
+	}
+	if err != nil {
+		HandleTenantUpdateError(u.db, err)
+	}
+}
+
+// postponedByOperation returns true if there is an ongoing bulk operation - the update isn't started until the operation
+// is finished, it is started by the next trigger
+func (u *TenantsUpdater) postponedByOperation(repo Repository) (bool, error) {
+	ongoing, err := isOperationOngoing(repo, u.config)
+	if err != nil {
+		return false, err
+	}
+	if ongoing {
+		log.Info(nil, map[string]interface{}{}, "there is an ongoing bulk operation - the update is postponed")
+	}
+	return ongoing, nil
+}
+
+// prepareForUpdating resets the tenants update and starts a new record in the run history
+func (u *TenantsUpdater) prepareForUpdating(repo Repository) error {
+	if err := repo.PrepareForUpdating(); err != nil {
+		return err
+	}
+	if err := repo.SetDriver(u.elector.Identity()); err != nil {
+		return err
+	}
+	// the batches of the previous runs that haven't been claimed yet are not needed anymore
+	if err := repo.CancelWorkItems(uuid.Nil, ""); err != nil {
+		return err
+	}
+	run := &Run{
+		TriggeredBy:   u.trigger,
+		ClusterFilter: u.limitToCluster,
+	}
+	if u.filterEnvType != AllTypes {
+		run.EnvTypeFilter = u.filterEnvType.GetLimit()
+	}
+	if err := repo.StartRun(run); err != nil {
+		return err
+	}
+	u.runID = run.ID
+	metric.ResetUpdateProgress()
+	return nil
+}
+
+// RetryFailedTenants updates again the tenants that failed in the latest update run - it doesn't matter which commit
+// the namespaces were updated by. If the list of tenant IDs is empty, then all failed tenants are retried.
+func (u *TenantsUpdater) RetryFailedTenants(tenantIDs []uuid.UUID) {
+	log.Info(nil, map[string]interface{}{
+		"tenant_ids": tenantIDs,
+	}, "triggering retry of failed tenants")
+
+	var followUp followUpFunc = func() error { return nil }
+	u.trigger = TriggerRetry
+	u.retryOf = tenantIDs
+
+	err := dbsupport.Transaction(u.db, lock(func(repo Repository) error {
+		tenantUpdate, err := repo.GetTenantsUpdate()
+		if err != nil {
+			return err
+		}
+		if tenantUpdate.IsOngoing() && !IsOlderThanTimeout(tenantUpdate.LastTimeUpdated, u.config) {
+			log.Info(nil, map[string]interface{}{
+				"last_time_updated": tenantUpdate.LastTimeUpdated,
+			}, "there is an ongoing update in process - the failed tenants won't be retried")
+			return nil
+		}
+		failed, err := repo.GetFailedTenants(tenantIDs)
+		if err != nil {
+			return err
+		}
+		if len(failed) == 0 {
+			log.Info(nil, map[string]interface{}{}, "there is no failed tenant to be retried")
+			return nil
+		}
+		if postponed, err := u.postponedByOperation(repo); postponed || err != nil {
+			return err
+		}
+		if err := u.prepareForUpdating(repo); err != nil {
+			return err
+		}
+		followUp = u.retryTenants(failed)
+		return nil
+	}))
+
+	if err == nil {
+		err = followUp()
+	}
+	if err != nil {
+		HandleTenantUpdateError(u.db, err)
+	}
+}
+
+func (u *TenantsUpdater) retryTenants(failed []*RunTenant) followUpFunc {
+	return func() error {
+		typesWithVersion := u.getTypesWithVersion(environment.DefaultEnvTypes)
+
+		tenantsPerCluster := map[string][]*tenant.Tenant{}
+		var clustersToUpdate []string
+		for _, result := range failed {
+			tnnt, err := tenant.NewTenantRepository(u.db, result.TenantID).GetTenant()
+			if err != nil {
+				log.Warn(nil, map[string]interface{}{
+					"tenant_id": result.TenantID,
+					"err":       err,
+				}, "unable to get the failed tenant - skipping it")
+				continue
+			}
+			if _, found := tenantsPerCluster[result.MasterURL]; !found {
+				clustersToUpdate = append(clustersToUpdate, result.MasterURL)
+			}
+			tenantsPerCluster[result.MasterURL] = append(tenantsPerCluster[result.MasterURL], tnnt)
+		}
+
+		stats, err := u.updateStage(Rollout, clustersToUpdate, allTenants, newClusterBreakers(u.config),
+			func(clusterURL string, run *stageRun) error {
+				canContinue, err := run.updateTenants(clusterURL, tenantsPerCluster[clusterURL], typesWithVersion, u.db, u.config,
+					u.updateExecutor, newPacer(u.config))
+				if !canContinue {
+					run.stats.markStopped()
+				}
+				return err
+			})
+		if err != nil {
+			return err
+		}
+
+		return dbsupport.Transaction(u.db, lock(func(repo Repository) error {
+			return u.setStatusAndVersionsAfterUpdate(repo, stats.postponed)
+		}))
+	}
+}
+
+// takesOver returns true if this updater runs on the leader and the ongoing update is driven by another replica
+func (u *TenantsUpdater) takesOver(tenantUpdate *TenantsUpdate) bool {
+	return u.elector != nil && u.elector.IsLeader() && tenantUpdate.Driver != "" && tenantUpdate.Driver != u.elector.Identity()
+}
+
 func HandleTenantUpdateError(db *gorm.DB, err error) {
 	sentry.LogError(nil, map[string]interface{}{
 		"commit": configuration.Commit,
 		"err":    err,
 	}, err, "automatic tenant update failed")
 	err = dbsupport.Transaction(db, lock(func(repo Repository) error {
-		return repo.UpdateStatus(Failed)
+		if err := repo.UpdateStatus(Failed); err != nil {
+			return err
+		}
+		return repo.SyncRun()
 	}))
 	if err != nil {
 		sentry.LogError(nil, map[string]interface{}{
@@ -174,8 +400,11 @@ func (u *TenantsUpdater) waitAndRecheck() error {
 			return err
 		}
 		if IsOlderThanTimeout(tenantUpdate.LastTimeUpdated, u.config) {
+			if postponed, err := u.postponedByOperation(repo); postponed || err != nil {
+				return err
+			}
 			log.Info(nil, map[string]interface{}{}, "last update was interrupted - restarting a new one")
-			err := repo.PrepareForUpdating()
+			err := u.prepareForUpdating(repo)
 			if err != nil {
 				return err
 			}
@@ -195,20 +424,46 @@ func (u *TenantsUpdater) waitAndRecheck() error {
 	return followUp()
 }
 
+// MarkInterrupted marks the ongoing update driven by the given replica as interrupted, so it is resumed by the next update.
+// It is called when the replica is shut down.
+func MarkInterrupted(db *gorm.DB, driver string) error {
+	return dbsupport.Transaction(db, lock(func(repo Repository) error {
+		tenantUpdate, err := repo.GetTenantsUpdate()
+		if err != nil {
+			return err
+		}
+		if !tenantUpdate.IsOngoing() || tenantUpdate.Driver != driver {
+			return nil
+		}
+		log.Info(nil, map[string]interface{}{
+			"driver": driver,
+		}, "marking the ongoing update as interrupted")
+		if err := repo.UpdateStatus(Interrupted); err != nil {
+			return err
+		}
+		return repo.SyncRun()
+	}))
+}
+
 func IsOlderThanTimeout(when time.Time, config *configuration.Data) bool {
 	return when.Before(time.Now().Add(-config.GetAutomatedUpdateRetrySleep()))
 }
 
-func (u *TenantsUpdater) updateTenantsForTypes(envTypes []environment.Type) followUpFunc {
-	return func() error {
-		mappedTemplates := environment.RetrieveMappedTemplates()
-		typesWithVersion := map[environment.Type]string{}
+func (u *TenantsUpdater) getTypesWithVersion(envTypes []environment.Type) map[environment.Type]string {
+	mappedTemplates := environment.RetrieveMappedTemplates()
+	typesWithVersion := map[environment.Type]string{}
 
-		for _, envType := range envTypes {
-			if u.filterEnvType.IsOk(envType) {
-				typesWithVersion[envType] = mappedTemplates[envType].ConstructCompleteVersion()
-			}
+	for _, envType := range envTypes {
+		if u.filterEnvType.IsOk(envType) {
+			typesWithVersion[envType] = mappedTemplates[envType].ConstructCompleteVersion()
 		}
+	}
+	return typesWithVersion
+}
+
+func (u *TenantsUpdater) updateTenantsForTypes(envTypes []environment.Type) followUpFunc {
+	return func() error {
+		typesWithVersion := u.getTypesWithVersion(envTypes)
 
 		var clustersToUpdate []string
 		if u.limitToCluster != "" {
@@ -220,57 +475,250 @@ func (u *TenantsUpdater) updateTenantsForTypes(envTypes []environment.Type) foll
 				return err
 			}
 		}
-		errorChan := make(chan error, len(clustersToUpdate))
-		wg := sync.WaitGroup{}
-		wg.Add(len(clustersToUpdate))
-		for _, cluster := range clustersToUpdate {
-			go func(clusterURL string, typesAndVersion map[environment.Type]string, db *gorm.DB, config *configuration.Data, updateExecutor Executor) {
-				defer wg.Done()
-				err := updateForCluster(clusterURL, typesAndVersion, db, config, updateExecutor)
-				if err != nil {
-					errorChan <- err
-					log.Error(nil, map[string]interface{}{
-						"cluster_URL": clusterURL,
-						"error":       err,
-					}, "the tenants updated failed for the cluster")
+
+		breakers := newClusterBreakers(u.config)
+		if u.config.IsAutomatedUpdateWorkDistributed() && !uuid.Equal(u.runID, uuid.Nil) {
+			// the batches are updated by all replicas, so the failures are counted in the breakers stored with the run
+			breakers = newRunBreakers(u.db, u.config, u.runID)
+		}
+		interrupted, postponed := false, false
+		if u.config.IsAutomatedUpdateCanaryEnabled() {
+			stats, err := u.updateStage(Canary, clustersToUpdate, newCanaryCohort(u.config).isSelected, breakers,
+				u.updateOutdated(typesWithVersion))
+			if err != nil {
+				return err
+			}
+			interrupted, postponed = stats.stopped || stats.postponed, stats.postponed
+			if !interrupted {
+				if err := evaluateCanary(u.db, u.config, stats, typesWithVersion); err != nil {
+					return u.haltAfterCanary(err)
 				}
-			}(cluster, typesWithVersion, u.db, u.config, u.updateExecutor)
+			}
 		}
-		wg.Wait()
-		close(errorChan)
-		errorMsg := utils.ListErrorsInMessage(errorChan, len(clustersToUpdate))
-		if errorMsg != "" {
-			return fmt.Errorf(errorMsg)
+
+		if !interrupted {
+			stats, err := u.updateStage(Rollout, clustersToUpdate, allTenants, breakers, u.updateOutdated(typesWithVersion))
+			if err != nil {
+				return err
+			}
+			postponed = stats.postponed
 		}
 
 		err := dbsupport.Transaction(u.db, lock(func(repo Repository) error {
-			return u.setStatusAndVersionsAfterUpdate(repo)
+			return u.setStatusAndVersionsAfterUpdate(repo, postponed)
 		}))
 
 		return err
 	}
 }
 
-func updateForCluster(clusterURL string, typesWithVersion map[environment.Type]string, db *gorm.DB, config *configuration.Data, updateExecutor Executor) error {
-	for {
-		dbService := tenant.NewDBService(db)
-		toUpdate, err := dbService.GetTenantsToUpdate(typesWithVersion, 100, configuration.Commit, clusterURL)
+// clusterUpdateFunc updates tenants of one cluster within the given stage run
+type clusterUpdateFunc func(clusterURL string, run *stageRun) error
+
+// updateOutdated returns a function that updates all outdated tenants of a cluster
+func (u *TenantsUpdater) updateOutdated(typesWithVersion map[environment.Type]string) clusterUpdateFunc {
+	return func(clusterURL string, run *stageRun) error {
+		return updateForCluster(clusterURL, typesWithVersion, u.db, u.config, u.updateExecutor, run)
+	}
+}
+
+func (u *TenantsUpdater) updateStage(stage Stage, clustersToUpdate []string, selectTenant tenantSelector, breakers *clusterBreakers,
+	updateCluster clusterUpdateFunc) (*stageStats, error) {
+
+	log.Info(nil, map[string]interface{}{
+		"stage":    stage,
+		"clusters": clustersToUpdate,
+	}, "starting stage of the tenants update")
+	err := dbsupport.Transaction(u.db, lock(func(repo Repository) error {
+		if err := repo.UpdateStage(stage); err != nil {
+			return err
+		}
+		return repo.SyncRun()
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	run := &stageRun{
+		runID:        u.runID,
+		driver:       u.elector.Identity(),
+		stage:        stage,
+		selectTenant: selectTenant,
+		stats:        &stageStats{},
+		breakers:     breakers,
+		schedule:     u.schedule,
+		work:         newWorkQueue(u.db, u.config, u.schedule),
+	}
+	errorChan := make(chan error, len(clustersToUpdate))
+	wg := sync.WaitGroup{}
+	wg.Add(len(clustersToUpdate))
+	for _, cluster := range clustersToUpdate {
+		go func(clusterURL string) {
+			defer wg.Done()
+			err := updateCluster(clusterURL, run)
+			if err == nil && run.work != nil {
+				var canContinue bool
+				canContinue, err = run.work.await(run, clusterURL)
+				if !canContinue {
+					run.stats.markStopped()
+				}
+			}
+			if err != nil {
+				errorChan <- err
+				log.Error(nil, map[string]interface{}{
+					"cluster_URL": clusterURL,
+					"stage":       stage,
+					"error":       err,
+				}, "the tenants updated failed for the cluster")
+			}
+		}(cluster)
+	}
+	wg.Wait()
+	close(errorChan)
+	errorMsg := utils.ListErrorsInMessage(errorChan, len(clustersToUpdate))
+	if errorMsg != "" {
+		return nil, fmt.Errorf(errorMsg)
+	}
+	return run.stats, nil
+}
+
+// stageRun holds everything that is shared by the cluster goroutines updating tenants within one stage of the update
+type stageRun struct {
+	runID        uuid.UUID
+	driver       string
+	stage        Stage
+	selectTenant tenantSelector
+	stats        *stageStats
+	breakers     *clusterBreakers
+	schedule     *Schedule
+	work         *workQueue
+}
+
+// isWindowClosed returns true if the maintenance window of the cluster is closed. In such a case it marks
+// the stage as postponed so it can be resumed in the next window
+func (r *stageRun) isWindowClosed(clusterURL string) bool {
+	if r.schedule.IsOpen(clusterURL, time.Now()) {
+		return false
+	}
+	log.Info(nil, map[string]interface{}{
+		"cluster_url": clusterURL,
+		"stage":       r.stage,
+	}, "the maintenance window of the cluster is closed - postponing the update of the cluster")
+	r.stats.markPostponed()
+	return true
+}
+
+// updateTenants updates the batch of tenants of the cluster. When the work is distributed, the batch is only added
+// to the work queue so it can be claimed by any replica and the returned flag is always true
+func (r *stageRun) updateTenants(clusterURL string, tenants []*tenant.Tenant, typesWithVersion map[environment.Type]string,
+	db *gorm.DB, config *configuration.Data, updateExecutor Executor, pacer *pacer) (bool, error) {
+	if r.work != nil {
+		if shutdown.IsShuttingDown() {
+			return false, nil
+		}
+		return true, r.work.enqueue(r, clusterURL, tenants, typesWithVersion)
+	}
+	return updateTenants(clusterURL, tenants, typesWithVersion, db, config, updateExecutor, r, pacer)
+}
+
+// recordResult stores the result of the tenant update in the run history
+func (r *stageRun) recordResult(db *gorm.DB, clusterURL string, tnnt *tenant.Tenant, envTypes []environment.Type, nsNames []string,
+	updateErr error) {
+	if uuid.Equal(r.runID, uuid.Nil) {
+		return
+	}
+	err := dbsupport.Transaction(db, func(tx *gorm.DB) error {
+		return NewRepository(tx).RecordTenantResult(NewRunTenant(r.runID, tnnt.ID, clusterURL, envTypes, nsNames, updateErr))
+	})
+	if err != nil {
+		sentry.LogError(nil, map[string]interface{}{
+			"run_id":    r.runID,
+			"tenant_id": tnnt.ID,
+		}, err, "unable to store result of the tenant update")
+	}
+}
+
+func (u *TenantsUpdater) haltAfterCanary(reason error) error {
+	sentry.LogError(nil, map[string]interface{}{
+		"commit": configuration.Commit,
+		"stage":  Canary,
+	}, reason, "canary stage of the tenants update failed - the rest of the tenants won't be updated")
+
+	return dbsupport.Transaction(u.db, lock(func(repo Repository) error {
+		if err := repo.AddHaltReason(fmt.Sprintf("canary stage: %s", reason)); err != nil {
+			return err
+		}
+		if err := repo.UpdateStatus(Halted); err != nil {
+			return err
+		}
+		return repo.SyncRun()
+	}))
+}
+
+func haltUpdate(db *gorm.DB, clusterURL string, haltAll bool, reason string, lastErr error) error {
+	sentry.LogError(nil, map[string]interface{}{
+		"commit":      configuration.Commit,
+		"cluster_url": clusterURL,
+		"halt_all":    haltAll,
+		"reason":      reason,
+	}, lastErr, "tenants update was halted because of too many failed tenant updates")
+
+	return dbsupport.Transaction(db, lock(func(repo Repository) error {
+		if err := repo.AddHaltReason(fmt.Sprintf("%s: %s", clusterURL, reason)); err != nil {
+			return err
+		}
+		if haltAll {
+			return repo.Stop()
+		}
+		return nil
+	}))
+}
+
+func updateForCluster(clusterURL string, typesWithVersion map[environment.Type]string, db *gorm.DB, config *configuration.Data,
+	updateExecutor Executor, run *stageRun) error {
+
+	breaker := run.breakers.forCluster(clusterURL)
+	clusterPacer := newPacer(config)
+	dbService := tenant.NewDBService(db)
+	// the outdated tenants are counted only once - the count is decreased by the tenants of every processed batch
+	remaining, err := dbService.GetNumberOfOutdatedTenants(typesWithVersion, configuration.Commit, clusterURL)
+	if err != nil {
+		return err
+	}
+	metric.SetUpdateRemainingTenants(clusterURL, remaining)
+	after := uuid.Nil
+	for !breaker.isTripped() && !run.isWindowClosed(clusterURL) {
+		outdated, err := dbService.GetTenantsToUpdateAfter(typesWithVersion, 100, configuration.Commit, clusterURL, after)
 		if err != nil {
 			return err
 		}
-		if len(toUpdate) == 0 {
+		if len(outdated) == 0 {
 			break
 		}
+		after = outdated[len(outdated)-1].ID
+
+		var toUpdate []*tenant.Tenant
+		for _, tnnt := range outdated {
+			if run.selectTenant(tnnt) {
+				toUpdate = append(toUpdate, tnnt)
+			}
+		}
 		log.Info(nil, map[string]interface{}{
 			"number_of_tenants_to_update": len(toUpdate),
 			"master_url":                  clusterURL,
 		}, "starting update for next batch of outdated/failed tenants")
 
-		canContinue, err := updateTenants(toUpdate, typesWithVersion, db, config, updateExecutor)
+		canContinue, err := run.updateTenants(clusterURL, toUpdate, typesWithVersion, db, config, updateExecutor, clusterPacer)
+		remaining -= len(toUpdate)
+		if remaining < 0 {
+			remaining = 0
+		}
+		metric.SetUpdateRemainingTenants(clusterURL, remaining)
 		if err != nil {
 			return err
 		}
 		if !canContinue {
+			run.stats.markStopped()
 			break
 		}
 
@@ -284,22 +732,34 @@ func updateForCluster(clusterURL string, typesWithVersion map[environment.Type]s
 	return nil
 }
 
-func (u *TenantsUpdater) setStatusAndVersionsAfterUpdate(repo Repository) error {
+func (u *TenantsUpdater) setStatusAndVersionsAfterUpdate(repo Repository, postponed bool) error {
 	tenantUpdate, err := repo.GetTenantsUpdate()
 	if err != nil {
 		return err
 	}
-	if !tenantUpdate.CanContinue {
+	if tenantUpdate.Driver != u.elector.Identity() {
+		log.Info(nil, map[string]interface{}{
+			"driver": tenantUpdate.Driver,
+		}, "the update has been taken over by another replica - the status won't be set")
+		return nil
+	}
+	if shutdown.IsShuttingDown() {
+		tenantUpdate.Status = Interrupted
+	} else if tenantUpdate.HaltReason != "" {
+		tenantUpdate.Status = Halted
+	} else if !tenantUpdate.CanContinue {
 		tenantUpdate.Status = Killed
+	} else if postponed {
+		tenantUpdate.Status = Postponed
 	} else if tenantUpdate.FailedCount > 0 {
 		tenantUpdate.Status = Failed
-	} else if u.filterEnvType != AllTypes || u.limitToCluster != "" {
+	} else if u.filterEnvType != AllTypes || u.limitToCluster != "" || len(u.retryOf) > 0 {
 		tenantUpdate.Status = Incomplete
 	} else {
 		tenantUpdate.Status = Finished
 	}
 	for _, versionManager := range RetrieveVersionManagers() {
-		isOk := true
+		isOk := tenantUpdate.Status != Halted && tenantUpdate.Status != Postponed && tenantUpdate.Status != Interrupted
 		for _, envType := range versionManager.EnvTypes {
 			isOk = isOk && u.filterEnvType.IsOk(envType)
 		}
@@ -311,39 +771,171 @@ func (u *TenantsUpdater) setStatusAndVersionsAfterUpdate(repo Repository) error
 		"status":                   tenantUpdate.Status,
 		"number_of_failed_tenants": tenantUpdate.FailedCount,
 	}, "the whole tenants update process has been finished")
-	return repo.SaveTenantsUpdate(tenantUpdate)
+	if err := repo.SaveTenantsUpdate(tenantUpdate); err != nil {
+		return err
+	}
+	return repo.SyncRun()
+}
+
+// clusterBatch is a batch of tenants of one cluster that is being updated by a pool of workers
+type clusterBatch struct {
+	clusterURL       string
+	typesWithVersion map[environment.Type]string
+	db               *gorm.DB
+	config           *configuration.Data
+	updateExecutor   Executor
+	run              *stageRun
+	pacer            *pacer
+
+	mux         sync.Mutex
+	canContinue bool
+	finished    bool
+	err         error
 }
 
-func updateTenants(tenants []*tenant.Tenant, typesWithVersion map[environment.Type]string,
-	db *gorm.DB, config *configuration.Data, updateExecutor Executor) (bool, error) {
-	canContinue := true
-	var err error
+func updateTenants(clusterURL string, tenants []*tenant.Tenant, typesWithVersion map[environment.Type]string,
+	db *gorm.DB, config *configuration.Data, updateExecutor Executor, run *stageRun, pacer *pacer) (bool, error) {
+
+	batch := &clusterBatch{
+		clusterURL:       clusterURL,
+		typesWithVersion: typesWithVersion,
+		db:               db,
+		config:           config,
+		updateExecutor:   updateExecutor,
+		run:              run,
+		pacer:            pacer,
+		canContinue:      true,
+	}
+
+	concurrency := config.GetAutomatedUpdateConcurrency()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	tenantsChan := make(chan *tenant.Tenant)
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for tnnt := range tenantsChan {
+				batch.update(tnnt)
+			}
+		}()
+	}
 
 	for _, tnnt := range tenants {
+		if batch.isFinished() {
+			break
+		}
+		tenantsChan <- tnnt
+	}
+	close(tenantsChan)
+	wg.Wait()
+
+	return batch.canContinue, batch.err
+}
+
+func (b *clusterBatch) update(tnnt *tenant.Tenant) {
+	if b.isFinished() {
+		return
+	}
+	if shutdown.IsShuttingDown() {
+		log.Info(nil, map[string]interface{}{}, "the service is shutting down - stopping tenants update process")
+		b.finish(false, nil)
+		return
+	}
+	canContinue, err := waitWhilePaused(b.db, b.config, b.run.driver)
+	if !canContinue || err != nil {
+		log.Info(nil, map[string]interface{}{}, "stopping tenants update process")
+		b.finish(canContinue, err)
+		return
+	}
+	if b.run.isWindowClosed(b.clusterURL) {
+		b.finish(true, nil)
+		return
+	}
+
+	breakers := b.run.breakers
+	breaker := breakers.forCluster(b.clusterURL)
+	start := time.Now()
+	envTypes, nsNames, updateErr := updateTenant(b.updateExecutor, tnnt, b.typesWithVersion, b.db)
+	b.pacer.record(time.Since(start), updateErr)
+	b.run.stats.record(tnnt.ID, updateErr)
+	metric.RecordUpdateProgress(b.clusterURL, updateErr == nil)
+	b.run.recordResult(b.db, b.clusterURL, tnnt, envTypes, nsNames, updateErr)
+
+	if reason := breaker.record(updateErr); reason != "" {
+		b.finish(!breakers.haltsAll(), haltUpdate(b.db, b.clusterURL, breakers.haltsAll(), reason, updateErr))
+		return
+	}
+	if breaker.isTripped() {
+		b.finish(true, nil)
+		return
+	}
+	b.pacer.wait()
+}
+
+// waitWhilePaused blocks as long as the update is paused and returns whether the update can continue. While waiting,
+// it keeps updating the last_time_updated timestamp so the paused update isn't considered as interrupted.
+// The update cannot continue when it was stopped or taken over by another replica than the given driver.
+func waitWhilePaused(db *gorm.DB, config *configuration.Data, driver string) (bool, error) {
+	for {
+		var tenantsUpdate *TenantsUpdate
 		err := dbsupport.Transaction(db, func(tx *gorm.DB) error {
 			var err error
-			canContinue, err = NewRepository(tx).CanContinue()
+			tenantsUpdate, err = NewRepository(tx).GetTenantsUpdate()
 			return err
 		})
-		if !canContinue || err != nil {
-			log.Info(nil, map[string]interface{}{}, "stopping tenants update process")
-			break
+		if err != nil {
+			return false, err
+		}
+		if tenantsUpdate.Driver != driver {
+			log.Info(nil, map[string]interface{}{
+				"driver":     driver,
+				"new_driver": tenantsUpdate.Driver,
+			}, "the update has been taken over by another replica")
+			return false, nil
+		}
+		if !tenantsUpdate.CanContinue || tenantsUpdate.Status != Paused {
+			return tenantsUpdate.CanContinue, nil
 		}
 
-		updateTenant(updateExecutor, tnnt, typesWithVersion, db)
-		time.Sleep(config.GetAutomatedUpdateTimeGap())
+		err = dbsupport.Transaction(db, lock(func(repo Repository) error {
+			return repo.UpdateLastTimeUpdated()
+		}))
+		if err != nil {
+			return false, err
+		}
+		time.Sleep(config.GetAutomatedUpdatePauseCheckInterval())
 	}
-	return canContinue, err
 }
 
-func updateTenant(updateExecutor Executor, tnnt *tenant.Tenant, typesWithVersion map[environment.Type]string, db *gorm.DB) {
+func (b *clusterBatch) finish(canContinue bool, err error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.finished = true
+	b.canContinue = b.canContinue && canContinue
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *clusterBatch) isFinished() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.finished
+}
+
+// updateTenant updates the outdated namespaces of the given tenant and returns the types and names of the updated namespaces
+func updateTenant(updateExecutor Executor, tnnt *tenant.Tenant, typesWithVersion map[environment.Type]string, db *gorm.DB) (
+	[]environment.Type, []string, error) {
 	namespaces, err := tenant.NewTenantRepository(db, tnnt.ID).GetNamespaces()
 	if err != nil {
 		sentry.LogError(nil, map[string]interface{}{
 			"err":    err,
 			"tenant": tnnt.ID,
 		}, err, "unable to get current tenant namespaces during cluster-wide update")
-		return
+		return nil, nil, err
 	}
 
 	var envTypesToUpdate []environment.Type
@@ -364,7 +956,7 @@ func updateTenant(updateExecutor Executor, tnnt *tenant.Tenant, typesWithVersion
 		"ns_names_to_update": nsNamesToUpdate,
 	}
 	log.Info(nil, logParams, "starting update of tenant for outdated namespaces")
-	err = updateExecutor.Update(nil, tnnt, nil, envTypesToUpdate, false)
+	err = updateExecutor.Update(tenant.WithActor(nil, tenant.ActorUpdater), tnnt, nil, envTypesToUpdate, false)
 
 	if err != nil {
 		errIncr := dbsupport.Transaction(db, lock(func(repo Repository) error {
@@ -374,9 +966,10 @@ func updateTenant(updateExecutor Executor, tnnt *tenant.Tenant, typesWithVersion
 			sentry.LogError(nil, map[string]interface{}{}, errIncr, "unable to increment failed_count")
 		}
 		sentry.LogError(nil, logParams, err, "unable to automatically update tenant")
-	} else {
-		log.Info(nil, logParams, "update of tenant for outdated namespace finished")
+		return envTypesToUpdate, nsNamesToUpdate, err
 	}
+	log.Info(nil, logParams, "update of tenant for outdated namespace finished")
+	return envTypesToUpdate, nsNamesToUpdate, nil
 }
 
 func checkVersions(tu *TenantsUpdate) ([]environment.Type, error) {
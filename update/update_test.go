@@ -43,7 +43,7 @@ func (s *TenantsUpdaterTestSuite) TestUpdateAllTenantsForAllStatuses() {
 	testdoubles.SetTemplateVersions()
 	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
 
-	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete"} {
+	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete", "interrupted"} {
 		s.T().Run(fmt.Sprintf("running automated update process whould pass when status %s is set", status), func(t *testing.T) {
 			*updateExecutor.NumberOfCalls = 0
 			fxt := tf.FillDB(t, s.DB, tf.AddTenants(19), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
@@ -86,7 +86,7 @@ func (s *TenantsUpdaterTestSuite) TestUpdateOnlyOutdatedNamespacesForAllStatuses
 	defer reset()
 	testdoubles.SetTemplateVersions()
 
-	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete"} {
+	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete", "interrupted"} {
 		gock.OffAll()
 		calls := 0
 		testdoubles.MockPatchRequestsToOS(&calls, test.ClusterURL)
@@ -168,7 +168,7 @@ func (s *TenantsUpdaterTestSuite) TestDoNotUpdateAnythingWhenAllNamespacesAreUpT
 	testdoubles.SetTemplateVersions()
 	configuration.Commit = "124abcd"
 
-	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete"} {
+	for _, status := range []string{"finished", "updating", "failed", "killed", "incomplete", "interrupted"} {
 
 		s.T().Run(fmt.Sprintf("running automated update process should pass (without updating anything) when status %s is set", status), func(t *testing.T) {
 			*updateExecutor.NumberOfCalls = 0
@@ -442,6 +442,44 @@ func (s *TenantsUpdaterTestSuite) TestUpdateAllTenantsForMoreClustersInParallel(
 	assert.True(s.T(), before.After(finished.Add(-7*time.Second)))
 }
 
+func (s *TenantsUpdaterTestSuite) TestUpdateTenantsOfOneClusterByMoreWorkers() {
+	// given
+	defer gock.OffAll()
+	resetConcurrency := test.SetEnvironments(test.Env("F8_AUTOMATED_UPDATE_CONCURRENCY", "5"))
+	defer resetConcurrency()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	updateExecutor.TimeToSleep = 100 * time.Millisecond
+	tenantsUpdater, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(20), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		return testupdate.UpdateVersionsTo(repo, "0")
+	})
+	configuration.Commit = "xyz"
+	before := time.Now()
+
+	// when
+	tenantsUpdater.UpdateAllTenants()
+
+	// then
+	assert.Equal(s.T(), 20, int(*updateExecutor.NumberOfCalls))
+	s.assertStatusAndAllVersionAreUpToDate(s.T(), update.Finished)
+	for _, tnnt := range fxt.Tenants {
+		assertion.AssertTenantFromDB(s.T(), s.DB, tnnt.ID).
+			HasNamespacesThat(func(assertion *assertion.NamespaceAssertion) {
+				assertion.
+					HasCurrentCompleteVersion().
+					HasUpdatedBy("xyz").
+					HasState(tenant.Ready).
+					WasUpdatedAfter(before)
+			})
+	}
+}
+
 func (s *TenantsUpdaterTestSuite) TestMoreGoroutinesTryingToUpdate() {
 	//given
 	defer gock.OffAll()
@@ -479,6 +517,65 @@ func (s *TenantsUpdaterTestSuite) TestTwoExecutorsDoUpdateBecauseOfLowerWaitTime
 	assert.NotZero(s.T(), *updateExecs[1].NumberOfCalls)
 }
 
+func (s *TenantsUpdaterTestSuite) TestMarkInterruptedOnlyUpdateDrivenByTheReplica() {
+	s.T().Run("ongoing update driven by the replica is marked as interrupted", func(t *testing.T) {
+		// given
+		s.tx(t, func(repo update.Repository) error {
+			if err := repo.PrepareForUpdating(); err != nil {
+				return err
+			}
+			return repo.SetDriver("replica-to-shut-down")
+		})
+
+		// when
+		err := update.MarkInterrupted(s.DB, "replica-to-shut-down")
+
+		// then
+		require.NoError(t, err)
+		tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+		require.NoError(t, err)
+		assert.Equal(t, update.Interrupted, tenantsUpdate.Status)
+	})
+
+	s.T().Run("update driven by another replica is not changed", func(t *testing.T) {
+		// given
+		s.tx(t, func(repo update.Repository) error {
+			if err := repo.PrepareForUpdating(); err != nil {
+				return err
+			}
+			return repo.SetDriver("another-replica")
+		})
+
+		// when
+		err := update.MarkInterrupted(s.DB, "replica-to-shut-down")
+
+		// then
+		require.NoError(t, err)
+		tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+		require.NoError(t, err)
+		assert.Equal(t, update.Updating, tenantsUpdate.Status)
+	})
+
+	s.T().Run("finished update is not changed", func(t *testing.T) {
+		// given
+		s.tx(t, func(repo update.Repository) error {
+			if err := repo.UpdateStatus(update.Finished); err != nil {
+				return err
+			}
+			return repo.SetDriver("replica-to-shut-down")
+		})
+
+		// when
+		err := update.MarkInterrupted(s.DB, "replica-to-shut-down")
+
+		// then
+		require.NoError(t, err)
+		tenantsUpdate, err := update.NewRepository(s.DB).GetTenantsUpdate()
+		require.NoError(t, err)
+		assert.Equal(t, update.Finished, tenantsUpdate.Status)
+	})
+}
+
 func (s *TenantsUpdaterTestSuite) prepareForParallelTest(numberOfTnnts, count int, timeToWait, timeToSleep time.Duration) (*sync.WaitGroup, *sync.WaitGroup, []*testupdate.DummyUpdateExecutor) {
 	var goroutinesCanContinue sync.WaitGroup
 	goroutinesCanContinue.Add(1)
@@ -537,7 +634,8 @@ func (s *TenantsUpdaterTestSuite) newTenantsUpdater(updateExecutor *testupdate.D
 		test.Env("F8_AUTH_TOKEN_KEY", "foo"),
 		test.Env("F8_AUTOMATED_UPDATE_RETRY_SLEEP", timeout.String()),
 		test.Env("F8_API_SERVER_USE_TLS", "false"),
-		test.Env("F8_AUTOMATED_UPDATE_TIME_GAP", "0"))
+		test.Env("F8_AUTOMATED_UPDATE_TIME_GAP", "0"),
+		test.Env("F8_AUTOMATED_UPDATE_PACING_MAX_DELAY", "0"))
 
 	saToken, err := test.NewToken(
 		map[string]interface{}{
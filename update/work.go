@@ -0,0 +1,566 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/dbsupport"
+	"github.com/fabric8-services/fabric8-tenant/environment"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+const WorkItemsTableName = "tenants_update_work_items"
+
+// WorkStatus is a state of a batch of tenants in the work queue shared by all replicas
+type WorkStatus string
+
+const (
+	// WorkPending is used for batches waiting for a replica to claim them
+	WorkPending WorkStatus = "pending"
+	// WorkClaimed is used for batches that are being updated by a replica
+	WorkClaimed WorkStatus = "claimed"
+	// WorkFinished is used for batches whose update was finished - the results are stored in the item
+	WorkFinished WorkStatus = "finished"
+	// WorkAbandoned is used for batches that were reassigned too many times because the replicas stopped reporting the heartbeat
+	WorkAbandoned WorkStatus = "abandoned"
+	// WorkCancelled is used for batches that won't be updated because the update of the cluster or the whole run was interrupted
+	WorkCancelled WorkStatus = "cancelled"
+)
+
+// WorkItem is a batch of tenants of one cluster that can be claimed and updated by any replica
+type WorkItem struct {
+	ID               uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	RunID            uuid.UUID `sql:"type:uuid"`
+	Stage            Stage
+	Driver           string
+	MasterURL        string
+	TenantIDs        pq.StringArray `sql:"type:uuid[]"`
+	TypesWithVersion string
+	CommitSha        string
+	Scheduled        bool
+	Status           WorkStatus
+	ClaimedBy        string
+	Attempts         int
+	HeartbeatAt      *time.Time
+	UpdatedIDs       pq.StringArray `sql:"type:uuid[]"`
+	FailedIDs        pq.StringArray `sql:"type:uuid[]"`
+	Stopped          bool
+	Postponed        bool
+	Tripped          bool
+	Error            string
+	CreatedAt        time.Time
+	FinishedAt       *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (w WorkItem) TableName() string {
+	return WorkItemsTableName
+}
+
+// GetTenantIDs returns the IDs of the tenants of the batch
+func (w *WorkItem) GetTenantIDs() []uuid.UUID {
+	return toUUIDs(w.TenantIDs)
+}
+
+// GetUpdatedIDs returns the IDs of the tenants that were successfully updated
+func (w *WorkItem) GetUpdatedIDs() []uuid.UUID {
+	return toUUIDs(w.UpdatedIDs)
+}
+
+// GetFailedIDs returns the IDs of the tenants whose update failed
+func (w *WorkItem) GetFailedIDs() []uuid.UUID {
+	return toUUIDs(w.FailedIDs)
+}
+
+func (w *WorkItem) getTypesWithVersion() (map[environment.Type]string, error) {
+	versions := map[string]string{}
+	if err := json.Unmarshal([]byte(w.TypesWithVersion), &versions); err != nil {
+		return nil, errs.Wrapf(err, "unable to parse the versions of the environment types of the work item %s", w.ID)
+	}
+	typesWithVersion := map[environment.Type]string{}
+	for envType, version := range versions {
+		typesWithVersion[environment.Type(envType)] = version
+	}
+	return typesWithVersion, nil
+}
+
+func (w *WorkItem) isDone() bool {
+	return w.Status == WorkFinished || w.Status == WorkAbandoned || w.Status == WorkCancelled
+}
+
+func toArray(ids []uuid.UUID) pq.StringArray {
+	values := pq.StringArray{}
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return values
+}
+
+func toUUIDs(ids pq.StringArray) []uuid.UUID {
+	var result []uuid.UUID
+	for _, id := range ids {
+		if value, err := uuid.FromString(id); err == nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// AddWorkItem adds the batch to the work queue so it can be claimed by any replica
+func (r *GormRepository) AddWorkItem(item *WorkItem) error {
+	item.ID = uuid.NewV4()
+	item.Status = WorkPending
+	item.CreatedAt = time.Now()
+	if err := r.tx.Create(item).Error; err != nil {
+		return errs.Wrapf(err, "failed to add work item to %s table", WorkItemsTableName)
+	}
+	return nil
+}
+
+// ClaimWorkItem assigns the oldest pending batch created by the same commit to the given worker. The rows locked by
+// other replicas are skipped so more replicas can claim batches at the same time. The batches of the clusters whose
+// breaker has been tripped within the run are not handed out. Returns nil if there is no pending batch.
+func (r *GormRepository) ClaimWorkItem(worker, commit string) (*WorkItem, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET status = ?, claimed_by = ?, attempts = attempts + 1, heartbeat_at = NOW()
+		WHERE id = (SELECT id FROM %[1]s w WHERE status = ? AND commit_sha = ?
+			AND NOT EXISTS (SELECT 1 FROM %[2]s b WHERE b.run_id = w.run_id AND b.tripped AND b.master_url IN (w.master_url, ''))
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING *`, WorkItemsTableName, RunBreakersTableName)
+	var items []*WorkItem
+	if err := r.tx.Raw(query, WorkClaimed, worker, WorkPending, commit).Scan(&items).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed to claim work item from %s table", WorkItemsTableName)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
+
+// HeartbeatWorkItem reports that the worker is still updating the batch. Returns false if the batch
+// isn't assigned to the worker anymore
+func (r *GormRepository) HeartbeatWorkItem(itemID uuid.UUID, worker string) (bool, error) {
+	result := r.tx.Table(WorkItemsTableName).Where("id = ? AND claimed_by = ? AND status = ?", itemID, worker, WorkClaimed).
+		UpdateColumn("heartbeat_at", gorm.Expr("NOW()"))
+	if result.Error != nil {
+		return false, errs.Wrapf(result.Error, "failed to update heartbeat of work item %s", itemID)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FinishWorkItem stores the results of the batch update. The results are ignored if the batch was reassigned to another worker
+func (r *GormRepository) FinishWorkItem(item *WorkItem) error {
+	err := r.tx.Table(WorkItemsTableName).Where("id = ? AND claimed_by = ? AND status = ?", item.ID, item.ClaimedBy, WorkClaimed).
+		Updates(map[string]interface{}{
+			"status":      WorkFinished,
+			"updated_ids": item.UpdatedIDs,
+			"failed_ids":  item.FailedIDs,
+			"stopped":     item.Stopped,
+			"postponed":   item.Postponed,
+			"tripped":     item.Tripped,
+			"error":       item.Error,
+			"finished_at": time.Now(),
+		}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to finish work item %s", item.ID)
+	}
+	return nil
+}
+
+// ReleaseWorkItem returns the batch claimed by the worker back to the queue without counting the attempt
+func (r *GormRepository) ReleaseWorkItem(item *WorkItem) error {
+	err := r.tx.Table(WorkItemsTableName).Where("id = ? AND claimed_by = ? AND status = ?", item.ID, item.ClaimedBy, WorkClaimed).
+		Updates(map[string]interface{}{
+			"status":   WorkPending,
+			"attempts": gorm.Expr("attempts - 1"),
+		}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to release work item %s", item.ID)
+	}
+	return nil
+}
+
+// ReassignStaleWorkItems returns the batches of the run whose workers stopped reporting the heartbeat back to the queue.
+// The batches that were already claimed the maximal number of times are abandoned
+func (r *GormRepository) ReassignStaleWorkItems(runID uuid.UUID, staleTimeout time.Duration, maxAttempts int) error {
+	query := fmt.Sprintf(`UPDATE %s SET
+		status = CASE WHEN attempts >= ? THEN ? ELSE ? END,
+		error = CASE WHEN attempts >= ? THEN 'no heartbeat from ' || claimed_by ELSE error END,
+		finished_at = CASE WHEN attempts >= ? THEN NOW() ELSE NULL END
+		WHERE run_id = ? AND status = ? AND heartbeat_at < NOW() - make_interval(secs => ?)`, WorkItemsTableName)
+	err := r.tx.Exec(query, maxAttempts, WorkAbandoned, WorkPending, maxAttempts, maxAttempts, runID, WorkClaimed,
+		staleTimeout.Seconds()).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to reassign stale work items of run %s", runID)
+	}
+	return nil
+}
+
+// CancelWorkItems cancels the pending batches of the given run and cluster. If the run ID is nil, then all pending
+// batches of all runs are cancelled; if the cluster URL is empty, then the batches of all clusters are cancelled
+func (r *GormRepository) CancelWorkItems(runID uuid.UUID, clusterURL string) error {
+	query := r.tx.Table(WorkItemsTableName).Where("status = ?", WorkPending)
+	if !uuid.Equal(runID, uuid.Nil) {
+		query = query.Where("run_id = ?", runID)
+	}
+	if clusterURL != "" {
+		query = query.Where("master_url = ?", clusterURL)
+	}
+	err := query.Updates(map[string]interface{}{"status": WorkCancelled, "finished_at": time.Now()}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed to cancel work items in %s table", WorkItemsTableName)
+	}
+	return nil
+}
+
+// GetWorkItems returns all batches of the given run ordered by the time they were created
+func (r *GormRepository) GetWorkItems(runID uuid.UUID) ([]*WorkItem, error) {
+	var items []*WorkItem
+	err := r.tx.Table(WorkItemsTableName).Where("run_id = ?", runID).Order("created_at").Find(&items).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to get work items of the update run %s", runID)
+	}
+	return items, nil
+}
+
+// workQueue dispatches the batches of tenants to the work queue shared by all replicas and collects the results
+type workQueue struct {
+	db        *gorm.DB
+	config    *configuration.Data
+	scheduled bool
+}
+
+func newWorkQueue(db *gorm.DB, config *configuration.Data, schedule *Schedule) *workQueue {
+	if !config.IsAutomatedUpdateWorkDistributed() {
+		return nil
+	}
+	return &workQueue{
+		db:        db,
+		config:    config,
+		scheduled: schedule != nil,
+	}
+}
+
+func (q *workQueue) enqueue(run *stageRun, clusterURL string, tenants []*tenant.Tenant, typesWithVersion map[environment.Type]string) error {
+	if len(tenants) == 0 {
+		return nil
+	}
+	var ids []uuid.UUID
+	for _, tnnt := range tenants {
+		ids = append(ids, tnnt.ID)
+	}
+	versions := map[string]string{}
+	for envType, version := range typesWithVersion {
+		versions[envType.String()] = version
+	}
+	return dbsupport.Transaction(q.db, func(tx *gorm.DB) error {
+		return NewRepository(tx).AddWorkItem(&WorkItem{
+			RunID:            run.runID,
+			Stage:            run.stage,
+			Driver:           run.driver,
+			MasterURL:        clusterURL,
+			TenantIDs:        toArray(ids),
+			TypesWithVersion: marshalVersions(versions),
+			CommitSha:        configuration.Commit,
+			Scheduled:        q.scheduled,
+		})
+	})
+}
+
+// await waits until all batches of the cluster enqueued within the stage are done and adds their results to the stage
+// statistics. Returns false if the update of any batch was stopped
+func (q *workQueue) await(run *stageRun, clusterURL string) (bool, error) {
+	canContinue := true
+	collected := map[uuid.UUID]bool{}
+	for {
+		var items []*WorkItem
+		err := dbsupport.Transaction(q.db, lock(func(repo Repository) error {
+			err := repo.ReassignStaleWorkItems(run.runID, q.config.GetAutomatedUpdateWorkStaleTimeout(),
+				q.config.GetAutomatedUpdateWorkMaxAttempts())
+			if err != nil {
+				return err
+			}
+			if items, err = repo.GetWorkItems(run.runID); err != nil {
+				return err
+			}
+			return repo.UpdateLastTimeUpdated()
+		}))
+		if err != nil {
+			return false, err
+		}
+
+		if run.breakers.forCluster(clusterURL).isTripped() {
+			// the batches of the cluster that haven't been claimed yet won't be handed out anymore
+			err := dbsupport.Transaction(q.db, func(tx *gorm.DB) error {
+				return NewRepository(tx).CancelWorkItems(run.runID, clusterURL)
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+
+		remaining := 0
+		for _, item := range items {
+			if item.Stage != run.stage || item.MasterURL != clusterURL {
+				continue
+			}
+			if !item.isDone() {
+				remaining++
+				continue
+			}
+			if collected[item.ID] {
+				continue
+			}
+			collected[item.ID] = true
+			itemCanContinue, err := q.collect(run, item)
+			if err != nil {
+				return false, err
+			}
+			canContinue = canContinue && itemCanContinue
+		}
+		if remaining == 0 {
+			return canContinue, nil
+		}
+		time.Sleep(q.config.GetAutomatedUpdateWorkPollInterval())
+	}
+}
+
+func (q *workQueue) collect(run *stageRun, item *WorkItem) (bool, error) {
+	switch item.Status {
+	case WorkAbandoned:
+		tenantIDs := item.GetTenantIDs()
+		sentry.LogError(nil, map[string]interface{}{
+			"work_item":   item.ID,
+			"cluster_url": item.MasterURL,
+			"attempts":    item.Attempts,
+		}, errs.New(item.Error), "the batch of tenants was abandoned")
+		for _, tenantID := range tenantIDs {
+			run.stats.record(tenantID, errs.New(item.Error))
+		}
+		return true, dbsupport.Transaction(q.db, lock(func(repo Repository) error {
+			for range tenantIDs {
+				if err := repo.IncrementFailedCount(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	case WorkFinished:
+		for _, tenantID := range item.GetUpdatedIDs() {
+			run.stats.record(tenantID, nil)
+		}
+		for _, tenantID := range item.GetFailedIDs() {
+			run.stats.record(tenantID, fmt.Errorf("update of tenant failed"))
+		}
+		if item.Postponed {
+			run.stats.markPostponed()
+		}
+		if item.Tripped {
+			// the breaker of the cluster was tripped by the worker - the rest of the batches of the cluster won't be updated
+			err := dbsupport.Transaction(q.db, func(tx *gorm.DB) error {
+				return NewRepository(tx).CancelWorkItems(run.runID, item.MasterURL)
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+		if item.Error != "" {
+			return false, fmt.Errorf("the update of batch %s of cluster %s failed: %s", item.ID, item.MasterURL, item.Error)
+		}
+		return !item.Stopped, nil
+	}
+	return true, nil
+}
+
+// Worker claims the batches of tenants from the work queue shared by all replicas and updates them. Every replica
+// runs its own worker, so the update throughput scales with the number of replicas.
+type Worker struct {
+	db             *gorm.DB
+	config         *configuration.Data
+	updateExecutor Executor
+	identity       string
+	stop           chan struct{}
+	stopOnce       sync.Once
+	stopped        sync.WaitGroup
+}
+
+// NewWorker creates a worker with the given identity - it should be unique among all replicas
+func NewWorker(db *gorm.DB, config *configuration.Data, updateExecutor Executor, identity string) *Worker {
+	return &Worker{
+		db:             db,
+		config:         config,
+		updateExecutor: updateExecutor,
+		identity:       identity,
+		stop:           make(chan struct{}),
+	}
+}
+
+// Start starts claiming the batches in the configured interval
+func (w *Worker) Start() {
+	w.stopped.Add(1)
+	go func() {
+		defer w.stopped.Done()
+		ticker := time.NewTicker(w.config.GetAutomatedUpdateWorkPollInterval())
+		defer ticker.Stop()
+		for {
+			// claim the next batch immediately when there was one
+			for w.claimAndUpdate() {
+				select {
+				case <-w.stop:
+					return
+				default:
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops claiming new batches and waits until the update of the current one is finished. It can be called repeatedly
+func (w *Worker) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	w.stopped.Wait()
+}
+
+func (w *Worker) claimAndUpdate() bool {
+	if shutdown.IsShuttingDown() {
+		return false
+	}
+	var item *WorkItem
+	err := dbsupport.Transaction(w.db, func(tx *gorm.DB) error {
+		var err error
+		item, err = NewRepository(tx).ClaimWorkItem(w.identity, configuration.Commit)
+		return err
+	})
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err":    err,
+			"worker": w.identity,
+		}, "unable to claim a batch of tenants")
+		return false
+	}
+	if item == nil {
+		return false
+	}
+	w.update(item)
+	return true
+}
+
+func (w *Worker) update(item *WorkItem) {
+	logParams := map[string]interface{}{
+		"work_item":   item.ID,
+		"worker":      w.identity,
+		"cluster_url": item.MasterURL,
+		"attempt":     item.Attempts,
+	}
+	log.Info(nil, logParams, "starting update of the claimed batch of tenants")
+
+	stopHeartbeat := w.heartbeat(item)
+	defer close(stopHeartbeat)
+
+	var tenants []*tenant.Tenant
+	for _, tenantID := range item.GetTenantIDs() {
+		tnnt, err := tenant.NewTenantRepository(w.db, tenantID).GetTenant()
+		if err != nil {
+			log.Warn(nil, map[string]interface{}{
+				"tenant_id": tenantID,
+				"err":       err,
+			}, "unable to get the tenant of the claimed batch - skipping it")
+			continue
+		}
+		tenants = append(tenants, tnnt)
+	}
+
+	run := &stageRun{
+		runID:        item.RunID,
+		driver:       item.Driver,
+		stage:        item.Stage,
+		selectTenant: allTenants,
+		stats:        &stageStats{},
+		breakers:     newRunBreakers(w.db, w.config, item.RunID),
+	}
+	if item.Scheduled {
+		schedule, err := NewSchedule(w.config)
+		if err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err": err,
+			}, "parsing of maintenance windows failed - the batch is updated regardless of the windows")
+		}
+		run.schedule = schedule
+	}
+
+	canContinue := false
+	typesWithVersion, err := item.getTypesWithVersion()
+	if err == nil {
+		canContinue, err = updateTenants(item.MasterURL, tenants, typesWithVersion, w.db, w.config, w.updateExecutor, run,
+			newPacer(w.config))
+	}
+
+	item.UpdatedIDs = toArray(run.stats.updated)
+	item.FailedIDs = toArray(run.stats.failed)
+	item.Stopped = !canContinue
+	item.Postponed = run.stats.postponed
+	item.Tripped = run.breakers.forCluster(item.MasterURL).isTripped()
+	if err != nil {
+		item.Error = err.Error()
+	}
+	err = dbsupport.Transaction(w.db, func(tx *gorm.DB) error {
+		if shutdown.IsShuttingDown() {
+			// the batch was interrupted - it is returned to the queue so another replica can finish it
+			return NewRepository(tx).ReleaseWorkItem(item)
+		}
+		return NewRepository(tx).FinishWorkItem(item)
+	})
+	if err != nil {
+		sentry.LogError(nil, logParams, err, "unable to store results of the batch of tenants")
+		return
+	}
+	log.Info(nil, logParams, "update of the claimed batch of tenants finished")
+}
+
+func (w *Worker) heartbeat(item *WorkItem) chan struct{} {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.config.GetAutomatedUpdateWorkHeartbeatInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+			err := dbsupport.Transaction(w.db, func(tx *gorm.DB) error {
+				assigned, err := NewRepository(tx).HeartbeatWorkItem(item.ID, w.identity)
+				if err == nil && !assigned {
+					log.Warn(nil, map[string]interface{}{
+						"work_item": item.ID,
+						"worker":    w.identity,
+					}, "the batch of tenants has been reassigned to another worker")
+				}
+				return err
+			})
+			if err != nil {
+				log.Error(nil, map[string]interface{}{
+					"err":       err,
+					"work_item": item.ID,
+				}, "unable to report heartbeat of the batch of tenants")
+			}
+		}
+	}()
+	return stop
+}
@@ -0,0 +1,277 @@
+package update_test
+
+import (
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/convert/ptr"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/dbsupport"
+	"github.com/fabric8-services/fabric8-tenant/tenant"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/doubles"
+	tf "github.com/fabric8-services/fabric8-tenant/test/testfixture"
+	"github.com/fabric8-services/fabric8-tenant/test/update"
+	"github.com/fabric8-services/fabric8-tenant/update"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	"time"
+)
+
+func (s *TenantsUpdaterTestSuite) TestUpdateIsDistributedAmongWorkers() {
+	// given
+	defer gock.OffAll()
+	testdoubles.MockCommunicationWithAuth(test.ClusterURL)
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	_, reset := s.newTenantsUpdater(updateExecutor, 0, update.AllTypes, "")
+	defer reset()
+	resetEnvs := test.SetEnvironments(
+		test.Env("F8_AUTOMATED_UPDATE_WORK_DISTRIBUTED", "true"),
+		test.Env("F8_AUTOMATED_UPDATE_WORK_POLL_INTERVAL", "50ms"))
+	defer resetEnvs()
+	testdoubles.SetTemplateVersions()
+	testdoubles.MockPatchRequestsToOS(ptr.Int(0), test.ClusterURL)
+
+	configuration.Commit = "124abcd"
+	tf.FillDB(s.T(), s.DB, tf.AddTenants(250), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	s.tx(s.T(), func(repo update.Repository) error {
+		return testupdate.UpdateVersionsTo(repo, "0")
+	})
+	first := update.NewWorker(s.DB, s.Configuration, updateExecutor, "first-replica")
+	first.Start()
+	defer first.Stop()
+	second := update.NewWorker(s.DB, s.Configuration, updateExecutor, "second-replica")
+	second.Start()
+	defer second.Stop()
+
+	// when
+	update.NewTenantsUpdater(s.DB, s.Configuration, updateExecutor.ClusterService, updateExecutor, update.AllTypes, "").
+		UpdateAllTenants()
+
+	// then
+	assert.Equal(s.T(), 250, int(*updateExecutor.NumberOfCalls))
+	s.assertStatusAndAllVersionAreUpToDate(s.T(), update.Finished)
+
+	repo := update.NewRepository(s.DB)
+	tenantsUpdate, err := repo.GetTenantsUpdate()
+	require.NoError(s.T(), err)
+	items, err := repo.GetWorkItems(*tenantsUpdate.RunID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), items, 3)
+	for _, item := range items {
+		assert.Equal(s.T(), update.WorkFinished, item.Status)
+		assert.Contains(s.T(), []string{"first-replica", "second-replica"}, item.ClaimedBy)
+		assert.Len(s.T(), item.GetUpdatedIDs(), len(item.GetTenantIDs()))
+	}
+	tenants, err := repo.GetRunTenants(*tenantsUpdate.RunID)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), tenants, 250)
+}
+
+func (s *TenantsUpdaterTestSuite) TestWorkerFailsBatchWithCorruptedVersions() {
+	// given
+	updateExecutor := testupdate.NewDummyUpdateExecutor(s.DB, s.Configuration)
+	resetEnvs := test.SetEnvironments(test.Env("F8_AUTOMATED_UPDATE_WORK_POLL_INTERVAL", "50ms"))
+	defer resetEnvs()
+	config, reset := test.LoadTestConfig(s.T())
+	defer reset()
+
+	configuration.Commit = "124abcd"
+	fxt := tf.FillDB(s.T(), s.DB, tf.AddTenants(2), tf.AddDefaultNamespaces().State(tenant.Ready).Outdated())
+	run := &update.Run{TriggeredBy: update.TriggerAPI}
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		repo := update.NewRepository(tx)
+		if err := repo.StartRun(run); err != nil {
+			return err
+		}
+		return repo.AddWorkItem(&update.WorkItem{
+			RunID:            run.ID,
+			Stage:            update.Rollout,
+			MasterURL:        test.ClusterURL,
+			TenantIDs:        pq.StringArray{fxt.Tenants[0].ID.String(), fxt.Tenants[1].ID.String()},
+			TypesWithVersion: "{corrupted",
+			CommitSha:        "124abcd",
+		})
+	})
+	require.NoError(s.T(), err)
+	worker := update.NewWorker(s.DB, config, updateExecutor, "first-replica")
+
+	// when
+	worker.Start()
+	defer worker.Stop()
+
+	// then
+	var items []*update.WorkItem
+	err = test.WaitWithTimeout(5 * time.Second).Until(func() error {
+		items, err = update.NewRepository(s.DB).GetWorkItems(run.ID)
+		if err != nil {
+			return err
+		}
+		if len(items) != 1 || items[0].Status != update.WorkFinished {
+			return fmt.Errorf("the batch hasn't been finished yet")
+		}
+		return nil
+	})
+	require.NoError(s.T(), err)
+	assert.Contains(s.T(), items[0].Error, "unable to parse the versions of the environment types")
+	assert.Empty(s.T(), items[0].GetUpdatedIDs())
+	assert.Equal(s.T(), 0, int(*updateExecutor.NumberOfCalls))
+}
+
+func (s *UpdateRepoTestSuite) TestClaimAndReassignWorkItems() {
+	// given
+	run := &update.Run{TriggeredBy: update.TriggerAPI}
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		repo := update.NewRepository(tx)
+		if err := repo.StartRun(run); err != nil {
+			return err
+		}
+		return repo.AddWorkItem(&update.WorkItem{
+			RunID:     run.ID,
+			Stage:     update.Rollout,
+			MasterURL: "http://api.cluster1/",
+			TenantIDs: pq.StringArray{uuid.NewV4().String()},
+			CommitSha: "124abcd",
+		})
+	})
+	require.NoError(s.T(), err)
+
+	claim := func(worker, commit string) *update.WorkItem {
+		var item *update.WorkItem
+		err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+			var err error
+			item, err = update.NewRepository(tx).ClaimWorkItem(worker, commit)
+			return err
+		})
+		require.NoError(s.T(), err)
+		return item
+	}
+	reassign := func() {
+		err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+			return update.NewRepository(tx).ReassignStaleWorkItems(run.ID, 0, 2)
+		})
+		require.NoError(s.T(), err)
+	}
+
+	// when
+	otherCommit := claim("first-replica", "xyz")
+	first := claim("first-replica", "124abcd")
+	nothingLeft := claim("second-replica", "124abcd")
+	time.Sleep(10 * time.Millisecond)
+	reassign()
+	second := claim("second-replica", "124abcd")
+	time.Sleep(10 * time.Millisecond)
+	reassign()
+
+	// then
+	assert.Nil(s.T(), otherCommit)
+	require.NotNil(s.T(), first)
+	assert.Equal(s.T(), "first-replica", first.ClaimedBy)
+	assert.Equal(s.T(), 1, first.Attempts)
+	assert.Nil(s.T(), nothingLeft)
+	require.NotNil(s.T(), second)
+	assert.Equal(s.T(), "second-replica", second.ClaimedBy)
+	assert.Equal(s.T(), 2, second.Attempts)
+
+	items, err := update.NewRepository(s.DB).GetWorkItems(run.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), items, 1)
+	assert.Equal(s.T(), update.WorkAbandoned, items[0].Status)
+	assert.Equal(s.T(), "no heartbeat from second-replica", items[0].Error)
+	assert.NotNil(s.T(), items[0].FinishedAt)
+}
+
+func (s *UpdateRepoTestSuite) TestBreakerOfRunCountsResultsOfAllBatches() {
+	// given
+	run := &update.Run{TriggeredBy: update.TriggerAPI}
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		return update.NewRepository(tx).StartRun(run)
+	})
+	require.NoError(s.T(), err)
+	var states []*update.RunBreaker
+	record := func(failed bool) {
+		err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+			state, err := update.NewRepository(tx).RecordBreakerResult(run.ID, "http://api.cluster1/", failed)
+			states = append(states, state)
+			return err
+		})
+		require.NoError(s.T(), err)
+	}
+
+	// when
+	record(true)
+	record(true)
+	record(false)
+	record(true)
+	var first, second bool
+	err = dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		var err error
+		if first, err = update.NewRepository(tx).TripBreaker(run.ID, "http://api.cluster1/"); err != nil {
+			return err
+		}
+		second, err = update.NewRepository(tx).TripBreaker(run.ID, "http://api.cluster1/")
+		return err
+	})
+
+	// then
+	require.NoError(s.T(), err)
+	require.Len(s.T(), states, 4)
+	assert.Equal(s.T(), 2, states[1].Consecutive)
+	last := states[3]
+	assert.Equal(s.T(), 4, last.Attempts)
+	assert.Equal(s.T(), 3, last.Failed)
+	assert.Equal(s.T(), 1, last.Consecutive)
+	assert.True(s.T(), first)
+	assert.False(s.T(), second)
+	tripped, err := update.NewRepository(s.DB).IsBreakerTripped(run.ID, "http://api.cluster1/")
+	require.NoError(s.T(), err)
+	assert.True(s.T(), tripped)
+}
+
+func (s *UpdateRepoTestSuite) TestWorkItemsOfTrippedClusterAreNotClaimed() {
+	// given
+	run := &update.Run{TriggeredBy: update.TriggerAPI}
+	err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+		repo := update.NewRepository(tx)
+		if err := repo.StartRun(run); err != nil {
+			return err
+		}
+		for _, clusterURL := range []string{"http://api.cluster1/", "http://api.cluster2/"} {
+			err := repo.AddWorkItem(&update.WorkItem{
+				RunID:     run.ID,
+				Stage:     update.Rollout,
+				MasterURL: clusterURL,
+				TenantIDs: pq.StringArray{uuid.NewV4().String()},
+				CommitSha: "124abcd",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := repo.RecordBreakerResult(run.ID, "http://api.cluster1/", true); err != nil {
+			return err
+		}
+		_, err := repo.TripBreaker(run.ID, "http://api.cluster1/")
+		return err
+	})
+	require.NoError(s.T(), err)
+
+	// when
+	var claimed []*update.WorkItem
+	for i := 0; i < 2; i++ {
+		err := dbsupport.Transaction(s.DB, func(tx *gorm.DB) error {
+			item, err := update.NewRepository(tx).ClaimWorkItem("first-replica", "124abcd")
+			if item != nil {
+				claimed = append(claimed, item)
+			}
+			return err
+		})
+		require.NoError(s.T(), err)
+	}
+
+	// then
+	require.Len(s.T(), claimed, 1)
+	assert.Equal(s.T(), "http://api.cluster2/", claimed[0].MasterURL)
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/configuration"
+	"github.com/fabric8-services/fabric8-tenant/dbsupport"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/fabric8-services/fabric8-tenant/shutdown"
+	"github.com/jinzhu/gorm"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader contains the HMAC-SHA256 of the request body computed with the secret of the subscription
+	SignatureHeader = "X-Tenant-Signature"
+	// EventHeader contains the type of the sent event
+	EventHeader = "X-Tenant-Event"
+	// DeliveryHeader contains the ID of the delivery - it is the same for all attempts of the delivery
+	DeliveryHeader = "X-Tenant-Delivery"
+
+	maxStoredErrorLength = 1024
+)
+
+// Sign computes the signature of the body sent in the SignatureHeader. The receiver should compute it from the received body
+// using the same secret and compare it with the header value
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next attempt of a delivery that has already been attempted the given number of times.
+// The delay is doubled after every attempt up to the maximal one.
+func Backoff(attempts int, initial, max time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// Dispatcher periodically claims the pending deliveries and sends them to the webhooks. Every replica runs its own dispatcher - the
+// deliveries are claimed in the DB so every attempt is sent only by one of them.
+type Dispatcher struct {
+	db     *gorm.DB
+	config *configuration.Data
+	client *http.Client
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher sending the deliveries using the given transport (the default one is used when nil)
+func NewDispatcher(db *gorm.DB, config *configuration.Data, transport http.RoundTripper) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		config: config,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.GetWebhookDeliveryTimeout(),
+		},
+		stop: make(chan struct{}),
+	}
+}
+
+// Start starts sending the deliveries in the configured interval
+func (d *Dispatcher) Start() {
+	d.stopped.Add(1)
+	go func() {
+		defer d.stopped.Done()
+		ticker := time.NewTicker(d.config.GetWebhookDeliveryPollInterval())
+		defer ticker.Stop()
+		for {
+			// claim the next deliveries immediately when the whole batch was claimed
+			for d.Dispatch() >= d.config.GetWebhookDeliveryBatchSize() {
+				select {
+				case <-d.stop:
+					return
+				default:
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops claiming new deliveries and waits until the current ones are sent
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+	d.stopped.Wait()
+}
+
+// Dispatch claims one batch of the pending deliveries and sends them in parallel. Returns the number of claimed deliveries
+func (d *Dispatcher) Dispatch() int {
+	if shutdown.IsShuttingDown() {
+		return 0
+	}
+	var deliveries []*Delivery
+	err := dbsupport.Transaction(d.db, func(tx *gorm.DB) error {
+		var err error
+		// the lease covers all attempts of the request including the time for storing the result
+		deliveries, err = NewRepository(tx).ClaimDeliveries(d.config.GetWebhookDeliveryBatchSize(), 2*d.config.GetWebhookDeliveryTimeout())
+		return err
+	})
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to claim webhook deliveries")
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(deliveries))
+	for _, delivery := range deliveries {
+		go func(delivery *Delivery) {
+			defer wg.Done()
+			d.deliver(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+func (d *Dispatcher) deliver(delivery *Delivery) {
+	logParams := map[string]interface{}{
+		"delivery_id":     delivery.ID,
+		"subscription_id": delivery.SubscriptionID,
+		"event_type":      delivery.EventType,
+		"attempt":         delivery.Attempts,
+	}
+	repo := NewRepository(d.db)
+	subscription, err := repo.getSubscriptionByID(delivery.SubscriptionID)
+	if err != nil {
+		sentry.LogError(nil, logParams, err, "unable to get the subscription of the webhook delivery")
+		return
+	}
+	if subscription == nil {
+		// the subscription was removed in the meantime - the delivery was removed together with it
+		return
+	}
+
+	statusCode, err := d.send(subscription, delivery)
+	if err == nil {
+		if err := repo.MarkDelivered(delivery, statusCode); err != nil {
+			sentry.LogError(nil, logParams, err, "unable to store the result of the webhook delivery")
+		}
+		log.Info(nil, logParams, "webhook event delivered")
+		return
+	}
+
+	var retryAt *time.Time
+	if delivery.Attempts < d.config.GetWebhookDeliveryMaxAttempts() {
+		next := time.Now().Add(Backoff(delivery.Attempts, d.config.GetWebhookDeliveryInitialBackoff(), d.config.GetWebhookDeliveryMaxBackoff()))
+		retryAt = &next
+	}
+	failure := err.Error()
+	if len(failure) > maxStoredErrorLength {
+		failure = failure[:maxStoredErrorLength]
+	}
+	if err := repo.MarkFailedAttempt(delivery, statusCode, failure, retryAt); err != nil {
+		sentry.LogError(nil, logParams, err, "unable to store the result of the webhook delivery")
+	}
+	logParams["err"] = failure
+	logParams["status_code"] = statusCode
+	if retryAt == nil {
+		log.Error(nil, logParams, "webhook delivery failed the maximal number of times - giving up")
+	} else {
+		logParams["retry_at"] = retryAt
+		log.Warn(nil, logParams, "webhook delivery failed - it will be retried")
+	}
+}
+
+func (d *Dispatcher) send(subscription *Subscription, delivery *Delivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, body))
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("the webhook %s responded with %s", subscription.URL, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,187 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/fabric8-services/fabric8-tenant/test"
+	"github.com/fabric8-services/fabric8-tenant/test/gormsupport"
+	"github.com/fabric8-services/fabric8-tenant/webhook"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/h2non/gock.v1"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type DispatcherTestSuite struct {
+	gormsupport.DBTestSuite
+}
+
+func TestDispatcher(t *testing.T) {
+	suite.Run(t, &DispatcherTestSuite{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, webhook.Backoff(1, time.Second, time.Minute))
+	assert.Equal(t, 2*time.Second, webhook.Backoff(2, time.Second, time.Minute))
+	assert.Equal(t, 8*time.Second, webhook.Backoff(4, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, webhook.Backoff(7, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, webhook.Backoff(100, time.Second, time.Minute))
+}
+
+func (s *DispatcherTestSuite) TestPublishStoresDeliveriesOnlyForInterestedSubscriptions() {
+	// given
+	webhook.Init(s.DB)
+	defer webhook.Init(nil)
+	all := s.subscribe("http://all.example.com/events")
+	ready := s.subscribe("http://ready.example.com/events", webhook.NamespaceReady)
+	deleted := s.subscribe("http://deleted.example.com/events", webhook.TenantDeleted)
+	event := webhook.NewTenantEvent(webhook.TenantDeleted, uuid.NewV4(), nil)
+
+	// when
+	webhook.Publish(context.Background(), event)
+
+	// then
+	s.assertDeliveries(all, webhook.DeliveryPending)
+	s.assertDeliveries(ready)
+	deliveries := s.assertDeliveries(deleted, webhook.DeliveryPending)
+	assert.Equal(s.T(), event.ID, deliveries[0].EventID)
+	var payload webhook.Event
+	require.NoError(s.T(), json.Unmarshal([]byte(deliveries[0].Payload), &payload))
+	assert.Equal(s.T(), event.TenantID, payload.TenantID)
+	assert.Equal(s.T(), webhook.TenantDeleted, payload.Type)
+}
+
+func (s *DispatcherTestSuite) TestDispatchSendsSignedEvent() {
+	// given
+	defer gock.OffAll()
+	webhook.Init(s.DB)
+	defer webhook.Init(nil)
+	subscription := s.subscribe("http://billing.example.com/events")
+	event := webhook.NewNamespaceEvent(webhook.NamespaceReady, uuid.NewV4(), &webhook.Namespace{
+		Name: "john-che", Type: "che", MasterURL: test.ClusterURL, State: "ready", Action: "create"})
+	webhook.Publish(context.Background(), event)
+
+	var receivedSignature, receivedBody string
+	gock.New("http://billing.example.com").
+		Post("/events").
+		MatchHeader(webhook.EventHeader, string(webhook.NamespaceReady)).
+		AddMatcher(func(req *http.Request, gockReq *gock.Request) (bool, error) {
+			body, err := ioutil.ReadAll(req.Body)
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			receivedBody = string(body)
+			receivedSignature = req.Header.Get(webhook.SignatureHeader)
+			return err == nil, err
+		}).
+		Reply(200)
+
+	// when
+	claimed := webhook.NewDispatcher(s.DB, s.Configuration, nil).Dispatch()
+
+	// then
+	assert.Equal(s.T(), 1, claimed)
+	assert.True(s.T(), gock.IsDone())
+	assert.Equal(s.T(), webhook.Sign(subscription.Secret, []byte(receivedBody)), receivedSignature)
+	assert.Contains(s.T(), receivedBody, `"name":"john-che"`)
+	deliveries := s.assertDeliveries(subscription, webhook.DeliveryDelivered)
+	assert.Equal(s.T(), 1, deliveries[0].Attempts)
+	assert.Equal(s.T(), 200, deliveries[0].LastStatusCode)
+	assert.NotNil(s.T(), deliveries[0].DeliveredAt)
+}
+
+func (s *DispatcherTestSuite) TestFailedDeliveryIsRetriedWithBackoff() {
+	// given
+	defer gock.OffAll()
+	webhook.Init(s.DB)
+	defer webhook.Init(nil)
+	reset := test.SetEnvironments(
+		test.Env("F8_WEBHOOK_DELIVERY_INITIAL_BACKOFF", "1h"),
+		test.Env("F8_WEBHOOK_DELIVERY_MAX_ATTEMPTS", "2"))
+	defer reset()
+	subscription := s.subscribe("http://che.example.com/events")
+	webhook.Publish(context.Background(), webhook.NewTenantEvent(webhook.TenantUpdated, uuid.NewV4(), nil))
+	gock.New("http://che.example.com").
+		Post("/events").
+		Reply(503)
+	dispatcher := webhook.NewDispatcher(s.DB, s.Configuration, nil)
+
+	// when
+	claimed := dispatcher.Dispatch()
+
+	// then
+	assert.Equal(s.T(), 1, claimed)
+	deliveries := s.assertDeliveries(subscription, webhook.DeliveryPending)
+	assert.Equal(s.T(), 1, deliveries[0].Attempts)
+	assert.Equal(s.T(), 503, deliveries[0].LastStatusCode)
+	assert.Contains(s.T(), deliveries[0].LastError, "503")
+	assert.True(s.T(), deliveries[0].NextAttemptAt.After(time.Now().Add(59*time.Minute)))
+	// the next attempt isn't due yet
+	assert.Equal(s.T(), 0, dispatcher.Dispatch())
+
+	s.T().Run("delivery is marked as failed when the max attempts is reached", func(t *testing.T) {
+		// given
+		err := s.DB.Table(webhook.DeliveriesTableName).Where("id = ?", deliveries[0].ID).
+			UpdateColumn("next_attempt_at", time.Now().Add(-time.Second)).Error
+		require.NoError(t, err)
+		gock.New("http://che.example.com").
+			Post("/events").
+			ReplyError(assert.AnError)
+
+		// when
+		claimed := dispatcher.Dispatch()
+
+		// then
+		assert.Equal(t, 1, claimed)
+		deliveries := s.assertDeliveries(subscription, webhook.DeliveryFailed)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.Equal(t, 0, deliveries[0].LastStatusCode)
+		assert.Equal(t, 0, dispatcher.Dispatch())
+	})
+}
+
+func (s *DispatcherTestSuite) TestDeleteSubscriptionDropsDeliveries() {
+	// given
+	webhook.Init(s.DB)
+	defer webhook.Init(nil)
+	subscription := s.subscribe("http://all.example.com/events")
+	webhook.Publish(context.Background(), webhook.NewTenantEvent(webhook.TenantCreated, uuid.NewV4(), nil))
+	repo := webhook.NewRepository(s.DB)
+
+	// when
+	deleted, err := repo.DeleteSubscription(subscription.Owner, subscription.ID)
+
+	// then
+	require.NoError(s.T(), err)
+	assert.True(s.T(), deleted)
+	s.assertDeliveries(subscription)
+	deleted, err = repo.DeleteSubscription(subscription.Owner, subscription.ID)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), deleted)
+}
+
+func (s *DispatcherTestSuite) subscribe(url string, events ...webhook.EventType) *webhook.Subscription {
+	subscription := &webhook.Subscription{
+		Owner:  "rh-che",
+		URL:    url,
+		Secret: "very-secret-webhook-key",
+	}
+	subscription.SetEvents(events)
+	require.NoError(s.T(), webhook.NewRepository(s.DB).CreateSubscription(subscription))
+	return subscription
+}
+
+func (s *DispatcherTestSuite) assertDeliveries(subscription *webhook.Subscription, expectedStatuses ...webhook.DeliveryStatus) []*webhook.Delivery {
+	deliveries, count, err := webhook.NewRepository(s.DB).GetDeliveries(subscription.ID, 0, 100)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deliveries, len(expectedStatuses))
+	assert.Equal(s.T(), len(expectedStatuses), count)
+	for index, status := range expectedStatuses {
+		assert.Equal(s.T(), status, deliveries[index].Status)
+	}
+	return deliveries
+}
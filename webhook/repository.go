@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+const (
+	SubscriptionsTableName = "webhook_subscriptions"
+	DeliveriesTableName    = "webhook_deliveries"
+)
+
+// DeliveryStatus is a state of a delivery of one event to one subscription
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is used for deliveries that haven't been delivered yet and will be (re)tried
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered is used for deliveries the webhook responded to with 2xx code
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed is used for deliveries that failed the maximal number of times - they won't be retried anymore
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Subscription is a webhook registered by a service account. The events are sent to the URL signed by the secret
+type Subscription struct {
+	ID        uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	Owner     string
+	URL       string
+	Secret    string
+	Events    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (s Subscription) TableName() string {
+	return SubscriptionsTableName
+}
+
+// GetEvents returns the types of the events the subscription is interested in. An empty list means all of them
+func (s *Subscription) GetEvents() []EventType {
+	var events []EventType
+	if s.Events == "" {
+		return events
+	}
+	for _, event := range strings.Split(s.Events, ",") {
+		events = append(events, EventType(event))
+	}
+	return events
+}
+
+// SetEvents sets the types of the events the subscription is interested in
+func (s *Subscription) SetEvents(events []EventType) {
+	var values []string
+	for _, event := range events {
+		values = append(values, string(event))
+	}
+	s.Events = strings.Join(values, ",")
+}
+
+// Accepts returns true if the subscription is interested in the given type of events
+func (s *Subscription) Accepts(eventType EventType) bool {
+	events := s.GetEvents()
+	if len(events) == 0 {
+		return true
+	}
+	for _, event := range events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is a record of sending one event to one subscription. It serves both as a queue of the events
+// waiting to be (re)sent and as a log of the sent ones
+type Delivery struct {
+	ID             uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	SubscriptionID uuid.UUID `sql:"type:uuid"`
+	EventID        uuid.UUID `sql:"type:uuid"`
+	EventType      EventType
+	Payload        string
+	Status         DeliveryStatus
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (d Delivery) TableName() string {
+	return DeliveriesTableName
+}
+
+type Repository interface {
+	CreateSubscription(subscription *Subscription) error
+	GetSubscription(owner string, id uuid.UUID) (*Subscription, error)
+	GetSubscriptions(owner string) ([]*Subscription, error)
+	GetSubscriptionsFor(eventType EventType) ([]*Subscription, error)
+	DeleteSubscription(owner string, id uuid.UUID) (bool, error)
+	AddDelivery(delivery *Delivery) error
+	ClaimDeliveries(limit int, lease time.Duration) ([]*Delivery, error)
+	MarkDelivered(delivery *Delivery, statusCode int) error
+	MarkFailedAttempt(delivery *Delivery, statusCode int, failure string, retryAt *time.Time) error
+	GetDeliveries(subscriptionID uuid.UUID, offset, limit int) ([]*Delivery, int, error)
+}
+
+type GormRepository struct {
+	tx *gorm.DB
+}
+
+func NewRepository(tx *gorm.DB) *GormRepository {
+	return &GormRepository{
+		tx: tx,
+	}
+}
+
+// CreateSubscription stores a new subscription
+func (r *GormRepository) CreateSubscription(subscription *Subscription) error {
+	subscription.ID = uuid.NewV4()
+	if err := r.tx.Create(subscription).Error; err != nil {
+		return errors.Wrapf(err, "failed to create subscription of %s", subscription.Owner)
+	}
+	return nil
+}
+
+// GetSubscription returns the subscription with the given ID registered by the given owner. Returns nil if there is no such subscription
+func (r *GormRepository) GetSubscription(owner string, id uuid.UUID) (*Subscription, error) {
+	var subscriptions []*Subscription
+	err := r.tx.Table(SubscriptionsTableName).Where("id = ? AND owner = ?", id, owner).Find(&subscriptions).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get subscription %s", id)
+	}
+	if len(subscriptions) == 0 {
+		return nil, nil
+	}
+	return subscriptions[0], nil
+}
+
+func (r *GormRepository) getSubscriptionByID(id uuid.UUID) (*Subscription, error) {
+	var subscriptions []*Subscription
+	err := r.tx.Table(SubscriptionsTableName).Where("id = ?", id).Find(&subscriptions).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get subscription %s", id)
+	}
+	if len(subscriptions) == 0 {
+		return nil, nil
+	}
+	return subscriptions[0], nil
+}
+
+// GetSubscriptions returns all subscriptions registered by the given owner
+func (r *GormRepository) GetSubscriptions(owner string) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	err := r.tx.Table(SubscriptionsTableName).Where("owner = ?", owner).Order("created_at").Find(&subscriptions).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get subscriptions of %s", owner)
+	}
+	return subscriptions, nil
+}
+
+// GetSubscriptionsFor returns all subscriptions interested in the given type of events
+func (r *GormRepository) GetSubscriptionsFor(eventType EventType) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	err := r.tx.Table(SubscriptionsTableName).
+		Where("events = '' OR events IS NULL OR ? = ANY(string_to_array(events, ','))", eventType).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get subscriptions for %s events", eventType)
+	}
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes the subscription together with its deliveries. Returns false if there was no such subscription
+func (r *GormRepository) DeleteSubscription(owner string, id uuid.UUID) (bool, error) {
+	result := r.tx.Table(SubscriptionsTableName).Where("id = ? AND owner = ?", id, owner).Delete(&Subscription{})
+	if result.Error != nil {
+		return false, errors.Wrapf(result.Error, "failed to delete subscription %s", id)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AddDelivery adds a pending delivery that is sent as soon as any replica claims it
+func (r *GormRepository) AddDelivery(delivery *Delivery) error {
+	delivery.ID = uuid.NewV4()
+	delivery.Status = DeliveryPending
+	delivery.CreatedAt = time.Now()
+	delivery.NextAttemptAt = delivery.CreatedAt
+	if err := r.tx.Create(delivery).Error; err != nil {
+		return errors.Wrapf(err, "failed to add delivery of event %s to subscription %s", delivery.EventID, delivery.SubscriptionID)
+	}
+	return nil
+}
+
+// ClaimDeliveries takes the oldest pending deliveries whose time for the next attempt has come and counts the attempt. The next attempt
+// of the claimed deliveries is moved by the lease, so if the replica dies before the result is stored, another replica retries it later.
+// The rows locked by other replicas are skipped so more replicas can claim deliveries at the same time.
+func (r *GormRepository) ClaimDeliveries(limit int, lease time.Duration) ([]*Delivery, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => ?)
+		WHERE id IN (SELECT id FROM %[1]s WHERE status = ? AND next_attempt_at <= NOW() ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED)
+		RETURNING *`, DeliveriesTableName)
+	var deliveries []*Delivery
+	if err := r.tx.Raw(query, lease.Seconds(), DeliveryPending, limit).Scan(&deliveries).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to claim deliveries from %s table", DeliveriesTableName)
+	}
+	return deliveries, nil
+}
+
+// MarkDelivered stores the successful result of the delivery
+func (r *GormRepository) MarkDelivered(delivery *Delivery, statusCode int) error {
+	err := r.tx.Table(DeliveriesTableName).Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":           DeliveryDelivered,
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"delivered_at":     time.Now(),
+		}).Error
+	if err != nil {
+		return errors.Wrapf(err, "failed to mark delivery %s as delivered", delivery.ID)
+	}
+	return nil
+}
+
+// MarkFailedAttempt stores the result of the failed attempt. If the retry time is nil, then the delivery is marked as failed and won't be retried
+func (r *GormRepository) MarkFailedAttempt(delivery *Delivery, statusCode int, failure string, retryAt *time.Time) error {
+	values := map[string]interface{}{
+		"last_status_code": statusCode,
+		"last_error":       failure,
+	}
+	if retryAt == nil {
+		values["status"] = DeliveryFailed
+	} else {
+		values["next_attempt_at"] = *retryAt
+	}
+	err := r.tx.Table(DeliveriesTableName).Where("id = ?", delivery.ID).Updates(values).Error
+	if err != nil {
+		return errors.Wrapf(err, "failed to store failed attempt of delivery %s", delivery.ID)
+	}
+	return nil
+}
+
+// GetDeliveries returns the deliveries of the subscription starting from the latest one together with the total number of them
+func (r *GormRepository) GetDeliveries(subscriptionID uuid.UUID, offset, limit int) ([]*Delivery, int, error) {
+	var deliveries []*Delivery
+	var count int
+	err := r.tx.Table(DeliveriesTableName).Where("subscription_id = ?", subscriptionID).Count(&count).Error
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to count deliveries of subscription %s", subscriptionID)
+	}
+	err = r.tx.Table(DeliveriesTableName).Where("subscription_id = ?", subscriptionID).
+		Order("created_at desc").Offset(offset).Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to get deliveries of subscription %s", subscriptionID)
+	}
+	return deliveries, count, nil
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fabric8-services/fabric8-common/log"
+	"github.com/fabric8-services/fabric8-tenant/sentry"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+// EventType is a type of the tenant lifecycle event sent to the webhooks
+type EventType string
+
+const (
+	// TenantCreated is sent when a new tenant entity is created
+	TenantCreated EventType = "tenant.created"
+	// NamespaceReady is sent when a namespace was successfully provisioned or updated
+	NamespaceReady EventType = "namespace.ready"
+	// NamespaceFailed is sent when the provisioning, update or removal of a namespace failed
+	NamespaceFailed EventType = "namespace.failed"
+	// TenantUpdated is sent when the update of all namespaces of a tenant was finished
+	TenantUpdated EventType = "tenant.updated"
+	// TenantCleaned is sent when the namespaces of a tenant were cleaned
+	TenantCleaned EventType = "tenant.cleaned"
+	// TenantDeleted is sent when a tenant was removed together with its namespaces
+	TenantDeleted EventType = "tenant.deleted"
+	// TenantSuspended is sent when the access of the user to the namespaces of a tenant was cut off
+	TenantSuspended EventType = "tenant.suspended"
+	// TenantResumed is sent when the access of the user to the namespaces of a suspended tenant was restored
+	TenantResumed EventType = "tenant.resumed"
+)
+
+// AllEventTypes contains all types of events a webhook can subscribe to
+var AllEventTypes = []EventType{TenantCreated, NamespaceReady, NamespaceFailed, TenantUpdated, TenantCleaned, TenantDeleted,
+	TenantSuspended, TenantResumed}
+
+// Event is a tenant lifecycle event - it is sent to the webhooks as a JSON body of a POST request
+type Event struct {
+	ID        uuid.UUID              `json:"id"`
+	Type      EventType              `json:"type"`
+	Time      time.Time              `json:"time"`
+	TenantID  uuid.UUID              `json:"tenant_id"`
+	Namespace *Namespace             `json:"namespace,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Namespace is information about the namespace the event is related to
+type Namespace struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	MasterURL string `json:"cluster_url"`
+	Version   string `json:"version,omitempty"`
+	State     string `json:"state"`
+	Action    string `json:"action"`
+}
+
+// NewTenantEvent creates an event of the given type related to the whole tenant
+func NewTenantEvent(eventType EventType, tenantID uuid.UUID, data map[string]interface{}) *Event {
+	return &Event{
+		ID:       uuid.NewV4(),
+		Type:     eventType,
+		Time:     time.Now().UTC(),
+		TenantID: tenantID,
+		Data:     data,
+	}
+}
+
+// NewNamespaceEvent creates an event of the given type related to one namespace of the tenant
+func NewNamespaceEvent(eventType EventType, tenantID uuid.UUID, namespace *Namespace) *Event {
+	event := NewTenantEvent(eventType, tenantID, nil)
+	event.Namespace = namespace
+	return event
+}
+
+var (
+	publisherDB  *gorm.DB
+	publisherMux sync.RWMutex
+)
+
+// Init sets the DB the published events are stored to. Until it is called the events are dropped
+func Init(db *gorm.DB) {
+	publisherMux.Lock()
+	defer publisherMux.Unlock()
+	publisherDB = db
+}
+
+// Publish stores a pending delivery of the event for every subscription interested in the type of the event. The deliveries
+// are sent asynchronously by the Dispatcher so the caller is never blocked nor failed by a webhook.
+func Publish(ctx context.Context, event *Event) {
+	publisherMux.RLock()
+	db := publisherDB
+	publisherMux.RUnlock()
+	if db == nil {
+		return
+	}
+
+	logParams := map[string]interface{}{
+		"event_id":   event.ID,
+		"event_type": event.Type,
+		"tenant_id":  event.TenantID,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		sentry.LogError(ctx, logParams, err, "unable to marshal the webhook event")
+		return
+	}
+
+	repo := NewRepository(db)
+	subscriptions, err := repo.GetSubscriptionsFor(event.Type)
+	if err != nil {
+		sentry.LogError(ctx, logParams, err, "unable to get the webhook subscriptions for the event")
+		return
+	}
+	for _, subscription := range subscriptions {
+		err := repo.AddDelivery(&Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+		})
+		if err != nil {
+			logParams["subscription_id"] = subscription.ID
+			sentry.LogError(ctx, logParams, err, "unable to store the delivery of the webhook event")
+		}
+	}
+	if len(subscriptions) > 0 {
+		log.Debug(ctx, logParams, "webhook event published")
+	}
+}